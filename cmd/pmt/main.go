@@ -5,13 +5,21 @@ import (
 	"context"
 	"fmt"
 	"os"
+	"os/signal"
+	"syscall"
 
 	"github.com/pdok/pdok-metadata-tool/v2/internal/app"
 )
 
 func main() {
-	ctx := context.Background()
-	if err := app.PDOKMetadataToolCLI.Run(ctx, os.Args); err != nil {
+	// Cancel running requests on an interrupt, so a harvest can save its checkpoint
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+
+	err := app.PDOKMetadataToolCLI.Run(ctx, os.Args)
+
+	stop()
+
+	if err != nil {
 		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
 		os.Exit(1)
 	}
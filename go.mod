@@ -8,6 +8,7 @@ require (
 	github.com/ucarion/c14n v0.1.0
 	github.com/urfave/cli-docs/v3 v3.0.0-alpha6
 	github.com/urfave/cli/v3 v3.4.1
+	go.etcd.io/bbolt v1.4.2
 	gopkg.in/yaml.v3 v3.0.1
 )
 
@@ -16,4 +17,5 @@ require (
 	github.com/davecgh/go-spew v1.1.1 // indirect
 	github.com/pmezard/go-difflib v1.0.0 // indirect
 	github.com/russross/blackfriday/v2 v2.1.0 // indirect
+	golang.org/x/sys v0.29.0 // indirect
 )
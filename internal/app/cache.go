@@ -0,0 +1,284 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/pdok/pdok-metadata-tool/v2/internal/common"
+	"github.com/pdok/pdok-metadata-tool/v2/pkg/catalogue"
+	"github.com/pdok/pdok-metadata-tool/v2/pkg/client"
+	"github.com/urfave/cli/v3"
+)
+
+func getStoreCacheCommand() *cli.Command {
+	return &cli.Command{
+		Name:  "cache",
+		Usage: "Manage the cache of harvested XML metadata records.",
+		Commands: []*cli.Command{
+			getStoreCacheStatsCommand(),
+			getStoreCachePruneCommand(),
+			getStoreCacheVerifyCommand(),
+			getStoreCacheExportCommand(),
+		},
+	}
+}
+
+func getStoreCacheStatsCommand() *cli.Command {
+	return &cli.Command{
+		Name:  "stats",
+		Usage: "Shows the number of cached records, their size and an age histogram per endpoint (cache namespace).",
+		Flags: []cli.Flag{
+			flagCachePath,
+		},
+		Action: func(_ context.Context, cmd *cli.Command) error {
+			stats, err := client.GetCacheStats(cmd.String("cache-path"), time.Now())
+			if err != nil {
+				return err
+			}
+
+			printCacheStats(stats)
+
+			return nil
+		},
+	}
+}
+
+func getStoreCachePruneCommand() *cli.Command {
+	return &cli.Command{
+		Name: "prune",
+		Usage: "Removes cached records that were fetched longer ago than --older-than, or that were not part of the last " +
+			"harvest of their endpoint (--not-in-last-harvest). Pruned records are also removed from the catalogue index.",
+		Flags: []cli.Flag{
+			flagCachePath,
+			flagStatePath,
+			flagIndexPath,
+			&cli.DurationFlag{
+				Name:  "older-than",
+				Usage: "Remove records that were fetched longer ago than this duration, e.g. 720h.",
+			},
+			&cli.BoolFlag{
+				Name: "not-in-last-harvest",
+				Usage: "Remove records that are not in the last successful harvest of their endpoint in the harvest state. " +
+					"Endpoints without a harvest state are not affected.",
+			},
+			&cli.BoolFlag{
+				Name:  "dry-run",
+				Usage: "Only list the records that would be removed.",
+			},
+		},
+		Action: func(_ context.Context, cmd *cli.Command) error {
+			options := client.CachePruneOptions{
+				OlderThan: cmd.Duration("older-than"),
+				DryRun:    cmd.Bool("dry-run"),
+			}
+
+			if cmd.Bool("not-in-last-harvest") {
+				state, err := client.LoadHarvestState(harvestStatePath(cmd))
+				if err != nil {
+					return err
+				}
+
+				if len(state.Entries) == 0 {
+					return fmt.Errorf("--not-in-last-harvest needs a harvest state, none found at %s", harvestStatePath(cmd))
+				}
+
+				if options.Keep, err = client.HarvestedIdentifiers(state); err != nil {
+					return err
+				}
+			}
+
+			if options.OlderThan <= 0 && options.Keep == nil {
+				return errors.New("use --older-than and/or --not-in-last-harvest")
+			}
+
+			pruned, err := client.PruneCache(cmd.String("cache-path"), options, time.Now())
+			if err != nil {
+				return err
+			}
+
+			if options.DryRun {
+				for _, record := range pruned {
+					fmt.Printf("%-36s %-40s %s\n", record.UUID, record.Namespace, record.FetchedAt.Format(time.RFC3339))
+				}
+
+				fmt.Printf("Would remove %d records\n", len(pruned))
+
+				return nil
+			}
+
+			if err := removeFromCatalogueIndex(cmd, pruned); err != nil {
+				return err
+			}
+
+			fmt.Printf("Removed %d records from %s\n", len(pruned), cmd.String("cache-path"))
+
+			return nil
+		},
+	}
+}
+
+func getStoreCacheVerifyCommand() *cli.Command {
+	return &cli.Command{
+		Name:  "verify",
+		Usage: "Parses every cached record and reports corrupt records. Exits with an error when corrupt records are found.",
+		Flags: []cli.Flag{
+			flagCachePath,
+			flagIndexPath,
+			&cli.BoolFlag{
+				Name:  "remove",
+				Usage: "Remove corrupt records from the cache and the catalogue index, so they are harvested again.",
+			},
+		},
+		Action: func(_ context.Context, cmd *cli.Command) error {
+			cachePath := cmd.String("cache-path")
+
+			checked, problems, err := client.VerifyCache(cachePath)
+			if err != nil {
+				return err
+			}
+
+			for _, problem := range problems {
+				fmt.Printf("%-36s %-40s %s\n", problem.Record.UUID, problem.Record.Namespace, problem.Err)
+			}
+
+			fmt.Printf("Verified %d records: %d corrupt\n", checked, len(problems))
+
+			if len(problems) == 0 {
+				return nil
+			}
+
+			if !cmd.Bool("remove") {
+				return fmt.Errorf("found %d corrupt records", len(problems))
+			}
+
+			records := make([]client.CachedRecord, 0, len(problems))
+
+			for _, problem := range problems {
+				if err := client.RemoveCachedRecord(cachePath, &problem.Record); err != nil {
+					return err
+				}
+
+				records = append(records, problem.Record)
+			}
+
+			if err := removeFromCatalogueIndex(cmd, records); err != nil {
+				return err
+			}
+
+			fmt.Printf("Removed %d corrupt records\n", len(records))
+
+			return nil
+		},
+	}
+}
+
+func getStoreCacheExportCommand() *cli.Command {
+	return &cli.Command{
+		Name:  "export",
+		Usage: "Exports the cached records and their sidecars to a zip or tar.gz archive with a manifest.json.",
+		Flags: []cli.Flag{
+			flagCachePath,
+			&cli.StringFlag{
+				Name:  "format",
+				Value: client.ExportFormatZip,
+				Usage: "Archive format: 'zip' or 'tar.gz'.",
+			},
+			&cli.StringFlag{
+				Name:  "o",
+				Usage: "Output file path. Defaults to cache-export.<format> in the parent of cache-path.",
+			},
+		},
+		Action: func(_ context.Context, cmd *cli.Command) error {
+			format := cmd.String("format")
+			if format != client.ExportFormatZip && format != client.ExportFormatTarGz {
+				return fmt.Errorf("invalid --format: %s (allowed: %s, %s)",
+					format, client.ExportFormatZip, client.ExportFormatTarGz)
+			}
+
+			outputPath := cmd.String("o")
+			if outputPath == "" {
+				outputPath = filepath.Join(filepath.Dir(cmd.String("cache-path")), "cache-export."+format)
+			}
+
+			if err := os.MkdirAll(filepath.Dir(outputPath), permDir0750); err != nil {
+				return err
+			}
+
+			file, err := os.Create(outputPath)
+			if err != nil {
+				return err
+			}
+			defer common.SafeClose(file)
+
+			manifest, err := client.ExportCache(cmd.String("cache-path"), file, format, time.Now())
+			if err != nil {
+				return err
+			}
+
+			fmt.Printf("Exported %d records to %s\n", len(manifest.Records), outputPath)
+
+			return nil
+		},
+	}
+}
+
+// removeFromCatalogueIndex removes the records from the catalogue index, when there is one.
+func removeFromCatalogueIndex(cmd *cli.Command, records []client.CachedRecord) error {
+	indexPath := catalogueIndexPath(cmd)
+	if _, err := os.Stat(indexPath); os.IsNotExist(err) || len(records) == 0 {
+		return nil
+	}
+
+	index, err := catalogue.Open(indexPath)
+	if err != nil {
+		return err
+	}
+	defer common.SafeClose(index)
+
+	for _, record := range records {
+		if err := index.Remove(record.Namespace, record.UUID); err != nil {
+			return err
+		}
+	}
+
+	slog.Debug("Removed records from the catalogue index", "records", len(records), "index", indexPath)
+
+	return nil
+}
+
+func printCacheStats(stats []client.CacheStats) {
+	if len(stats) == 0 {
+		return
+	}
+
+	header := fmt.Sprintf("%-50s %-8s %-10s", "NAMESPACE", "RECORDS", "SIZE (KB)")
+	for _, bucket := range stats[0].Ages {
+		header += fmt.Sprintf(" %-7s", bucket.Label)
+	}
+
+	fmt.Println(header)
+
+	for _, s := range stats {
+		namespace := s.Namespace
+		if namespace == "" {
+			namespace = "TOTAL"
+		}
+
+		line := fmt.Sprintf("%-50s %-8d %-10d", namespace, s.Records, s.Size/1024) //nolint:mnd
+		for _, bucket := range s.Ages {
+			line += fmt.Sprintf(" %-7d", bucket.Records)
+		}
+
+		fmt.Println(line)
+	}
+
+	if total := stats[len(stats)-1]; total.Records > 0 {
+		fmt.Printf("Oldest record fetched at %s, newest at %s\n",
+			total.Oldest.Format(time.RFC3339), total.Newest.Format(time.RFC3339))
+	}
+}
@@ -0,0 +1,415 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/pdok/pdok-metadata-tool/v2/internal/common"
+	"github.com/pdok/pdok-metadata-tool/v2/pkg/checker"
+	"github.com/pdok/pdok-metadata-tool/v2/pkg/client"
+	"github.com/pdok/pdok-metadata-tool/v2/pkg/model/inspire"
+	"github.com/pdok/pdok-metadata-tool/v2/pkg/model/iso1911x"
+	"github.com/pdok/pdok-metadata-tool/v2/pkg/model/metadata"
+	"github.com/pdok/pdok-metadata-tool/v2/pkg/scoring"
+	"github.com/urfave/cli/v3"
+)
+
+const (
+	defaultLinkConcurrency  = 8
+	defaultLinkTimeoutSecs  = 10
+	defaultHostIntervalMsec = 200
+)
+
+func init() {
+	command := &cli.Command{
+		Name: "check",
+		Usage: "Used to check sets of metadata records, i.e. for broken links, service-dataset coupling, orphans " +
+			"and quality.",
+		Commands: []*cli.Command{
+			getCheckLinksCommand(),
+			getCheckCouplingCommand(),
+			getCheckOrphansCommand(),
+			getCheckQualityCommand(),
+		},
+	}
+	PDOKMetadataToolCLI.Commands = append(PDOKMetadataToolCLI.Commands, command)
+}
+
+func getCheckLinksCommand() *cli.Command {
+	return &cli.Command{
+		Name:  "links",
+		Usage: "Checks all URLs in metadata records (access points, licenses, thumbnails, contact URLs, operatesOn, ...).",
+		Flags: []cli.Flag{
+			flagInputPath,
+			flagInputEndpoint,
+			flagReportOutput,
+			&cli.IntFlag{
+				Name:  "concurrency",
+				Value: defaultLinkConcurrency,
+				Usage: "Number of URLs that are checked concurrently.",
+			},
+			&cli.IntFlag{
+				Name:  "timeout",
+				Value: defaultLinkTimeoutSecs,
+				Usage: "Timeout in seconds for a single request.",
+			},
+			&cli.IntFlag{
+				Name:  "host-interval",
+				Value: defaultHostIntervalMsec,
+				Usage: "Minimum time in milliseconds between two requests to the same host.",
+			},
+			&cli.StringFlag{
+				Name:  "link-cache-path",
+				Value: common.LinkCheckCachePath,
+				Usage: "Path of the file in which link check results are cached.",
+			},
+			&cli.IntFlag{
+				Name:  "link-cache-ttl",
+				Value: DefaultCacheTTLHrs,
+				Usage: "Time-to-live for cached link check results in hours. Use 0 to check all links again.",
+			},
+		},
+		Action: func(ctx context.Context, cmd *cli.Command) error {
+			records, err := loadRecordsFromInput(cmd)
+			if err != nil {
+				return err
+			}
+
+			// The proxy and CA certificates of the --http-* flags apply, but the timeout is --timeout and
+			// links are not retried: a failing link is reported, and HEAD falls back to GET.
+			config, err := httpConfigFromFlags(cmd)
+			if err != nil {
+				return err
+			}
+
+			config.Timeout = time.Duration(cmd.Int("timeout")) * time.Second
+			config.MaxRetries = 0
+
+			httpClient, err := client.NewHTTPClient(config)
+			if err != nil {
+				return err
+			}
+
+			lc := checker.NewLinkChecker(
+				httpClient,
+				cmd.Int("concurrency"),
+				time.Duration(cmd.Int("host-interval"))*time.Millisecond,
+			)
+			lc.CacheTTL = time.Duration(cmd.Int("link-cache-ttl")) * time.Hour
+
+			cachePath := cmd.String("link-cache-path")
+			if err := lc.LoadCache(cachePath); err != nil {
+				return err
+			}
+
+			recordLinks := make([]checker.RecordLinks, 0, len(records))
+			for i := range records {
+				recordLinks = append(recordLinks, checker.ExtractLinks(&records[i].MD, records[i].Data))
+			}
+
+			reports := lc.CheckRecords(ctx, recordLinks)
+
+			if err := os.MkdirAll(filepath.Dir(cachePath), permDir0750); err != nil {
+				return err
+			}
+
+			if err := lc.SaveCache(cachePath); err != nil {
+				return err
+			}
+
+			printLinkReports(reports)
+			printLinkSummaries(checker.SummarizeLinksByOrganisation(reports))
+
+			return writeJSONReport(cmd.String("o"), reports)
+		},
+	}
+}
+
+func getCheckCouplingCommand() *cli.Command {
+	return &cli.Command{
+		Name: "coupling",
+		Usage: "Harvests services and datasets and checks that operatesOn references resolve, that INSPIRE themes " +
+			"and HVD categories match, and that the service bounding box covers its datasets.",
+		Flags: []cli.Flag{
+			flagCswEndpoint,
+			flagCachePath,
+			flagCacheTTL,
+			flagConcurrency,
+			flagRateLimit,
+			flagPageSize,
+			flagHarvestMode,
+			&cli.StringFlag{
+				Name: "filter-org",
+				Usage: "Optional filter on the services by organisation name. Datasets of all organisations " +
+					"are harvested, as services may operate on datasets of other organisations.",
+			},
+			flagHvdURL,
+			flagHvdLocalPath,
+			flagReportOutput,
+		},
+		Action: func(ctx context.Context, cmd *cli.Command) error {
+			services, err := harvestFlat[metadata.NLServiceMetadata](ctx, cmd, iso1911x.Service, cmd.String("filter-org"))
+			if err != nil {
+				return err
+			}
+
+			datasets, err := harvestFlat[metadata.NLDatasetMetadata](ctx, cmd, iso1911x.Dataset, "")
+			if err != nil {
+				return err
+			}
+
+			issues := checker.CheckCoupling(services, datasets)
+			printCouplingIssues(issues)
+			fmt.Printf("Checked %d services against %d datasets: %d issues\n", len(services), len(datasets), len(issues))
+
+			return writeJSONReport(cmd.String("o"), issues)
+		},
+	}
+}
+
+func getCheckOrphansCommand() *cli.Command {
+	return &cli.Command{
+		Name: "orphans",
+		Usage: "Harvests services and datasets and reports datasets without a view, download or OGC API service, " +
+			"and services that operate on no existing dataset.",
+		Flags: []cli.Flag{
+			flagCswEndpoint,
+			flagCachePath,
+			flagCacheTTL,
+			&cli.StringFlag{
+				Name: "filter-org",
+				Usage: "Optional filter on the report by organisation name. Services and datasets of all " +
+					"organisations are harvested, as services may operate on datasets of other organisations.",
+			},
+			flagConcurrency,
+			flagRateLimit,
+			flagPageSize,
+			flagHarvestMode,
+			&cli.StringFlag{
+				Name:  "inspire-variant",
+				Usage: "Optional filter on the datasets by INSPIRE variant: 'harmonised' or 'asis'.",
+			},
+			&cli.StringFlag{
+				Name:  "format",
+				Value: "csv",
+				Usage: "Output format: 'csv' or 'json'.",
+			},
+			&cli.StringFlag{
+				Name:  "o",
+				Usage: "Output file path. Defaults to orphans.<format> in the parent of cache-path.",
+			},
+			flagHvdURL,
+			flagHvdLocalPath,
+		},
+		Action: func(ctx context.Context, cmd *cli.Command) error {
+			filter := checker.OrphanFilter{OrganisationName: cmd.String("filter-org")}
+
+			switch variant := strings.ToUpper(cmd.String("inspire-variant")); variant {
+			case "", string(inspire.Harmonised), string(inspire.AsIs):
+				filter.InspireVariant = inspire.InspireVariant(variant)
+			default:
+				return fmt.Errorf("invalid --inspire-variant: %s (allowed: harmonised, asis)", variant)
+			}
+
+			format := cmd.String("format")
+			if format != "csv" && format != "json" {
+				return fmt.Errorf("invalid --format: %s (allowed: csv, json)", format)
+			}
+
+			// Services and datasets of all organisations are harvested, as services may operate on datasets of
+			// other organisations. The organisation filter only applies to the report.
+			services, err := harvestFlat[metadata.NLServiceMetadata](ctx, cmd, iso1911x.Service, "")
+			if err != nil {
+				return err
+			}
+
+			datasets, err := harvestFlat[metadata.NLDatasetMetadata](ctx, cmd, iso1911x.Dataset, "")
+			if err != nil {
+				return err
+			}
+
+			report := checker.FindOrphans(services, datasets, filter)
+
+			outputPath := cmd.String("o")
+			if outputPath == "" {
+				outputPath = filepath.Join(filepath.Dir(cmd.String("cache-path")), "orphans."+format)
+			}
+
+			if err := os.MkdirAll(filepath.Dir(outputPath), permDir0750); err != nil {
+				return err
+			}
+
+			if format == "json" {
+				if err := writeJSONReport(outputPath, report); err != nil {
+					return err
+				}
+			} else if err := writeOrphansCSV(outputPath, &report); err != nil {
+				return err
+			}
+
+			fmt.Printf("Found %d datasets missing services and %d services without datasets\n",
+				len(report.Datasets), len(report.Services))
+
+			return nil
+		},
+	}
+}
+
+func getCheckQualityCommand() *cli.Command {
+	return &cli.Command{
+		Name: "quality",
+		Usage: "Scores the completeness and quality of metadata records (optional fields, thumbnail, license, " +
+			"controlled keywords, abstract length and revision date) per record and organisation.",
+		Flags: []cli.Flag{
+			flagInputPath,
+			flagInputEndpoint,
+			flagReportOutput,
+			&cli.StringFlag{
+				Name:  "weights",
+				Usage: "Optional path to a YAML file with weights and thresholds, see examples/scoring/weights.yaml.",
+			},
+			&cli.StringFlag{
+				Name:  "html",
+				Usage: "Optional output path for an HTML scorecard.",
+			},
+		},
+		Action: func(_ context.Context, cmd *cli.Command) error {
+			config := scoring.DefaultConfig()
+
+			if path := cmd.String("weights"); path != "" {
+				var err error
+				if config, err = scoring.LoadConfig(path); err != nil {
+					return err
+				}
+			}
+
+			scorer, err := scoring.NewScorer(config)
+			if err != nil {
+				return err
+			}
+
+			mds, err := loadMDMetadataFromInput(cmd)
+			if err != nil {
+				return err
+			}
+
+			records := scorer.ScoreAll(mds)
+			organisations := scoring.AggregateByOrganisation(records)
+			printOrganisationScores(organisations)
+
+			if err := writeScorecardHTML(cmd.String("html"), records, organisations); err != nil {
+				return err
+			}
+
+			return writeJSONReport(cmd.String("o"), struct {
+				Organisations []scoring.OrganisationScore `json:"organisations"`
+				Records       []scoring.RecordScore       `json:"records"`
+			}{organisations, records})
+		},
+	}
+}
+
+func writeScorecardHTML(outputPath string, records []scoring.RecordScore, organisations []scoring.OrganisationScore) error {
+	if outputPath == "" {
+		return nil
+	}
+
+	//nolint:gosec
+	file, err := os.Create(outputPath)
+	if err != nil {
+		return fmt.Errorf("failed to create HTML file: %w", err)
+	}
+	defer common.SafeClose(file)
+
+	if err := scoring.WriteHTML(file, records, organisations); err != nil {
+		return fmt.Errorf("failed to write HTML: %w", err)
+	}
+
+	fmt.Printf("Wrote scorecard to %s\n", outputPath)
+
+	return nil
+}
+
+func printOrganisationScores(organisations []scoring.OrganisationScore) {
+	fmt.Printf("%-40s %-8s %-8s %-8s %-8s\n", "ORGANISATION", "RECORDS", "AVERAGE", "MIN", "MAX")
+
+	repeatCount := 76
+	fmt.Println(strings.Repeat("-", repeatCount))
+
+	maxLength := 40
+	for _, org := range organisations {
+		fmt.Printf("%-40s %-8d %-8.1f %-8.1f %-8.1f\n",
+			common.TruncateString(org.OrganisationName, maxLength),
+			org.Records,
+			org.Average,
+			org.Min,
+			org.Max)
+	}
+}
+
+func writeOrphansCSV(outputPath string, report *checker.OrphanReport) error {
+	//nolint:gosec
+	file, err := os.Create(outputPath)
+	if err != nil {
+		return fmt.Errorf("failed to create CSV file: %w", err)
+	}
+	defer common.SafeClose(file)
+
+	if err := report.WriteCSV(file); err != nil {
+		return fmt.Errorf("failed to write CSV: %w", err)
+	}
+
+	fmt.Printf("Wrote report to %s\n", outputPath)
+
+	return nil
+}
+
+func printLinkReports(reports []checker.RecordLinkReport) {
+	for _, report := range reports {
+		if len(report.Failures) == 0 {
+			continue
+		}
+
+		fmt.Printf("%-36s %s (%s)\n", report.MetadataID, report.Title, report.OrganisationName)
+
+		for _, failure := range report.Failures {
+			status := failure.Result.Error
+			if status == "" {
+				status = fmt.Sprintf("HTTP %d", failure.Result.StatusCode)
+			}
+
+			fmt.Printf("      %-12s %s: %s\n", failure.Source, failure.URL, status)
+		}
+	}
+}
+
+func printLinkSummaries(summaries []checker.OrganisationLinkSummary) {
+	fmt.Printf("\n%-40s %-8s %-8s %-8s\n", "ORGANISATION", "RECORDS", "LINKS", "BROKEN")
+
+	repeatCount := 67
+	fmt.Println(strings.Repeat("-", repeatCount))
+
+	maxLength := 40
+	for _, summary := range summaries {
+		fmt.Printf("%-40s %-8d %-8d %-8d\n",
+			common.TruncateString(summary.OrganisationName, maxLength),
+			summary.Records,
+			summary.Links,
+			summary.Broken)
+	}
+}
+
+func printCouplingIssues(issues []checker.CouplingIssue) {
+	fmt.Printf("%-36s %-24s %-36s %s\n", "SERVICE", "ISSUE", "DATASET", "MESSAGE")
+
+	repeatCount := 140
+	fmt.Println(strings.Repeat("-", repeatCount))
+
+	for _, issue := range issues {
+		fmt.Printf("%-36s %-24s %-36s %s\n", issue.ServiceID, issue.Type, issue.DatasetID, issue.Message)
+	}
+}
@@ -40,6 +40,7 @@ func init() {
 			url := cmd.String("url")
 			localPath := cmd.String("local-path")
 			repo := repository.NewHVDRepository(url, localPath)
+			repo.SetHTTPClient(httpClientFromContext(ctx))
 
 			return context.WithValue(ctx, hvdRepoKey, repo), nil
 		},
@@ -71,7 +72,7 @@ func getHvdDownloadCommand() *cli.Command {
 				return errors.New("failed to get HVDRepository from context")
 			}
 
-			_, err = repo.Download()
+			_, err = repo.Download(ctx)
 
 			return err
 		},
@@ -90,7 +91,7 @@ func getHvdListCommand() *cli.Command {
 				return errors.New("failed to get HVDRepository from context")
 			}
 
-			categories, err := hvdRepo.GetAllHVDCategories()
+			categories, err := hvdRepo.GetAllHVDCategories(ctx)
 			if err != nil {
 				return fmt.Errorf("failed to get HVD categories: %w", err)
 			}
@@ -146,7 +147,7 @@ func getHvdCSVCommand() *cli.Command {
 				return errors.New("failed to get HVDRepository from context")
 			}
 
-			categories, err := hvdRepo.GetAllHVDCategories()
+			categories, err := hvdRepo.GetAllHVDCategories(ctx)
 			if err != nil {
 				return fmt.Errorf("failed to get HVD categories: %w", err)
 			}
@@ -47,7 +47,7 @@ func getInspireListCommand() *cli.Command {
 	return &cli.Command{
 		Name:  "list",
 		Usage: "List inspire themes or layers. Usage: pmt inspire list <theme|layer>",
-		Action: func(_ context.Context, cmd *cli.Command) error {
+		Action: func(ctx context.Context, cmd *cli.Command) error {
 			if cmd.NArg() == 0 {
 				return errSpecifyRegisterKind()
 			}
@@ -59,9 +59,10 @@ func getInspireListCommand() *cli.Command {
 			fmt.Println("list inspire: ", cmd.Args().First())
 
 			repo := repository.NewInspireRepository(common.InspireLocalPath)
+			repo.SetHTTPClient(httpClientFromContext(ctx))
 
 			if cmd.Args().First() == theme {
-				themes, err := repo.GetThemes()
+				themes, err := repo.GetThemes(ctx)
 				if err != nil {
 					return err
 				}
@@ -72,7 +73,7 @@ func getInspireListCommand() *cli.Command {
 			}
 
 			if cmd.Args().First() == layer {
-				layers, err := repo.GetLayers()
+				layers, err := repo.GetLayers(ctx)
 				if err != nil {
 					return err
 				}
@@ -109,7 +110,7 @@ func getInspireCSVCommand() *cli.Command {
 				Usage: "Output file path for the CSV file.",
 			},
 		},
-		Action: func(_ context.Context, cmd *cli.Command) error {
+		Action: func(ctx context.Context, cmd *cli.Command) error {
 			if cmd.NArg() == 0 {
 				return errSpecifyRegisterKind()
 			}
@@ -123,6 +124,7 @@ func getInspireCSVCommand() *cli.Command {
 			fmt.Println("csv inspire: ", choice)
 
 			repo := repository.NewInspireRepository(common.InspireLocalPath)
+			repo.SetHTTPClient(httpClientFromContext(ctx))
 			outputPath := cmd.String("o")
 
 			if outputPath == "" {
@@ -141,7 +143,7 @@ func getInspireCSVCommand() *cli.Command {
 			defer writer.Flush()
 
 			if choice == theme {
-				themes, err := repo.GetThemes()
+				themes, err := repo.GetThemes(ctx)
 				if err != nil {
 					return err
 				}
@@ -170,7 +172,7 @@ func getInspireCSVCommand() *cli.Command {
 			}
 
 			if choice == layer {
-				layers, err := repo.GetLayers()
+				layers, err := repo.GetLayers(ctx)
 				if err != nil {
 					return err
 				}
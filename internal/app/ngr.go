@@ -0,0 +1,1005 @@
+package app
+
+import (
+	"cmp"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/pdok/pdok-metadata-tool/v2/internal/common"
+	"github.com/pdok/pdok-metadata-tool/v2/pkg/client"
+	"github.com/pdok/pdok-metadata-tool/v2/pkg/generator/iso19119"
+	"github.com/pdok/pdok-metadata-tool/v2/pkg/mef"
+	"github.com/pdok/pdok-metadata-tool/v2/pkg/model/ngr"
+	"github.com/urfave/cli/v3"
+)
+
+const ngrClientKey contextKey = "NgrClientKey"
+
+var (
+	flagNgrGroup = &cli.StringFlag{
+		Name:     "group",
+		Usage:    "Name, label or ID of the NGR group.",
+		Required: true,
+	}
+	flagNgrDryRun = &cli.BoolFlag{
+		Name:  "dry-run",
+		Usage: "Only list the selected records.",
+	}
+	flagNgrURL = &cli.StringFlag{
+		Name:  "ngr-url",
+		Value: ngr.NgrURL,
+		Usage: "Base URL of NGR (GeoNetwork).",
+	}
+	flagNgrUser = &cli.StringFlag{
+		Name:    "ngr-user",
+		Usage:   "NGR user name.",
+		Sources: cli.EnvVars("PMT_NGR_USER"),
+	}
+	flagNgrPassword = &cli.StringFlag{
+		Name:    "ngr-password",
+		Usage:   "NGR password.",
+		Sources: cli.EnvVars("PMT_NGR_PASSWORD"),
+	}
+	flagNgrToken = &cli.StringFlag{
+		Name:    "ngr-token",
+		Usage:   "NGR API token, used instead of the user name and password.",
+		Sources: cli.EnvVars("PMT_NGR_TOKEN"),
+	}
+)
+
+func init() {
+	command := &cli.Command{
+		Name: "ngr",
+		Usage: "Manages groups, categories, privileges and attachments of records in NGR. Records are selected by " +
+			"UUID arguments, or with --filter by the records that match a CQL filter in the CSW endpoint.",
+		Flags: []cli.Flag{flagNgrURL, flagNgrUser, flagNgrPassword, flagNgrToken},
+		Before: func(ctx context.Context, cmd *cli.Command) (context.Context, error) {
+			return context.WithValue(ctx, ngrClientKey, newNgrClient(ctx, cmd)), nil
+		},
+		Commands: []*cli.Command{
+			getNgrGroupsCommand(),
+			getNgrCategoriesCommand(),
+			getNgrTagsCommand(),
+			getNgrPrivilegesCommand(),
+			getNgrTransferOwnershipCommand(),
+			getNgrPublishCommand(true),
+			getNgrPublishCommand(false),
+			getNgrAttachmentsCommand(),
+			getNgrUploadServiceCommand(),
+			getNgrImportMEFCommand(),
+			getNgrValidateCommand(),
+		},
+	}
+	PDOKMetadataToolCLI.Commands = append(PDOKMetadataToolCLI.Commands, command)
+}
+
+func getNgrGroupsCommand() *cli.Command {
+	return &cli.Command{
+		Name:  "groups",
+		Usage: "Lists the groups in NGR.",
+		Action: func(ctx context.Context, _ *cli.Command) error {
+			ngrClient, err := ngrClientFromContext(ctx)
+			if err != nil {
+				return err
+			}
+
+			groups, err := ngrClient.GetGroups(ctx)
+			if err != nil {
+				return err
+			}
+
+			fmt.Printf("%-8s %-30s %-40s\n", "ID", "NAME", "LABEL")
+
+			for _, group := range groups {
+				fmt.Printf("%-8d %-30s %-40s\n", group.ID, group.Name, dutchLabel(group.Label))
+			}
+
+			return nil
+		},
+	}
+}
+
+func getNgrCategoriesCommand() *cli.Command {
+	return &cli.Command{
+		Name:  "categories",
+		Usage: "Lists the categories in NGR.",
+		Action: func(ctx context.Context, _ *cli.Command) error {
+			ngrClient, err := ngrClientFromContext(ctx)
+			if err != nil {
+				return err
+			}
+
+			categories, err := ngrClient.GetCategories(ctx)
+			if err != nil {
+				return err
+			}
+
+			printNgrTags(categories)
+
+			return nil
+		},
+	}
+}
+
+func getNgrTagsCommand() *cli.Command {
+	flagTag := &cli.StringFlag{
+		Name:     "tag",
+		Usage:    "Name, label or ID of the tag.",
+		Required: true,
+	}
+
+	tagRecords := func(add bool) cli.ActionFunc {
+		return func(ctx context.Context, cmd *cli.Command) error {
+			ngrClient, uuids, err := selectNgrRecords(ctx, cmd)
+			if err != nil || uuids == nil {
+				return err
+			}
+
+			tag, err := resolveNgrTag(ctx, ngrClient, cmd.String("tag"))
+			if err != nil {
+				return err
+			}
+
+			if add {
+				report, err := ngrClient.AddTagToRecords(ctx, uuids, tag.ID)
+				if err != nil {
+					return err
+				}
+
+				return printProcessingReport("Tagged "+tag.Name+":", report)
+			}
+
+			report, err := ngrClient.RemoveTagFromRecords(ctx, uuids, tag.ID)
+			if err != nil {
+				return err
+			}
+
+			return printProcessingReport("Untagged "+tag.Name+":", report)
+		}
+	}
+
+	return &cli.Command{
+		Name:  "tags",
+		Usage: "Lists tags and adds or removes them on records. In the NGR user interface, tags are called categories.",
+		Commands: []*cli.Command{
+			{
+				Name:  "list",
+				Usage: "Lists the tags in NGR.",
+				Action: func(ctx context.Context, _ *cli.Command) error {
+					ngrClient, err := ngrClientFromContext(ctx)
+					if err != nil {
+						return err
+					}
+
+					tags, err := ngrClient.GetCategories(ctx)
+					if err != nil {
+						return err
+					}
+
+					printNgrTags(tags)
+
+					return nil
+				},
+			},
+			{
+				Name:      "add",
+				Usage:     "Adds a tag to records.",
+				ArgsUsage: "[uuid...]",
+				Flags:     []cli.Flag{flagTag, flagCswEndpoint, flagFilter, flagNgrDryRun},
+				Action:    tagRecords(true),
+			},
+			{
+				Name:      "remove",
+				Usage:     "Removes a tag from records.",
+				ArgsUsage: "[uuid...]",
+				Flags:     []cli.Flag{flagTag, flagCswEndpoint, flagFilter, flagNgrDryRun},
+				Action:    tagRecords(false),
+			},
+		},
+	}
+}
+
+func getNgrPrivilegesCommand() *cli.Command {
+	return &cli.Command{
+		Name:  "privileges",
+		Usage: "Shows or sets the sharing privileges of records per group.",
+		Commands: []*cli.Command{
+			{
+				Name:      "get",
+				Usage:     "Shows the owner and the privileges of a record.",
+				ArgsUsage: "<uuid>",
+				Action: func(ctx context.Context, cmd *cli.Command) error {
+					if cmd.NArg() != 1 {
+						return errors.New("please specify the UUID of a record")
+					}
+
+					ngrClient, err := ngrClientFromContext(ctx)
+					if err != nil {
+						return err
+					}
+
+					sharing, err := ngrClient.GetSharing(ctx, cmd.Args().First())
+					if err != nil {
+						return err
+					}
+
+					printSharing(sharing)
+
+					return nil
+				},
+			},
+			{
+				Name: "set",
+				Usage: "Sets the view, download and dynamic privileges of a group on records. Privileges that are not " +
+					"given are left unchanged.",
+				ArgsUsage: "[uuid...]",
+				Flags: []cli.Flag{
+					flagNgrGroup,
+					&cli.BoolFlag{Name: "view", Usage: "Allow (true) or disallow (false) the group to view the records."},
+					&cli.BoolFlag{Name: "download", Usage: "Allow (true) or disallow (false) the group to download the data."},
+					&cli.BoolFlag{Name: "dynamic", Usage: "Allow (true) or disallow (false) the group to use the services."},
+					flagCswEndpoint,
+					flagFilter,
+					flagNgrDryRun,
+				},
+				Action: func(ctx context.Context, cmd *cli.Command) error {
+					operations := map[ngr.Operation]bool{}
+
+					for _, operation := range []ngr.Operation{ngr.OperationView, ngr.OperationDownload, ngr.OperationDynamic} {
+						if cmd.IsSet(string(operation)) {
+							operations[operation] = cmd.Bool(string(operation))
+						}
+					}
+
+					if len(operations) == 0 {
+						return errors.New("please specify --view, --download and/or --dynamic")
+					}
+
+					ngrClient, uuids, err := selectNgrRecords(ctx, cmd)
+					if err != nil || uuids == nil {
+						return err
+					}
+
+					groupID, err := resolveNgrGroup(ctx, ngrClient, cmd.String("group"))
+					if err != nil {
+						return err
+					}
+
+					privileges := []ngr.GroupPrivileges{{Group: groupID, Operations: operations}}
+
+					for _, uuid := range uuids {
+						if err := ngrClient.SetPrivileges(ctx, uuid, privileges, false); err != nil {
+							return fmt.Errorf("failed to set the privileges of %s: %w", uuid, err)
+						}
+					}
+
+					fmt.Printf("Set the privileges of group %d on %d records.\n", groupID, len(uuids))
+
+					return nil
+				},
+			},
+		},
+	}
+}
+
+func getNgrTransferOwnershipCommand() *cli.Command {
+	return &cli.Command{
+		Name:      "transfer-ownership",
+		Usage:     "Makes a user and a group the owners of records.",
+		ArgsUsage: "[uuid...]",
+		Flags: []cli.Flag{
+			flagNgrGroup,
+			&cli.IntFlag{
+				Name:     "user-id",
+				Usage:    "ID of the NGR user that becomes the owner.",
+				Required: true,
+			},
+			flagCswEndpoint,
+			flagFilter,
+			flagNgrDryRun,
+		},
+		Action: func(ctx context.Context, cmd *cli.Command) error {
+			ngrClient, uuids, err := selectNgrRecords(ctx, cmd)
+			if err != nil || uuids == nil {
+				return err
+			}
+
+			groupID, err := resolveNgrGroup(ctx, ngrClient, cmd.String("group"))
+			if err != nil {
+				return err
+			}
+
+			report, err := ngrClient.TransferOwnership(ctx, uuids, cmd.Int("user-id"), groupID)
+			if err != nil {
+				return err
+			}
+
+			return printProcessingReport("Transferred ownership of", report)
+		},
+	}
+}
+
+func getNgrPublishCommand(publish bool) *cli.Command {
+	name, verb, usage := "publish", "Published", "Publishes records, which allows everyone to view them."
+	if !publish {
+		name, verb, usage = "unpublish", "Unpublished", "Unpublishes records, which disallows everyone to view them."
+	}
+
+	return &cli.Command{
+		Name:      name,
+		Usage:     usage,
+		ArgsUsage: "[uuid...]",
+		Flags: []cli.Flag{
+			flagCswEndpoint,
+			flagFilter,
+			flagNgrDryRun,
+		},
+		Action: func(ctx context.Context, cmd *cli.Command) error {
+			ngrClient, uuids, err := selectNgrRecords(ctx, cmd)
+			if err != nil || uuids == nil {
+				return err
+			}
+
+			var report client.ProcessingReport
+			if publish {
+				report, err = ngrClient.PublishRecords(ctx, uuids)
+			} else {
+				report, err = ngrClient.UnpublishRecords(ctx, uuids)
+			}
+
+			if err != nil {
+				return err
+			}
+
+			return printProcessingReport(verb, report)
+		},
+	}
+}
+
+func getNgrAttachmentsCommand() *cli.Command {
+	return &cli.Command{
+		Name:  "attachments",
+		Usage: "Lists, uploads and deletes the attachments (files) of a record.",
+		Commands: []*cli.Command{
+			{
+				Name:      "list",
+				Usage:     "Lists the attachments of a record.",
+				ArgsUsage: "<uuid>",
+				Action: func(ctx context.Context, cmd *cli.Command) error {
+					if cmd.NArg() != 1 {
+						return errors.New("please specify the UUID of a record")
+					}
+
+					ngrClient, err := ngrClientFromContext(ctx)
+					if err != nil {
+						return err
+					}
+
+					attachments, err := ngrClient.GetAttachments(ctx, cmd.Args().First())
+					if err != nil {
+						return err
+					}
+
+					fmt.Printf("%-30s %-10s %-10s %s\n", "FILENAME", "SIZE", "VISIBILITY", "URL")
+
+					for _, attachment := range attachments {
+						fmt.Printf("%-30s %-10d %-10s %s\n", attachment.Filename, attachment.Size,
+							strings.ToLower(string(attachment.Visibility)), attachment.URL)
+					}
+
+					return nil
+				},
+			},
+			{
+				Name:      "upload",
+				Usage:     "Uploads files as attachments of a record. An attachment with the same file name is replaced.",
+				ArgsUsage: "<uuid> <file...>",
+				Flags: []cli.Flag{
+					&cli.BoolFlag{
+						Name:  "private",
+						Usage: "Only allow users with download privileges to download the attachments.",
+					},
+					&cli.BoolFlag{
+						Name:  "thumbnail",
+						Usage: "Check that the files are images that can be used as thumbnails.",
+					},
+				},
+				Action: func(ctx context.Context, cmd *cli.Command) error {
+					if cmd.NArg() < 2 {
+						return errors.New("please specify the UUID of a record and the files to upload")
+					}
+
+					ngrClient, err := ngrClientFromContext(ctx)
+					if err != nil {
+						return err
+					}
+
+					visibility := ngr.VisibilityPublic
+					if cmd.Bool("private") {
+						visibility = ngr.VisibilityPrivate
+					}
+
+					uuid := cmd.Args().First()
+
+					for _, file := range cmd.Args().Tail() {
+						//nolint:gosec
+						content, err := os.ReadFile(file)
+						if err != nil {
+							return err
+						}
+
+						if cmd.Bool("thumbnail") {
+							if err := client.CheckThumbnail(file, content); err != nil {
+								return err
+							}
+						}
+
+						attachment, err := ngrClient.UploadAttachment(ctx, uuid, filepath.Base(file), content, visibility)
+						if err != nil {
+							return err
+						}
+
+						fmt.Printf("Uploaded %s: %s\n", file, attachment.URL)
+					}
+
+					return nil
+				},
+			},
+			{
+				Name:      "delete",
+				Usage:     "Deletes attachments of a record.",
+				ArgsUsage: "<uuid> <filename...>",
+				Action: func(ctx context.Context, cmd *cli.Command) error {
+					if cmd.NArg() < 2 {
+						return errors.New("please specify the UUID of a record and the file names of the attachments")
+					}
+
+					ngrClient, err := ngrClientFromContext(ctx)
+					if err != nil {
+						return err
+					}
+
+					for _, filename := range cmd.Args().Tail() {
+						if err := ngrClient.DeleteAttachment(ctx, cmd.Args().First(), filename); err != nil {
+							return err
+						}
+
+						fmt.Printf("Deleted %s\n", filename)
+					}
+
+					return nil
+				},
+			},
+		},
+	}
+}
+
+func getNgrUploadServiceCommand() *cli.Command {
+	return &cli.Command{
+		Name: "upload-service",
+		Usage: "Generates service metadata, like generate service, and creates or updates the records in NGR. " +
+			"Thumbnails that refer to local files are uploaded as attachments of the records, and the references " +
+			"are replaced by the URLs of the attachments.",
+		Flags: []cli.Flag{
+			&cli.StringFlag{
+				Name:     "input_file_service_specifics",
+				Required: true,
+				Usage: "Path to input file containing service specifics in json, yml or yaml format. Paths of " +
+					"thumbnails are relative to the directory of this file.",
+			},
+			&cli.StringFlag{
+				Name:  "group",
+				Usage: "Name, label or ID of the NGR group that owns the records.",
+			},
+			&cli.StringFlag{
+				Name:  "category",
+				Usage: "Name, label or ID of the category of the records.",
+			},
+			&cli.BoolFlag{
+				Name:  "publish-to-all",
+				Usage: "Publish the records, which allows everyone to view them.",
+			},
+		},
+		Action: func(ctx context.Context, cmd *cli.Command) error {
+			ngrClient, err := ngrClientFromContext(ctx)
+			if err != nil {
+				return err
+			}
+
+			inputFile := cmd.String("input_file_service_specifics")
+
+			var serviceSpecifics iso19119.ServiceSpecifics
+			if err := serviceSpecifics.LoadFromYamlOrJson(inputFile); err != nil {
+				return err
+			}
+
+			if err := serviceSpecifics.Validate(); err != nil {
+				return err
+			}
+
+			generator, err := iso19119.NewGenerator(serviceSpecifics, "", nil, nil)
+			if err != nil {
+				return err
+			}
+
+			records, err := generator.GenerateAsStrings()
+			if err != nil {
+				return err
+			}
+
+			// The thumbnails are checked before any record is created, so an invalid thumbnail does not leave
+			// a (published) record that refers to a local file
+			thumbnails := make(map[string][]client.Thumbnail, len(records))
+			for uuid, record := range records {
+				if thumbnails[uuid], err = client.ReadThumbnails(record, filepath.Dir(inputFile)); err != nil {
+					return fmt.Errorf("invalid thumbnail of %s: %w", uuid, err)
+				}
+			}
+
+			var groupID, categoryID *string
+
+			if group := cmd.String("group"); group != "" {
+				id, err := resolveNgrGroup(ctx, ngrClient, group)
+				if err != nil {
+					return err
+				}
+
+				groupID = common.Ptr(strconv.Itoa(id))
+			}
+
+			if category := cmd.String("category"); category != "" {
+				tag, err := resolveNgrTag(ctx, ngrClient, category)
+				if err != nil {
+					return err
+				}
+
+				categoryID = common.Ptr(strconv.Itoa(tag.ID))
+			}
+
+			for _, uuid := range slices.Sorted(maps.Keys(records)) {
+				record := records[uuid]
+
+				// The record is created first, because attachments can only be added to an existing record
+				err := ngrClient.CreateOrUpdateServiceMetadataRecord(ctx, record, categoryID, groupID,
+					cmd.Bool("publish-to-all"))
+				if err != nil {
+					return fmt.Errorf("failed to upload %s: %w", uuid, err)
+				}
+
+				uploaded, err := ngrClient.UploadThumbnails(ctx, uuid, record, thumbnails[uuid])
+				if err != nil {
+					return err
+				}
+
+				if uploaded != record {
+					err = ngrClient.CreateOrUpdateServiceMetadataRecord(ctx, uploaded, categoryID, groupID,
+						cmd.Bool("publish-to-all"))
+					if err != nil {
+						return fmt.Errorf("failed to update the thumbnails of %s: %w", uuid, err)
+					}
+				}
+
+				fmt.Printf("Uploaded %s\n", uuid)
+			}
+
+			return nil
+		},
+	}
+}
+
+func getNgrImportMEFCommand() *cli.Command {
+	return &cli.Command{
+		Name: "import-mef",
+		Usage: "Creates or updates the records of MEF archives (Metadata Exchange Format) in NGR, with their " +
+			"categories, privileges and attachments. Categories and groups are matched by name; those that do not " +
+			"exist in NGR are skipped with a warning.",
+		ArgsUsage: "<file.mef...>",
+		Flags: []cli.Flag{
+			&cli.StringFlag{
+				Name:  "group",
+				Usage: "Name, label or ID of the NGR group that owns the records.",
+			},
+			flagNgrDryRun,
+		},
+		Action: func(ctx context.Context, cmd *cli.Command) error {
+			if cmd.NArg() == 0 {
+				return errors.New("please specify the MEF archives to import")
+			}
+
+			ngrClient, err := ngrClientFromContext(ctx)
+			if err != nil {
+				return err
+			}
+
+			var records []mef.Record
+
+			for _, file := range cmd.Args().Slice() {
+				archived, err := mef.ReadFile(file)
+				if err != nil {
+					return fmt.Errorf("%s: %w", file, err)
+				}
+
+				records = append(records, archived...)
+			}
+
+			if cmd.Bool("dry-run") {
+				for _, record := range records {
+					fmt.Printf("%s (%d categories, %d groups, %d public and %d private attachments)\n", record.UUID(),
+						len(record.Info.Categories), len(record.Info.Privileges), len(record.Public), len(record.Private))
+				}
+
+				fmt.Printf("Selected %d records.\n", len(records))
+
+				return nil
+			}
+
+			var groupID *string
+
+			if group := cmd.String("group"); group != "" {
+				id, err := resolveNgrGroup(ctx, ngrClient, group)
+				if err != nil {
+					return err
+				}
+
+				groupID = common.Ptr(strconv.Itoa(id))
+			}
+
+			if err := ngrClient.ImportMEF(ctx, records, groupID); err != nil {
+				return err
+			}
+
+			fmt.Printf("Imported %d records.\n", len(records))
+
+			return nil
+		},
+	}
+}
+
+// ngrValidationReport is the JSON report of ngr validate, which also serves as baseline.
+type ngrValidationReport struct {
+	NumberOfRecords           int                      `json:"numberOfRecords"`
+	NumberOfRecordsProcessed  int                      `json:"numberOfRecordsProcessed"`
+	NumberOfRecordsWithErrors int                      `json:"numberOfRecordsWithErrors"`
+	Issues                    []client.ValidationIssue `json:"issues"`
+}
+
+func getNgrValidateCommand() *cli.Command {
+	return &cli.Command{
+		Name: "validate",
+		Usage: "Validates records in NGR and shows the errors per record and per rule. Fails when there are errors " +
+			"that are not in the --baseline, so it can be used in CI.",
+		ArgsUsage: "[uuid...]",
+		Flags: []cli.Flag{
+			flagCswEndpoint,
+			flagFilter,
+			flagNgrDryRun,
+			&cli.StringFlag{
+				Name:  "format",
+				Value: "json",
+				Usage: "Format of the report at -o: 'csv' or 'json'.",
+			},
+			&cli.StringFlag{
+				Name:  "o",
+				Usage: "Optional output file path for the report.",
+			},
+			&cli.StringFlag{
+				Name:  "baseline",
+				Usage: "Optional path of a JSON report of a previous validation. Only errors that are not in it fail the command.",
+			},
+			&cli.BoolFlag{
+				Name:  "update-baseline",
+				Usage: "Write the report to --baseline instead of comparing with it.",
+			},
+		},
+		Action: func(ctx context.Context, cmd *cli.Command) error {
+			format := cmd.String("format")
+			if format != "csv" && format != "json" {
+				return fmt.Errorf("invalid --format: %s (allowed: csv, json)", format)
+			}
+
+			baselinePath := cmd.String("baseline")
+			if cmd.Bool("update-baseline") && baselinePath == "" {
+				return errors.New("--update-baseline requires --baseline")
+			}
+
+			ngrClient, uuids, err := selectNgrRecords(ctx, cmd)
+			if err != nil || cmd.Bool("dry-run") {
+				return err
+			}
+
+			// In CI, validating nothing must not pass as validating without new errors
+			if len(uuids) == 0 {
+				return errors.New("no records match the filter, so no records were validated")
+			}
+
+			result, err := ngrClient.ValidateRecords(ctx, uuids)
+			if err != nil {
+				return err
+			}
+
+			if result.NumberOfRecordsProcessed == 0 {
+				return fmt.Errorf("NGR validated none of the %d selected records", len(uuids))
+			}
+
+			report := ngrValidationReport{
+				NumberOfRecords:           result.NumberOfRecords,
+				NumberOfRecordsProcessed:  result.NumberOfRecordsProcessed,
+				NumberOfRecordsWithErrors: result.NumberOfRecordsWithErrors,
+				Issues:                    result.Issues(),
+			}
+
+			printValidationIssues(report)
+
+			if format == "json" {
+				err = writeJSONReport(cmd.String("o"), report)
+			} else {
+				err = writeValidationCSV(cmd.String("o"), report.Issues)
+			}
+
+			if err != nil {
+				return err
+			}
+
+			if cmd.Bool("update-baseline") {
+				return writeJSONReport(baselinePath, report)
+			}
+
+			var baseline ngrValidationReport
+
+			if baselinePath != "" {
+				//nolint:gosec
+				data, err := os.ReadFile(baselinePath)
+				if err != nil {
+					return fmt.Errorf("failed to read baseline (create it with --update-baseline): %w", err)
+				}
+
+				if err := json.Unmarshal(data, &baseline); err != nil {
+					return fmt.Errorf("failed to parse baseline %s: %w", baselinePath, err)
+				}
+			}
+
+			newIssues := client.NewValidationIssues(report.Issues, baseline.Issues)
+			if len(newIssues) == 0 {
+				return nil
+			}
+
+			if baselinePath != "" {
+				fmt.Printf("\nNew errors compared with %s:\n", baselinePath)
+
+				for _, issue := range newIssues {
+					fmt.Printf("  %-36s %s\n", issue.UUID, issue.Rule)
+				}
+			}
+
+			return fmt.Errorf("%d validation errors that are not in the baseline", len(newIssues))
+		},
+	}
+}
+
+// printValidationIssues prints the errors per record and the number of records per rule.
+func printValidationIssues(report ngrValidationReport) {
+	records := map[string]int{}
+
+	for _, issue := range report.Issues {
+		if records[issue.UUID] == 0 {
+			fmt.Println(issue.UUID)
+		}
+
+		records[issue.UUID]++
+		fmt.Printf("      %s\n", issue.Rule)
+	}
+
+	rules := map[string]int{}
+	for _, issue := range report.Issues {
+		rules[issue.Rule]++
+	}
+
+	if len(rules) > 0 {
+		fmt.Printf("\n%-8s %s\n", "RECORDS", "RULE")
+
+		for _, rule := range slices.SortedFunc(maps.Keys(rules), func(a, b string) int {
+			return cmp.Or(rules[b]-rules[a], strings.Compare(a, b))
+		}) {
+			fmt.Printf("%-8d %s\n", rules[rule], rule)
+		}
+	}
+
+	fmt.Printf("Validated %d of %d records, %d with errors (%d errors).\n", report.NumberOfRecordsProcessed,
+		report.NumberOfRecords, len(records), len(report.Issues))
+}
+
+func writeValidationCSV(outputPath string, issues []client.ValidationIssue) error {
+	if outputPath == "" {
+		return nil
+	}
+
+	//nolint:gosec
+	file, err := os.Create(outputPath)
+	if err != nil {
+		return fmt.Errorf("failed to create CSV file: %w", err)
+	}
+	defer common.SafeClose(file)
+
+	writer := csv.NewWriter(file)
+	_ = writer.Write([]string{"uuid", "rule"})
+
+	for _, issue := range issues {
+		_ = writer.Write([]string{issue.UUID, issue.Rule})
+	}
+
+	writer.Flush()
+
+	if err := writer.Error(); err != nil {
+		return fmt.Errorf("failed to write CSV: %w", err)
+	}
+
+	fmt.Printf("Wrote report to %s\n", outputPath)
+
+	return nil
+}
+
+// newNgrClient creates an NgrClient with the NGR flags and the HTTP client of the context.
+func newNgrClient(ctx context.Context, cmd *cli.Command) *client.NgrClient {
+	ngrURL := strings.TrimSuffix(cmd.String("ngr-url"), "/")
+	user, password, token := cmd.String("ngr-user"), cmd.String("ngr-password"), cmd.String("ngr-token")
+
+	ngrClient := client.NewNgrClient(client.NgrConfig{
+		NgrUrl:      &ngrURL,
+		NgrUserName: &user,
+		NgrPassword: &password,
+		NgrToken:    &token,
+	})
+	ngrClient.SetHTTPClient(httpClientFromContext(ctx))
+
+	return &ngrClient
+}
+
+func ngrClientFromContext(ctx context.Context) (*client.NgrClient, error) {
+	ngrClient, ok := ctx.Value(ngrClientKey).(*client.NgrClient)
+	if !ok {
+		return nil, errors.New("failed to get NgrClient from context")
+	}
+
+	return ngrClient, nil
+}
+
+// selectNgrRecords returns the client and the UUIDs of the records given as arguments or selected with --filter.
+// With --dry-run, the records are listed and no UUIDs are returned.
+func selectNgrRecords(ctx context.Context, cmd *cli.Command) (*client.NgrClient, []string, error) {
+	ngrClient, err := ngrClientFromContext(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	uuids := cmd.Args().Slice()
+
+	filter, err := parseFilterFlag(cmd)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	switch {
+	case filter != nil && len(uuids) > 0:
+		return nil, nil, errors.New("use either UUID arguments or --filter")
+	case filter != nil:
+		u, err := url.Parse(cmd.String("csw-endpoint"))
+		if err != nil {
+			return nil, nil, err
+		}
+
+		cswClient := client.NewCswClient(u)
+		cswClient.SetHTTPClient(httpClientFromContext(ctx))
+
+		records, err := cswClient.GetAllRecordsByFilter(ctx, filter)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		for _, record := range records {
+			uuids = append(uuids, record.Identifier)
+		}
+	case len(uuids) == 0:
+		return nil, nil, errors.New("please specify the UUIDs of records or a --filter")
+	}
+
+	slices.Sort(uuids)
+	uuids = slices.Compact(uuids)
+
+	if cmd.Bool("dry-run") {
+		for _, uuid := range uuids {
+			fmt.Println(uuid)
+		}
+
+		fmt.Printf("Selected %d records.\n", len(uuids))
+
+		return ngrClient, nil, nil
+	}
+
+	return ngrClient, uuids, nil
+}
+
+// resolveNgrGroup returns the ID of a group given by ID, name or label.
+func resolveNgrGroup(ctx context.Context, ngrClient *client.NgrClient, group string) (int, error) {
+	if id, err := strconv.Atoi(group); err == nil {
+		return id, nil
+	}
+
+	resolved, err := ngrClient.GetGroupByName(ctx, group)
+	if err != nil {
+		return 0, err
+	}
+
+	return resolved.ID, nil
+}
+
+// resolveNgrTag returns the tag given by ID, name or label.
+func resolveNgrTag(ctx context.Context, ngrClient *client.NgrClient, tag string) (ngr.Tag, error) {
+	if id, err := strconv.Atoi(tag); err == nil {
+		return ngr.Tag{ID: id, Name: tag}, nil
+	}
+
+	return ngrClient.GetCategoryByName(ctx, tag)
+}
+
+func printNgrTags(tags []ngr.Tag) {
+	fmt.Printf("%-8s %-30s %-40s\n", "ID", "NAME", "LABEL")
+
+	for _, tag := range tags {
+		fmt.Printf("%-8d %-30s %-40s\n", tag.ID, tag.Name, dutchLabel(tag.Label))
+	}
+}
+
+func printSharing(sharing ngr.Sharing) {
+	fmt.Printf("Owner: %s, group owner: %s\n", sharing.Owner, sharing.GroupOwner)
+	fmt.Printf("%-8s %-6s %-9s %-8s %-8s\n", "GROUP", "VIEW", "DOWNLOAD", "DYNAMIC", "EDITING")
+
+	for _, privileges := range sharing.Privileges {
+		operations := privileges.Operations
+		if !slices.Contains(
+			[]bool{operations[ngr.OperationView], operations[ngr.OperationDownload], operations[ngr.OperationDynamic],
+				operations[ngr.OperationEditing]}, true) {
+			continue
+		}
+
+		fmt.Printf("%-8d %-6t %-9t %-8t %-8t\n", privileges.Group, operations[ngr.OperationView],
+			operations[ngr.OperationDownload], operations[ngr.OperationDynamic], operations[ngr.OperationEditing])
+	}
+}
+
+// printProcessingReport prints the outcome of a bulk operation, and returns an error when records failed.
+func printProcessingReport(verb string, report client.ProcessingReport) error {
+	fmt.Printf("%s %d of %d records (%d not found, %d not editable, %d with errors).\n", verb,
+		report.NumberOfRecordsProcessed, report.NumberOfRecords, report.NumberOfRecordNotFound,
+		report.NumberOfRecordsNotEditable, report.NumberOfRecordsWithErrors)
+
+	for id, errs := range report.MetadataErrors {
+		for _, err := range errs {
+			fmt.Printf("  %s: %s\n", id, err.Message)
+		}
+	}
+
+	if report.NumberOfRecordsWithErrors > 0 || report.NumberOfRecordNotFound > 0 || report.NumberOfRecordsNotEditable > 0 {
+		return fmt.Errorf("%d of %d records were not processed",
+			report.NumberOfRecords-report.NumberOfRecordsProcessed, report.NumberOfRecords)
+	}
+
+	return nil
+}
+
+func dutchLabel(labels map[string]string) string {
+	if label, ok := labels["dut"]; ok {
+		return label
+	}
+
+	return labels["eng"]
+}
@@ -0,0 +1,32 @@
+package app
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestNgrValidateWithoutRecords(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("request") != "GetRecords" {
+			rw.WriteHeader(http.StatusNotFound)
+
+			return
+		}
+
+		_, _ = fmt.Fprint(rw, `<csw:GetRecordsResponse xmlns:csw="http://www.opengis.net/cat/csw/2.0.2">`+
+			`<csw:SearchResults numberOfRecordsMatched="0" nextRecord="0"></csw:SearchResults></csw:GetRecordsResponse>`)
+	}))
+	t.Cleanup(server.Close)
+
+	args := []string{
+		"pmt", "ngr", "--ngr-url", server.URL, "validate",
+		"--csw-endpoint", server.URL, "--filter", "keyword = 'unknown'",
+	}
+
+	err := PDOKMetadataToolCLI.Run(t.Context(), args)
+	require.ErrorContains(t, err, "no records were validated")
+}
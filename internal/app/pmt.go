@@ -2,13 +2,19 @@ package app
 
 import (
 	"context"
+	"fmt"
 	"log/slog"
+	"net/http"
+	"net/url"
 	"os"
 	"strings"
 
+	"github.com/pdok/pdok-metadata-tool/v2/pkg/client"
 	"github.com/urfave/cli/v3"
 )
 
+const httpClientKey contextKey = "HTTPClientKey"
+
 // PDOKMetadataToolCLI contains the logic of the PDOK Metadata Tool CLI.
 var PDOKMetadataToolCLI = &cli.Command{
 	Name:                  "pmt",
@@ -20,6 +26,24 @@ var PDOKMetadataToolCLI = &cli.Command{
 			Usage: "Set log level: debug, info, warn, error (env: PMT_LOG_LEVEL)",
 			Value: "info",
 		},
+		&cli.DurationFlag{
+			Name:  "http-timeout",
+			Usage: "Timeout of a single HTTP request attempt; 0 disables the timeout",
+			Value: client.DefaultHTTPTimeout,
+		},
+		&cli.IntFlag{
+			Name:  "http-retries",
+			Usage: "Number of retries of a failed HTTP request (network errors, 429 and 5xx responses)",
+			Value: client.DefaultHTTPConfig().MaxRetries,
+		},
+		&cli.StringFlag{
+			Name:  "http-proxy",
+			Usage: "Proxy URL for all HTTP requests (default: from HTTPS_PROXY/HTTP_PROXY)",
+		},
+		&cli.StringFlag{
+			Name:  "ca-cert",
+			Usage: "PEM file with CA certificates to trust in addition to the system certificates",
+		},
 	},
 	Before: func(ctx context.Context, cmd *cli.Command) (context.Context, error) {
 		// Allow env var override
@@ -31,10 +55,61 @@ var PDOKMetadataToolCLI = &cli.Command{
 		handler := slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: lvl})
 		slog.SetDefault(slog.New(handler))
 
-		return ctx, nil
+		httpClient, err := newHTTPClient(cmd)
+		if err != nil {
+			return ctx, err
+		}
+
+		return context.WithValue(ctx, httpClientKey, httpClient), nil
 	},
 }
 
+// newHTTPClient creates the HTTP client that is shared by all commands from the --http-* flags.
+func newHTTPClient(cmd *cli.Command) (*http.Client, error) {
+	config, err := httpConfigFromFlags(cmd)
+	if err != nil {
+		return nil, err
+	}
+
+	return client.NewHTTPClient(config)
+}
+
+// httpConfigFromFlags returns the HTTP configuration of the --http-* and --ca-cert flags.
+func httpConfigFromFlags(cmd *cli.Command) (client.HTTPConfig, error) {
+	config := client.DefaultHTTPConfig()
+	config.Timeout = cmd.Duration("http-timeout")
+	config.MaxRetries = cmd.Int("http-retries")
+	config.CACertFile = cmd.String("ca-cert")
+
+	if config.Timeout < 0 {
+		return config, fmt.Errorf("invalid --http-timeout: %s (allowed: 0 or more)", config.Timeout)
+	}
+
+	if config.MaxRetries < 0 {
+		return config, fmt.Errorf("invalid --http-retries: %d (allowed: 0 or more)", config.MaxRetries)
+	}
+
+	if proxy := cmd.String("http-proxy"); proxy != "" {
+		proxyURL, err := url.Parse(proxy)
+		if err != nil || proxyURL.Host == "" {
+			return config, fmt.Errorf("invalid --http-proxy: %s (allowed: an absolute URL)", proxy)
+		}
+
+		config.Proxy = proxyURL
+	}
+
+	return config, nil
+}
+
+// httpClientFromContext returns the HTTP client of the root command, or a client with the default configuration.
+func httpClientFromContext(ctx context.Context) *http.Client {
+	if httpClient, ok := ctx.Value(httpClientKey).(*http.Client); ok {
+		return httpClient
+	}
+
+	return client.DefaultHTTPClient()
+}
+
 func parseLogLevel(s string) slog.Leveler {
 	switch strings.ToLower(strings.TrimSpace(s)) {
 	case "debug":
@@ -3,18 +3,28 @@ package app
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"log/slog"
 	"net/url"
 	"os"
 	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/pdok/pdok-metadata-tool/v2/internal/common"
+	"github.com/pdok/pdok-metadata-tool/v2/pkg/catalogue"
 	"github.com/pdok/pdok-metadata-tool/v2/pkg/client"
+	"github.com/pdok/pdok-metadata-tool/v2/pkg/mef"
 	"github.com/pdok/pdok-metadata-tool/v2/pkg/model/csw"
 	"github.com/pdok/pdok-metadata-tool/v2/pkg/model/hvd"
 	"github.com/pdok/pdok-metadata-tool/v2/pkg/model/iso1911x"
 	"github.com/pdok/pdok-metadata-tool/v2/pkg/model/metadata"
 	"github.com/pdok/pdok-metadata-tool/v2/pkg/model/ngr"
+	"github.com/pdok/pdok-metadata-tool/v2/pkg/model/oaipmh"
+	"github.com/pdok/pdok-metadata-tool/v2/pkg/model/ogcrecords"
 	"github.com/pdok/pdok-metadata-tool/v2/pkg/repository"
 	"github.com/urfave/cli/v3"
 )
@@ -29,7 +39,7 @@ var (
 	flagCachePath = &cli.StringFlag{
 		Name:  "cache-path",
 		Value: common.MetadataCachePath,
-		Usage: "Local path where raw CSW metadata records (XML) are cached.",
+		Usage: "Local path where raw metadata records (XML) are cached, in a directory per endpoint.",
 	}
 	flagCacheTTL = &cli.IntFlag{
 		Name:  "cache-ttl",
@@ -44,6 +54,42 @@ var (
 		Name:  "filter-type",
 		Usage: "Optional filter by metadata type: 'service' or 'dataset'. If omitted, all types are harvested.",
 	}
+	flagFilter = &cli.StringFlag{
+		Name: "filter",
+		Usage: "Optional CQL filter, combined with the other filters, e.g. " +
+			"\"keyword = 'inspire' AND Modified DURING '2024-01-01/..'\". Supports AND, OR, NOT, =, <>, <, <=, >, >=, " +
+			"LIKE, BETWEEN, DURING 'from/to' and BBOX(ows:BoundingBox, west, south, east, north). " +
+			"Quote values with single quotes and write a quote in a value as ''.",
+	}
+	flagStatePath = &cli.StringFlag{
+		Name:  "state-path",
+		Usage: "Path of the file with the last successful harvest per endpoint and filter. Defaults to harvest-state.json in the parent of cache-path.",
+	}
+	flagIndexPath = &cli.StringFlag{
+		Name:  "index-path",
+		Usage: "Path of the catalogue index of harvested records. Defaults to catalogue-index.db in the parent of cache-path.",
+	}
+	flagConcurrency = &cli.IntFlag{
+		Name:  "concurrency",
+		Value: DefaultHarvestConcurrency,
+		Usage: "Number of records that are retrieved concurrently while harvesting.",
+	}
+	flagRateLimit = &cli.FloatFlag{
+		Name:  "rate-limit",
+		Value: DefaultHarvestRateLimit,
+		Usage: "Maximum number of requests per second to the CSW endpoint. Use 0 for no limit.",
+	}
+	flagPageSize = &cli.IntFlag{
+		Name:  "page-size",
+		Value: DefaultHarvestPageSize,
+		Usage: "Number of records requested per GetRecords page.",
+	}
+	flagHarvestMode = &cli.StringFlag{
+		Name:  "harvest-mode",
+		Value: string(client.HarvestByRecordID),
+		Usage: "How full records are harvested: 'record' pages through summaries and retrieves every record by ID " +
+			"(reusing cached records), 'page' retrieves full records directly from the GetRecords pages.",
+	}
 	// HVD repository flags used to enrich HVD categories in flat outputs
 	flagHvdURL = &cli.StringFlag{
 		Name:        "hvd-url",
@@ -58,10 +104,34 @@ var (
 	}
 )
 
+// Values for the --source flag of store harvest.
 const (
-	DefaultCacheTTLHrs = 168
-	permDir0750        = 0o750
-	permFile0600       = 0o600
+	sourceCSW    = "csw"
+	sourceOaiPmh = "oai-pmh"
+	// sourceOgcRecords harvests the ISO 19139 XML of OGC API Records via alternate links.
+	sourceOgcRecords = "ogcapi-records"
+)
+
+// Values for the --source and --format flags of store export.
+const (
+	sourceCache     = "cache"
+	sourceNGR       = "ngr"
+	exportFormatMEF = "mef"
+)
+
+// Values for the --output flag of store query.
+const (
+	outputTable = "table"
+	outputJSON  = "json"
+)
+
+const (
+	DefaultCacheTTLHrs        = 168
+	DefaultHarvestConcurrency = 4
+	DefaultHarvestRateLimit   = 10
+	DefaultHarvestPageSize    = 50
+	permDir0750               = 0o750
+	permFile0600              = 0o600
 )
 
 func init() {
@@ -70,16 +140,101 @@ func init() {
 		Usage: "The store is used to interact with metadata CSW store service.",
 		Commands: []*cli.Command{
 			{
-				Name:  "harvest",
-				Usage: "Harvest original XML metadata records from a CSW source using optional CQL filters. Records are cached on disk for inspection and reuse.",
+				Name: "harvest",
+				Usage: "Harvest original XML metadata records from a CSW source using optional CQL filters, or from an OAI-PMH or OGC API Records source (--source). Records are cached on disk for inspection and reuse. " +
+					"After a first harvest, only records modified since the last successful harvest are retrieved, and deleted records are removed from the cache.",
 				Flags: []cli.Flag{
+					&cli.StringFlag{
+						Name:  "source",
+						Value: sourceCSW,
+						Usage: "Type of the harvest source: 'csw', 'oai-pmh' or 'ogcapi-records'.",
+					},
 					flagCswEndpoint,
+					&cli.StringFlag{
+						Name:  "oai-pmh-endpoint",
+						Usage: "OAI-PMH base URL, used with --source oai-pmh.",
+					},
+					&cli.StringFlag{
+						Name:  "oai-pmh-set",
+						Usage: "Optional OAI-PMH set to harvest, used with --source oai-pmh.",
+					},
+					&cli.StringFlag{
+						Name:  "oai-pmh-metadata-prefix",
+						Value: oaipmh.DefaultMetadataPrefix,
+						Usage: "OAI-PMH metadata prefix of the ISO 19139 records, used with --source oai-pmh.",
+					},
+					&cli.StringFlag{
+						Name:  "records-endpoint",
+						Usage: "OGC API Records landing page URL, used with --source ogcapi-records.",
+					},
+					&cli.StringFlag{
+						Name:  "records-collection",
+						Usage: "OGC API Records collection to harvest, used with --source ogcapi-records.",
+					},
+					&cli.StringFlag{
+						Name:  "records-filter",
+						Usage: "Optional CQL2 text filter, used with --source ogcapi-records.",
+					},
+					&cli.StringFlag{
+						Name:  "records-bbox",
+						Usage: "Optional bounding box 'west,south,east,north' in longitude/latitude, used with --source ogcapi-records.",
+					},
+					&cli.StringFlag{
+						Name:  "records-datetime",
+						Usage: "Optional datetime instant or interval, e.g. '2024-01-01T00:00:00Z/..', used with --source ogcapi-records.",
+					},
 					flagCachePath,
 					flagCacheTTL,
 					flagFilterType,
 					flagFilterOrg,
+					flagFilter,
+					&cli.StringFlag{
+						Name: "tag",
+						Usage: "Only harvest the records with this NGR tag (name, label or ID), e.g. inspire. The CSW " +
+							"request is narrowed down to the tag (GeoNetwork category), after which the tags of every " +
+							"remaining record are verified with the NGR API at --ngr-url: one extra request per record. " +
+							"Requires --harvest-mode record.",
+					},
+					&cli.StringFlag{
+						Name:  "ngr-url",
+						Value: ngr.NgrURL,
+						Usage: "Base URL of NGR (GeoNetwork), to read the tags of records for --tag.",
+					},
+					flagConcurrency,
+					flagRateLimit,
+					flagPageSize,
+					flagHarvestMode,
+					&cli.BoolFlag{
+						Name:  "full",
+						Usage: "Harvest all records instead of only the records modified since the last successful harvest.",
+					},
+					&cli.BoolFlag{
+						Name:  "resume",
+						Usage: "Resume an interrupted harvest from the checkpoint file, retrieving only the records that were not harvested yet.",
+					},
+					&cli.BoolFlag{
+						Name:  "retry-failed",
+						Usage: "Retrieve only the records that failed in the harvest of the checkpoint file.",
+					},
+					&cli.StringFlag{
+						Name:  "checkpoint-path",
+						Usage: "Path of the checkpoint file of a harvest in progress. Defaults to harvest-checkpoint.json in the parent of cache-path.",
+					},
+					flagStatePath,
+					flagIndexPath,
 				},
-				Action: func(_ context.Context, cmd *cli.Command) error {
+				Action: func(ctx context.Context, cmd *cli.Command) error {
+					switch source := cmd.String("source"); source {
+					case sourceCSW:
+					case sourceOaiPmh:
+						return harvestOaiPmh(ctx, cmd)
+					case sourceOgcRecords:
+						return harvestOgcRecords(ctx, cmd)
+					default:
+						return fmt.Errorf("invalid --source: %s (allowed: %s, %s, %s)",
+							source, sourceCSW, sourceOaiPmh, sourceOgcRecords)
+					}
+
 					cswEndpoint := cmd.String("csw-endpoint")
 
 					u, err := url.Parse(cswEndpoint)
@@ -88,10 +243,19 @@ func init() {
 					}
 
 					cswClient := client.NewCswClient(u)
+					cswClient.SetHTTPClient(httpClientFromContext(ctx))
 
 					cachePath := cmd.String("cache-path")
 					cacheTTL := cmd.Int("cache-ttl")
 					cswClient.SetCache(cachePath, cacheTTL)
+					if err := configureHarvest(cmd, &cswClient); err != nil {
+						return err
+					}
+
+					tagFilter, err := configureTagSelection(ctx, cmd, &cswClient)
+					if err != nil {
+						return err
+					}
 
 					// Build CQL constraint from flags
 					var constraint csw.GetRecordsCQLConstraint
@@ -116,17 +280,56 @@ func init() {
 						constraint.OrganisationName = &org
 					}
 
-					mds, err := cswClient.HarvestByCQLConstraint(&constraint)
+					filter, err := parseFilterFlag(cmd)
 					if err != nil {
 						return err
 					}
 
-					fmt.Printf(
-						"Harvested %d records. Cached XML in %s (TTL %d hours).\n",
-						len(mds),
-						cachePath,
-						cacheTTL,
-					)
+					switch {
+					case tagFilter == nil:
+						constraint.Filter = filter
+					case filter == nil:
+						constraint.Filter = tagFilter
+					default:
+						constraint.Filter = csw.And{Filters: []csw.Filter{filter, tagFilter}}
+					}
+
+					checkpointPath := cmd.String("checkpoint-path")
+					if checkpointPath == "" {
+						checkpointPath = filepath.Join(filepath.Dir(cachePath), "harvest-checkpoint.json")
+					}
+
+					cswClient.SetCheckpointPath(checkpointPath)
+
+					if cmd.Bool("resume") || cmd.Bool("retry-failed") {
+						return resumeHarvest(ctx, cmd, &cswClient, u, checkpointPath)
+					}
+
+					statePath := harvestStatePath(cmd)
+
+					state, err := client.LoadHarvestState(statePath)
+					if err != nil {
+						return err
+					}
+
+					result, err := cswClient.HarvestIncremental(ctx, &constraint, state, cmd.Bool("full"))
+					if err != nil {
+						return err
+					}
+
+					if err := state.Save(statePath); err != nil {
+						return err
+					}
+
+					if err := updateCatalogueIndex(cmd, u, result.Records, result.Removed); err != nil {
+						return err
+					}
+
+					printHarvestResult(cmd, &result)
+
+					if len(result.Failures) > 0 {
+						fmt.Printf("Use --retry-failed to retrieve the failed records again (checkpoint: %s).\n", checkpointPath)
+					}
 
 					return nil
 				},
@@ -139,11 +342,17 @@ func init() {
 					flagCachePath,
 					flagCacheTTL,
 					flagFilterOrg,
+					flagFilter,
+					flagConcurrency,
+					flagRateLimit,
+					flagPageSize,
+					flagHarvestMode,
 					flagHvdURL,
 					flagHvdLocalPath,
 				},
-				Action: func(_ context.Context, cmd *cli.Command) error {
+				Action: func(ctx context.Context, cmd *cli.Command) error {
 					return harvestFlatToFile[metadata.NLServiceMetadata](
+						ctx,
 						cmd,
 						iso1911x.Service,
 						"service-metadata",
@@ -159,11 +368,17 @@ func init() {
 					flagCachePath,
 					flagCacheTTL,
 					flagFilterOrg,
+					flagFilter,
+					flagConcurrency,
+					flagRateLimit,
+					flagPageSize,
+					flagHarvestMode,
 					flagHvdURL,
 					flagHvdLocalPath,
 				},
-				Action: func(_ context.Context, cmd *cli.Command) error {
+				Action: func(ctx context.Context, cmd *cli.Command) error {
 					return harvestFlatToFile[metadata.NLDatasetMetadata](
+						ctx,
 						cmd,
 						iso1911x.Dataset,
 						"dataset-metadata",
@@ -171,6 +386,65 @@ func init() {
 					)
 				},
 			},
+			getStoreCacheCommand(),
+			getStoreExportCommand(),
+			{
+				Name: "query",
+				Usage: "Query the catalogue index of harvested records. The index is updated by store harvest; " +
+					"use store reindex to rebuild it from the cached XML records.",
+				Flags: []cli.Flag{
+					flagCachePath,
+					flagIndexPath,
+					&cli.StringFlag{
+						Name: "where",
+						Usage: "Optional CQL filter on the index, e.g. \"OrganisationName = 'Kadaster' AND Type = 'dataset'\". " +
+							"Supports the operators of --filter on the properties " + strings.Join(catalogue.PropertyNames(), ", ") +
+							". A BBOX must be in longitude/latitude (EPSG:4326).",
+					},
+					&cli.StringFlag{
+						Name:  "bbox",
+						Usage: "Optional bounding box 'west,south,east,north' in longitude/latitude; only records that intersect are returned.",
+					},
+					&cli.StringFlag{
+						Name:  "output",
+						Value: outputTable,
+						Usage: "Output format: 'table' or 'json'.",
+					},
+				},
+				Action: func(_ context.Context, cmd *cli.Command) error {
+					return queryCatalogue(cmd)
+				},
+			},
+			{
+				Name:  "reindex",
+				Usage: "Rebuild the catalogue index from the cached XML records.",
+				Flags: []cli.Flag{
+					flagCachePath,
+					flagIndexPath,
+				},
+				Action: func(_ context.Context, cmd *cli.Command) error {
+					indexPath := catalogueIndexPath(cmd)
+
+					index, err := catalogue.Open(indexPath)
+					if err != nil {
+						return err
+					}
+					defer common.SafeClose(index)
+
+					count, errs, err := index.Rebuild(cmd.String("cache-path"))
+					if err != nil {
+						return err
+					}
+
+					for _, err := range errs {
+						slog.Warn("Error indexing record", "err", err)
+					}
+
+					fmt.Printf("Indexed %d records (%d skipped) in %s\n", count, len(errs), indexPath)
+
+					return nil
+				},
+			},
 		},
 	}
 	PDOKMetadataToolCLI.Commands = append(PDOKMetadataToolCLI.Commands, command)
@@ -179,62 +453,606 @@ func init() {
 // harvestFlatToFile centralizes the shared logic for harvesting flat models (service/dataset),
 // marshalling them to JSON, and writing the output to a file under the parent of cache-path.
 func harvestFlatToFile[T any](
+	ctx context.Context,
 	cmd *cli.Command,
 	mt iso1911x.MetadataType,
 	outBase string,
 	summaryLabel string,
 ) error {
+	res, err := harvestFlat[T](ctx, cmd, mt, cmd.String("filter-org"))
+	if err != nil {
+		return err
+	}
+
+	// Marshal to pretty JSON
+	b, err := json.MarshalIndent(res, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	// Determine output file under parent dir of cachePath
+	cachePath := cmd.String("cache-path")
+	parentDir := filepath.Dir(cachePath)
+	org := cmd.String("filter-org")
+	norm := common.NormalizeForFilename(org)
+
+	outPath := filepath.Join(parentDir, fmt.Sprintf("%s-%s.json", outBase, norm))
+	if err := os.MkdirAll(parentDir, permDir0750); err != nil {
+		return err
+	}
+
+	if err := os.WriteFile(outPath, b, permFile0600); err != nil {
+		return err
+	}
+
+	fmt.Printf("Wrote %d %s to %s\n", len(res), summaryLabel, outPath)
+
+	return nil
+}
+
+// harvestFlat harvests flat models (service/dataset) of the given type, using the CSW, cache and HVD flags.
+// An empty org harvests the records of all organisations.
+func harvestFlat[T any](ctx context.Context, cmd *cli.Command, mt iso1911x.MetadataType, org string) ([]T, error) {
 	// Init repository and propagate cache
 	cswEndpoint := cmd.String("csw-endpoint")
 
 	repo, err := repository.NewMetadataRepository(cswEndpoint)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	cachePath := cmd.String("cache-path")
-	cacheTTL := cmd.Int("cache-ttl")
-	repo.SetCache(cachePath, cacheTTL)
+	repo.CswClient.SetHTTPClient(httpClientFromContext(ctx))
+	repo.SetCache(cmd.String("cache-path"), cmd.Int("cache-ttl"))
+	if err := configureHarvest(cmd, repo.CswClient); err != nil {
+		return nil, err
+	}
 
 	// Configure HVD Repository for enrichment
 	hvdRepo := repository.NewHVDRepository(cmd.String("hvd-url"), cmd.String("hvd-local-path"))
+	hvdRepo.SetHTTPClient(httpClientFromContext(ctx))
 	repo.SetHVDRepo(hvdRepo)
 
 	// Build constraint with static MetadataType and optional org filter
 	var constraint csw.GetRecordsCQLConstraint
 
 	constraint.MetadataType = &mt
-	if org := cmd.String("filter-org"); org != "" {
+	if org != "" {
 		constraint.OrganisationName = &org
 	}
 
+	if constraint.Filter, err = parseFilterFlag(cmd); err != nil {
+		return nil, err
+	}
+
 	// Harvest using generic repo method
-	res, err := repository.HarvestByCQLConstraint[T](repo, &constraint)
+	return repository.HarvestByCQLConstraint[T](ctx, repo, &constraint)
+}
+
+// parseFilterFlag parses the --filter flag, if given.
+func parseFilterFlag(cmd *cli.Command) (csw.Filter, error) {
+	cql := cmd.String("filter")
+	if cql == "" {
+		return nil, nil //nolint:nilnil
+	}
+
+	filter, err := csw.ParseCQL(cql)
+	if err != nil {
+		return nil, fmt.Errorf("invalid --filter: %w", err)
+	}
+
+	return filter, nil
+}
+
+// configureHarvest applies the concurrency, rate limit, page size and harvest mode flags to the CSW client.
+func configureHarvest(cmd *cli.Command, cswClient *client.CswClient) error {
+	mode := client.HarvestMode(cmd.String("harvest-mode"))
+	if mode != client.HarvestByRecordID && mode != client.HarvestByPage {
+		return fmt.Errorf("invalid --harvest-mode: %s (allowed: record, page)", mode)
+	}
+
+	cswClient.SetHarvestMode(mode)
+	cswClient.SetConcurrency(cmd.Int("concurrency"))
+	cswClient.SetRateLimit(cmd.Float("rate-limit"))
+	cswClient.SetPageSize(cmd.Int("page-size"))
+
+	return nil
+}
+
+// harvestOaiPmh harvests the records of an OAI-PMH repository into the cache, incrementally after a first harvest.
+func harvestOaiPmh(ctx context.Context, cmd *cli.Command) error {
+	if err := checkUnsupportedHarvestFlags(cmd, sourceOaiPmh); err != nil {
+		return err
+	}
+
+	endpoint := cmd.String("oai-pmh-endpoint")
+	if endpoint == "" {
+		return fmt.Errorf("--oai-pmh-endpoint is required with --source %s", sourceOaiPmh)
+	}
+
+	u, err := url.Parse(endpoint)
 	if err != nil {
 		return err
 	}
 
-	// Marshal to pretty JSON
-	b, err := json.MarshalIndent(res, "", "  ")
+	oaiClient := client.NewOaiPmhClient(u)
+	oaiClient.SetHTTPClient(httpClientFromContext(ctx))
+	oaiClient.SetCache(cmd.String("cache-path"), cmd.Int("cache-ttl"))
+	oaiClient.SetRateLimit(cmd.Float("rate-limit"))
+	oaiClient.SetMetadataPrefix(cmd.String("oai-pmh-metadata-prefix"))
+
+	statePath := harvestStatePath(cmd)
+
+	state, err := client.LoadHarvestState(statePath)
 	if err != nil {
 		return err
 	}
 
-	// Determine output file under parent dir of cachePath
-	parentDir := filepath.Dir(cachePath)
-	org := cmd.String("filter-org")
-	norm := common.NormalizeForFilename(org)
+	result, err := oaiClient.HarvestIncremental(ctx, oaipmh.Selection{Set: cmd.String("oai-pmh-set")}, state, cmd.Bool("full"))
+	if err != nil {
+		return err
+	}
 
-	outPath := filepath.Join(parentDir, fmt.Sprintf("%s-%s.json", outBase, norm))
-	if err := os.MkdirAll(parentDir, permDir0750); err != nil {
+	if err := state.Save(statePath); err != nil {
 		return err
 	}
 
-	if err := os.WriteFile(outPath, b, permFile0600); err != nil {
+	if err := updateCatalogueIndex(cmd, u, result.Records, result.Removed); err != nil {
 		return err
 	}
 
-	fmt.Printf("Wrote %d %s to %s\n", len(res), summaryLabel, outPath)
+	printHarvestResult(cmd, &result)
+
+	return nil
+}
+
+// harvestOgcRecords harvests the ISO 19139 XML of the records of an OGC API Records collection into the cache.
+func harvestOgcRecords(ctx context.Context, cmd *cli.Command) error {
+	if err := checkUnsupportedHarvestFlags(cmd, sourceOgcRecords); err != nil {
+		return err
+	}
+
+	endpoint, collection := cmd.String("records-endpoint"), cmd.String("records-collection")
+	if endpoint == "" || collection == "" {
+		return fmt.Errorf("--records-endpoint and --records-collection are required with --source %s", sourceOgcRecords)
+	}
+
+	u, err := url.Parse(endpoint)
+	if err != nil {
+		return err
+	}
+
+	query := ogcrecords.ItemsQuery{
+		Limit:    cmd.Int("page-size"),
+		Datetime: cmd.String("records-datetime"),
+		Filter:   cmd.String("records-filter"),
+	}
+
+	if bbox := cmd.String("records-bbox"); bbox != "" {
+		if query.BBox, err = parseBBox(bbox); err != nil {
+			return fmt.Errorf("invalid --records-bbox: %w", err)
+		}
+	}
+
+	recordsClient := client.NewOgcRecordsClient(u, collection)
+	recordsClient.SetHTTPClient(httpClientFromContext(ctx))
+	recordsClient.SetCache(cmd.String("cache-path"), cmd.Int("cache-ttl"))
+	recordsClient.SetRateLimit(cmd.Float("rate-limit"))
+
+	result, err := recordsClient.HarvestISORecords(ctx, query)
+	if err != nil {
+		return err
+	}
+
+	for _, failure := range result.Failures {
+		slog.Warn("Error retrieving record", "identifier", failure.Identifier, "title", failure.Title, "err", failure.Err)
+	}
+
+	if err := updateCatalogueIndex(cmd, u, result.Records, nil); err != nil {
+		return err
+	}
+
+	fmt.Printf("Harvested %d records (%d without ISO 19139 XML, %d failed). Cached XML in %s (TTL %d hours).\n",
+		len(result.Records), len(result.WithoutISO), len(result.Failures), cmd.String("cache-path"), cmd.Int("cache-ttl"))
+
+	return nil
+}
+
+// configureTagSelection makes the CSW client only harvest the records with the tag of the --tag flag, if given.
+// It returns the filter that narrows the records down in the CSW request; the tags of the remaining records
+// are verified with the NGR API, a request per record.
+func configureTagSelection(ctx context.Context, cmd *cli.Command, cswClient *client.CswClient) (csw.Filter, error) {
+	if cmd.String("tag") == "" {
+		return nil, nil
+	}
+
+	if client.HarvestMode(cmd.String("harvest-mode")) != client.HarvestByRecordID {
+		return nil, fmt.Errorf("--tag is not supported with --harvest-mode %s", cmd.String("harvest-mode"))
+	}
+
+	ngrURL := strings.TrimSuffix(cmd.String("ngr-url"), "/")
+	ngrClient := client.NewNgrClient(client.NgrConfig{NgrUrl: &ngrURL})
+	ngrClient.SetHTTPClient(httpClientFromContext(ctx))
+
+	tag, err := resolveNgrTag(ctx, &ngrClient, cmd.String("tag"))
+	if err != nil {
+		return nil, err
+	}
+
+	cswClient.SetRecordSelector("&tag="+strconv.Itoa(tag.ID),
+		func(ctx context.Context, records []csw.SummaryRecord) ([]csw.SummaryRecord, error) {
+			uuids := make([]string, 0, len(records))
+			for _, record := range records {
+				uuids = append(uuids, record.Identifier)
+			}
+
+			tagged, err := ngrClient.RecordsWithTag(ctx, uuids, tag.ID)
+			if err != nil {
+				return nil, err
+			}
+
+			taggedSet := make(map[string]bool, len(tagged))
+			for _, uuid := range tagged {
+				taggedSet[uuid] = true
+			}
+
+			return slices.DeleteFunc(records, func(record csw.SummaryRecord) bool {
+				return !taggedSet[record.Identifier]
+			}), nil
+		})
+
+	slog.Info("Harvesting only records with tag", "tag", tag.Name, "id", tag.ID)
+
+	return client.TagFilter(tag), nil
+}
+
+// checkUnsupportedHarvestFlags returns an error when flags that only apply to CSW are used with another source.
+func checkUnsupportedHarvestFlags(cmd *cli.Command, source string) error {
+	for _, name := range []string{"filter-type", "filter-org", "filter", "tag", "resume", "retry-failed"} {
+		if cmd.IsSet(name) {
+			return fmt.Errorf("--%s is not supported with --source %s", name, source)
+		}
+	}
 
 	return nil
 }
+
+// parseBBox parses a bounding box 'west,south,east,north'.
+func parseBBox(value string) (*[4]float64, error) {
+	parts := strings.Split(value, ",")
+	if len(parts) != len([4]float64{}) {
+		return nil, fmt.Errorf("expected west,south,east,north, got %q", value)
+	}
+
+	var bbox [4]float64
+
+	for i, part := range parts {
+		v, err := strconv.ParseFloat(strings.TrimSpace(part), 64)
+		if err != nil {
+			return nil, err
+		}
+
+		bbox[i] = v
+	}
+
+	if bbox[0] > bbox[2] || bbox[1] > bbox[3] {
+		return nil, errors.New("west must not exceed east and south must not exceed north")
+	}
+
+	return &bbox, nil
+}
+
+// harvestStatePath returns the --state-path flag, defaulting to harvest-state.json in the parent of cache-path.
+func harvestStatePath(cmd *cli.Command) string {
+	if statePath := cmd.String("state-path"); statePath != "" {
+		return statePath
+	}
+
+	return filepath.Join(filepath.Dir(cmd.String("cache-path")), "harvest-state.json")
+}
+
+// catalogueIndexPath returns the --index-path flag, defaulting to catalogue-index.db in the parent of cache-path.
+func catalogueIndexPath(cmd *cli.Command) string {
+	if indexPath := cmd.String("index-path"); indexPath != "" {
+		return indexPath
+	}
+
+	return filepath.Join(filepath.Dir(cmd.String("cache-path")), "catalogue-index.db")
+}
+
+// updateCatalogueIndex adds the records harvested from an endpoint to the catalogue index and removes the
+// records that were removed from the cache. Records that cannot be indexed are logged and skipped.
+func updateCatalogueIndex(cmd *cli.Command, endpoint *url.URL, mds []iso1911x.MDMetadata, removed []string) error {
+	index, err := catalogue.Open(catalogueIndexPath(cmd))
+	if err != nil {
+		return err
+	}
+	defer common.SafeClose(index)
+
+	namespace := client.CacheNamespace(endpoint)
+
+	skipped, err := index.Add(namespace, mds...)
+	if err != nil {
+		return err
+	}
+
+	for _, err := range skipped {
+		slog.Warn("Error indexing record", "err", err)
+	}
+
+	return index.Remove(namespace, removed...)
+}
+
+// printHarvestResult prints the outcome of an incremental harvest.
+func printHarvestResult(cmd *cli.Command, result *client.IncrementalHarvestResult) {
+	if result.Incremental {
+		fmt.Printf("Harvested records modified since %s.\n", result.Since.Format(time.RFC3339))
+	}
+
+	fmt.Printf(
+		"Harvested %d records (%d added, %d updated, %d deleted of which %d removed from the cache, %d unchanged, "+
+			"%d failed). Cached XML in %s (TTL %d hours).\n",
+		len(result.Records),
+		len(result.Added),
+		len(result.Updated),
+		len(result.Deleted),
+		len(result.Removed),
+		result.Unchanged,
+		len(result.Failures),
+		cmd.String("cache-path"),
+		cmd.Int("cache-ttl"),
+	)
+}
+
+// resumeHarvest continues the harvest of the checkpoint, or retries its failed records.
+// The harvest state is not updated, so the next harvest again retrieves the records modified since
+// the last complete harvest.
+func resumeHarvest(
+	ctx context.Context,
+	cmd *cli.Command,
+	cswClient *client.CswClient,
+	endpoint *url.URL,
+	checkpointPath string,
+) error {
+	if cmd.Bool("resume") && cmd.Bool("retry-failed") {
+		return errors.New("use either --resume or --retry-failed")
+	}
+
+	checkpoint, err := client.LoadHarvestCheckpoint(checkpointPath)
+	if err != nil {
+		return err
+	}
+
+	var (
+		mds      []iso1911x.MDMetadata
+		failures []client.HarvestFailure
+	)
+
+	if cmd.Bool("resume") {
+		mds, failures, err = cswClient.ResumeHarvest(ctx, checkpoint)
+	} else {
+		mds, failures, err = cswClient.RetryFailed(ctx, checkpoint)
+	}
+
+	if err != nil {
+		return err
+	}
+
+	if err := updateCatalogueIndex(cmd, endpoint, mds, nil); err != nil {
+		return err
+	}
+
+	fmt.Printf("Harvested %d records (%d failed). Cached XML in %s.\n", len(mds), len(failures), cmd.String("cache-path"))
+
+	if len(failures) > 0 {
+		fmt.Printf("Use --retry-failed to retrieve the failed records again (checkpoint: %s).\n", checkpointPath)
+	}
+
+	return nil
+}
+
+// queryCatalogue prints the entries of the catalogue index that match --where and --bbox.
+func queryCatalogue(cmd *cli.Command) error {
+	output := cmd.String("output")
+	if output != outputTable && output != outputJSON {
+		return fmt.Errorf("invalid --output: %s (allowed: %s, %s)", output, outputTable, outputJSON)
+	}
+
+	var filters []csw.Filter
+
+	if where := cmd.String("where"); where != "" {
+		filter, err := csw.ParseCQL(where)
+		if err != nil {
+			return fmt.Errorf("invalid --where: %w", err)
+		}
+
+		filters = append(filters, filter)
+	}
+
+	if value := cmd.String("bbox"); value != "" {
+		bbox, err := parseBBox(value)
+		if err != nil {
+			return fmt.Errorf("invalid --bbox: %w", err)
+		}
+
+		filters = append(filters, csw.BBox{West: bbox[0], South: bbox[1], East: bbox[2], North: bbox[3]})
+	}
+
+	var filter csw.Filter
+
+	switch len(filters) {
+	case 0:
+	case 1:
+		filter = filters[0]
+	default:
+		filter = csw.And{Filters: filters}
+	}
+
+	index, err := catalogue.OpenReadOnly(catalogueIndexPath(cmd))
+	if err != nil {
+		return err
+	}
+	defer common.SafeClose(index)
+
+	entries, err := index.Query(filter)
+	if err != nil {
+		return err
+	}
+
+	count, err := index.Count()
+	if err != nil {
+		return err
+	}
+
+	if output == outputJSON {
+		if entries == nil {
+			entries = []catalogue.Entry{}
+		}
+
+		data, err := json.MarshalIndent(entries, "", "  ")
+		if err != nil {
+			return err
+		}
+
+		fmt.Println(string(data))
+
+		return nil
+	}
+
+	fmt.Printf("%-36s %-40s %-8s %-30s %s\n", "METADATA ID", "NAMESPACE", "TYPE", "ORGANISATION", "TITLE")
+
+	for _, entry := range entries {
+		fmt.Printf("%-36s %-40s %-8s %-30s %s\n",
+			entry.MetadataID, entry.Namespace, entry.Type, entry.OrganisationName, entry.Title)
+	}
+
+	fmt.Printf("Found %d of %d records\n", len(entries), count)
+
+	return nil
+}
+
+func getStoreExportCommand() *cli.Command {
+	return &cli.Command{
+		Name: "export",
+		Usage: "Exports records to a MEF archive (Metadata Exchange Format), which can be imported with ngr import-mef " +
+			"or in GeoNetwork. Records are exported from the cache, or from NGR with their categories, privileges " +
+			"and attachments. Records are selected by UUID arguments; without arguments, all cached records of " +
+			"--csw-endpoint are exported, or with --filter the records that match a CQL filter in the CSW endpoint.",
+		ArgsUsage: "[uuid...]",
+		Flags: []cli.Flag{
+			&cli.StringFlag{
+				Name:  "source",
+				Value: sourceCache,
+				Usage: "Source of the records: 'cache' or 'ngr'.",
+			},
+			&cli.StringFlag{
+				Name:  "format",
+				Value: exportFormatMEF,
+				Usage: "Archive format: 'mef'.",
+			},
+			&cli.StringFlag{
+				Name:  "o",
+				Usage: "Output file path. Defaults to export.<format> in the parent of cache-path.",
+			},
+			flagCachePath,
+			flagCswEndpoint,
+			flagFilter,
+			flagNgrURL,
+			flagNgrUser,
+			flagNgrPassword,
+			flagNgrToken,
+		},
+		Action: func(ctx context.Context, cmd *cli.Command) error {
+			if format := cmd.String("format"); format != exportFormatMEF {
+				return fmt.Errorf("invalid --format: %s (allowed: %s)", format, exportFormatMEF)
+			}
+
+			var (
+				records []mef.Record
+				err     error
+			)
+
+			switch source := cmd.String("source"); source {
+			case sourceCache:
+				records, err = exportCachedRecords(cmd)
+			case sourceNGR:
+				records, err = exportNgrRecords(ctx, cmd)
+			default:
+				return fmt.Errorf("invalid --source: %s (allowed: %s, %s)", source, sourceCache, sourceNGR)
+			}
+
+			if err != nil {
+				return err
+			}
+
+			outputPath := cmd.String("o")
+			if outputPath == "" {
+				outputPath = filepath.Join(filepath.Dir(cmd.String("cache-path")), "export."+exportFormatMEF)
+			}
+
+			if err := os.MkdirAll(filepath.Dir(outputPath), permDir0750); err != nil {
+				return err
+			}
+
+			if err := mef.WriteFile(outputPath, records); err != nil {
+				return err
+			}
+
+			fmt.Printf("Exported %d records to %s\n", len(records), outputPath)
+
+			return nil
+		},
+	}
+}
+
+// exportCachedRecords returns the cached records of the CSW endpoint that are given as arguments, or else all.
+func exportCachedRecords(cmd *cli.Command) ([]mef.Record, error) {
+	if cmd.IsSet("filter") {
+		return nil, fmt.Errorf("--filter is not supported with --source %s", sourceCache)
+	}
+
+	endpoint, err := url.Parse(cmd.String("csw-endpoint"))
+	if err != nil {
+		return nil, err
+	}
+
+	cached, err := client.ListCachedRecords(cmd.String("cache-path"))
+	if err != nil {
+		return nil, err
+	}
+
+	namespace := client.CacheNamespace(endpoint)
+	uuids := cmd.Args().Slice()
+
+	var records []mef.Record
+
+	for _, record := range cached {
+		if record.Namespace != namespace || (len(uuids) > 0 && !slices.Contains(uuids, record.UUID)) {
+			continue
+		}
+
+		mefRecord, err := client.CachedMEFRecord(cmd.String("cache-path"), record)
+		if err != nil {
+			return nil, err
+		}
+
+		records = append(records, mefRecord)
+	}
+
+	for _, uuid := range uuids {
+		if !slices.ContainsFunc(records, func(r mef.Record) bool { return r.UUID() == uuid }) {
+			return nil, fmt.Errorf("record %s is not in the cache of %s", uuid, endpoint)
+		}
+	}
+
+	return records, nil
+}
+
+// exportNgrRecords returns the records that are given as arguments or selected with --filter from NGR.
+func exportNgrRecords(ctx context.Context, cmd *cli.Command) ([]mef.Record, error) {
+	ngrClient, uuids, err := selectNgrRecords(context.WithValue(ctx, ngrClientKey, newNgrClient(ctx, cmd)), cmd)
+	if err != nil {
+		return nil, err
+	}
+
+	return ngrClient.ExportMEF(ctx, uuids)
+}
@@ -0,0 +1,298 @@
+package app
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"maps"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/pdok/pdok-metadata-tool/v2/internal/common"
+	"github.com/pdok/pdok-metadata-tool/v2/pkg/client"
+	"github.com/pdok/pdok-metadata-tool/v2/pkg/model/csw"
+	"github.com/pdok/pdok-metadata-tool/v2/pkg/model/iso1911x"
+	"github.com/pdok/pdok-metadata-tool/v2/pkg/model/ngr"
+	"github.com/pdok/pdok-metadata-tool/v2/pkg/repository"
+	"github.com/pdok/pdok-metadata-tool/v2/pkg/validator"
+	"github.com/urfave/cli/v3"
+)
+
+var (
+	flagInputPath = &cli.StringFlag{
+		Name:  "input",
+		Value: common.MetadataCachePath,
+		Usage: "Path to a metadata record (XML) or a directory with records, i.e. generated output or the harvest cache.",
+	}
+	flagInputEndpoint = &cli.StringFlag{
+		Name:  "csw-endpoint",
+		Value: ngr.NgrEndpoint,
+		Usage: "Endpoint of the CSW service whose records are read when --input is the harvest cache. Default is NGR.",
+	}
+	flagReportOutput = &cli.StringFlag{
+		Name:  "o",
+		Usage: "Optional output file path for the full report as JSON.",
+	}
+)
+
+func init() {
+	command := &cli.Command{
+		Name:  "validate",
+		Usage: "Used to check metadata records against INSPIRE and HVD requirements.",
+		Commands: []*cli.Command{
+			getValidateInspireCommand(),
+			getValidateHvdCommand(),
+		},
+	}
+	PDOKMetadataToolCLI.Commands = append(PDOKMetadataToolCLI.Commands, command)
+}
+
+func getValidateInspireCommand() *cli.Command {
+	return &cli.Command{
+		Name:  "inspire",
+		Usage: "Checks records against the INSPIRE metadata Technical Guidelines 2.0 and reports per requirement.",
+		Flags: []cli.Flag{
+			flagInputPath,
+			flagInputEndpoint,
+			flagReportOutput,
+		},
+		Action: func(_ context.Context, cmd *cli.Command) error {
+			mds, err := loadMDMetadataFromInput(cmd)
+			if err != nil {
+				return err
+			}
+
+			inspireValidator, err := validator.NewInspireValidator()
+			if err != nil {
+				return err
+			}
+
+			reports := inspireValidator.ValidateAll(mds)
+			printRecordReports(reports)
+			printOrganisationSummaries(validator.SummarizeByOrganisation(reports))
+
+			return writeJSONReport(cmd.String("o"), reports)
+		},
+	}
+}
+
+func getValidateHvdCommand() *cli.Command {
+	return &cli.Command{
+		Name: "hvd",
+		Usage: "Checks records with HVD categories against Implementing Regulation 2023/138 and the Dutch " +
+			"HVD guidelines. Datasets are coupled to the services among the checked records.",
+		Flags: []cli.Flag{
+			flagInputPath,
+			flagInputEndpoint,
+			flagReportOutput,
+			flagHvdURL,
+			flagHvdLocalPath,
+		},
+		Action: func(ctx context.Context, cmd *cli.Command) error {
+			mds, err := loadMDMetadataFromInput(cmd)
+			if err != nil {
+				return err
+			}
+
+			hvdRepo := repository.NewHVDRepository(cmd.String("hvd-url"), cmd.String("hvd-local-path"))
+			hvdRepo.SetHTTPClient(httpClientFromContext(ctx))
+
+			// Load the thesaurus up front, so a failed download is not reported as unknown categories
+			if _, err := hvdRepo.GetAllHVDCategories(ctx); err != nil {
+				return err
+			}
+
+			hvdValidator, err := validator.NewHVDValidator(hvdRepo)
+			if err != nil {
+				return err
+			}
+
+			reports := hvdValidator.ValidateAll(mds)
+			printRecordReports(reports)
+			printOrganisationSummaries(validator.SummarizeByOrganisation(reports))
+
+			return writeJSONReport(cmd.String("o"), reports)
+		},
+	}
+}
+
+// localRecord is a metadata record read from disk, together with its raw XML.
+type localRecord struct {
+	Path string
+	Data []byte
+	MD   iso1911x.MDMetadata
+}
+
+// loadMDMetadataFromInput reads the records of the --input and --csw-endpoint flags, see loadRecordsFromPath.
+func loadMDMetadataFromInput(cmd *cli.Command) ([]iso1911x.MDMetadata, error) {
+	records, err := loadRecordsFromInput(cmd)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]iso1911x.MDMetadata, 0, len(records))
+	for _, record := range records {
+		result = append(result, record.MD)
+	}
+
+	return result, nil
+}
+
+// loadRecordsFromInput is like loadMDMetadataFromInput, but also keeps the raw XML of each record.
+func loadRecordsFromInput(cmd *cli.Command) ([]localRecord, error) {
+	endpoint, err := url.Parse(cmd.String("csw-endpoint"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid --csw-endpoint: %w", err)
+	}
+
+	return loadRecordsFromPath(cmd.String("input"), client.CacheNamespace(endpoint))
+}
+
+// loadRecordsFromPath reads a single XML record or all XML records in a directory. Both plain MD_Metadata
+// documents and cached GetRecordById responses are supported.
+// A directory is read as a record cache (<namespace>/<uuid>.xml) when it has namespace subdirectories with
+// records. Then only the records of the given namespace are read, so records of different endpoints are not
+// mixed, and records in its root are legacy and skipped. Otherwise, the XML files in the directory itself
+// are read, e.g. generated output.
+func loadRecordsFromPath(path, namespace string) ([]localRecord, error) {
+	fi, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+
+	files := []string{path}
+	if fi.IsDir() {
+		if files, err = recordFilesInDir(path, namespace); err != nil {
+			return nil, err
+		}
+	}
+
+	result := make([]localRecord, 0, len(files))
+
+	for _, file := range files {
+		//nolint:gosec
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return nil, err
+		}
+
+		md, err := csw.UnmarshalMDMetadata(data)
+		if err != nil {
+			slog.Warn("Skipping unreadable metadata record", "file", file, "err", err)
+
+			continue
+		}
+
+		result = append(result, localRecord{Path: file, Data: data, MD: md})
+	}
+
+	slog.Info("Loaded metadata records", "path", path, "count", len(result))
+
+	return result, nil
+}
+
+// recordFilesInDir returns the paths of the record files in a directory, see loadRecordsFromPath.
+func recordFilesInDir(dir, namespace string) ([]string, error) {
+	cached, err := client.ListCachedRecords(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var legacy, selected []string
+
+	namespaces := map[string]bool{}
+
+	for _, record := range cached {
+		switch record.Namespace {
+		case "":
+			legacy = append(legacy, filepath.Join(dir, record.File))
+		case namespace:
+			selected = append(selected, filepath.Join(dir, record.File))
+		}
+
+		namespaces[record.Namespace] = true
+	}
+
+	delete(namespaces, "")
+
+	if len(namespaces) == 0 {
+		return legacy, nil
+	}
+
+	if len(selected) == 0 {
+		return nil, fmt.Errorf("no records of namespace %s in the cache %s, use --csw-endpoint to select one of: %s",
+			namespace, dir, strings.Join(slices.Sorted(maps.Keys(namespaces)), ", "))
+	}
+
+	if len(legacy) > 0 {
+		slog.Warn("Skipping legacy records in the root of the cache", "path", dir, "count", len(legacy))
+	}
+
+	return selected, nil
+}
+
+func printRecordReports(reports []validator.RecordReport) {
+	applicable := 0
+
+	for _, report := range reports {
+		if !report.Applicable {
+			continue
+		}
+
+		applicable++
+
+		status := validator.Pass
+		if !report.Passed() {
+			status = validator.Fail
+		}
+
+		fmt.Printf("%-5s %-36s %s\n", status, report.MetadataID, report.Title)
+
+		for _, result := range report.Results {
+			if result.Status == validator.Fail || result.Status == validator.Warning {
+				fmt.Printf("      %-8s %-45s %s\n", result.Status, result.ID, result.Message)
+			}
+		}
+	}
+
+	fmt.Printf("Checked %d of %d records (others not applicable)\n", applicable, len(reports))
+}
+
+func printOrganisationSummaries(summaries []validator.OrganisationSummary) {
+	fmt.Printf("\n%-40s %-8s %-10s %-14s\n", "ORGANISATION", "RECORDS", "COMPLIANT", "NON-COMPLIANT")
+
+	repeatCount := 75
+	fmt.Println(strings.Repeat("-", repeatCount))
+
+	maxLength := 40
+	for _, summary := range summaries {
+		fmt.Printf("%-40s %-8d %-10d %-14d\n",
+			common.TruncateString(summary.OrganisationName, maxLength),
+			summary.Records,
+			summary.Compliant,
+			summary.NonCompliant)
+	}
+}
+
+func writeJSONReport(outputPath string, report any) error {
+	if outputPath == "" {
+		return nil
+	}
+
+	b, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	if err := os.WriteFile(outputPath, b, permFile0600); err != nil {
+		return err
+	}
+
+	fmt.Printf("Wrote report to %s\n", outputPath)
+
+	return nil
+}
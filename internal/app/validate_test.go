@@ -0,0 +1,154 @@
+package app
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testCachedRecord is a minimal dataset record with a single download link.
+const testCachedRecord = `<gmd:MD_Metadata xmlns:gmd="http://www.isotc211.org/2005/gmd"
+		xmlns:gco="http://www.isotc211.org/2005/gco">
+	<gmd:fileIdentifier><gco:CharacterString>%s</gco:CharacterString></gmd:fileIdentifier>
+	<gmd:hierarchyLevel><gmd:MD_ScopeCode codeListValue="dataset">dataset</gmd:MD_ScopeCode></gmd:hierarchyLevel>
+	<gmd:identificationInfo><gmd:MD_DataIdentification><gmd:citation><gmd:CI_Citation>
+		<gmd:title><gco:CharacterString>Record %[1]s</gco:CharacterString></gmd:title>
+	</gmd:CI_Citation></gmd:citation></gmd:MD_DataIdentification></gmd:identificationInfo>
+	<gmd:distributionInfo><gmd:MD_Distribution><gmd:transferOptions><gmd:MD_DigitalTransferOptions>
+		<gmd:onLine><gmd:CI_OnlineResource><gmd:linkage><gmd:URL>%s/download</gmd:URL></gmd:linkage>
+		</gmd:CI_OnlineResource></gmd:onLine>
+	</gmd:MD_DigitalTransferOptions></gmd:transferOptions></gmd:MD_Distribution></gmd:distributionInfo>
+</gmd:MD_Metadata>`
+
+// writeTestCache writes a record cache with two records in namespaces, a legacy record in the root and a
+// temporary file of an interrupted write.
+func writeTestCache(t *testing.T, linkURL string) string {
+	t.Helper()
+
+	cacheDir := t.TempDir()
+	files := map[string]string{
+		"acc-example-org-csw/record-1.xml":           "record-1",
+		"nationaalgeoregister-nl-csw/record-2.xml":   "record-2",
+		"nationaalgeoregister-nl-csw/.record-3.xml":  "record-3",
+		"legacy-record.xml":                          "legacy-record",
+		"nationaalgeoregister-nl-csw/record-2.json":  "",
+		"nationaalgeoregister-nl-csw/notes.txt":      "",
+		"acc-example-org-csw/.record-1.xml.1234.tmp": "",
+	}
+
+	for name, uuid := range files {
+		path := filepath.Join(cacheDir, name)
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0o750))
+		require.NoError(t, os.WriteFile(path, fmt.Appendf(nil, testCachedRecord, uuid, linkURL), 0o600))
+	}
+
+	return cacheDir
+}
+
+func TestLoadRecordsFromPath(t *testing.T) {
+	cacheDir := writeTestCache(t, "https://example.org")
+
+	t.Run("Namespaced cache", func(t *testing.T) {
+		records, err := loadRecordsFromPath(cacheDir, "nationaalgeoregister-nl-csw")
+		require.NoError(t, err)
+		require.Len(t, records, 1)
+		assert.Equal(t, filepath.Join(cacheDir, "nationaalgeoregister-nl-csw", "record-2.xml"), records[0].Path)
+	})
+
+	t.Run("Namespace not in cache", func(t *testing.T) {
+		_, err := loadRecordsFromPath(cacheDir, "prod-example-org-csw")
+		require.ErrorContains(t, err, "acc-example-org-csw, nationaalgeoregister-nl-csw")
+	})
+
+	t.Run("Directory with records", func(t *testing.T) {
+		records, err := loadRecordsFromPath(filepath.Join(cacheDir, "acc-example-org-csw"), "nationaalgeoregister-nl-csw")
+		require.NoError(t, err)
+		require.Len(t, records, 1)
+		assert.Equal(t, "record-1", records[0].MD.UUID)
+	})
+
+	t.Run("Single record", func(t *testing.T) {
+		records, err := loadRecordsFromPath(filepath.Join(cacheDir, "legacy-record.xml"), "nationaalgeoregister-nl-csw")
+		require.NoError(t, err)
+		require.Len(t, records, 1)
+		assert.Equal(t, "legacy-record", records[0].MD.UUID)
+	})
+}
+
+func TestCommandsOnNamespacedCache(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, _ *http.Request) {
+		rw.WriteHeader(http.StatusOK)
+	}))
+	t.Cleanup(server.Close)
+
+	cacheDir := writeTestCache(t, server.URL)
+	outputDir := t.TempDir()
+
+	tests := []struct {
+		name string
+		args []string
+		ids  func(data []byte) []string
+	}{
+		{
+			name: "validate inspire",
+			args: []string{"validate", "inspire"},
+			ids:  reportMetadataIDs,
+		},
+		{
+			name: "check links",
+			args: []string{"check", "links", "--link-cache-path", filepath.Join(outputDir, "link-check.json")},
+			ids:  reportMetadataIDs,
+		},
+		{
+			name: "check quality",
+			args: []string{"check", "quality"},
+			ids: func(data []byte) []string {
+				var report struct {
+					Records json.RawMessage `json:"records"`
+				}
+				if err := json.Unmarshal(data, &report); err != nil {
+					return nil
+				}
+
+				return reportMetadataIDs(report.Records)
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			output := filepath.Join(outputDir, tt.name+".json")
+			args := append([]string{"pmt"}, tt.args...)
+			args = append(args, "--input", cacheDir, "--csw-endpoint", "https://acc.example.org/csw", "-o", output)
+
+			require.NoError(t, PDOKMetadataToolCLI.Run(t.Context(), args))
+
+			data, err := os.ReadFile(output)
+			require.NoError(t, err)
+			assert.Equal(t, []string{"record-1"}, tt.ids(data))
+		})
+	}
+}
+
+func reportMetadataIDs(data []byte) []string {
+	var reports []struct {
+		MetadataID string `json:"metadataId"`
+	}
+	if err := json.Unmarshal(data, &reports); err != nil {
+		return nil
+	}
+
+	ids := make([]string, 0, len(reports))
+	for _, report := range reports {
+		ids = append(ids, report.MetadataID)
+	}
+
+	return ids
+}
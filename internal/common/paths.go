@@ -18,6 +18,8 @@ var (
 	InspireLocalPath = CachePath
 	// MetadataCachePath is a local path for the metadata records cache.
 	MetadataCachePath = filepath.Join(CachePath, "records")
+	// LinkCheckCachePath is a local path for the results of the link checker.
+	LinkCheckCachePath = filepath.Join(CachePath, "link-check.json")
 )
 
 // GetProjectRoot returns the root of the project as a string.
@@ -0,0 +1,24 @@
+// Package testutil provides shared helpers for loading test data in tests.
+package testutil
+
+import (
+	"os"
+	"testing"
+
+	"github.com/pdok/pdok-metadata-tool/v2/pkg/model/csw"
+	"github.com/pdok/pdok-metadata-tool/v2/pkg/model/iso1911x"
+	"github.com/stretchr/testify/require"
+)
+
+// LoadMDMetadata reads and unmarshals the metadata record at path, failing the test on errors.
+func LoadMDMetadata(t *testing.T, path string) iso1911x.MDMetadata {
+	t.Helper()
+
+	data, err := os.ReadFile(path) //nolint:gosec
+	require.NoError(t, err)
+
+	md, err := csw.UnmarshalMDMetadata(data)
+	require.NoError(t, err)
+
+	return md
+}
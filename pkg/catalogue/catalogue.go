@@ -0,0 +1,544 @@
+// Package catalogue holds a local index of harvested metadata records, so the cache can be queried
+// without parsing every record. The index is an embedded bbolt database.
+package catalogue
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"math"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pdok/pdok-metadata-tool/v2/pkg/client"
+	"github.com/pdok/pdok-metadata-tool/v2/pkg/model/csw"
+	"github.com/pdok/pdok-metadata-tool/v2/pkg/model/hvd"
+	"github.com/pdok/pdok-metadata-tool/v2/pkg/model/iso1911x"
+	"github.com/pdok/pdok-metadata-tool/v2/pkg/model/metadata"
+	bolt "go.etcd.io/bbolt"
+	berrors "go.etcd.io/bbolt/errors"
+)
+
+const (
+	permDir0750  = 0o750
+	permFile0600 = 0o600
+
+	// lockTimeout is how long Open waits for another process that has the index open.
+	lockTimeout = 5 * time.Minute
+
+	// gridCellSize is the size in degrees of the cells of the spatial index.
+	gridCellSize = 10
+)
+
+var (
+	// bucketEntries holds the entries as JSON by key, see entryKey.
+	bucketEntries = []byte("entries")
+	// bucketGrid is the spatial index: a key per grid cell that the bounding box of an entry covers,
+	// see gridKey. BBOX queries only read the entries in the cells of the queried bounding box.
+	bucketGrid = []byte("grid")
+)
+
+// Entry holds the fields of a metadata record that can be queried.
+type Entry struct {
+	Namespace        string                `json:"namespace"` // Cache namespace of the endpoint, see client.CacheNamespace
+	MetadataID       string                `json:"metadataId"`
+	Type             string                `json:"type"` // dataset or service
+	Title            string                `json:"title"`
+	Abstract         string                `json:"abstract,omitempty"`
+	OrganisationName string                `json:"organisationName,omitempty"`
+	Keywords         []string              `json:"keywords,omitempty"`
+	InspireThemes    []string              `json:"inspireThemes,omitempty"`
+	HVDCategories    []string              `json:"hvdCategories,omitempty"` // HVD category codes
+	ServiceType      string                `json:"serviceType,omitempty"`
+	OperatesOn       []string              `json:"operatesOn,omitempty"`
+	BoundingBox      *metadata.BoundingBox `json:"boundingBox,omitempty"`
+	CreationDate     string                `json:"creationDate,omitempty"`
+	RevisionDate     string                `json:"revisionDate,omitempty"`
+	IndexedAt        time.Time             `json:"indexedAt"`
+}
+
+// Index is the local catalogue index. Entries are keyed by cache namespace and metadata ID, so a record that
+// is harvested from several endpoints (e.g. acceptance and production) has an entry per endpoint.
+//
+// The database file is locked while it is open: other processes that share the cache wait in Open until
+// it is closed. Every change is a transaction, so a crash never leaves a partially written index.
+type Index struct {
+	db *bolt.DB // Nil for the empty index of a missing file
+}
+
+// NewEntry creates an index entry based on the flat model of the record.
+func NewEntry(m *iso1911x.MDMetadata) (Entry, error) {
+	var entry Entry
+
+	switch m.GetMetaDataType() {
+	case iso1911x.Service:
+		if m.IdentificationInfo.SVServiceIdentification == nil {
+			return entry, errors.New("service record has no service identification")
+		}
+
+		sm := metadata.NewNLServiceMetadataFromMDMetadata(m)
+		entry = Entry{
+			MetadataID:       sm.MetadataID,
+			Type:             iso1911x.Service.String(),
+			Title:            sm.Title,
+			Abstract:         sm.Abstract,
+			OrganisationName: sm.OrganisationName,
+			Keywords:         sm.Keywords,
+			InspireThemes:    sm.InspireThemes,
+			HVDCategories:    hvdCodes(sm.HVDCategories),
+			ServiceType:      sm.ServiceType,
+			OperatesOn:       sm.OperatesOn,
+			BoundingBox:      sm.BoundingBox,
+			CreationDate:     sm.CreationDate,
+			RevisionDate:     sm.RevisionDate,
+		}
+	default:
+		if m.IdentificationInfo.MDDataIdentification == nil {
+			return entry, errors.New("dataset record has no data identification")
+		}
+
+		dm := metadata.NewNLDatasetMetadataFromMDMetadata(m)
+		entry = Entry{
+			MetadataID:       dm.MetadataID,
+			Type:             iso1911x.Dataset.String(),
+			Title:            dm.Title,
+			Abstract:         dm.Abstract,
+			OrganisationName: dm.OrganisationName,
+			Keywords:         dm.Keywords,
+			InspireThemes:    dm.InspireThemes,
+			HVDCategories:    hvdCodes(dm.HVDCategories),
+			BoundingBox:      dm.BoundingBox,
+			CreationDate:     dm.CreationDate,
+			RevisionDate:     m.GetRevisionDate(),
+		}
+	}
+
+	if entry.MetadataID == "" {
+		return entry, errors.New("record has no fileIdentifier")
+	}
+
+	if entry.BoundingBox.IsEmpty() {
+		entry.BoundingBox = nil
+	}
+
+	entry.IndexedAt = time.Now().UTC()
+
+	return entry, nil
+}
+
+// Open opens the index in a database file, which is created when it does not exist.
+// Close the index as soon as possible, so other processes can open it.
+func Open(path string) (*Index, error) {
+	if err := os.MkdirAll(filepath.Dir(path), permDir0750); err != nil {
+		return nil, err
+	}
+
+	return open(path, &bolt.Options{Timeout: lockTimeout})
+}
+
+// OpenReadOnly opens an existing index for queries. Other processes can also read the index meanwhile, but
+// processes that update it wait until it is closed. A missing file results in an empty index.
+func OpenReadOnly(path string) (*Index, error) {
+	if _, err := os.Stat(path); os.IsNotExist(err) {
+		return &Index{}, nil
+	}
+
+	return open(path, &bolt.Options{Timeout: lockTimeout, ReadOnly: true})
+}
+
+func open(path string, options *bolt.Options) (*Index, error) {
+	db, err := bolt.Open(path, permFile0600, options)
+	if err != nil {
+		if errors.Is(err, berrors.ErrTimeout) {
+			return nil, fmt.Errorf("catalogue index %s is in use by another process", path)
+		}
+
+		return nil, fmt.Errorf("failed to open catalogue index %s: %w", path, err)
+	}
+
+	if !options.ReadOnly {
+		err = db.Update(func(tx *bolt.Tx) error {
+			return createBuckets(tx)
+		})
+		if err != nil {
+			_ = db.Close()
+
+			return nil, err
+		}
+	}
+
+	return &Index{db: db}, nil
+}
+
+// Close closes the database file.
+func (idx *Index) Close() error {
+	if idx.db == nil {
+		return nil
+	}
+
+	return idx.db.Close()
+}
+
+// Add adds or replaces the entries of the records of a cache namespace. Records that cannot be indexed are
+// skipped and returned as errors; the error is set when the index could not be updated.
+func (idx *Index) Add(namespace string, mds ...iso1911x.MDMetadata) (skipped []error, err error) {
+	err = idx.db.Update(func(tx *bolt.Tx) error {
+		skipped = addEntries(tx, namespace, mds)
+
+		return nil
+	})
+
+	return skipped, err
+}
+
+// Remove removes the entries of the metadata IDs of a cache namespace.
+func (idx *Index) Remove(namespace string, metadataIDs ...string) error {
+	return idx.db.Update(func(tx *bolt.Tx) error {
+		for _, id := range metadataIDs {
+			if err := deleteEntry(tx, entryKey(namespace, id)); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+}
+
+// Count returns the number of entries.
+func (idx *Index) Count() (count int, err error) {
+	if idx.db == nil {
+		return 0, nil
+	}
+
+	err = idx.db.View(func(tx *bolt.Tx) error {
+		if entries := tx.Bucket(bucketEntries); entries != nil {
+			count = entries.Stats().KeyN
+		}
+
+		return nil
+	})
+
+	return count, err
+}
+
+// Rebuild replaces the entries with the records in the cache directory (<namespace>/<uuid>.xml files).
+// Legacy records in the root of the cache are not indexed. Records that cannot be read or indexed are
+// skipped and returned as errors. It returns the number of indexed records.
+func (idx *Index) Rebuild(cacheDir string) (count int, skipped []error, err error) {
+	records, err := client.ListCachedRecords(cacheDir)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	err = idx.db.Update(func(tx *bolt.Tx) error {
+		for _, name := range [][]byte{bucketEntries, bucketGrid} {
+			if err := tx.DeleteBucket(name); err != nil && !errors.Is(err, berrors.ErrBucketNotFound) {
+				return err
+			}
+		}
+
+		if err := createBuckets(tx); err != nil {
+			return err
+		}
+
+		for _, record := range records {
+			if record.Namespace == "" {
+				continue
+			}
+
+			//nolint:gosec
+			data, err := os.ReadFile(filepath.Join(cacheDir, record.File))
+			if err != nil {
+				skipped = append(skipped, err)
+
+				continue
+			}
+
+			md, err := csw.UnmarshalMDMetadata(data)
+			if err != nil {
+				skipped = append(skipped, fmt.Errorf("%s: %w", record.File, err))
+
+				continue
+			}
+
+			if errs := addEntries(tx, record.Namespace, []iso1911x.MDMetadata{md}); len(errs) > 0 {
+				skipped = append(skipped, errs...)
+
+				continue
+			}
+
+			count++
+		}
+
+		return nil
+	})
+
+	return count, skipped, err
+}
+
+// Query returns the entries that match the filter, ordered by title. A nil filter matches all entries.
+// A BBOX filter, also as part of AND, only reads the entries in the cells of the spatial index it covers.
+func (idx *Index) Query(filter csw.Filter) ([]Entry, error) {
+	if filter != nil {
+		if err := validate(filter); err != nil {
+			return nil, err
+		}
+	}
+
+	var result []Entry
+
+	if idx.db == nil {
+		return result, nil
+	}
+
+	err := idx.db.View(func(tx *bolt.Tx) error {
+		entries := tx.Bucket(bucketEntries)
+		if entries == nil {
+			return nil
+		}
+
+		match := func(value []byte) error {
+			var entry Entry
+			if err := json.Unmarshal(value, &entry); err != nil {
+				return err
+			}
+
+			if filter != nil {
+				if ok, err := Match(filter, &entry); err != nil || !ok {
+					return err
+				}
+			}
+
+			result = append(result, entry)
+
+			return nil
+		}
+
+		bbox, ok := spatialFilter(filter)
+		if !ok {
+			return entries.ForEach(func(_, value []byte) error { return match(value) })
+		}
+
+		for _, key := range gridCandidates(tx.Bucket(bucketGrid), bbox) {
+			if value := entries.Get([]byte(key)); value != nil {
+				if err := match(value); err != nil {
+					return err
+				}
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	slices.SortFunc(result, func(a, b Entry) int {
+		if c := strings.Compare(strings.ToLower(a.Title), strings.ToLower(b.Title)); c != 0 {
+			return c
+		}
+
+		if c := strings.Compare(a.MetadataID, b.MetadataID); c != 0 {
+			return c
+		}
+
+		return strings.Compare(a.Namespace, b.Namespace)
+	})
+
+	return result, nil
+}
+
+func createBuckets(tx *bolt.Tx) error {
+	for _, name := range [][]byte{bucketEntries, bucketGrid} {
+		if _, err := tx.CreateBucketIfNotExists(name); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// addEntries adds or replaces the entries of the records and returns the records that cannot be indexed.
+func addEntries(tx *bolt.Tx, namespace string, mds []iso1911x.MDMetadata) (skipped []error) {
+	for i := range mds {
+		entry, err := NewEntry(&mds[i])
+		if err == nil {
+			entry.Namespace = namespace
+			err = putEntry(tx, &entry)
+		}
+
+		if err != nil {
+			skipped = append(skipped, fmt.Errorf("%s: %w", iso1911x.NormalizeXMLText(mds[i].UUID), err))
+		}
+	}
+
+	return skipped
+}
+
+func putEntry(tx *bolt.Tx, entry *Entry) error {
+	key := entryKey(entry.Namespace, entry.MetadataID)
+	if err := deleteEntry(tx, key); err != nil {
+		return err
+	}
+
+	value, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+
+	if err := tx.Bucket(bucketEntries).Put([]byte(key), value); err != nil {
+		return err
+	}
+
+	grid := tx.Bucket(bucketGrid)
+	for _, cell := range gridCells(entry.BoundingBox) {
+		if err := grid.Put([]byte(gridKey(cell, key)), nil); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// deleteEntry deletes an entry and its keys in the spatial index, when it exists.
+func deleteEntry(tx *bolt.Tx, key string) error {
+	entries := tx.Bucket(bucketEntries)
+
+	value := entries.Get([]byte(key))
+	if value == nil {
+		return nil
+	}
+
+	var entry Entry
+	if err := json.Unmarshal(value, &entry); err == nil {
+		grid := tx.Bucket(bucketGrid)
+		for _, cell := range gridCells(entry.BoundingBox) {
+			if err := grid.Delete([]byte(gridKey(cell, key))); err != nil {
+				return err
+			}
+		}
+	}
+
+	return entries.Delete([]byte(key))
+}
+
+// entryKey returns the key of an entry. Namespaces contain no slashes, see client.CacheNamespace.
+func entryKey(namespace, metadataID string) string {
+	return namespace + "/" + metadataID
+}
+
+// gridCell is a cell of the spatial index, by column (longitude) and row (latitude).
+type gridCell struct {
+	column, row int
+}
+
+const (
+	gridColumns = 360 / gridCellSize
+	gridRows    = 180 / gridCellSize
+)
+
+// gridKey returns the key of an entry in a cell of the spatial index, prefixed by the cell.
+func gridKey(cell gridCell, key string) string {
+	return gridPrefix(cell) + key
+}
+
+func gridPrefix(cell gridCell) string {
+	return fmt.Sprintf("%02d%02d/", cell.column, cell.row)
+}
+
+// gridCells returns the cells that a bounding box covers. A bounding box that crosses the antimeridian
+// (west > east) covers all columns. Invalid bounding boxes cover no cells.
+func gridCells(bbox *metadata.BoundingBox) []gridCell {
+	if bbox.IsEmpty() {
+		return nil
+	}
+
+	var coordinates [4]float64
+
+	for i, value := range []string{
+		bbox.WestBoundLongitude, bbox.SouthBoundLatitude, bbox.EastBoundLongitude, bbox.NorthBoundLatitude,
+	} {
+		v, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+		if err != nil {
+			return nil
+		}
+
+		coordinates[i] = v
+	}
+
+	return cellsOf(coordinates[0], coordinates[1], coordinates[2], coordinates[3])
+}
+
+func cellsOf(west, south, east, north float64) []gridCell {
+	if south > north {
+		return nil
+	}
+
+	firstColumn, lastColumn := gridIndex(west, 180, gridColumns), gridIndex(east, 180, gridColumns)
+	if west > east {
+		firstColumn, lastColumn = 0, gridColumns-1
+	}
+
+	var cells []gridCell
+
+	for column := firstColumn; column <= lastColumn; column++ {
+		for row := gridIndex(south, 90, gridRows); row <= gridIndex(north, 90, gridRows); row++ {
+			cells = append(cells, gridCell{column: column, row: row})
+		}
+	}
+
+	return cells
+}
+
+// gridIndex returns the column or row of a coordinate, clamped to the grid.
+func gridIndex(coordinate, offset float64, size int) int {
+	return min(max(int(math.Floor((coordinate+offset)/gridCellSize)), 0), size-1)
+}
+
+// spatialFilter returns the BBOX of a filter that every matching entry must intersect: the filter itself
+// or a BBOX in a top-level AND.
+func spatialFilter(filter csw.Filter) (csw.BBox, bool) {
+	switch f := filter.(type) {
+	case csw.BBox:
+		return f, true
+	case csw.And:
+		for _, sub := range f.Filters {
+			if bbox, ok := sub.(csw.BBox); ok {
+				return bbox, true
+			}
+		}
+	}
+
+	return csw.BBox{}, false
+}
+
+// gridCandidates returns the keys of the entries in the cells of the spatial index that a BBOX covers,
+// in key order.
+func gridCandidates(grid *bolt.Bucket, bbox csw.BBox) []string {
+	seen := map[string]bool{}
+
+	for _, cell := range cellsOf(bbox.West, bbox.South, bbox.East, bbox.North) {
+		prefix := []byte(gridPrefix(cell))
+		cursor := grid.Cursor()
+
+		for k, _ := cursor.Seek(prefix); k != nil && strings.HasPrefix(string(k), string(prefix)); k, _ = cursor.Next() {
+			seen[string(k[len(prefix):])] = true
+		}
+	}
+
+	return slices.Sorted(maps.Keys(seen))
+}
+
+func hvdCodes(categories []hvd.HVDCategory) []string {
+	codes := make([]string, 0, len(categories))
+	for _, category := range categories {
+		codes = append(codes, category.ID)
+	}
+
+	return codes
+}
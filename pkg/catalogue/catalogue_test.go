@@ -0,0 +1,339 @@
+package catalogue
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/pdok/pdok-metadata-tool/v2/internal/common"
+	"github.com/pdok/pdok-metadata-tool/v2/internal/testutil"
+	"github.com/pdok/pdok-metadata-tool/v2/pkg/model/csw"
+	"github.com/pdok/pdok-metadata-tool/v2/pkg/model/iso1911x"
+	"github.com/pdok/pdok-metadata-tool/v2/pkg/model/metadata"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	testNamespace        = "example-com-csw"
+	testOtherNamespace   = "acc-example-com-csw"
+	testDatasetID        = "5951efa2-1ff3-4763-a966-a2f5497679ee"
+	testDatasetFile      = testDatasetID + ".xml"
+	testServiceID        = "dae8f9e3-99af-4d21-9feb-29f2a1693077"
+	testServiceCachePath = testNamespace + "/" + testServiceID + ".xml"
+)
+
+// buildTestCache copies the service examples to a namespace of a cache directory, with a legacy record in
+// the root of the cache.
+func buildTestCache(t *testing.T) string {
+	t.Helper()
+
+	root := common.GetProjectRoot()
+	cacheDir := t.TempDir()
+
+	files, err := filepath.Glob(filepath.Join(root, "examples", "ISO19119", "*.xml"))
+	require.NoError(t, err)
+	require.NoError(t, os.MkdirAll(filepath.Join(cacheDir, testNamespace), 0o750))
+
+	for _, file := range files {
+		data, err := os.ReadFile(file)
+		require.NoError(t, err)
+		require.NoError(t, os.WriteFile(filepath.Join(cacheDir, testNamespace, filepath.Base(file)), data, 0o600))
+	}
+
+	data, err := os.ReadFile(filepath.Join(root, "examples", "ISO19115", testDatasetFile))
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(filepath.Join(cacheDir, testDatasetFile), data, 0o600))
+
+	return cacheDir
+}
+
+func loadTestRecords(t *testing.T, files ...string) []iso1911x.MDMetadata {
+	t.Helper()
+
+	mds := make([]iso1911x.MDMetadata, 0, len(files))
+
+	for _, file := range files {
+		mds = append(mds, testutil.LoadMDMetadata(t, filepath.Join(common.GetProjectRoot(), "examples", "ISO19115", file)))
+	}
+
+	return mds
+}
+
+func buildTestIndex(t *testing.T) *Index {
+	t.Helper()
+
+	index, err := Open(filepath.Join(t.TempDir(), "catalogue-index.db"))
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = index.Close() })
+
+	count, skipped, err := index.Rebuild(buildTestCache(t))
+	require.NoError(t, err)
+	require.Empty(t, skipped)
+	require.Equal(t, 6, count, "the legacy record is not indexed")
+
+	skipped, err = index.Add(testNamespace, loadTestRecords(t,
+		testDatasetFile,
+		"F646DFB9-5BF6-EAB9-042B-CAB6FF2DC275.xml",
+		"500d396f-5ec6-4e4b-a151-5fb3cddd8082.xml",
+	)...)
+	require.NoError(t, err)
+	require.Empty(t, skipped)
+
+	return index
+}
+
+func TestIndex_Query(t *testing.T) {
+	index := buildTestIndex(t)
+
+	count, err := index.Count()
+	require.NoError(t, err)
+	require.Equal(t, 9, count)
+
+	tests := []struct {
+		name    string
+		where   string
+		want    []string
+		wantErr bool
+	}{
+		{
+			name:  "All records",
+			where: "",
+			want: []string{
+				"0017219b-fb75-47aa-a6bf-496f2514e545", "F646DFB9-5BF6-EAB9-042B-CAB6FF2DC275",
+				"39d03482-fef0-4706-8f66-16ffb2617155", "500d396f-5ec6-4e4b-a151-5fb3cddd8082",
+				"1761ab61-c41d-4897-8ee3-a575e717d765", "C2DFBDBC-5092-11E0-BA8E-B62DE0D72086",
+				"392e6a4e-5274-11ea-954f-080027325297", "dae8f9e3-99af-4d21-9feb-29f2a1693077",
+				"5951efa2-1ff3-4763-a966-a2f5497679ee",
+			},
+		},
+		{
+			name:  "Type and organisation, case-insensitive",
+			where: "type = 'dataset' AND organisationname = 'kadaster'",
+			want:  []string{"5951efa2-1ff3-4763-a966-a2f5497679ee"},
+		},
+		{
+			name:  "INSPIRE theme or HVD category",
+			where: "InspireTheme = 'ge' OR HVDCategory = 'c_b79e35eb'",
+			want: []string{
+				"F646DFB9-5BF6-EAB9-042B-CAB6FF2DC275", "dae8f9e3-99af-4d21-9feb-29f2a1693077",
+				"5951efa2-1ff3-4763-a966-a2f5497679ee",
+			},
+		},
+		{
+			name:  "Keyword",
+			where: "keyword = 'luchtfoto'",
+			want:  []string{"1761ab61-c41d-4897-8ee3-a575e717d765"},
+		},
+		{
+			name:  "Title LIKE and NOT",
+			where: "Title LIKE '%WMS' AND NOT Title LIKE 'Luchtfoto%'",
+			want:  []string{"392e6a4e-5274-11ea-954f-080027325297", "dae8f9e3-99af-4d21-9feb-29f2a1693077"},
+		},
+		{
+			name:  "Revision date",
+			where: "RevisionDate DURING '2025-01-01/..'",
+			want: []string{
+				"0017219b-fb75-47aa-a6bf-496f2514e545", "392e6a4e-5274-11ea-954f-080027325297",
+				"dae8f9e3-99af-4d21-9feb-29f2a1693077",
+			},
+		},
+		{
+			name:  "Creation date comparison",
+			where: "type = 'service' AND CreationDate < '2019-01-01'",
+			want:  []string{"C2DFBDBC-5092-11E0-BA8E-B62DE0D72086"},
+		},
+		{
+			name:  "Bounding box intersection",
+			where: "BBOX(ows:BoundingBox, -80, 60, -70, 70)",
+			want:  []string{"392e6a4e-5274-11ea-954f-080027325297"},
+		},
+		{
+			name:  "Bounding box and type",
+			where: "type = 'dataset' AND BBOX(ows:BoundingBox, 4, 52, 5, 53)",
+			want: []string{
+				"F646DFB9-5BF6-EAB9-042B-CAB6FF2DC275", "500d396f-5ec6-4e4b-a151-5fb3cddd8082",
+				"5951efa2-1ff3-4763-a966-a2f5497679ee",
+			},
+		},
+		{
+			name:  "AnyText",
+			where: "AnyText = 'zwaveldioxide'",
+			want:  []string{"500d396f-5ec6-4e4b-a151-5fb3cddd8082"},
+		},
+		{
+			name:    "Bounding box in another CRS",
+			where:   "BBOX(ows:BoundingBox, 155000, 463000, 156000, 464000, 'EPSG:28992')",
+			wantErr: true,
+		},
+		{
+			name:  "Bounding box in the default CRS",
+			where: "type = 'dataset' AND BBOX(ows:BoundingBox, 4, 52, 5, 53, 'EPSG:4326')",
+			want: []string{
+				"F646DFB9-5BF6-EAB9-042B-CAB6FF2DC275", "500d396f-5ec6-4e4b-a151-5fb3cddd8082",
+				"5951efa2-1ff3-4763-a966-a2f5497679ee",
+			},
+		},
+		{
+			name:    "Unknown property",
+			where:   "Colour = 'red'",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var filter csw.Filter
+
+			if tt.where != "" {
+				var err error
+
+				filter, err = csw.ParseCQL(tt.where)
+				require.NoError(t, err)
+			}
+
+			entries, err := index.Query(filter)
+			if tt.wantErr {
+				require.Error(t, err)
+
+				return
+			}
+
+			require.NoError(t, err)
+
+			ids := make([]string, 0, len(entries))
+			for _, entry := range entries {
+				ids = append(ids, entry.MetadataID)
+			}
+
+			assert.Equal(t, tt.want, ids)
+		})
+	}
+}
+
+func TestIndex_Namespaces(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "index", "catalogue-index.db")
+
+	index, err := Open(path)
+	require.NoError(t, err)
+
+	for _, namespace := range []string{testNamespace, testOtherNamespace} {
+		skipped, err := index.Add(namespace, loadTestRecords(t, testDatasetFile)...)
+		require.NoError(t, err)
+		require.Empty(t, skipped)
+	}
+
+	entries, err := index.Query(nil)
+	require.NoError(t, err)
+	require.Len(t, entries, 2, "the record has an entry per namespace")
+	assert.Equal(t, testOtherNamespace, entries[0].Namespace)
+	assert.Equal(t, testNamespace, entries[1].Namespace)
+
+	require.NoError(t, index.Remove(testOtherNamespace, testDatasetID))
+	require.NoError(t, index.Close())
+
+	t.Run("Removal only affects its namespace", func(t *testing.T) {
+		index, err := OpenReadOnly(path)
+		require.NoError(t, err)
+
+		defer index.Close()
+
+		entries, err := index.Query(csw.BBox{West: 3, South: 50, East: 8, North: 54})
+		require.NoError(t, err)
+		require.Len(t, entries, 1)
+		assert.Equal(t, testNamespace, entries[0].Namespace)
+		assert.Equal(t, testDatasetID, entries[0].MetadataID)
+	})
+
+	t.Run("Missing index", func(t *testing.T) {
+		index, err := OpenReadOnly(filepath.Join(t.TempDir(), "missing.db"))
+		require.NoError(t, err)
+
+		defer index.Close()
+
+		entries, err := index.Query(nil)
+		require.NoError(t, err)
+		assert.Empty(t, entries)
+	})
+}
+
+func TestIndex_Lock(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "catalogue-index.db")
+
+	index, err := Open(path)
+	require.NoError(t, err)
+
+	done := make(chan error)
+
+	go func() {
+		other, err := Open(path)
+		if err == nil {
+			_, err = other.Add(testOtherNamespace, loadTestRecords(t, testDatasetFile)...)
+			err = errors.Join(err, other.Close())
+		}
+
+		done <- err
+	}()
+
+	_, err = index.Add(testNamespace, loadTestRecords(t, testDatasetFile)...)
+	require.NoError(t, err)
+
+	select {
+	case err := <-done:
+		t.Fatalf("the index was opened while it was locked: %v", err)
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	require.NoError(t, index.Close())
+	require.NoError(t, <-done)
+
+	index, err = OpenReadOnly(path)
+	require.NoError(t, err)
+
+	defer index.Close()
+
+	count, err := index.Count()
+	require.NoError(t, err)
+	assert.Equal(t, 2, count, "no update is lost")
+}
+
+func TestGridCells(t *testing.T) {
+	tests := []struct {
+		name string
+		bbox *metadata.BoundingBox
+		want []gridCell
+	}{
+		{name: "No bounding box", bbox: nil, want: nil},
+		{
+			name: "Netherlands",
+			bbox: &metadata.BoundingBox{
+				WestBoundLongitude: "3.2", SouthBoundLatitude: "50.7", EastBoundLongitude: "7.2", NorthBoundLatitude: "53.6",
+			},
+			want: []gridCell{{column: 18, row: 14}},
+		},
+		{
+			name: "Clamped to the grid",
+			bbox: &metadata.BoundingBox{
+				WestBoundLongitude: "175", SouthBoundLatitude: "85", EastBoundLongitude: "185", NorthBoundLatitude: "95",
+			},
+			want: []gridCell{{column: 35, row: 17}},
+		},
+		{
+			name: "Invalid",
+			bbox: &metadata.BoundingBox{
+				WestBoundLongitude: "west", SouthBoundLatitude: "50", EastBoundLongitude: "7", NorthBoundLatitude: "53",
+			},
+			want: nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, gridCells(tt.bbox))
+		})
+	}
+
+	assert.Len(t, cellsOf(170, 0, -170, 5), gridColumns, "crossing the antimeridian covers all columns")
+}
@@ -0,0 +1,295 @@
+package catalogue
+
+import (
+	"cmp"
+	"fmt"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pdok/pdok-metadata-tool/v2/pkg/model/csw"
+	"github.com/pdok/pdok-metadata-tool/v2/pkg/model/metadata"
+)
+
+// properties maps the (lower case) property names that can be queried to the values of an entry.
+// Multi-valued properties match when any of the values matches.
+var properties = map[string]func(e *Entry) []string{
+	"namespace":        func(e *Entry) []string { return []string{e.Namespace} },
+	"identifier":       func(e *Entry) []string { return []string{e.MetadataID} },
+	"metadataid":       func(e *Entry) []string { return []string{e.MetadataID} },
+	"type":             func(e *Entry) []string { return []string{e.Type} },
+	"title":            func(e *Entry) []string { return []string{e.Title} },
+	"abstract":         func(e *Entry) []string { return []string{e.Abstract} },
+	"organisationname": func(e *Entry) []string { return []string{e.OrganisationName} },
+	"keyword":          func(e *Entry) []string { return e.Keywords },
+	"subject":          func(e *Entry) []string { return e.Keywords },
+	"inspiretheme":     func(e *Entry) []string { return e.InspireThemes },
+	"hvdcategory":      func(e *Entry) []string { return e.HVDCategories },
+	"servicetype":      func(e *Entry) []string { return []string{e.ServiceType} },
+	"operateson":       func(e *Entry) []string { return e.OperatesOn },
+	"creationdate":     func(e *Entry) []string { return []string{e.CreationDate} },
+	"revisiondate":     func(e *Entry) []string { return []string{e.RevisionDate} },
+	"anytext": func(e *Entry) []string {
+		return slices.Concat([]string{e.MetadataID, e.Title, e.Abstract, e.OrganisationName},
+			e.Keywords, e.InspireThemes, e.HVDCategories)
+	},
+}
+
+// PropertyNames returns the property names that can be used in a query.
+func PropertyNames() []string {
+	return []string{
+		"Namespace", "Identifier", "MetadataID", "Type", "Title", "Abstract", "OrganisationName", "Keyword", "Subject",
+		"InspireTheme", "HVDCategory", "ServiceType", "OperatesOn", "CreationDate", "RevisionDate", "AnyText",
+	}
+}
+
+// Match returns true when the entry matches the filter. Text comparisons are case-insensitive, dates are
+// compared as dates and BBOX matches entries of which the bounding box intersects. Entries without a bounding
+// box never match BBOX.
+//
+//nolint:cyclop
+func Match(filter csw.Filter, e *Entry) (bool, error) {
+	switch f := filter.(type) {
+	case csw.And:
+		for _, sub := range f.Filters {
+			if ok, err := Match(sub, e); err != nil || !ok {
+				return false, err
+			}
+		}
+
+		return true, nil
+	case csw.Or:
+		for _, sub := range f.Filters {
+			if ok, err := Match(sub, e); err != nil || ok {
+				return ok, err
+			}
+		}
+
+		return false, nil
+	case csw.Not:
+		ok, err := Match(f.Filter, e)
+
+		return !ok, err
+	case csw.Keyword:
+		return matchAny(e.Keywords, func(v string) bool { return strings.EqualFold(v, f.Value) }), nil
+	case csw.AnyText:
+		value := strings.ToLower(f.Value)
+
+		return matchAny(properties["anytext"](e), func(v string) bool {
+			return strings.Contains(strings.ToLower(v), value)
+		}), nil
+	case csw.Comparison:
+		values, err := propertyValues(f.Property, e)
+		if err != nil {
+			return false, err
+		}
+
+		if f.Operator == csw.NotEqual {
+			return !matchAny(values, func(v string) bool { return compare(v, f.Value) == 0 }), nil
+		}
+
+		return matchAny(values, func(v string) bool { return compareWith(v, f.Operator, f.Value) }), nil
+	case csw.Like:
+		values, err := propertyValues(f.Property, e)
+		if err != nil {
+			return false, err
+		}
+
+		re, err := likeRegexp(f.Pattern)
+		if err != nil {
+			return false, err
+		}
+
+		return matchAny(values, re.MatchString), nil
+	case csw.Between:
+		values, err := propertyValues(f.Property, e)
+		if err != nil {
+			return false, err
+		}
+
+		return matchAny(values, func(v string) bool {
+			return compareWith(v, csw.GreaterThanOrEqual, f.Lower) && compareWith(v, csw.LessThanOrEqual, f.Upper)
+		}), nil
+	case csw.DateRange:
+		values, err := propertyValues(f.Property, e)
+		if err != nil {
+			return false, err
+		}
+
+		return matchAny(values, func(v string) bool {
+			return (f.From == nil || compareWith(v, csw.GreaterThanOrEqual, *f.From)) &&
+				(f.To == nil || compareWith(v, csw.LessThanOrEqual, *f.To))
+		}), nil
+	case csw.BBox:
+		if e.BoundingBox.IsEmpty() {
+			return false, nil
+		}
+
+		format := func(v float64) string { return strconv.FormatFloat(v, 'f', -1, 64) }
+		bbox := &metadata.BoundingBox{
+			WestBoundLongitude: format(f.West),
+			EastBoundLongitude: format(f.East),
+			SouthBoundLatitude: format(f.South),
+			NorthBoundLatitude: format(f.North),
+		}
+
+		ok, err := bbox.Intersects(e.BoundingBox)
+		if err != nil {
+			return false, nil //nolint:nilerr // An invalid bounding box in a record does not intersect.
+		}
+
+		return ok, nil
+	default:
+		return false, fmt.Errorf("unsupported filter %T", filter)
+	}
+}
+
+// validate returns an error when the filter uses a property that cannot be queried.
+func validate(filter csw.Filter) error {
+	var property string
+
+	switch f := filter.(type) {
+	case csw.And:
+		return validateAll(f.Filters)
+	case csw.Or:
+		return validateAll(f.Filters)
+	case csw.Not:
+		return validate(f.Filter)
+	case csw.Comparison:
+		property = f.Property
+	case csw.Like:
+		property = f.Property
+	case csw.Between:
+		property = f.Property
+	case csw.DateRange:
+		property = f.Property
+	case csw.BBox:
+		// The bounding boxes of the records are in degrees, and a query is not reprojected
+		if f.CRS != "" && !strings.EqualFold(f.CRS, csw.DefaultCRS) {
+			return fmt.Errorf("unsupported CRS %q of BBOX (allowed: %s)", f.CRS, csw.DefaultCRS)
+		}
+
+		return nil
+	default:
+		return nil
+	}
+
+	_, err := propertyValues(property, &Entry{})
+
+	return err
+}
+
+func validateAll(filters []csw.Filter) error {
+	for _, f := range filters {
+		if err := validate(f); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func propertyValues(property string, e *Entry) ([]string, error) {
+	values, ok := properties[strings.ToLower(property)]
+	if !ok {
+		return nil, fmt.Errorf("unknown property %q (allowed: %s)", property, strings.Join(PropertyNames(), ", "))
+	}
+
+	return values(e), nil
+}
+
+func matchAny(values []string, match func(v string) bool) bool {
+	for _, v := range values {
+		if v != "" && match(v) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func compareWith(value string, operator csw.Operator, other any) bool {
+	c := compare(value, other)
+
+	switch operator {
+	case csw.Equal:
+		return c == 0
+	case csw.NotEqual:
+		return c != 0
+	case csw.LessThan:
+		return c < 0
+	case csw.LessThanOrEqual:
+		return c <= 0
+	case csw.GreaterThan:
+		return c > 0
+	case csw.GreaterThanOrEqual:
+		return c >= 0
+	default:
+		return false
+	}
+}
+
+// compare compares the value of an entry with a literal: as numbers when both are numeric, as dates when
+// both are dates and otherwise as case-insensitive text.
+func compare(value string, literal any) int {
+	switch l := literal.(type) {
+	case float64:
+		if v, err := strconv.ParseFloat(value, 64); err == nil {
+			return cmp.Compare(v, l)
+		}
+	case time.Time:
+		if v, ok := parseDate(value); ok {
+			return v.Compare(l)
+		}
+	case string:
+		if v, ok := parseDate(value); ok {
+			if t, ok := parseDate(l); ok {
+				return v.Compare(t)
+			}
+		}
+	}
+
+	return strings.Compare(strings.ToLower(value), strings.ToLower(fmt.Sprint(literal)))
+}
+
+func parseDate(value string) (time.Time, bool) {
+	for _, layout := range []string{time.DateOnly, time.RFC3339, "2006-01-02T15:04:05"} {
+		if t, err := time.Parse(layout, strings.TrimSpace(value)); err == nil {
+			return t, true
+		}
+	}
+
+	return time.Time{}, false
+}
+
+// likeRegexp converts a LIKE pattern (% and _ as wildcards, \ as escape) to a case-insensitive regular expression.
+func likeRegexp(pattern string) (*regexp.Regexp, error) {
+	var sb strings.Builder
+
+	sb.WriteString("(?is)^")
+
+	escaped := false
+
+	for _, r := range pattern {
+		switch {
+		case escaped:
+			sb.WriteString(regexp.QuoteMeta(string(r)))
+
+			escaped = false
+		case r == '\\':
+			escaped = true
+		case r == '%':
+			sb.WriteString(".*")
+		case r == '_':
+			sb.WriteString(".")
+		default:
+			sb.WriteString(regexp.QuoteMeta(string(r)))
+		}
+	}
+
+	sb.WriteString("$")
+
+	return regexp.Compile(sb.String())
+}
@@ -0,0 +1,195 @@
+package checker
+
+import (
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/pdok/pdok-metadata-tool/v2/pkg/model/hvd"
+	"github.com/pdok/pdok-metadata-tool/v2/pkg/model/metadata"
+)
+
+// CouplingIssueType holds the kinds of inconsistencies between a service and the datasets it operates on.
+type CouplingIssueType string
+
+// Values for CouplingIssueType.
+const (
+	DanglingReference    CouplingIssueType = "dangling-reference"
+	InspireThemeMismatch CouplingIssueType = "inspire-theme-mismatch"
+	HVDCategoryMismatch  CouplingIssueType = "hvd-category-mismatch"
+	BBoxNotCovered       CouplingIssueType = "bbox-not-covered"
+)
+
+// CouplingIssue is a single inconsistency between a service and (one of) its datasets.
+type CouplingIssue struct {
+	ServiceID        string            `json:"serviceId"`
+	ServiceTitle     string            `json:"serviceTitle"`
+	OrganisationName string            `json:"organisationName"`
+	DatasetID        string            `json:"datasetId,omitempty"` // Empty when the issue concerns all datasets
+	Type             CouplingIssueType `json:"type"`
+	Message          string            `json:"message"`
+}
+
+// CheckCoupling resolves the operatesOn references of all services against the datasets and reports
+// dangling references, INSPIRE theme and HVD category mismatches, and bounding boxes that are not covered.
+// A reference resolves to a dataset by metadata ID, or else by the source identifier of the dataset.
+func CheckCoupling(
+	services []metadata.NLServiceMetadata,
+	datasets []metadata.NLDatasetMetadata,
+) (issues []CouplingIssue) {
+	index := newDatasetIndex(datasets)
+
+	for i := range services {
+		service := &services[i]
+		newIssue := func(datasetID string, issueType CouplingIssueType, message string) CouplingIssue {
+			return CouplingIssue{
+				ServiceID:        service.MetadataID,
+				ServiceTitle:     service.Title,
+				OrganisationName: service.OrganisationName,
+				DatasetID:        datasetID,
+				Type:             issueType,
+				Message:          message,
+			}
+		}
+
+		var coupled []*metadata.NLDatasetMetadata
+
+		for _, ref := range service.OperatesOn {
+			dataset, ok := index.resolve(ref)
+			if !ok {
+				issues = append(issues, newIssue(ref, DanglingReference,
+					"operatesOn refers to a dataset that does not exist"))
+
+				continue
+			}
+
+			coupled = append(coupled, dataset)
+
+			if issue, ok := checkBBoxCovered(service, dataset); ok {
+				issues = append(issues, newIssue(dataset.MetadataID, BBoxNotCovered, issue))
+			}
+		}
+
+		if len(coupled) == 0 {
+			continue
+		}
+
+		var datasetThemes, datasetCategories []string
+		for _, dataset := range coupled {
+			datasetThemes = append(datasetThemes, dataset.InspireThemes...)
+			datasetCategories = append(datasetCategories, getHVDCategoryIDs(dataset.HVDCategories)...)
+		}
+
+		if message, ok := compareSets("INSPIRE themes", service.InspireThemes, datasetThemes); ok {
+			issues = append(issues, newIssue("", InspireThemeMismatch, message))
+		}
+
+		serviceCategories := getHVDCategoryIDs(service.HVDCategories)
+		if message, ok := compareSets("HVD categories", serviceCategories, datasetCategories); ok {
+			issues = append(issues, newIssue("", HVDCategoryMismatch, message))
+		}
+	}
+
+	return issues
+}
+
+// datasetIndex resolves operatesOn references to datasets,
+// by metadata ID or else by the source identifier of the dataset.
+type datasetIndex struct {
+	byMetadataID map[string]*metadata.NLDatasetMetadata
+	bySourceID   map[string]*metadata.NLDatasetMetadata
+}
+
+func newDatasetIndex(datasets []metadata.NLDatasetMetadata) *datasetIndex {
+	index := &datasetIndex{
+		byMetadataID: map[string]*metadata.NLDatasetMetadata{},
+		bySourceID:   map[string]*metadata.NLDatasetMetadata{},
+	}
+
+	for i := range datasets {
+		index.byMetadataID[strings.ToLower(datasets[i].MetadataID)] = &datasets[i]
+		if datasets[i].SourceID != "" {
+			index.bySourceID[strings.ToLower(datasets[i].SourceID)] = &datasets[i]
+		}
+	}
+
+	return index
+}
+
+func (i *datasetIndex) resolve(ref string) (*metadata.NLDatasetMetadata, bool) {
+	ref = strings.ToLower(strings.TrimSpace(ref))
+
+	if dataset, ok := i.byMetadataID[ref]; ok {
+		return dataset, true
+	}
+
+	dataset, ok := i.bySourceID[ref]
+
+	return dataset, ok
+}
+
+// checkBBoxCovered returns a message when the service bounding box does not cover the dataset bounding box.
+// Missing or invalid bounding boxes are not reported, as they cannot be compared.
+func checkBBoxCovered(
+	service *metadata.NLServiceMetadata,
+	dataset *metadata.NLDatasetMetadata,
+) (string, bool) {
+	if service.BoundingBox.IsEmpty() || dataset.BoundingBox.IsEmpty() {
+		return "", false
+	}
+
+	covers, err := service.BoundingBox.Covers(dataset.BoundingBox)
+	if err != nil || covers {
+		return "", false
+	}
+
+	return "service bounding box does not cover the dataset bounding box", true
+}
+
+// compareSets returns a message when the service values differ from the values of its datasets.
+func compareSets(label string, serviceValues []string, datasetValues []string) (string, bool) {
+	missing := difference(datasetValues, serviceValues)
+	extra := difference(serviceValues, datasetValues)
+
+	if len(missing) == 0 && len(extra) == 0 {
+		return "", false
+	}
+
+	var parts []string
+	if len(missing) > 0 {
+		parts = append(parts, label+" of the datasets missing in the service: "+strings.Join(missing, ", "))
+	}
+
+	if len(extra) > 0 {
+		parts = append(parts, label+" of the service not in any dataset: "+strings.Join(extra, ", "))
+	}
+
+	return strings.Join(parts, "; "), true
+}
+
+// difference returns the normalized values of a that are not in b, sorted and without duplicates.
+func difference(a []string, b []string) (result []string) {
+	normalizedB := make([]string, 0, len(b))
+	for _, v := range b {
+		normalizedB = append(normalizedB, strings.ToLower(strings.TrimSpace(v)))
+	}
+
+	for _, v := range a {
+		v = strings.ToLower(strings.TrimSpace(v))
+		if v != "" && !slices.Contains(normalizedB, v) && !slices.Contains(result, v) {
+			result = append(result, v)
+		}
+	}
+
+	sort.Strings(result)
+
+	return result
+}
+
+func getHVDCategoryIDs(categories []hvd.HVDCategory) (ids []string) {
+	for _, category := range categories {
+		ids = append(ids, category.ID)
+	}
+
+	return ids
+}
@@ -0,0 +1,116 @@
+package checker
+
+import (
+	"testing"
+
+	"github.com/pdok/pdok-metadata-tool/v2/pkg/model/hvd"
+	"github.com/pdok/pdok-metadata-tool/v2/pkg/model/metadata"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCheckCoupling(t *testing.T) {
+	netherlands := &metadata.BoundingBox{
+		WestBoundLongitude: "3.2",
+		EastBoundLongitude: "7.22",
+		SouthBoundLatitude: "50.75",
+		NorthBoundLatitude: "53.7",
+	}
+	utrecht := &metadata.BoundingBox{
+		WestBoundLongitude: "4.9",
+		EastBoundLongitude: "5.3",
+		SouthBoundLatitude: "51.9",
+		NorthBoundLatitude: "52.2",
+	}
+
+	datasets := []metadata.NLDatasetMetadata{
+		{
+			MetadataID:    "dataset-nl",
+			SourceID:      "source-nl",
+			InspireThemes: []string{"tn"},
+			HVDCategories: []hvd.HVDCategory{{ID: "c_b79e35eb"}},
+			BoundingBox:   netherlands,
+		},
+		{
+			MetadataID:    "dataset-utrecht",
+			InspireThemes: []string{"tn"},
+			BoundingBox:   utrecht,
+		},
+	}
+
+	tests := []struct {
+		name    string
+		service metadata.NLServiceMetadata
+		want    []CouplingIssue
+	}{
+		{
+			name: "Consistent service",
+			service: metadata.NLServiceMetadata{
+				MetadataID:    "service",
+				OperatesOn:    []string{"dataset-nl", "DATASET-UTRECHT"},
+				InspireThemes: []string{"tn"},
+				HVDCategories: []hvd.HVDCategory{{ID: "c_b79e35eb"}},
+				BoundingBox:   netherlands,
+			},
+		},
+		{
+			name: "Reference by source identifier and without bounding box",
+			service: metadata.NLServiceMetadata{
+				MetadataID:    "service",
+				OperatesOn:    []string{"source-nl"},
+				InspireThemes: []string{"tn"},
+				HVDCategories: []hvd.HVDCategory{{ID: "c_b79e35eb"}},
+			},
+		},
+		{
+			name: "Dangling reference",
+			service: metadata.NLServiceMetadata{
+				MetadataID:       "service",
+				OrganisationName: "Org",
+				OperatesOn:       []string{"deleted"},
+			},
+			want: []CouplingIssue{
+				{
+					ServiceID:        "service",
+					OrganisationName: "Org",
+					DatasetID:        "deleted",
+					Type:             DanglingReference,
+					Message:          "operatesOn refers to a dataset that does not exist",
+				},
+			},
+		},
+		{
+			name: "Theme and HVD mismatch, bounding box not covered",
+			service: metadata.NLServiceMetadata{
+				MetadataID:    "service",
+				OperatesOn:    []string{"dataset-nl"},
+				InspireThemes: []string{"TN", "hy"},
+				BoundingBox:   utrecht,
+			},
+			want: []CouplingIssue{
+				{
+					ServiceID: "service",
+					DatasetID: "dataset-nl",
+					Type:      BBoxNotCovered,
+					Message:   "service bounding box does not cover the dataset bounding box",
+				},
+				{
+					ServiceID: "service",
+					Type:      InspireThemeMismatch,
+					Message:   "INSPIRE themes of the service not in any dataset: hy",
+				},
+				{
+					ServiceID: "service",
+					Type:      HVDCategoryMismatch,
+					Message:   "HVD categories of the datasets missing in the service: c_b79e35eb",
+				},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			issues := CheckCoupling([]metadata.NLServiceMetadata{tt.service}, datasets)
+			assert.Equal(t, tt.want, issues)
+		})
+	}
+}
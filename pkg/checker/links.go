@@ -0,0 +1,448 @@
+// Package checker provides checks on (sets of) metadata records that go beyond a single record,
+// i.e. checking the URLs in records and the coupling between services and datasets.
+package checker
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"io"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pdok/pdok-metadata-tool/v2/internal/common"
+	"github.com/pdok/pdok-metadata-tool/v2/pkg/model/iso1911x"
+)
+
+// Sources of a link within a metadata record.
+const (
+	LinkSourceAccessPoint = "accessPoint"
+	LinkSourceLicense     = "license"
+	LinkSourceThumbnail   = "thumbnail"
+	LinkSourceContact     = "contact"
+	LinkSourceOperatesOn  = "operatesOn"
+	LinkSourceXML         = "xml"
+)
+
+// ignoredXMLAttributes holds attributes with URIs that are identifiers rather than links.
+var ignoredXMLAttributes = []string{"schemaLocation", "codeList", "codeSpace", "uom", "xmlns"}
+
+// Link is a URL found in a metadata record.
+type Link struct {
+	URL    string `json:"url"`
+	Source string `json:"source"`
+}
+
+// LinkResult holds the outcome of checking a single URL.
+type LinkResult struct {
+	URL        string    `json:"url"`
+	StatusCode int       `json:"statusCode,omitempty"`
+	OK         bool      `json:"ok"`
+	Error      string    `json:"error,omitempty"`
+	CheckedAt  time.Time `json:"checkedAt"`
+}
+
+// LinkFailure is a link in a record that failed the check.
+type LinkFailure struct {
+	Link
+	Result LinkResult `json:"result"`
+}
+
+// RecordLinks holds the links found in a single metadata record.
+type RecordLinks struct {
+	MetadataID       string `json:"metadataId"`
+	Title            string `json:"title"`
+	OrganisationName string `json:"organisationName"`
+	Links            []Link `json:"links"`
+}
+
+// RecordLinkReport holds the failed links of a single metadata record.
+type RecordLinkReport struct {
+	MetadataID       string        `json:"metadataId"`
+	Title            string        `json:"title"`
+	OrganisationName string        `json:"organisationName"`
+	Links            int           `json:"links"`
+	Failures         []LinkFailure `json:"failures"`
+}
+
+// OrganisationLinkSummary aggregates the link reports for a single organisation.
+type OrganisationLinkSummary struct {
+	OrganisationName string `json:"organisationName"`
+	Records          int    `json:"records"`
+	Links            int    `json:"links"`
+	Broken           int    `json:"broken"`
+}
+
+// LinkChecker checks URLs concurrently with a per host rate limit and caches the results.
+type LinkChecker struct {
+	Client      *http.Client
+	Concurrency int
+	// HostInterval is the minimum time between two requests to the same host.
+	HostInterval time.Duration
+	// CacheTTL is how long a cached successful result is reused. Zero means results are only reused within a run.
+	CacheTTL time.Duration
+
+	createdAt time.Time
+	cacheMu   sync.Mutex
+	cache     map[string]LinkResult
+	hostsMu   sync.Mutex
+	hostNext  map[string]time.Time
+}
+
+// NewLinkChecker creates a new instance of LinkChecker that checks links with the HTTP client, see
+// client.NewHTTPClient. A client without retries is recommended: failing links are reported, not retried.
+func NewLinkChecker(httpClient *http.Client, concurrency int, hostInterval time.Duration) *LinkChecker {
+	if concurrency < 1 {
+		concurrency = 1
+	}
+
+	return &LinkChecker{
+		Client:       httpClient,
+		Concurrency:  concurrency,
+		HostInterval: hostInterval,
+		createdAt:    time.Now(),
+		cache:        map[string]LinkResult{},
+		hostNext:     map[string]time.Time{},
+	}
+}
+
+// ExtractLinks returns all URLs in a record. Known links are found through the MDMetadata helpers,
+// the remaining URLs are found with a generic walk over the XML. Each URL is returned once.
+func ExtractLinks(md *iso1911x.MDMetadata, data []byte) RecordLinks {
+	result := RecordLinks{
+		MetadataID:       iso1911x.NormalizeXMLText(md.UUID),
+		Title:            md.GetTitle(),
+		OrganisationName: md.GetOrganisationName(),
+	}
+	seen := map[string]bool{}
+
+	add := func(rawURL string, source string) {
+		rawURL = iso1911x.NormalizeXMLText(rawURL)
+		if !isHTTPURL(rawURL) || seen[rawURL] {
+			return
+		}
+
+		seen[rawURL] = true
+		result.Links = append(result.Links, Link{URL: rawURL, Source: source})
+	}
+
+	for _, ep := range md.GetServiceEndpointsForService() {
+		add(ep.URL, LinkSourceAccessPoint)
+	}
+
+	for _, oc := range md.GetOtherConstraints() {
+		add(oc.Href, LinkSourceLicense)
+	}
+
+	add(md.GetThumbnailURL(), LinkSourceThumbnail)
+	add(md.GetContactURL(), LinkSourceContact)
+
+	if md.GetMetaDataType() == iso1911x.Service && md.IdentificationInfo.SVServiceIdentification != nil {
+		for _, operatesOn := range md.IdentificationInfo.SVServiceIdentification.OperatesOn {
+			add(operatesOn.Href, LinkSourceOperatesOn)
+		}
+	}
+
+	for _, rawURL := range extractURLsFromXML(data) {
+		add(rawURL, LinkSourceXML)
+	}
+
+	return result
+}
+
+// Check checks the given URLs concurrently and returns the results by URL.
+// Cached results are reused when still valid.
+func (lc *LinkChecker) Check(ctx context.Context, urls []string) map[string]LinkResult {
+	jobs := make(chan string)
+	results := make(map[string]LinkResult, len(urls))
+
+	var (
+		mu sync.Mutex
+		wg sync.WaitGroup
+	)
+
+	for range lc.Concurrency {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			for u := range jobs {
+				result := lc.checkURL(ctx, u)
+
+				mu.Lock()
+				results[u] = result
+				mu.Unlock()
+			}
+		}()
+	}
+
+	for _, u := range uniqueStrings(urls) {
+		jobs <- u
+	}
+
+	close(jobs)
+	wg.Wait()
+
+	return results
+}
+
+// CheckRecords checks the links of all records and reports the failures per record.
+func (lc *LinkChecker) CheckRecords(ctx context.Context, records []RecordLinks) []RecordLinkReport {
+	var urls []string
+	for _, record := range records {
+		for _, link := range record.Links {
+			urls = append(urls, link.URL)
+		}
+	}
+
+	results := lc.Check(ctx, urls)
+
+	reports := make([]RecordLinkReport, 0, len(records))
+	for _, record := range records {
+		report := RecordLinkReport{
+			MetadataID:       record.MetadataID,
+			Title:            record.Title,
+			OrganisationName: record.OrganisationName,
+			Links:            len(record.Links),
+		}
+
+		for _, link := range record.Links {
+			if result := results[link.URL]; !result.OK {
+				report.Failures = append(report.Failures, LinkFailure{Link: link, Result: result})
+			}
+		}
+
+		reports = append(reports, report)
+	}
+
+	return reports
+}
+
+// LoadCache reads previously checked results from a JSON file. A missing file is not an error.
+func (lc *LinkChecker) LoadCache(path string) error {
+	//nolint:gosec
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	var cached map[string]LinkResult
+	if err := json.Unmarshal(data, &cached); err != nil {
+		return err
+	}
+
+	lc.cacheMu.Lock()
+	defer lc.cacheMu.Unlock()
+
+	for u, result := range cached {
+		lc.cache[u] = result
+	}
+
+	return nil
+}
+
+// SaveCache writes the checked results to a JSON file.
+func (lc *LinkChecker) SaveCache(path string) error {
+	lc.cacheMu.Lock()
+	data, err := json.MarshalIndent(lc.cache, "", "  ")
+	lc.cacheMu.Unlock()
+
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(path, data, 0o600)
+}
+
+// SummarizeLinksByOrganisation aggregates the link reports per organisation, sorted by organisation name.
+func SummarizeLinksByOrganisation(reports []RecordLinkReport) []OrganisationLinkSummary {
+	summaries := map[string]*OrganisationLinkSummary{}
+
+	for _, report := range reports {
+		summary, ok := summaries[report.OrganisationName]
+		if !ok {
+			summary = &OrganisationLinkSummary{OrganisationName: report.OrganisationName}
+			summaries[report.OrganisationName] = summary
+		}
+
+		summary.Records++
+		summary.Links += report.Links
+		summary.Broken += len(report.Failures)
+	}
+
+	result := make([]OrganisationLinkSummary, 0, len(summaries))
+	for _, summary := range summaries {
+		result = append(result, *summary)
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].OrganisationName < result[j].OrganisationName
+	})
+
+	return result
+}
+
+func (lc *LinkChecker) checkURL(ctx context.Context, rawURL string) LinkResult {
+	if result, ok := lc.getCached(rawURL); ok {
+		return result
+	}
+
+	result := LinkResult{URL: rawURL}
+
+	statusCode, err := lc.request(ctx, http.MethodHead, rawURL)
+	// Not all servers support HEAD, so fall back to GET
+	if err != nil || statusCode >= http.StatusBadRequest {
+		statusCode, err = lc.request(ctx, http.MethodGet, rawURL)
+	}
+
+	result.StatusCode = statusCode
+	if err != nil {
+		result.Error = err.Error()
+	}
+
+	result.OK = err == nil && statusCode < http.StatusBadRequest
+	result.CheckedAt = time.Now()
+
+	// Do not cache results of a cancelled run, they say nothing about the link
+	if ctx.Err() == nil {
+		lc.cacheMu.Lock()
+		lc.cache[rawURL] = result
+		lc.cacheMu.Unlock()
+	}
+
+	return result
+}
+
+func (lc *LinkChecker) getCached(rawURL string) (LinkResult, bool) {
+	lc.cacheMu.Lock()
+	defer lc.cacheMu.Unlock()
+
+	result, ok := lc.cache[rawURL]
+	if !ok {
+		return LinkResult{}, false
+	}
+
+	// Results from this run are always reused, successful results from earlier runs only within the TTL.
+	// Failures from earlier runs are checked again, they may have been temporary.
+	if result.CheckedAt.Before(lc.createdAt) && (!result.OK || time.Since(result.CheckedAt) > lc.CacheTTL) {
+		return LinkResult{}, false
+	}
+
+	return result, true
+}
+
+func (lc *LinkChecker) request(ctx context.Context, method string, rawURL string) (int, error) {
+	req, err := http.NewRequestWithContext(ctx, method, rawURL, nil)
+	if err != nil {
+		return 0, err
+	}
+
+	if err := lc.waitForHost(ctx, req.URL.Host); err != nil {
+		return 0, err
+	}
+
+	resp, err := lc.Client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer common.SafeClose(resp.Body)
+
+	// Drain a small part of the body so the connection can be reused
+	_, _ = io.CopyN(io.Discard, resp.Body, 1024) //nolint:mnd
+
+	return resp.StatusCode, nil
+}
+
+// waitForHost blocks until a request to the host is allowed by the per host rate limit.
+func (lc *LinkChecker) waitForHost(ctx context.Context, host string) error {
+	if lc.HostInterval <= 0 {
+		return nil
+	}
+
+	lc.hostsMu.Lock()
+
+	now := time.Now()
+
+	slot := lc.hostNext[host]
+	if slot.Before(now) {
+		slot = now
+	}
+
+	lc.hostNext[host] = slot.Add(lc.HostInterval)
+	lc.hostsMu.Unlock()
+
+	timer := time.NewTimer(time.Until(slot))
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// extractURLsFromXML walks all elements and returns http(s) URLs found in attributes and text.
+func extractURLsFromXML(data []byte) (urls []string) {
+	decoder := xml.NewDecoder(bytes.NewReader(data))
+
+	for {
+		token, err := decoder.Token()
+		if err != nil {
+			return urls
+		}
+
+		switch t := token.(type) {
+		case xml.StartElement:
+			for _, attr := range t.Attr {
+				if attr.Name.Space == "xmlns" || isIgnoredXMLAttribute(attr.Name.Local) {
+					continue
+				}
+
+				if isHTTPURL(strings.TrimSpace(attr.Value)) {
+					urls = append(urls, strings.TrimSpace(attr.Value))
+				}
+			}
+		case xml.CharData:
+			if text := strings.TrimSpace(string(t)); isHTTPURL(text) && !strings.ContainsAny(text, " \n\t") {
+				urls = append(urls, text)
+			}
+		}
+	}
+}
+
+func isIgnoredXMLAttribute(name string) bool {
+	for _, ignored := range ignoredXMLAttributes {
+		if name == ignored {
+			return true
+		}
+	}
+
+	return false
+}
+
+func isHTTPURL(s string) bool {
+	return strings.HasPrefix(s, "http://") || strings.HasPrefix(s, "https://")
+}
+
+func uniqueStrings(values []string) (result []string) {
+	seen := map[string]bool{}
+
+	for _, v := range values {
+		if !seen[v] {
+			seen[v] = true
+			result = append(result, v)
+		}
+	}
+
+	return result
+}
@@ -0,0 +1,209 @@
+package checker
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/pdok/pdok-metadata-tool/v2/internal/common"
+	"github.com/pdok/pdok-metadata-tool/v2/internal/testutil"
+	"github.com/pdok/pdok-metadata-tool/v2/pkg/client"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func buildMockWebserverLinks(requests *atomic.Int32) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		requests.Add(1)
+
+		switch req.URL.Path {
+		case "/ok":
+			rw.WriteHeader(http.StatusOK)
+		case "/no-head":
+			if req.Method == http.MethodHead {
+				rw.WriteHeader(http.StatusMethodNotAllowed)
+
+				return
+			}
+
+			rw.WriteHeader(http.StatusOK)
+		case "/slow":
+			time.Sleep(200 * time.Millisecond)
+			rw.WriteHeader(http.StatusOK)
+		default:
+			rw.WriteHeader(http.StatusNotFound)
+		}
+	}))
+}
+
+func TestExtractLinks(t *testing.T) {
+	path := filepath.Join(common.GetProjectRoot(), "pkg", "generator", "iso19119", "testdata", "expected",
+		"inspire_hvd_complex_oaf_interoperable.xml")
+	md := testutil.LoadMDMetadata(t, path)
+
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+
+	record := ExtractLinks(&md, data)
+
+	assert.Equal(t, "00000000-0000-0000-0000-000000000016", record.MetadataID)
+	assert.Equal(t, "Beheer PDOK", record.OrganisationName)
+
+	sources := map[string]string{}
+	for _, link := range record.Links {
+		_, duplicate := sources[link.URL]
+		assert.False(t, duplicate, "duplicate link %s", link.URL)
+
+		sources[link.URL] = link.Source
+	}
+
+	assert.Equal(t, LinkSourceLicense, sources["http://creativecommons.org/licenses/by/4.0/deed.nl"])
+	assert.Equal(t, LinkSourceThumbnail, sources["https://test.nl/thumb.png"])
+	assert.Equal(t, LinkSourceContact, sources["https://www.pdok.nl/contact"])
+	assert.Equal(t, LinkSourceXML, sources["http://data.europa.eu/bna/c_ac64a52d"])
+	assert.NotContains(t, sources, "http://www.isotc211.org/2005/gmd")
+	assert.NotContains(t, sources, "http://schemas.opengis.net/csw/2.0.2/profiles/apiso/1.0.0/apiso.xsd")
+
+	for _, link := range record.Links {
+		if link.Source == LinkSourceAccessPoint || link.Source == LinkSourceOperatesOn {
+			return
+		}
+	}
+
+	t.Error("expected an access point or operatesOn link")
+}
+
+func TestLinkChecker_CheckRecords(t *testing.T) {
+	var requests atomic.Int32
+
+	server := buildMockWebserverLinks(&requests)
+	defer server.Close()
+
+	records := []RecordLinks{
+		{
+			MetadataID:       "a",
+			OrganisationName: "Org B",
+			Links: []Link{
+				{URL: server.URL + "/ok", Source: LinkSourceAccessPoint},
+				{URL: server.URL + "/missing", Source: LinkSourceThumbnail},
+			},
+		},
+		{
+			MetadataID:       "b",
+			OrganisationName: "Org A",
+			Links: []Link{
+				{URL: server.URL + "/ok", Source: LinkSourceLicense},
+				{URL: server.URL + "/no-head", Source: LinkSourceContact},
+			},
+		},
+	}
+
+	lc := NewLinkChecker(newTestHTTPClient(t, time.Second), 4, 0)
+	reports := lc.CheckRecords(context.Background(), records)
+
+	require.Len(t, reports, 2)
+	require.Len(t, reports[0].Failures, 1)
+	assert.Equal(t, server.URL+"/missing", reports[0].Failures[0].URL)
+	assert.Equal(t, http.StatusNotFound, reports[0].Failures[0].Result.StatusCode)
+	assert.Empty(t, reports[1].Failures)
+
+	// ok: HEAD, missing: HEAD + GET, no-head: HEAD + GET
+	assert.Equal(t, int32(5), requests.Load())
+
+	// A second run is served from the result cache
+	lc.CheckRecords(context.Background(), records)
+	assert.Equal(t, int32(5), requests.Load())
+
+	assert.Equal(t, []OrganisationLinkSummary{
+		{OrganisationName: "Org A", Records: 1, Links: 2, Broken: 0},
+		{OrganisationName: "Org B", Records: 1, Links: 2, Broken: 1},
+	}, SummarizeLinksByOrganisation(reports))
+}
+
+func TestLinkChecker_Check(t *testing.T) {
+	var requests atomic.Int32
+
+	server := buildMockWebserverLinks(&requests)
+	defer server.Close()
+
+	t.Run("Timeout", func(t *testing.T) {
+		lc := NewLinkChecker(newTestHTTPClient(t, 50*time.Millisecond), 1, 0)
+		results := lc.Check(context.Background(), []string{server.URL + "/slow"})
+
+		assert.False(t, results[server.URL+"/slow"].OK)
+		assert.NotEmpty(t, results[server.URL+"/slow"].Error)
+	})
+
+	t.Run("Per host rate limit", func(t *testing.T) {
+		interval := 50 * time.Millisecond
+		lc := NewLinkChecker(newTestHTTPClient(t, time.Second), 4, interval)
+
+		start := time.Now()
+		results := lc.Check(context.Background(), []string{
+			server.URL + "/ok?1", server.URL + "/ok?2", server.URL + "/ok?3",
+		})
+
+		assert.Len(t, results, 3)
+		assert.GreaterOrEqual(t, time.Since(start), 2*interval)
+	})
+
+	t.Run("Persistent cache", func(t *testing.T) {
+		cachePath := filepath.Join(t.TempDir(), "links.json")
+		u := server.URL + "/ok?cached"
+
+		lc := NewLinkChecker(newTestHTTPClient(t, time.Second), 1, 0)
+		lc.Check(context.Background(), []string{u})
+		require.NoError(t, lc.SaveCache(cachePath))
+
+		before := requests.Load()
+
+		lc = NewLinkChecker(newTestHTTPClient(t, time.Second), 1, 0)
+		lc.CacheTTL = time.Hour
+		require.NoError(t, lc.LoadCache(cachePath))
+		lc.Check(context.Background(), []string{u})
+		assert.Equal(t, before, requests.Load())
+
+		// Without a TTL, results from earlier runs are checked again
+		lc = NewLinkChecker(newTestHTTPClient(t, time.Second), 1, 0)
+		require.NoError(t, lc.LoadCache(cachePath))
+		lc.Check(context.Background(), []string{u})
+		assert.Equal(t, before+1, requests.Load())
+	})
+
+	t.Run("Proxy", func(t *testing.T) {
+		var proxied atomic.Int32
+
+		proxy := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+			proxied.Add(1)
+			assert.Equal(t, "link.test", req.URL.Host)
+			rw.WriteHeader(http.StatusOK)
+		}))
+		defer proxy.Close()
+
+		proxyURL, err := url.Parse(proxy.URL)
+		require.NoError(t, err)
+
+		httpClient, err := client.NewHTTPClient(client.HTTPConfig{Timeout: time.Second, Proxy: proxyURL})
+		require.NoError(t, err)
+
+		results := NewLinkChecker(httpClient, 1, 0).Check(context.Background(), []string{"http://link.test/ok"})
+		assert.True(t, results["http://link.test/ok"].OK)
+		assert.Equal(t, int32(1), proxied.Load())
+	})
+}
+
+// newTestHTTPClient returns an HTTP client without retries, as used by pmt check links.
+func newTestHTTPClient(t *testing.T, timeout time.Duration) *http.Client {
+	t.Helper()
+
+	httpClient, err := client.NewHTTPClient(client.HTTPConfig{Timeout: timeout})
+	require.NoError(t, err)
+
+	return httpClient
+}
@@ -0,0 +1,223 @@
+package checker
+
+import (
+	"encoding/csv"
+	"io"
+	"slices"
+	"strings"
+
+	"github.com/pdok/pdok-metadata-tool/v2/pkg/model/inspire"
+	"github.com/pdok/pdok-metadata-tool/v2/pkg/model/metadata"
+)
+
+// ServiceKind holds the kinds of services a dataset is expected to be served by.
+type ServiceKind string
+
+// Values for ServiceKind.
+const (
+	ViewService     ServiceKind = "view"
+	DownloadService ServiceKind = "download"
+	OGCAPIService   ServiceKind = "ogc-api"
+)
+
+// serviceKinds holds all kinds in the order in which they are reported.
+var serviceKinds = []ServiceKind{ViewService, DownloadService, OGCAPIService}
+
+const ogcAPIProtocolPrefix = "OGC:API"
+
+// OrphanFilter limits the orphan report to an organisation and/or INSPIRE variant. Empty values match all.
+type OrphanFilter struct {
+	OrganisationName string
+	InspireVariant   inspire.InspireVariant // Only applies to datasets
+}
+
+// DatasetCoverage holds the services that operate on a dataset, by kind.
+type DatasetCoverage struct {
+	MetadataID       string                   `json:"metadataId"`
+	Title            string                   `json:"title"`
+	OrganisationName string                   `json:"organisationName"`
+	InspireVariant   inspire.InspireVariant   `json:"inspireVariant,omitempty"`
+	Services         map[ServiceKind][]string `json:"services"` // Service metadata IDs by kind
+	Missing          []ServiceKind            `json:"missing"`
+}
+
+// OrphanService is a service that does not operate on any existing dataset.
+type OrphanService struct {
+	MetadataID       string   `json:"metadataId"`
+	Title            string   `json:"title"`
+	OrganisationName string   `json:"organisationName"`
+	ServiceType      string   `json:"serviceType"`
+	OperatesOn       []string `json:"operatesOn"` // The unresolved references, if any
+}
+
+// OrphanReport holds the datasets that miss a kind of service and the services without datasets.
+type OrphanReport struct {
+	Datasets []DatasetCoverage `json:"datasetsMissingServices"`
+	Services []OrphanService   `json:"servicesWithoutDatasets"`
+}
+
+// FindOrphans joins services and datasets through operatesOn and reports the datasets that miss a view,
+// download or OGC API service, and the services that do not operate on any existing dataset.
+// The filter only limits the report, so pass the services and datasets of all organisations to resolve
+// services that operate on datasets of other organisations.
+func FindOrphans(
+	services []metadata.NLServiceMetadata,
+	datasets []metadata.NLDatasetMetadata,
+	filter OrphanFilter,
+) OrphanReport {
+	index := newDatasetIndex(datasets)
+	coverage := map[string]map[ServiceKind][]string{}
+
+	var report OrphanReport
+
+	for i := range services {
+		service := &services[i]
+		kinds := getServiceKinds(service)
+		resolved := false
+
+		for _, ref := range service.OperatesOn {
+			dataset, ok := index.resolve(ref)
+			if !ok {
+				continue
+			}
+
+			resolved = true
+
+			if coverage[dataset.MetadataID] == nil {
+				coverage[dataset.MetadataID] = map[ServiceKind][]string{}
+			}
+
+			for _, kind := range kinds {
+				coverage[dataset.MetadataID][kind] = append(coverage[dataset.MetadataID][kind], service.MetadataID)
+			}
+		}
+
+		if !resolved && filter.matchesOrganisation(service.OrganisationName) {
+			report.Services = append(report.Services, OrphanService{
+				MetadataID:       service.MetadataID,
+				Title:            service.Title,
+				OrganisationName: service.OrganisationName,
+				ServiceType:      service.ServiceType,
+				OperatesOn:       service.OperatesOn,
+			})
+		}
+	}
+
+	for i := range datasets {
+		dataset := &datasets[i]
+		if !filter.matchesOrganisation(dataset.OrganisationName) ||
+			(filter.InspireVariant != "" && filter.InspireVariant != dataset.InspireVariant) {
+			continue
+		}
+
+		services := coverage[dataset.MetadataID]
+		if services == nil {
+			services = map[ServiceKind][]string{}
+		}
+
+		var missing []ServiceKind
+
+		for _, kind := range serviceKinds {
+			if len(services[kind]) == 0 {
+				missing = append(missing, kind)
+			}
+		}
+
+		if len(missing) == 0 {
+			continue
+		}
+
+		report.Datasets = append(report.Datasets, DatasetCoverage{
+			MetadataID:       dataset.MetadataID,
+			Title:            dataset.Title,
+			OrganisationName: dataset.OrganisationName,
+			InspireVariant:   dataset.InspireVariant,
+			Services:         services,
+			Missing:          missing,
+		})
+	}
+
+	return report
+}
+
+// WriteCSV writes the report as CSV, with one row per dataset or service.
+func (r *OrphanReport) WriteCSV(w io.Writer) error {
+	writer := csv.NewWriter(w)
+
+	header := []string{
+		"Kind", "MetadataID", "Title", "OrganisationName", "InspireVariant",
+		"ViewServices", "DownloadServices", "OGCAPIServices", "Missing", "ServiceType", "OperatesOn",
+	}
+	if err := writer.Write(header); err != nil {
+		return err
+	}
+
+	for _, dataset := range r.Datasets {
+		missing := make([]string, 0, len(dataset.Missing))
+		for _, kind := range dataset.Missing {
+			missing = append(missing, string(kind))
+		}
+
+		row := []string{
+			"dataset",
+			dataset.MetadataID,
+			dataset.Title,
+			dataset.OrganisationName,
+			string(dataset.InspireVariant),
+			strings.Join(dataset.Services[ViewService], " "),
+			strings.Join(dataset.Services[DownloadService], " "),
+			strings.Join(dataset.Services[OGCAPIService], " "),
+			strings.Join(missing, " "),
+			"",
+			"",
+		}
+		if err := writer.Write(row); err != nil {
+			return err
+		}
+	}
+
+	for _, service := range r.Services {
+		row := []string{
+			"service",
+			service.MetadataID,
+			service.Title,
+			service.OrganisationName,
+			"",
+			"",
+			"",
+			"",
+			"",
+			service.ServiceType,
+			strings.Join(service.OperatesOn, " "),
+		}
+		if err := writer.Write(row); err != nil {
+			return err
+		}
+	}
+
+	writer.Flush()
+
+	return writer.Error()
+}
+
+func (f OrphanFilter) matchesOrganisation(organisationName string) bool {
+	return f.OrganisationName == "" || strings.EqualFold(f.OrganisationName, organisationName)
+}
+
+// getServiceKinds determines the kinds of a service from the service type and the protocols of its endpoints.
+func getServiceKinds(service *metadata.NLServiceMetadata) (kinds []ServiceKind) {
+	switch strings.ToLower(service.ServiceType) {
+	case string(ViewService):
+		kinds = append(kinds, ViewService)
+	case string(DownloadService):
+		kinds = append(kinds, DownloadService)
+	}
+
+	for _, ep := range service.Endpoints {
+		if strings.HasPrefix(ep.Protocol, ogcAPIProtocolPrefix) && !slices.Contains(kinds, OGCAPIService) {
+			kinds = append(kinds, OGCAPIService)
+		}
+	}
+
+	return kinds
+}
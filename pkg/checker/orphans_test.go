@@ -0,0 +1,102 @@
+package checker
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/pdok/pdok-metadata-tool/v2/pkg/model/inspire"
+	"github.com/pdok/pdok-metadata-tool/v2/pkg/model/iso1911x"
+	"github.com/pdok/pdok-metadata-tool/v2/pkg/model/metadata"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func getOrphanTestdata() ([]metadata.NLServiceMetadata, []metadata.NLDatasetMetadata) {
+	services := []metadata.NLServiceMetadata{
+		{MetadataID: "wms", OrganisationName: "Org A", ServiceType: "view", OperatesOn: []string{"full", "view-only"}},
+		{MetadataID: "atom", OrganisationName: "Org A", ServiceType: "download", OperatesOn: []string{"full"}},
+		{
+			MetadataID:       "oaf",
+			OrganisationName: "Org A",
+			ServiceType:      "download",
+			OperatesOn:       []string{"source-full"},
+			Endpoints:        []iso1911x.ServiceEndpoint{{URL: "https://test.nl/ogc/v1", Protocol: "OGC:API features"}},
+		},
+		{MetadataID: "dangling", OrganisationName: "Org A", ServiceType: "view", OperatesOn: []string{"deleted"}},
+		{MetadataID: "empty", OrganisationName: "Org B", ServiceType: "download"},
+		{MetadataID: "wms-b", OrganisationName: "Org B", ServiceType: "view", OperatesOn: []string{"full"}},
+	}
+	datasets := []metadata.NLDatasetMetadata{
+		{MetadataID: "full", SourceID: "source-full", OrganisationName: "Org A", InspireVariant: inspire.Harmonised},
+		{MetadataID: "view-only", OrganisationName: "Org A", InspireVariant: inspire.AsIs},
+		{MetadataID: "none", OrganisationName: "Org B"},
+	}
+
+	return services, datasets
+}
+
+func TestFindOrphans(t *testing.T) {
+	services, datasets := getOrphanTestdata()
+
+	tests := []struct {
+		name         string
+		filter       OrphanFilter
+		wantDatasets map[string][]ServiceKind
+		wantServices []string
+	}{
+		{
+			name: "No filter",
+			wantDatasets: map[string][]ServiceKind{
+				"view-only": {DownloadService, OGCAPIService},
+				"none":      {ViewService, DownloadService, OGCAPIService},
+			},
+			wantServices: []string{"dangling", "empty"},
+		},
+		{
+			name:   "Filter by organisation",
+			filter: OrphanFilter{OrganisationName: "org b"},
+			wantDatasets: map[string][]ServiceKind{
+				"none": {ViewService, DownloadService, OGCAPIService},
+			},
+			wantServices: []string{"empty"},
+		},
+		{
+			name:         "Filter by INSPIRE variant",
+			filter:       OrphanFilter{InspireVariant: inspire.AsIs},
+			wantDatasets: map[string][]ServiceKind{"view-only": {DownloadService, OGCAPIService}},
+			wantServices: []string{"dangling", "empty"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			report := FindOrphans(services, datasets, tt.filter)
+
+			gotDatasets := map[string][]ServiceKind{}
+			for _, dataset := range report.Datasets {
+				gotDatasets[dataset.MetadataID] = dataset.Missing
+			}
+
+			var gotServices []string
+			for _, service := range report.Services {
+				gotServices = append(gotServices, service.MetadataID)
+			}
+
+			assert.Equal(t, tt.wantDatasets, gotDatasets)
+			assert.Equal(t, tt.wantServices, gotServices)
+		})
+	}
+}
+
+func TestOrphanReport_WriteCSV(t *testing.T) {
+	services, datasets := getOrphanTestdata()
+	report := FindOrphans(services, datasets, OrphanFilter{OrganisationName: "Org A"})
+
+	var buf bytes.Buffer
+	require.NoError(t, report.WriteCSV(&buf))
+
+	expected := "Kind,MetadataID,Title,OrganisationName,InspireVariant,ViewServices,DownloadServices,OGCAPIServices,Missing,ServiceType,OperatesOn\n" +
+		"dataset,view-only,,Org A,ASIS,wms,,,download ogc-api,,\n" +
+		"service,dangling,,Org A,,,,,,view,deleted\n"
+	assert.Equal(t, expected, buf.String())
+}
@@ -0,0 +1,230 @@
+package client
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/pdok/pdok-metadata-tool/v2/internal/common"
+)
+
+// CacheEntryInfo is stored next to every cached record as <uuid>.json. It tells where and when the record
+// was fetched, and holds the validators of the HTTP response, so the record can be revalidated.
+type CacheEntryInfo struct {
+	FetchedAt    time.Time `json:"fetchedAt"`
+	SourceURL    string    `json:"sourceUrl"`
+	ETag         string    `json:"etag,omitempty"`
+	LastModified string    `json:"lastModified,omitempty"`
+	SHA256       string    `json:"sha256"` // Hash of the cached record
+}
+
+// recordCache stores raw metadata records on disk, wrapped in a CSW GetRecordByIdResponse, as
+// <cacheDir>/<namespace>/<uuid>.xml with a CacheEntryInfo sidecar. The namespace is derived from the
+// endpoint, so records of different endpoints (e.g. acceptance and production) are never mixed.
+//
+// Files are written atomically (temporary file plus rename), so several processes can share a cache.
+// The record is written before its sidecar; a record of which the hash does not match its sidecar is
+// being replaced by another process and is treated as missing.
+// All methods are no-ops on a nil cache.
+type recordCache struct {
+	dir string // Directory of the namespace
+	ttl time.Duration
+}
+
+// CacheNamespace returns the name of the cache directory of the records of an endpoint, based on
+// its host and path, e.g. nationaalgeoregister-nl-geonetwork-srv-dut-csw.
+func CacheNamespace(endpoint *url.URL) string {
+	return common.NormalizeForFilename(endpoint.Host + "/" + strings.Trim(endpoint.Path, "/"))
+}
+
+func newRecordCache(cacheDir string, ttlHours int, endpoint *url.URL) *recordCache {
+	return &recordCache{
+		dir: filepath.Join(cacheDir, CacheNamespace(endpoint)),
+		ttl: time.Duration(ttlHours) * time.Hour,
+	}
+}
+
+// get returns the cached record when it exists and is fresh.
+func (rc *recordCache) get(uuid string) ([]byte, bool, error) {
+	if rc == nil {
+		return nil, false, nil
+	}
+
+	info, err := rc.info(uuid)
+	if err != nil || info == nil {
+		return nil, false, err
+	}
+
+	if time.Since(info.FetchedAt) > rc.ttl {
+		return nil, false, nil
+	}
+
+	// #nosec G304 -- reading from a constructed path under controlled cache directory
+	data, err := os.ReadFile(rc.path(uuid))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, false, nil
+		}
+
+		return nil, false, err
+	}
+
+	if hashRecord(data) != info.SHA256 {
+		return nil, false, nil
+	}
+
+	return data, true, nil
+}
+
+// info returns the sidecar of a cached record, fresh or not, or nil when the record is not cached.
+func (rc *recordCache) info(uuid string) (*CacheEntryInfo, error) {
+	if rc == nil {
+		return nil, nil //nolint:nilnil
+	}
+
+	return readCacheEntryInfo(rc.infoPath(uuid))
+}
+
+// stale returns a cached record with its sidecar regardless of its age, so it can be revalidated with a
+// conditional request. Nothing is returned when the record is not cached or has no HTTP validators.
+func (rc *recordCache) stale(uuid string) ([]byte, *CacheEntryInfo, error) {
+	info, err := rc.info(uuid)
+	if err != nil || info == nil || (info.ETag == "" && info.LastModified == "") {
+		return nil, nil, err
+	}
+
+	// #nosec G304 -- reading from a constructed path under controlled cache directory
+	data, err := os.ReadFile(rc.path(uuid))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil, nil
+		}
+
+		return nil, nil, err
+	}
+
+	if hashRecord(data) != info.SHA256 {
+		return nil, nil, nil
+	}
+
+	return data, info, nil
+}
+
+// refresh marks a cached record as fetched now, after the server answered a conditional request with
+// 304 Not Modified. Validators in the headers of that response replace the stored ones.
+func (rc *recordCache) refresh(uuid string, info *CacheEntryInfo, header http.Header) error {
+	if rc == nil || info == nil {
+		return nil
+	}
+
+	sidecar, err := json.MarshalIndent(info.refreshed(header), "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return common.WriteFileAtomic(rc.infoPath(uuid), sidecar, permFile0600)
+}
+
+// put stores a record with its sidecar. The hash is computed and the fetch time defaults to now.
+func (rc *recordCache) put(uuid string, data []byte, info CacheEntryInfo) error {
+	if rc == nil {
+		return nil
+	}
+
+	if err := os.MkdirAll(rc.dir, permDir0750); err != nil {
+		return err
+	}
+
+	if info.FetchedAt.IsZero() {
+		info.FetchedAt = time.Now().UTC()
+	}
+
+	info.SHA256 = hashRecord(data)
+
+	sidecar, err := json.MarshalIndent(info, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	if err := common.WriteFileAtomic(rc.path(uuid), data, permFile0600); err != nil {
+		return err
+	}
+
+	return common.WriteFileAtomic(rc.infoPath(uuid), sidecar, permFile0600)
+}
+
+// remove removes a record and its sidecar from the cache (best-effort).
+func (rc *recordCache) remove(uuid string) {
+	if rc == nil {
+		return
+	}
+
+	_ = os.Remove(rc.infoPath(uuid))
+	_ = os.Remove(rc.path(uuid))
+}
+
+func (rc *recordCache) path(uuid string) string {
+	return filepath.Join(rc.dir, uuid+".xml")
+}
+
+func (rc *recordCache) infoPath(uuid string) string {
+	return filepath.Join(rc.dir, uuid+".json")
+}
+
+// newCacheEntryInfo returns the sidecar of a record fetched from the URL with the response headers.
+func newCacheEntryInfo(sourceURL string, header http.Header) CacheEntryInfo {
+	return CacheEntryInfo{
+		SourceURL:    sourceURL,
+		ETag:         header.Get("ETag"),
+		LastModified: header.Get("Last-Modified"),
+	}
+}
+
+// refreshed returns a copy fetched now, for a 304 Not Modified response with the headers. Validators in
+// the headers replace the stored ones.
+func (info *CacheEntryInfo) refreshed(header http.Header) CacheEntryInfo {
+	refreshed := *info
+	refreshed.FetchedAt = time.Now().UTC()
+
+	if etag := header.Get("ETag"); etag != "" {
+		refreshed.ETag = etag
+	}
+
+	if lastModified := header.Get("Last-Modified"); lastModified != "" {
+		refreshed.LastModified = lastModified
+	}
+
+	return refreshed
+}
+
+// readCacheEntryInfo reads a sidecar; a missing sidecar results in nil.
+func readCacheEntryInfo(path string) (*CacheEntryInfo, error) {
+	// #nosec G304 -- reading from a constructed path under controlled cache directory
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil //nolint:nilnil
+		}
+
+		return nil, err
+	}
+
+	var info CacheEntryInfo
+	if err := json.Unmarshal(data, &info); err != nil {
+		return nil, err
+	}
+
+	return &info, nil
+}
+
+func hashRecord(data []byte) string {
+	sum := sha256.Sum256(data)
+
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,192 @@
+package client
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCacheNamespace(t *testing.T) {
+	tests := []struct {
+		endpoint string
+		want     string
+	}{
+		{endpoint: "https://nationaalgeoregister.nl/geonetwork/srv/dut/csw", want: "nationaalgeoregister-nl-geonetwork-srv-dut-csw"},
+		{endpoint: "https://nationaalgeoregister.nl/geonetwork/srv/dut/csw/", want: "nationaalgeoregister-nl-geonetwork-srv-dut-csw"},
+		{endpoint: "http://localhost:8080/csw", want: "localhost-8080-csw"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.endpoint, func(t *testing.T) {
+			endpoint, err := url.Parse(tt.endpoint)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, CacheNamespace(endpoint))
+		})
+	}
+}
+
+func TestRecordCache(t *testing.T) {
+	cacheDir := t.TempDir()
+	production := newRecordCache(cacheDir, 1, &url.URL{Scheme: "https", Host: "example.com", Path: "/csw"})
+	acceptance := newRecordCache(cacheDir, 1, &url.URL{Scheme: "https", Host: "acc.example.com", Path: "/csw"})
+
+	require.NoError(t, production.put(testDatasetUUID, []byte("<production/>"), CacheEntryInfo{SourceURL: "https://example.com/csw"}))
+	require.NoError(t, acceptance.put(testDatasetUUID, []byte("<acceptance/>"), CacheEntryInfo{}))
+
+	t.Run("Namespaces are separated", func(t *testing.T) {
+		data, ok, err := production.get(testDatasetUUID)
+		require.NoError(t, err)
+		require.True(t, ok)
+		assert.Equal(t, "<production/>", string(data))
+
+		data, ok, err = acceptance.get(testDatasetUUID)
+		require.NoError(t, err)
+		require.True(t, ok)
+		assert.Equal(t, "<acceptance/>", string(data))
+	})
+
+	t.Run("Sidecar", func(t *testing.T) {
+		info, err := production.info(testDatasetUUID)
+		require.NoError(t, err)
+		require.NotNil(t, info)
+		assert.Equal(t, "https://example.com/csw", info.SourceURL)
+		assert.Equal(t, hashRecord([]byte("<production/>")), info.SHA256)
+		assert.WithinDuration(t, time.Now(), info.FetchedAt, time.Minute)
+
+		files, err := filepath.Glob(filepath.Join(production.dir, "*"))
+		require.NoError(t, err)
+		assert.Len(t, files, 2, "no temporary files are left behind")
+	})
+
+	t.Run("Expired record is a miss", func(t *testing.T) {
+		require.NoError(t, production.put(testServiceUUID, []byte("<old/>"),
+			CacheEntryInfo{FetchedAt: time.Now().Add(-2 * time.Hour)}))
+
+		_, ok, err := production.get(testServiceUUID)
+		require.NoError(t, err)
+		assert.False(t, ok)
+
+		info, err := production.info(testServiceUUID)
+		require.NoError(t, err)
+		assert.NotNil(t, info, "the sidecar of an expired record is still available")
+	})
+
+	t.Run("Record that does not match its sidecar is a miss", func(t *testing.T) {
+		require.NoError(t, os.WriteFile(acceptance.path(testDatasetUUID), []byte("<replaced/>"), permFile0600))
+
+		_, ok, err := acceptance.get(testDatasetUUID)
+		require.NoError(t, err)
+		assert.False(t, ok)
+	})
+
+	t.Run("Record without sidecar is a miss", func(t *testing.T) {
+		require.NoError(t, os.Remove(production.infoPath(testDatasetUUID)))
+
+		_, ok, err := production.get(testDatasetUUID)
+		require.NoError(t, err)
+		assert.False(t, ok)
+	})
+
+	t.Run("Nil cache", func(t *testing.T) {
+		var rc *recordCache
+
+		_, ok, err := rc.get(testDatasetUUID)
+		require.NoError(t, err)
+		assert.False(t, ok)
+		require.NoError(t, rc.put(testDatasetUUID, []byte("<record/>"), CacheEntryInfo{}))
+	})
+}
+
+func TestCswClient_GetRawRecordByID_Cache(t *testing.T) {
+	var requests atomic.Int32
+
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, _ *http.Request) {
+		requests.Add(1)
+		rw.Header().Set("ETag", `"v1"`)
+		rw.Header().Set("Last-Modified", "Mon, 02 Jun 2025 10:00:00 GMT")
+		_, _ = rw.Write([]byte(wrapAsGetRecordByIDResponse("<gmd:MD_Metadata/>")))
+	}))
+	defer server.Close()
+
+	cswClient := getCswClient(t, server)
+	cswClient.SetCache(t.TempDir(), 1)
+
+	for range 2 {
+		_, err := cswClient.GetRawRecordByID(t.Context(), testDatasetUUID)
+		require.NoError(t, err)
+	}
+
+	assert.Equal(t, int32(1), requests.Load(), "second request is served from the cache")
+
+	info, err := cswClient.cache.info(testDatasetUUID)
+	require.NoError(t, err)
+	require.NotNil(t, info)
+	assert.Equal(t, cswClient.getRecordByIDUrl(testDatasetUUID), info.SourceURL)
+	assert.Equal(t, `"v1"`, info.ETag)
+	assert.Equal(t, "Mon, 02 Jun 2025 10:00:00 GMT", info.LastModified)
+}
+
+func TestCswClient_GetRawRecordByID_Conditional(t *testing.T) {
+	var full, notModified atomic.Int32
+
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		if req.Header.Get("If-None-Match") == `"v1"` {
+			notModified.Add(1)
+			rw.WriteHeader(http.StatusNotModified)
+
+			return
+		}
+
+		full.Add(1)
+		rw.Header().Set("ETag", `"v1"`)
+		_, _ = rw.Write([]byte(wrapAsGetRecordByIDResponse("<gmd:MD_Metadata/>")))
+	}))
+	defer server.Close()
+
+	cswClient := getCswClient(t, server)
+	cswClient.SetCache(t.TempDir(), 1)
+
+	expire := func() {
+		info, err := cswClient.cache.info(testDatasetUUID)
+		require.NoError(t, err)
+
+		data, err := os.ReadFile(cswClient.cache.path(testDatasetUUID))
+		require.NoError(t, err)
+
+		info.FetchedAt = time.Now().Add(-2 * time.Hour)
+		require.NoError(t, cswClient.cache.put(testDatasetUUID, data, *info))
+	}
+
+	first, err := cswClient.GetRawRecordByID(t.Context(), testDatasetUUID)
+	require.NoError(t, err)
+
+	expire()
+
+	second, err := cswClient.GetRawRecordByID(t.Context(), testDatasetUUID)
+	require.NoError(t, err)
+	assert.Equal(t, first, second, "the cached record is returned when it was not modified")
+	assert.Equal(t, int32(1), full.Load())
+	assert.Equal(t, int32(1), notModified.Load())
+
+	// The 304 refreshed the cache, so the record is fresh again
+	_, ok, err := cswClient.cache.get(testDatasetUUID)
+	require.NoError(t, err)
+	assert.True(t, ok)
+
+	// Without validators no conditional request is sent
+	expire()
+	require.NoError(t, os.WriteFile(cswClient.cache.infoPath(testDatasetUUID), []byte(`{}`), permFile0600))
+
+	_, err = cswClient.GetRawRecordByID(t.Context(), testDatasetUUID)
+	require.NoError(t, err)
+	assert.Equal(t, int32(2), full.Load())
+	assert.Equal(t, int32(1), notModified.Load())
+}
@@ -0,0 +1,476 @@
+package client
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/pdok/pdok-metadata-tool/v2/pkg/model/csw"
+	"github.com/pdok/pdok-metadata-tool/v2/pkg/model/iso1911x"
+)
+
+// LegacyNamespace is used in cache statistics for records in the root of the cache directory, as cached by
+// versions without namespaces. These records are not read anymore and can be pruned.
+const LegacyNamespace = "(legacy)"
+
+// Export formats of ExportCache.
+const (
+	ExportFormatZip   = "zip"
+	ExportFormatTarGz = "tar.gz"
+)
+
+// cacheAgeBuckets are the upper bounds of the age histogram; older records are counted in the last bucket.
+var cacheAgeBuckets = []struct {
+	Label  string
+	MaxAge time.Duration
+}{
+	{"<1d", 24 * time.Hour},
+	{"1-7d", 7 * 24 * time.Hour},
+	{"7-30d", 30 * 24 * time.Hour},
+	{"30-90d", 90 * 24 * time.Hour},
+	{">90d", 0},
+}
+
+// CachedRecord describes a record file in the cache.
+type CachedRecord struct {
+	Namespace string          `json:"namespace"` // Empty for legacy records in the root of the cache
+	UUID      string          `json:"uuid"`
+	File      string          `json:"file"` // Path of the record relative to the cache directory
+	Size      int64           `json:"size"`
+	FetchedAt time.Time       `json:"fetchedAt"` // From the sidecar, or else the modification time of the file
+	Info      *CacheEntryInfo `json:"info,omitempty"`
+}
+
+// CacheStats holds the statistics of the records in the cache of one namespace, or of all namespaces.
+type CacheStats struct {
+	Namespace string // Empty for the totals
+	Records   int
+	Size      int64
+	Oldest    time.Time
+	Newest    time.Time
+	Ages      []CacheAgeBucket
+}
+
+// CacheAgeBucket is a bucket of the age histogram of CacheStats.
+type CacheAgeBucket struct {
+	Label   string
+	Records int
+}
+
+// CacheProblem is a record file that could not be verified.
+type CacheProblem struct {
+	Record CachedRecord
+	Err    error
+}
+
+// CachePruneOptions selects the records that are pruned. A record is pruned when any of the options applies.
+type CachePruneOptions struct {
+	OlderThan time.Duration // Prune records fetched longer ago than this; 0 disables pruning by age
+	// Keep holds the UUIDs to keep by namespace, see HarvestedIdentifiers. When not nil, records of
+	// these namespaces that are not in the set are pruned; other namespaces are not affected.
+	Keep   map[string]map[string]bool
+	DryRun bool // Only report the records that would be pruned
+}
+
+// CacheManifest describes the records in a cache export.
+type CacheManifest struct {
+	CreatedAt time.Time      `json:"createdAt"`
+	Records   []CachedRecord `json:"records"`
+}
+
+// ListCachedRecords returns the record files (<namespace>/<uuid>.xml) in the cache directory, ordered by
+// namespace and UUID. Legacy records in the root of the cache directory are included with an empty namespace.
+// A missing directory results in an empty list.
+func ListCachedRecords(cacheDir string) ([]CachedRecord, error) {
+	entries, err := os.ReadDir(cacheDir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+
+		return nil, err
+	}
+
+	var records []CachedRecord
+
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			if record, ok, err := cachedRecord(cacheDir, "", entry); err != nil {
+				return nil, err
+			} else if ok {
+				records = append(records, record)
+			}
+
+			continue
+		}
+
+		files, err := os.ReadDir(filepath.Join(cacheDir, entry.Name()))
+		if err != nil {
+			return nil, err
+		}
+
+		for _, file := range files {
+			if record, ok, err := cachedRecord(cacheDir, entry.Name(), file); err != nil {
+				return nil, err
+			} else if ok {
+				records = append(records, record)
+			}
+		}
+	}
+
+	return records, nil
+}
+
+// HarvestedIdentifiers returns the identifiers of the last successful harvests in the state by cache namespace.
+func HarvestedIdentifiers(state *HarvestState) (map[string]map[string]bool, error) {
+	result := map[string]map[string]bool{}
+
+	for _, entry := range state.Entries {
+		endpoint, err := url.Parse(entry.Endpoint)
+		if err != nil {
+			return nil, err
+		}
+
+		namespace := CacheNamespace(endpoint)
+		if result[namespace] == nil {
+			result[namespace] = map[string]bool{}
+		}
+
+		for _, id := range entry.Identifiers {
+			result[namespace][id] = true
+		}
+	}
+
+	return result, nil
+}
+
+// GetCacheStats returns the statistics of the cache per namespace, followed by the totals (Namespace "").
+// Legacy records are counted for LegacyNamespace.
+func GetCacheStats(cacheDir string, now time.Time) ([]CacheStats, error) {
+	records, err := ListCachedRecords(cacheDir)
+	if err != nil {
+		return nil, err
+	}
+
+	byNamespace := map[string]*CacheStats{}
+	total := newCacheStats("")
+
+	for _, record := range records {
+		namespace := record.Namespace
+		if namespace == "" {
+			namespace = LegacyNamespace
+		}
+
+		stats, ok := byNamespace[namespace]
+		if !ok {
+			stats = newCacheStats(namespace)
+			byNamespace[namespace] = stats
+		}
+
+		stats.add(record, now)
+		total.add(record, now)
+	}
+
+	result := make([]CacheStats, 0, len(byNamespace)+1)
+	for _, stats := range byNamespace {
+		result = append(result, *stats)
+	}
+
+	slices.SortFunc(result, func(a, b CacheStats) int { return strings.Compare(a.Namespace, b.Namespace) })
+
+	return append(result, *total), nil
+}
+
+// PruneCache removes the records selected by the options, with their sidecars, and returns them.
+func PruneCache(cacheDir string, options CachePruneOptions, now time.Time) ([]CachedRecord, error) {
+	if options.OlderThan <= 0 && options.Keep == nil {
+		return nil, errors.New("nothing to prune: give a maximum age or the records to keep")
+	}
+
+	records, err := ListCachedRecords(cacheDir)
+	if err != nil {
+		return nil, err
+	}
+
+	var pruned []CachedRecord
+
+	for _, record := range records {
+		tooOld := options.OlderThan > 0 && now.Sub(record.FetchedAt) > options.OlderThan
+
+		keep, harvested := options.Keep[record.Namespace]
+		notKept := harvested && !keep[record.UUID]
+
+		if !tooOld && !notKept {
+			continue
+		}
+
+		if !options.DryRun {
+			if err := RemoveCachedRecord(cacheDir, &record); err != nil {
+				return pruned, err
+			}
+		}
+
+		pruned = append(pruned, record)
+	}
+
+	return pruned, nil
+}
+
+// VerifyCache parses every record file in the cache as a CSW GetRecordByIdResponse and returns the files that
+// cannot be parsed, have no fileIdentifier, have a fileIdentifier that does not match the file name, or have
+// no sidecar or a sidecar with another hash. Legacy records have no sidecar and are only parsed.
+func VerifyCache(cacheDir string) (checked int, problems []CacheProblem, err error) {
+	records, err := ListCachedRecords(cacheDir)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	for _, record := range records {
+		if err := verifyCachedRecord(cacheDir, &record); err != nil {
+			problems = append(problems, CacheProblem{Record: record, Err: err})
+		}
+	}
+
+	return len(records), problems, nil
+}
+
+// RemoveCachedRecord removes a record and its sidecar from the cache.
+func RemoveCachedRecord(cacheDir string, record *CachedRecord) error {
+	if err := os.Remove(filepath.Join(cacheDir, sidecarPath(record.File))); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+
+	if err := os.Remove(filepath.Join(cacheDir, record.File)); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+
+	return nil
+}
+
+// ExportCache writes the record files of the cache with their sidecars and a manifest.json to an archive
+// in the given format. Files keep their path relative to the cache directory, under records/.
+func ExportCache(cacheDir string, w io.Writer, format string, now time.Time) (manifest CacheManifest, err error) {
+	records, err := ListCachedRecords(cacheDir)
+	if err != nil {
+		return manifest, err
+	}
+
+	manifest = CacheManifest{CreatedAt: now.UTC(), Records: make([]CachedRecord, 0, len(records))}
+
+	var archive archiveWriter
+
+	switch format {
+	case ExportFormatZip:
+		archive = newZipArchive(w)
+	case ExportFormatTarGz:
+		archive = newTarGzArchive(w)
+	default:
+		return manifest, fmt.Errorf("unsupported export format: %s (allowed: %s, %s)",
+			format, ExportFormatZip, ExportFormatTarGz)
+	}
+
+	defer func() {
+		if closeErr := archive.Close(); err == nil {
+			err = closeErr
+		}
+	}()
+
+	for _, record := range records {
+		for _, file := range []string{record.File, sidecarPath(record.File)} {
+			//nolint:gosec
+			data, err := os.ReadFile(filepath.Join(cacheDir, file))
+			if errors.Is(err, os.ErrNotExist) && file != record.File {
+				continue // Legacy records have no sidecar
+			} else if err != nil {
+				return manifest, err
+			}
+
+			if err := archive.Add("records/"+filepath.ToSlash(file), data, record.FetchedAt); err != nil {
+				return manifest, err
+			}
+		}
+
+		manifest.Records = append(manifest.Records, record)
+	}
+
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return manifest, err
+	}
+
+	return manifest, archive.Add("manifest.json", data, now)
+}
+
+// cachedRecord returns the record of a directory entry; false when the entry is not a record file.
+func cachedRecord(cacheDir, namespace string, entry os.DirEntry) (CachedRecord, bool, error) {
+	if entry.IsDir() || filepath.Ext(entry.Name()) != ".xml" || strings.HasPrefix(entry.Name(), ".") {
+		return CachedRecord{}, false, nil
+	}
+
+	fi, err := entry.Info()
+	if err != nil {
+		return CachedRecord{}, false, err
+	}
+
+	record := CachedRecord{
+		Namespace: namespace,
+		UUID:      strings.TrimSuffix(entry.Name(), ".xml"),
+		File:      filepath.Join(namespace, entry.Name()),
+		Size:      fi.Size(),
+		FetchedAt: fi.ModTime(),
+	}
+
+	if namespace != "" {
+		// A corrupt sidecar is reported by VerifyCache
+		if info, err := readCacheEntryInfo(filepath.Join(cacheDir, sidecarPath(record.File))); err == nil && info != nil {
+			record.Info = info
+			record.FetchedAt = info.FetchedAt
+		}
+	}
+
+	return record, true, nil
+}
+
+func sidecarPath(recordPath string) string {
+	return strings.TrimSuffix(recordPath, ".xml") + ".json"
+}
+
+func newCacheStats(namespace string) *CacheStats {
+	stats := &CacheStats{Namespace: namespace, Ages: make([]CacheAgeBucket, len(cacheAgeBuckets))}
+	for i, bucket := range cacheAgeBuckets {
+		stats.Ages[i].Label = bucket.Label
+	}
+
+	return stats
+}
+
+func (s *CacheStats) add(record CachedRecord, now time.Time) {
+	s.Records++
+	s.Size += record.Size
+
+	if s.Oldest.IsZero() || record.FetchedAt.Before(s.Oldest) {
+		s.Oldest = record.FetchedAt
+	}
+
+	if record.FetchedAt.After(s.Newest) {
+		s.Newest = record.FetchedAt
+	}
+
+	age := now.Sub(record.FetchedAt)
+
+	for i, bucket := range cacheAgeBuckets {
+		if bucket.MaxAge == 0 || age < bucket.MaxAge {
+			s.Ages[i].Records++
+
+			break
+		}
+	}
+}
+
+func verifyCachedRecord(cacheDir string, record *CachedRecord) error {
+	//nolint:gosec
+	data, err := os.ReadFile(filepath.Join(cacheDir, record.File))
+	if err != nil {
+		return err
+	}
+
+	response := csw.GetRecordByIDResponse{}
+	if err := xml.Unmarshal(data, &response); err != nil { //nolint:musttag
+		return err
+	}
+
+	switch id := iso1911x.NormalizeXMLText(response.MDMetadata.UUID); id {
+	case "":
+		return errors.New("record has no fileIdentifier")
+	case record.UUID:
+	default:
+		return fmt.Errorf("fileIdentifier %s does not match the file name", id)
+	}
+
+	if record.Namespace == "" {
+		return nil
+	}
+
+	info, err := readCacheEntryInfo(filepath.Join(cacheDir, sidecarPath(record.File)))
+
+	switch {
+	case err != nil:
+		return fmt.Errorf("invalid sidecar: %w", err)
+	case info == nil:
+		return errors.New("sidecar is missing")
+	case info.SHA256 != hashRecord(data):
+		return errors.New("hash does not match the sidecar")
+	default:
+		return nil
+	}
+}
+
+// archiveWriter adds files to a zip or tar.gz archive.
+type archiveWriter interface {
+	Add(name string, data []byte, modTime time.Time) error
+	Close() error
+}
+
+type zipArchive struct {
+	zw *zip.Writer
+}
+
+func newZipArchive(w io.Writer) *zipArchive {
+	return &zipArchive{zw: zip.NewWriter(w)}
+}
+
+func (a *zipArchive) Add(name string, data []byte, modTime time.Time) error {
+	fw, err := a.zw.CreateHeader(&zip.FileHeader{Name: name, Method: zip.Deflate, Modified: modTime})
+	if err != nil {
+		return err
+	}
+
+	_, err = fw.Write(data)
+
+	return err
+}
+
+func (a *zipArchive) Close() error {
+	return a.zw.Close()
+}
+
+type tarGzArchive struct {
+	gw *gzip.Writer
+	tw *tar.Writer
+}
+
+func newTarGzArchive(w io.Writer) *tarGzArchive {
+	gw := gzip.NewWriter(w)
+
+	return &tarGzArchive{gw: gw, tw: tar.NewWriter(gw)}
+}
+
+func (a *tarGzArchive) Add(name string, data []byte, modTime time.Time) error {
+	if err := a.tw.WriteHeader(&tar.Header{
+		Name:    name,
+		Mode:    permFile0600,
+		Size:    int64(len(data)),
+		ModTime: modTime,
+	}); err != nil {
+		return err
+	}
+
+	_, err := a.tw.Write(data)
+
+	return err
+}
+
+func (a *tarGzArchive) Close() error {
+	return errors.Join(a.tw.Close(), a.gw.Close())
+}
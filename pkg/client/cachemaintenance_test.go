@@ -0,0 +1,328 @@
+package client
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"io"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	testDatasetUUID = "5951efa2-1ff3-4763-a966-a2f5497679ee"
+	testServiceUUID = "dae8f9e3-99af-4d21-9feb-29f2a1693077"
+)
+
+var (
+	testCswEndpoint = &url.URL{Scheme: "https", Host: "example.com", Path: "/csw"}
+	testOaiEndpoint = &url.URL{Scheme: "https", Host: "example.com", Path: "/oai"}
+)
+
+// buildTestCache creates a cache with records of two endpoints and a legacy record in the root:
+//
+//	csw: two valid records of different ages and a corrupt record
+//	oai: a record of which the fileIdentifier does not match the file name, and a record that does
+//	     not match the hash in its sidecar
+//	root: a legacy record without sidecar and a file that is not a record
+func buildTestCache(t *testing.T, now time.Time) string {
+	t.Helper()
+
+	cacheDir := t.TempDir()
+	csw := newRecordCache(cacheDir, 1, testCswEndpoint)
+	oai := newRecordCache(cacheDir, 1, testOaiEndpoint)
+
+	put := func(rc *recordCache, uuid, content string, age time.Duration) {
+		require.NoError(t, rc.put(uuid, []byte(content), CacheEntryInfo{FetchedAt: now.Add(-age)}))
+	}
+
+	readRecord := func(file string) string {
+		record, err := readFileToString(file)
+		require.NoError(t, err)
+
+		return wrapAsGetRecordByIDResponse(record[strings.Index(record, "<gmd:MD_Metadata"):])
+	}
+
+	dataset := readRecord("../../examples/ISO19115/5951efa2-1ff3-4763-a966-a2f5497679ee.xml")
+	service := readRecord("../../examples/ISO19119/dae8f9e3-99af-4d21-9feb-29f2a1693077.xml")
+
+	put(csw, testDatasetUUID, dataset, 2*time.Hour)
+	put(csw, testServiceUUID, service, 10*24*time.Hour)
+	put(csw, "11111111-1111-1111-1111-111111111111", "<csw:GetRecordByIdResponse><gmd:MD_Metadata>", time.Hour)
+
+	put(oai, "00000000-0000-0000-0000-000000000000", dataset, 40*24*time.Hour)
+	put(oai, testDatasetUUID, dataset, 3*time.Hour)
+	require.NoError(t, os.WriteFile(oai.path(testDatasetUUID), []byte(dataset+"\n"), permFile0600))
+
+	write := func(name, content string, age time.Duration) {
+		path := filepath.Join(cacheDir, name)
+		require.NoError(t, os.WriteFile(path, []byte(content), permFile0600))
+		require.NoError(t, os.Chtimes(path, now.Add(-age), now.Add(-age)))
+	}
+
+	write(testServiceUUID+".xml", service, 100*24*time.Hour)
+	write("harvest-notes.txt", "not a record", time.Hour)
+
+	return cacheDir
+}
+
+func TestGetCacheStats(t *testing.T) {
+	now := time.Now()
+	cacheDir := buildTestCache(t, now)
+
+	stats, err := GetCacheStats(cacheDir, now)
+	require.NoError(t, err)
+	require.Len(t, stats, 4)
+
+	ages := func(s CacheStats) []int {
+		result := make([]int, 0, len(s.Ages))
+		for _, bucket := range s.Ages {
+			result = append(result, bucket.Records)
+		}
+
+		return result
+	}
+
+	assert.Equal(t, LegacyNamespace, stats[0].Namespace)
+	assert.Equal(t, []int{0, 0, 0, 0, 1}, ages(stats[0]))
+	assert.Equal(t, CacheNamespace(testCswEndpoint), stats[1].Namespace)
+	assert.Equal(t, 3, stats[1].Records)
+	assert.Equal(t, []int{2, 0, 1, 0, 0}, ages(stats[1]))
+	assert.Equal(t, CacheNamespace(testOaiEndpoint), stats[2].Namespace)
+	assert.Equal(t, []int{1, 0, 0, 1, 0}, ages(stats[2]))
+
+	total := stats[3]
+	assert.Empty(t, total.Namespace)
+	assert.Equal(t, 6, total.Records)
+	assert.Equal(t, []int{3, 0, 1, 1, 1}, ages(total))
+	assert.Positive(t, total.Size)
+	assert.WithinDuration(t, now.Add(-100*24*time.Hour), total.Oldest, time.Second)
+	assert.WithinDuration(t, now.Add(-time.Hour), total.Newest, time.Second)
+}
+
+func TestHarvestedIdentifiers(t *testing.T) {
+	state := &HarvestState{Entries: map[string]HarvestStateEntry{
+		"a": {Endpoint: "https://example.com/csw", Identifiers: []string{testDatasetUUID, testServiceUUID}},
+		"b": {Endpoint: "https://example.com/csw/", Identifiers: []string{"other"}},
+		"c": {Endpoint: "https://example.com/oai", Identifiers: []string{testServiceUUID}},
+	}}
+
+	keep, err := HarvestedIdentifiers(state)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]map[string]bool{
+		CacheNamespace(testCswEndpoint): {testDatasetUUID: true, testServiceUUID: true, "other": true},
+		CacheNamespace(testOaiEndpoint): {testServiceUUID: true},
+	}, keep)
+}
+
+func TestPruneCache(t *testing.T) {
+	now := time.Now()
+	cswNamespace := CacheNamespace(testCswEndpoint)
+	oaiNamespace := CacheNamespace(testOaiEndpoint)
+
+	tests := []struct {
+		name    string
+		options CachePruneOptions
+		want    []string
+		remains int
+		wantErr bool
+	}{
+		{
+			name:    "Older than",
+			options: CachePruneOptions{OlderThan: 7 * 24 * time.Hour},
+			want: []string{
+				"/" + testServiceUUID, cswNamespace + "/" + testServiceUUID,
+				oaiNamespace + "/00000000-0000-0000-0000-000000000000",
+			},
+			remains: 3,
+		},
+		{
+			name: "Not in last harvest of one endpoint",
+			options: CachePruneOptions{Keep: map[string]map[string]bool{
+				cswNamespace: {testDatasetUUID: true, testServiceUUID: true},
+			}},
+			want:    []string{cswNamespace + "/11111111-1111-1111-1111-111111111111"},
+			remains: 5,
+		},
+		{
+			name: "Not in last harvest of both endpoints",
+			options: CachePruneOptions{Keep: map[string]map[string]bool{
+				cswNamespace: {testDatasetUUID: true, testServiceUUID: true},
+				oaiNamespace: {testDatasetUUID: true},
+			}},
+			want: []string{
+				cswNamespace + "/11111111-1111-1111-1111-111111111111",
+				oaiNamespace + "/00000000-0000-0000-0000-000000000000",
+			},
+			remains: 4,
+		},
+		{
+			name:    "Dry run",
+			options: CachePruneOptions{OlderThan: time.Minute, DryRun: true},
+			want: []string{
+				"/" + testServiceUUID,
+				cswNamespace + "/11111111-1111-1111-1111-111111111111",
+				cswNamespace + "/" + testDatasetUUID, cswNamespace + "/" + testServiceUUID,
+				oaiNamespace + "/00000000-0000-0000-0000-000000000000", oaiNamespace + "/" + testDatasetUUID,
+			},
+			remains: 6,
+		},
+		{
+			name:    "Nothing to prune",
+			options: CachePruneOptions{},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cacheDir := buildTestCache(t, now)
+
+			pruned, err := PruneCache(cacheDir, tt.options, now)
+			if tt.wantErr {
+				require.Error(t, err)
+
+				return
+			}
+
+			require.NoError(t, err)
+
+			ids := make([]string, 0, len(pruned))
+			for _, record := range pruned {
+				ids = append(ids, record.Namespace+"/"+record.UUID)
+			}
+
+			assert.Equal(t, tt.want, ids)
+
+			records, err := ListCachedRecords(cacheDir)
+			require.NoError(t, err)
+			assert.Len(t, records, tt.remains)
+
+			if !tt.options.DryRun {
+				for _, record := range pruned {
+					assert.NoFileExists(t, filepath.Join(cacheDir, sidecarPath(record.File)))
+				}
+			}
+		})
+	}
+}
+
+func TestVerifyCache(t *testing.T) {
+	cacheDir := buildTestCache(t, time.Now())
+
+	// A record of which the sidecar is missing
+	require.NoError(t, os.Remove(filepath.Join(cacheDir, CacheNamespace(testCswEndpoint), testServiceUUID+".json")))
+
+	checked, problems, err := VerifyCache(cacheDir)
+	require.NoError(t, err)
+	assert.Equal(t, 6, checked)
+	require.Len(t, problems, 4)
+	assert.Equal(t, "11111111-1111-1111-1111-111111111111", problems[0].Record.UUID)
+	assert.Equal(t, testServiceUUID, problems[1].Record.UUID)
+	assert.EqualError(t, problems[1].Err, "sidecar is missing")
+	assert.Equal(t, "00000000-0000-0000-0000-000000000000", problems[2].Record.UUID)
+	assert.ErrorContains(t, problems[2].Err, "does not match the file name")
+	assert.Equal(t, testDatasetUUID, problems[3].Record.UUID)
+	assert.EqualError(t, problems[3].Err, "hash does not match the sidecar")
+
+	require.NoError(t, RemoveCachedRecord(cacheDir, &problems[3].Record))
+	assert.NoFileExists(t, filepath.Join(cacheDir, problems[3].Record.File))
+	assert.NoFileExists(t, filepath.Join(cacheDir, sidecarPath(problems[3].Record.File)))
+}
+
+func TestExportCache(t *testing.T) {
+	now := time.Now()
+	cacheDir := buildTestCache(t, now)
+
+	readZip := func(t *testing.T, data []byte) map[string][]byte {
+		t.Helper()
+
+		zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+		require.NoError(t, err)
+
+		files := map[string][]byte{}
+
+		for _, f := range zr.File {
+			rc, err := f.Open()
+			require.NoError(t, err)
+
+			files[f.Name], err = io.ReadAll(rc)
+			require.NoError(t, err)
+			require.NoError(t, rc.Close())
+		}
+
+		return files
+	}
+
+	readTarGz := func(t *testing.T, data []byte) map[string][]byte {
+		t.Helper()
+
+		gr, err := gzip.NewReader(bytes.NewReader(data))
+		require.NoError(t, err)
+
+		tr := tar.NewReader(gr)
+		files := map[string][]byte{}
+
+		for {
+			header, err := tr.Next()
+			if err == io.EOF {
+				break
+			}
+
+			require.NoError(t, err)
+
+			files[header.Name], err = io.ReadAll(tr)
+			require.NoError(t, err)
+		}
+
+		return files
+	}
+
+	for format, read := range map[string]func(t *testing.T, data []byte) map[string][]byte{
+		ExportFormatZip:   readZip,
+		ExportFormatTarGz: readTarGz,
+	} {
+		t.Run(format, func(t *testing.T) {
+			var buf bytes.Buffer
+
+			manifest, err := ExportCache(cacheDir, &buf, format, now)
+			require.NoError(t, err)
+			require.Len(t, manifest.Records, 6)
+
+			files := read(t, buf.Bytes())
+			require.Len(t, files, 12, "6 records, 5 sidecars and the manifest")
+
+			var written CacheManifest
+			require.NoError(t, json.Unmarshal(files["manifest.json"], &written))
+			require.Len(t, written.Records, 6)
+
+			for _, record := range written.Records {
+				original, err := os.ReadFile(filepath.Join(cacheDir, record.File))
+				require.NoError(t, err)
+				assert.Equal(t, original, files["records/"+filepath.ToSlash(record.File)])
+
+				if record.Namespace == "" {
+					assert.Nil(t, record.Info)
+
+					continue
+				}
+
+				require.NotNil(t, record.Info)
+				assert.Len(t, record.Info.SHA256, 64)
+				assert.Contains(t, files, "records/"+filepath.ToSlash(sidecarPath(record.File)))
+			}
+		})
+	}
+
+	_, err := ExportCache(cacheDir, io.Discard, "rar", now)
+	require.Error(t, err)
+}
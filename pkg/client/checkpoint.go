@@ -0,0 +1,290 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/pdok/pdok-metadata-tool/v2/internal/common"
+	"github.com/pdok/pdok-metadata-tool/v2/pkg/model/csw"
+	"github.com/pdok/pdok-metadata-tool/v2/pkg/model/iso1911x"
+)
+
+// checkpointInterval is the minimum time between two checkpoint writes while records are retrieved.
+const checkpointInterval = 5 * time.Second
+
+// ErrNoCheckpoint is returned when a harvest is resumed without a checkpoint file.
+var ErrNoCheckpoint = errors.New("no checkpoint found")
+
+// HarvestCheckpoint holds the progress of a harvest, so it can be resumed after an interruption.
+type HarvestCheckpoint struct {
+	Endpoint   string                      `json:"endpoint"`
+	Constraint csw.GetRecordsCQLConstraint `json:"constraint"`
+	Offset     int                         `json:"offset"`  // Next page of summary records; 0 when paging is done
+	Matched    int                         `json:"matched"` // NumberOfRecordsMatched of the first page
+	Records    []csw.SummaryRecord         `json:"records"` // The records to harvest
+	Seen       []string                    `json:"seen"`    // Identifiers of the records that were harvested
+	Failed     []string                    `json:"failed"`  // Identifiers of the records that failed
+
+	path      string
+	lastWrite time.Time
+}
+
+// LoadHarvestCheckpoint reads a checkpoint from a JSON file. ErrNoCheckpoint is returned when it does not exist.
+func LoadHarvestCheckpoint(path string) (*HarvestCheckpoint, error) {
+	//nolint:gosec
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("%w: %s", ErrNoCheckpoint, path)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	checkpoint := &HarvestCheckpoint{path: path}
+	if err := json.Unmarshal(data, checkpoint); err != nil {
+		return nil, fmt.Errorf("failed to parse checkpoint %s: %w", path, err)
+	}
+
+	return checkpoint, nil
+}
+
+// Save writes the checkpoint to its file. The file is replaced atomically, so an interrupted harvest never
+// leaves a truncated checkpoint.
+func (cp *HarvestCheckpoint) Save() error {
+	data, err := json.Marshal(cp)
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(cp.path), permDir0750); err != nil {
+		return err
+	}
+
+	cp.lastWrite = time.Now()
+
+	return common.WriteFileAtomic(cp.path, data, permFile0600)
+}
+
+// SetCheckpointPath enables checkpoints while harvesting by record ID. A checkpoint is written after every page
+// of summary records and periodically while records are retrieved. It is removed when the harvest
+// completes without failures.
+func (c *CswClient) SetCheckpointPath(path string) {
+	c.checkpointPath = path
+}
+
+// ResumeHarvest continues an interrupted harvest: it pages through the remaining summary records, if any,
+// and retrieves the records that were neither harvested nor failed before.
+func (c *CswClient) ResumeHarvest(
+	ctx context.Context,
+	checkpoint *HarvestCheckpoint,
+) ([]iso1911x.MDMetadata, []HarvestFailure, error) {
+	if err := c.checkCheckpoint(checkpoint); err != nil {
+		return nil, nil, err
+	}
+
+	if checkpoint.Offset > 0 {
+		if err := c.pageWithCheckpoint(ctx, checkpoint); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	done := toSet(append(slices.Clone(checkpoint.Seen), checkpoint.Failed...))
+
+	var pending []csw.SummaryRecord
+
+	for _, record := range checkpoint.Records {
+		if !done[record.Identifier] {
+			pending = append(pending, record)
+		}
+	}
+
+	return c.harvestWithCheckpoint(ctx, checkpoint, pending)
+}
+
+// RetryFailed retrieves only the records that failed in the harvest of the checkpoint.
+func (c *CswClient) RetryFailed(
+	ctx context.Context,
+	checkpoint *HarvestCheckpoint,
+) ([]iso1911x.MDMetadata, []HarvestFailure, error) {
+	if err := c.checkCheckpoint(checkpoint); err != nil {
+		return nil, nil, err
+	}
+
+	failed := toSet(checkpoint.Failed)
+
+	var pending []csw.SummaryRecord
+
+	for _, record := range checkpoint.Records {
+		if failed[record.Identifier] {
+			pending = append(pending, record)
+		}
+	}
+
+	checkpoint.Failed = nil
+
+	return c.harvestWithCheckpoint(ctx, checkpoint, pending)
+}
+
+// newCheckpoint returns a checkpoint for a new harvest, or nil when checkpoints are disabled.
+// Checkpoints only apply to harvesting by record ID.
+func (c *CswClient) newCheckpoint(constraint *csw.GetRecordsCQLConstraint) *HarvestCheckpoint {
+	if c.checkpointPath == "" || c.harvestMode == HarvestByPage {
+		return nil
+	}
+
+	checkpoint := &HarvestCheckpoint{
+		Endpoint: c.endpoint.String(),
+		Offset:   1,
+		path:     c.checkpointPath,
+	}
+	if constraint != nil {
+		checkpoint.Constraint = *constraint
+	}
+
+	return checkpoint
+}
+
+func (c *CswClient) checkCheckpoint(checkpoint *HarvestCheckpoint) error {
+	if checkpoint.Endpoint != c.endpoint.String() {
+		return fmt.Errorf("checkpoint is for endpoint %s, not %s", checkpoint.Endpoint, c.endpoint.String())
+	}
+
+	return nil
+}
+
+// getAllRecordsWithCheckpoint is GetAllRecords, writing the checkpoint after every page when it is given.
+func (c *CswClient) getAllRecordsWithCheckpoint(
+	ctx context.Context,
+	constraint *csw.GetRecordsCQLConstraint,
+	checkpoint *HarvestCheckpoint,
+) ([]csw.SummaryRecord, error) {
+	if checkpoint == nil {
+		records, err := c.GetAllRecords(ctx, constraint)
+		if err != nil {
+			return nil, err
+		}
+
+		return c.selectRecords(ctx, records)
+	}
+
+	if err := c.pageWithCheckpoint(ctx, checkpoint); err != nil {
+		return nil, err
+	}
+
+	return checkpoint.Records, nil
+}
+
+// pageWithCheckpoint pages through the summary records from the offset of the checkpoint,
+// writing the checkpoint before every page, so an interrupted harvest resumes at the failed page.
+func (c *CswClient) pageWithCheckpoint(ctx context.Context, checkpoint *HarvestCheckpoint) error {
+	getPage := func(offset int) (csw.GetRecordsResponse, error) {
+		checkpoint.Offset = offset
+		if err := checkpoint.Save(); err != nil {
+			return csw.GetRecordsResponse{}, err
+		}
+
+		resp, err := c.GetRecordPage(ctx, &checkpoint.Constraint, offset)
+		if err == nil && offset == 1 {
+			checkpoint.Matched, _ = strconv.Atoi(resp.SearchResults.NumberOfRecordsMatched)
+		}
+
+		return resp, err
+	}
+	getRecords := func(resp *csw.GetRecordsResponse) []csw.SummaryRecord {
+		return resp.SearchResults.SummaryRecords
+	}
+
+	baseline := -1
+	if checkpoint.Offset > 1 {
+		baseline = checkpoint.Matched
+	}
+
+	if err := pageRecursive(getPage, getRecords, checkpoint.Offset, &checkpoint.Records, baseline, 0); err != nil {
+		return err
+	}
+
+	records, err := c.selectRecords(ctx, checkpoint.Records)
+	if err != nil {
+		return err
+	}
+
+	checkpoint.Records = records
+	checkpoint.Offset = 0
+
+	return checkpoint.Save()
+}
+
+// selectRecords applies the record selector of the client, if any.
+func (c *CswClient) selectRecords(ctx context.Context, records []csw.SummaryRecord) ([]csw.SummaryRecord, error) {
+	if c.selector == nil {
+		return records, nil
+	}
+
+	selected, err := c.selector(ctx, records)
+	if err != nil {
+		return nil, fmt.Errorf("cannot select records (%s): %w", c.selectorKey, err)
+	}
+
+	return selected, nil
+}
+
+// harvestWithCheckpoint retrieves the pending records by ID, keeping track of seen and failed identifiers
+// in the checkpoint when it is given. The checkpoint is removed when no records have failed.
+// When the context is done, records that were not retrieved are left pending in the checkpoint.
+func (c *CswClient) harvestWithCheckpoint(
+	ctx context.Context,
+	checkpoint *HarvestCheckpoint,
+	pending []csw.SummaryRecord,
+) ([]iso1911x.MDMetadata, []HarvestFailure, error) {
+	if checkpoint == nil {
+		result, failures := c.HarvestRecords(ctx, pending)
+
+		return result, failures, ctx.Err()
+	}
+
+	onDone := func(record csw.SummaryRecord, err error) {
+		if err != nil && ctx.Err() != nil && errors.Is(err, ctx.Err()) {
+			return // Still pending
+		}
+
+		if err != nil {
+			checkpoint.Failed = append(checkpoint.Failed, record.Identifier)
+		} else {
+			checkpoint.Seen = append(checkpoint.Seen, record.Identifier)
+		}
+
+		if time.Since(checkpoint.lastWrite) >= checkpointInterval {
+			_ = checkpoint.Save() // best-effort; the final write below reports errors
+		}
+	}
+
+	result, failures := c.harvestRecords(ctx, pending, onDone)
+
+	if err := ctx.Err(); err != nil {
+		if saveErr := checkpoint.Save(); saveErr != nil {
+			return result, failures, saveErr
+		}
+
+		return result, failures, err
+	}
+
+	if len(checkpoint.Failed) == 0 {
+		if err := os.Remove(checkpoint.path); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return result, failures, err
+		}
+
+		return result, failures, nil
+	}
+
+	slices.Sort(checkpoint.Failed)
+
+	return result, failures, checkpoint.Save()
+}
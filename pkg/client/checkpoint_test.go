@@ -0,0 +1,121 @@
+package client
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/pdok/pdok-metadata-tool/v2/pkg/model/csw"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// buildMockWebserverCheckpoint pages through the summary records of ids and serves every record by ID,
+// except for the pages and records in failing.
+func buildMockWebserverCheckpoint(ids []string, failing map[string]bool) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		query := req.URL.Query()
+
+		switch query.Get("request") {
+		case "GetRecords":
+			if failing["page-"+query.Get("startPosition")] {
+				rw.WriteHeader(http.StatusBadGateway)
+
+				return
+			}
+
+			start, _ := strconv.Atoi(query.Get("startPosition"))
+			pageSize, _ := strconv.Atoi(query.Get("maxRecords"))
+			end := min(start-1+pageSize, len(ids))
+
+			nextRecord := end + 1
+			if end == len(ids) {
+				nextRecord = 0
+			}
+
+			_, _ = fmt.Fprintf(rw, `<csw:GetRecordsResponse xmlns:csw="http://www.opengis.net/cat/csw/2.0.2" `+
+				`xmlns:dc="http://purl.org/dc/elements/1.1/"><csw:SearchResults numberOfRecordsMatched="%d" nextRecord="%d">`,
+				len(ids), nextRecord)
+
+			for _, id := range ids[start-1 : end] {
+				_, _ = fmt.Fprintf(rw, `<csw:SummaryRecord><dc:identifier>%s</dc:identifier></csw:SummaryRecord>`, id)
+			}
+
+			_, _ = fmt.Fprint(rw, `</csw:SearchResults></csw:GetRecordsResponse>`)
+		case "GetRecordById":
+			if failing[query.Get("id")] {
+				rw.WriteHeader(http.StatusInternalServerError)
+
+				return
+			}
+
+			record, _ := readFileToString("../../examples/ISO19115/5951efa2-1ff3-4763-a966-a2f5497679ee.xml")
+			_, _ = fmt.Fprint(rw, wrapAsGetRecordByIDResponse(record[strings.Index(record, "<gmd:MD_Metadata"):]))
+		default:
+			rw.WriteHeader(http.StatusNotFound)
+		}
+	}))
+}
+
+func TestCswClient_ResumeHarvest(t *testing.T) {
+	failing := map[string]bool{"page-3": true, "b": true}
+
+	server := buildMockWebserverCheckpoint([]string{"a", "b", "c", "d", "e"}, failing)
+	defer server.Close()
+
+	checkpointPath := filepath.Join(t.TempDir(), "harvest-checkpoint.json")
+
+	cswClient := getCswClient(t, server)
+	cswClient.SetHTTPClient(newTestHTTPClient(t, 1))
+	cswClient.SetPageSize(2)
+	cswClient.SetCheckpointPath(checkpointPath)
+
+	// The harvest stops at the second page
+	_, err := cswClient.HarvestByCQLConstraint(t.Context(), &csw.GetRecordsCQLConstraint{})
+	require.Error(t, err)
+
+	checkpoint, err := LoadHarvestCheckpoint(checkpointPath)
+	require.NoError(t, err)
+	assert.Equal(t, 3, checkpoint.Offset)
+	assert.Equal(t, 5, checkpoint.Matched)
+	assert.Len(t, checkpoint.Records, 2)
+
+	// Resuming continues paging at the second page; record b fails
+	failing["page-3"] = false
+
+	result, failures, err := cswClient.ResumeHarvest(t.Context(), checkpoint)
+	require.NoError(t, err)
+	assert.Len(t, result, 4)
+	require.Len(t, failures, 1)
+	assert.Equal(t, "b", failures[0].Identifier)
+
+	checkpoint, err = LoadHarvestCheckpoint(checkpointPath)
+	require.NoError(t, err)
+	assert.Equal(t, 0, checkpoint.Offset)
+	assert.Len(t, checkpoint.Records, 5)
+	assert.ElementsMatch(t, []string{"a", "c", "d", "e"}, checkpoint.Seen)
+	assert.Equal(t, []string{"b"}, checkpoint.Failed)
+
+	files, err := os.ReadDir(filepath.Dir(checkpointPath))
+	require.NoError(t, err)
+	assert.Len(t, files, 1, "the checkpoint is replaced without leaving temporary files")
+
+	// Retrying only fetches record b, after which the checkpoint is removed
+	failing["b"] = false
+
+	result, failures, err = cswClient.RetryFailed(t.Context(), checkpoint)
+	require.NoError(t, err)
+	assert.Len(t, result, 1)
+	assert.Empty(t, failures)
+
+	_, err = os.Stat(checkpointPath)
+	require.ErrorIs(t, err, os.ErrNotExist)
+
+	_, err = LoadHarvestCheckpoint(checkpointPath)
+	require.ErrorIs(t, err, ErrNoCheckpoint)
+}
@@ -2,14 +2,13 @@
 package client
 
 import (
+	"context"
 	"encoding/xml"
 	"errors"
+	"fmt"
 	"net/http"
 	"net/url"
-	"os"
-	"path/filepath"
 	"strconv"
-	"time"
 
 	"log/slog"
 
@@ -19,46 +18,121 @@ import (
 
 // CswClient is used as a client for doing CSW requests.
 type CswClient struct {
-	endpoint *url.URL
-	client   *http.Client
-	cacheDir *string
-	cacheTTL time.Duration
+	endpoint       *url.URL
+	client         *http.Client
+	cache          *recordCache // nil when caching is disabled
+	concurrency    int
+	pageSize       int
+	harvestMode    HarvestMode
+	limiter        *rateLimiter
+	progressFunc   ProgressFunc
+	checkpointPath string
+	selector       RecordSelector
+	selectorKey    string
+
+	transactionEndpoint *url.URL
+	username            string
+	password            string
 }
 
+// HarvestMode holds the ways in which full records are harvested.
+type HarvestMode string
+
+// Values for HarvestMode.
 const (
-	permDir0750  = 0o750
-	permFile0600 = 0o600
+	// HarvestByRecordID pages through summary records and retrieves every record by ID (using the cache).
+	HarvestByRecordID HarvestMode = "record"
+	// HarvestByPage pages through full records using outputSchema gmd and stores every record in the cache.
+	HarvestByPage HarvestMode = "page"
 )
 
-// NewCswClient creates a new instance of NgrClient.
-func NewCswClient(endpoint *url.URL) CswClient {
-	const defaultTimeoutSeconds = 20
-
-	client := &http.Client{
-		Timeout: defaultTimeoutSeconds * time.Second,
-	}
+const (
+	permDir0750     = 0o750
+	permFile0600    = 0o600
+	defaultPageSize = 50
+	gmdOutputSchema = "http://www.isotc211.org/2005/gmd"
+)
 
+// NewCswClient creates a new instance of CswClient, with an HTTP client with DefaultHTTPConfig.
+// Use SetHTTPClient for another configuration.
+func NewCswClient(endpoint *url.URL) CswClient {
 	return CswClient{
-		endpoint: endpoint,
-		client:   client,
-		cacheDir: nil,
+		endpoint:    endpoint,
+		client:      DefaultHTTPClient(),
+		concurrency: 1,
+		pageSize:    defaultPageSize,
+		harvestMode: HarvestByRecordID,
 	}
 }
 
+// SetHTTPClient sets the HTTP client, see NewHTTPClient.
+func (c *CswClient) SetHTTPClient(httpClient *http.Client) {
+	c.client = httpClient
+}
+
 // SetCache enables on-disk caching of raw CSW records.
 // ttlHours is the time-to-live expressed in hours.
 func (c *CswClient) SetCache(cacheDir string, ttlHours int) {
-	c.cacheDir = &cacheDir
-	c.cacheTTL = time.Duration(ttlHours) * time.Hour
+	c.cache = newRecordCache(cacheDir, ttlHours, c.endpoint)
 }
 
 func (c *CswClient) UnsetCache() {
-	c.cacheDir = nil
+	c.cache = nil
+}
+
+// SetConcurrency sets the number of records that are retrieved concurrently while harvesting.
+func (c *CswClient) SetConcurrency(concurrency int) {
+	c.concurrency = max(1, concurrency)
+}
+
+// SetPageSize sets the number of records requested per GetRecords page.
+func (c *CswClient) SetPageSize(pageSize int) {
+	if pageSize <= 0 {
+		pageSize = defaultPageSize
+	}
+
+	c.pageSize = pageSize
+}
+
+// SetHarvestMode sets how HarvestByCQLConstraint retrieves full records.
+func (c *CswClient) SetHarvestMode(mode HarvestMode) {
+	c.harvestMode = mode
+}
+
+// SetRateLimit limits the number of requests per second to the endpoint. The limit is shared with other
+// clients for the same host. A value of 0 or less disables rate limiting for this client.
+func (c *CswClient) SetRateLimit(requestsPerSecond float64) {
+	if requestsPerSecond <= 0 {
+		c.limiter = nil
+
+		return
+	}
+
+	c.limiter = getEndpointLimiter(c.endpoint, requestsPerSecond)
+}
+
+// SetProgressFunc sets a function that receives the progress while harvesting.
+// When not set, the progress is logged periodically.
+func (c *CswClient) SetProgressFunc(progressFunc ProgressFunc) {
+	c.progressFunc = progressFunc
+}
+
+var errRecordSelectorByPage = errors.New("records can only be selected with harvest mode " + string(HarvestByRecordID))
+
+// RecordSelector selects the summary records that are harvested, by properties that cannot be queried with CSW.
+type RecordSelector func(ctx context.Context, records []csw.SummaryRecord) ([]csw.SummaryRecord, error)
+
+// SetRecordSelector sets a selector that is applied to the summary records before the full records are
+// retrieved by ID. The key describes the selection; it is part of the identification of the harvest state, so
+// records that are no longer selected are reported as deleted. A selector requires HarvestByRecordID.
+func (c *CswClient) SetRecordSelector(key string, selector RecordSelector) {
+	c.selectorKey = key
+	c.selector = selector
 }
 
 // GetRecordByID returns a metadata record for a given id.
-func (c *CswClient) GetRecordByID(uuid string) (iso1911x.MDMetadata, error) {
-	raw, err := c.GetRawRecordByID(uuid)
+func (c *CswClient) GetRecordByID(ctx context.Context, uuid string) (iso1911x.MDMetadata, error) {
+	raw, err := c.GetRawRecordByID(ctx, uuid)
 	if err != nil {
 		return iso1911x.MDMetadata{}, err
 	}
@@ -74,38 +148,50 @@ func (c *CswClient) GetRecordByID(uuid string) (iso1911x.MDMetadata, error) {
 	return cswResponse.MDMetadata, nil
 }
 
-func (c *CswClient) GetRawRecordByID(uuid string) (rawRecord []byte, err error) {
+func (c *CswClient) GetRawRecordByID(ctx context.Context, uuid string) (rawRecord []byte, err error) {
 	// Try cache first when enabled and fresh
-	if c.cacheDir != nil {
-		if cached, ok, cacheErr := c.getCachedRecordIfFresh(uuid); cacheErr == nil && ok {
-			slog.Debug("Harvesting record from cache", "uuid", uuid)
+	if cached, ok, cacheErr := c.cache.get(uuid); cacheErr == nil && ok {
+		slog.Debug("Harvesting record from cache", "uuid", uuid)
 
-			return cached, nil
-		}
-		// if cacheErr != nil we ignore and proceed to fetch
+		return cached, nil
 	}
+	// if cacheErr != nil we ignore and proceed to fetch
+
+	// An expired record is revalidated with a conditional request
+	stale, validators, _ := c.cache.stale(uuid)
 
 	// Fetch from remote
 	cswURL := c.getRecordByIDUrl(uuid)
 	slog.Debug("Harvesting record from", "url", cswURL)
 
-	rawRecord, err = getResponseBody(cswURL, "GET", nil, *c.client)
+	if err := c.limiter.wait(ctx); err != nil {
+		return nil, err
+	}
+
+	rawRecord, header, notModified, err := getConditionalResponse(ctx, cswURL, validators, *c.client)
 	if err != nil {
 		return nil, err
 	}
 
-	// Store in cache when enabled
-	if c.cacheDir != nil {
-		_ = c.storeRecordInCache(uuid, rawRecord) // best-effort caching
+	if notModified {
+		slog.Debug("Record not modified, refreshing cache", "uuid", uuid)
+
+		_ = c.cache.refresh(uuid, validators, header) // best-effort caching
+
+		return stale, nil
 	}
 
+	// Store in cache when enabled
+	_ = c.cache.put(uuid, rawRecord, newCacheEntryInfo(cswURL, header)) // best-effort caching
+
 	return rawRecord, nil
 }
 
-// GetRecordPage returns the full CSW GetRecords response for a page, possibly using a constraint.
+// GetRecordPage returns the full CSW GetRecords response for a page of summary records, possibly using a constraint.
 // TODO Use this for harvesting service metadata in ETF-validator-go.
 // Note: Interface changed to return the entire GetRecordsResponse (not only records and next offset).
 func (c *CswClient) GetRecordPage(
+	ctx context.Context,
 	constraint *csw.GetRecordsCQLConstraint,
 	offset int,
 ) (csw.GetRecordsResponse, error) {
@@ -113,36 +199,63 @@ func (c *CswClient) GetRecordPage(
 		return csw.GetRecordsResponse{}, errors.New("offset must be greater than 0")
 	}
 
-	cswURL := c.endpoint.String() +
-		"?service=CSW" +
-		"&request=GetRecords" +
-		"&version=2.0.2" +
-		"&typeNames=gmd:MD_Metadata" +
-		"&resultType=results" +
-		"&startPosition=" + strconv.Itoa(offset) +
-		"&maxRecords=50"
+	var cswResponse = csw.GetRecordsResponse{}
 
-	if constraint != nil {
-		cswURL += constraint.ToQueryParameter()
+	if err := c.limiter.wait(ctx); err != nil {
+		return csw.GetRecordsResponse{}, err
 	}
 
-	var cswResponse = csw.GetRecordsResponse{}
+	err := getUnmarshalledXMLResponse(ctx, &cswResponse, c.getRecordsURL(constraint, offset, false), "GET", nil, *c.client)
+	if err != nil {
+		return csw.GetRecordsResponse{}, err
+	}
 
-	err := getUnmarshalledXMLResponse(&cswResponse, cswURL, "GET", nil, *c.client)
+	return cswResponse, nil
+}
+
+// GetFullRecordPage returns the GetRecords response for a page of full ISO records (outputSchema gmd,
+// elementSetName full), possibly using a constraint. The records are in SearchResults.Records.
+// When caching is enabled, every record is stored in the cache as if retrieved by ID.
+func (c *CswClient) GetFullRecordPage(
+	ctx context.Context,
+	constraint *csw.GetRecordsCQLConstraint,
+	offset int,
+) (csw.GetRecordsResponse, error) {
+	if offset == 0 {
+		return csw.GetRecordsResponse{}, errors.New("offset must be greater than 0")
+	}
+
+	cswURL := c.getRecordsURL(constraint, offset, true)
+
+	if err := c.limiter.wait(ctx); err != nil {
+		return csw.GetRecordsResponse{}, err
+	}
+
+	body, err := getResponseBody(ctx, cswURL, "GET", nil, *c.client)
 	if err != nil {
 		return csw.GetRecordsResponse{}, err
 	}
 
+	var cswResponse = csw.GetRecordsResponse{}
+	if err := xml.Unmarshal(body, &cswResponse); err != nil { //nolint:musttag // model types contain tags
+		return csw.GetRecordsResponse{}, fmt.Errorf("error unmarshalling NGR response from url %s: %w", cswURL, err)
+	}
+
+	if c.cache != nil {
+		c.storePageInCache(cswURL, body, cswResponse.SearchResults.Records)
+	}
+
 	return cswResponse, nil
 }
 
 // GetAllRecords returns all metadata records based on recursive paging, possibly using a constraint.
 func (c *CswClient) GetAllRecords(
+	ctx context.Context,
 	constraint *csw.GetRecordsCQLConstraint,
 ) ([]csw.SummaryRecord, error) {
 	var result []csw.SummaryRecord
 
-	err := c.getRecordsRecursive(constraint, 1, &result)
+	err := c.getRecordsRecursive(ctx, constraint, 1, &result)
 	if err != nil {
 		return nil, err
 	}
@@ -150,51 +263,78 @@ func (c *CswClient) GetAllRecords(
 	return result, nil
 }
 
+// GetAllRecordsByFilter returns all summary metadata records that match the filter, which is sent as CQL_TEXT.
+func (c *CswClient) GetAllRecordsByFilter(ctx context.Context, filter csw.Filter) ([]csw.SummaryRecord, error) {
+	return c.GetAllRecords(ctx, &csw.GetRecordsCQLConstraint{Filter: filter})
+}
+
+// GetAllFullRecords returns all full metadata records based on recursive paging with GetFullRecordPage,
+// possibly using a constraint.
+func (c *CswClient) GetAllFullRecords(
+	ctx context.Context,
+	constraint *csw.GetRecordsCQLConstraint,
+) ([]iso1911x.MDMetadata, error) {
+	var result []iso1911x.MDMetadata
+
+	getPage := func(offset int) (csw.GetRecordsResponse, error) {
+		return c.GetFullRecordPage(ctx, constraint, offset)
+	}
+	getRecords := func(resp *csw.GetRecordsResponse) []iso1911x.MDMetadata {
+		return resp.SearchResults.Records
+	}
+
+	if err := pageRecursive(getPage, getRecords, 1, &result, -1, 0); err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// HarvestByCQLConstraint returns all full metadata records that match the constraint, using the harvest mode
+// of the client. With HarvestByRecordID the records are in the order of the summary records,
+// and records that cannot be retrieved are logged and left out.
 func (c *CswClient) HarvestByCQLConstraint(
+	ctx context.Context,
 	constraint *csw.GetRecordsCQLConstraint,
-) (result []iso1911x.MDMetadata, err error) {
-	records, err := c.GetAllRecords(constraint)
-	if err != nil {
-		return result, err
-	}
-
-	for _, record := range records {
-		raw, err := c.GetRawRecordByID(record.Identifier)
-		if err != nil {
-			slog.Debug(
-				"Error retrieving record",
-				"identifier",
-				record.Identifier,
-				"type",
-				record.Type,
-				"title",
-				record.Title,
-				"err",
-				err,
-			)
+) ([]iso1911x.MDMetadata, error) {
+	if c.harvestMode == HarvestByPage {
+		if c.selector != nil {
+			return nil, errRecordSelectorByPage
 		}
 
-		cswResponse := csw.GetRecordByIDResponse{}
-
-		err = xml.Unmarshal(raw, &cswResponse) //nolint:musttag // model types contain tags
-		if err != nil {
-			slog.Debug(
-				"Error unmarshalling NGR response",
-				"identifier",
-				record.Identifier,
-				"err",
-				err,
-			)
-		} else {
-			result = append(result, cswResponse.MDMetadata)
-		}
+		return c.GetAllFullRecords(ctx, constraint)
 	}
 
-	return result, err
+	checkpoint := c.newCheckpoint(constraint)
+
+	records, err := c.getAllRecordsWithCheckpoint(ctx, constraint, checkpoint)
+	if err != nil {
+		return nil, err
+	}
+
+	result, failures, err := c.harvestWithCheckpoint(ctx, checkpoint, records)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, failure := range failures {
+		slog.Warn(
+			"Error retrieving record",
+			"identifier",
+			failure.Identifier,
+			"title",
+			failure.Title,
+			"err",
+			failure.Err,
+		)
+	}
+
+	return result, nil
 }
 
 // GetRecordsWithOGCFilter returns summary metadata records, using an OGC filter.
 func (c *CswClient) GetRecordsWithOGCFilter(
+	ctx context.Context,
 	filter *csw.GetRecordsOgcFilter,
 ) ([]csw.SummaryRecord, error) {
 	requestBody, err := filter.ToRequestBody()
@@ -204,13 +344,7 @@ func (c *CswClient) GetRecordsWithOGCFilter(
 
 	var cswResponse = csw.GetRecordsResponse{}
 
-	err = getUnmarshalledXMLResponse(
-		&cswResponse,
-		c.endpoint.String(),
-		"POST",
-		&requestBody,
-		*c.client,
-	)
+	err = getUnmarshalledXMLQueryResponse(ctx, &cswResponse, c.endpoint.String(), requestBody, *c.client)
 	if err != nil {
 		return nil, err
 	}
@@ -220,8 +354,6 @@ func (c *CswClient) GetRecordsWithOGCFilter(
 
 // --- Helper and unexported methods must be placed after exported methods (funcorder) ---
 
-// --- Helper and unexported methods must be placed after exported methods (funcorder) ---
-
 func (c *CswClient) getRecordByIDUrl(
 	uuid string,
 ) string {
@@ -229,84 +361,82 @@ func (c *CswClient) getRecordByIDUrl(
 		"?service=CSW" +
 		"&request=GetRecordById" +
 		"&version=2.0.2" +
-		"&outputSchema=http://www.isotc211.org/2005/gmd&elementSetName=full" +
+		"&outputSchema=" + gmdOutputSchema + "&elementSetName=full" +
 		"&id=" + uuid + "#MD_DataIdentification"
 }
 
-func (c *CswClient) getCachedRecordIfFresh(uuid string) ([]byte, bool, error) {
-	path := c.getCachePath(uuid)
-
-	fi, err := os.Stat(path)
-	if err != nil {
-		if os.IsNotExist(err) {
-			return nil, false, nil
-		}
-
-		return nil, false, err
-	}
+func (c *CswClient) getRecordsURL(
+	constraint *csw.GetRecordsCQLConstraint,
+	offset int,
+	full bool,
+) string {
+	cswURL := c.endpoint.String() +
+		"?service=CSW" +
+		"&request=GetRecords" +
+		"&version=2.0.2" +
+		"&typeNames=gmd:MD_Metadata" +
+		"&resultType=results" +
+		"&startPosition=" + strconv.Itoa(offset) +
+		"&maxRecords=" + strconv.Itoa(c.pageSize)
 
-	// Check freshness
-	if time.Since(fi.ModTime()) > c.cacheTTL {
-		return nil, false, nil
+	if full {
+		cswURL += "&outputSchema=" + gmdOutputSchema + "&elementSetName=full"
 	}
 
-	// #nosec G304 -- reading from a constructed path under controlled cache directory
-	data, err := os.ReadFile(path)
-	if err != nil {
-		return nil, false, err
+	if constraint != nil {
+		cswURL += constraint.ToQueryParameter()
 	}
 
-	return data, true, nil
+	return cswURL
 }
 
-func (c *CswClient) storeRecordInCache(uuid string, data []byte) error {
-	if c.cacheDir == nil {
-		return nil
-	}
+// storePageInCache stores the records of a full GetRecords page in the cache (best-effort).
+// The HTTP validators of the page do not apply to the records, so they are not stored.
+func (c *CswClient) storePageInCache(pageURL string, body []byte, records []iso1911x.MDMetadata) {
+	raws, err := csw.ExtractRawRecords(body)
+	if err != nil || len(raws) != len(records) {
+		slog.Warn("Could not split GetRecords page into records; page is not cached", "err", err)
 
-	if err := c.ensureCacheDir(); err != nil {
-		return err
+		return
 	}
 
-	path := c.getCachePath(uuid)
-
-	return os.WriteFile(path, data, permFile0600)
-}
-
-func (c *CswClient) ensureCacheDir() error {
-	if c.cacheDir == nil {
-		return nil
+	for i, raw := range raws {
+		if uuid := iso1911x.NormalizeXMLText(records[i].UUID); uuid != "" {
+			_ = c.cache.put(uuid, raw, CacheEntryInfo{SourceURL: pageURL}) // best-effort caching
+		}
 	}
-
-	return os.MkdirAll(*c.cacheDir, permDir0750)
 }
 
-func (c *CswClient) getCachePath(uuid string) string {
-	// simple filename: <uuid>.xml inside cacheDir
-	return filepath.Join(*c.cacheDir, uuid+".xml")
-}
-
-// getRecordsRecursive recursively pages through all records.
-// It guards against changes in NumberOfRecordsMatched by restarting from offset 1 if detected.
+// getRecordsRecursive recursively pages through all summary records.
 func (c *CswClient) getRecordsRecursive(
+	ctx context.Context,
 	constraint *csw.GetRecordsCQLConstraint,
 	offset int,
 	result *[]csw.SummaryRecord,
 ) (err error) {
-	return c.getRecordsRecursiveWithState(constraint, offset, result, -1, 0)
+	getPage := func(offset int) (csw.GetRecordsResponse, error) {
+		return c.GetRecordPage(ctx, constraint, offset)
+	}
+	getRecords := func(resp *csw.GetRecordsResponse) []csw.SummaryRecord {
+		return resp.SearchResults.SummaryRecords
+	}
+
+	return pageRecursive(getPage, getRecords, offset, result, -1, 0)
 }
 
-// getRecordsRecursiveWithState is the internal implementation tracking baseline matched and restart attempts.
-func (c *CswClient) getRecordsRecursiveWithState(
-	constraint *csw.GetRecordsCQLConstraint,
+// pageRecursive recursively pages through all records, tracking baseline matched and restart attempts.
+// It guards against changes in NumberOfRecordsMatched by restarting from offset 1 if detected.
+func pageRecursive[T any](
+	getPage func(offset int) (csw.GetRecordsResponse, error),
+	getRecords func(resp *csw.GetRecordsResponse) []T,
 	offset int,
-	result *[]csw.SummaryRecord,
+	result *[]T,
 	baselineMatched int,
 	restarts int,
 ) (err error) {
 	const maxRestarts = 3
 
-	resp, err := c.GetRecordPage(constraint, offset)
+	resp, err := getPage(offset)
 	if err != nil {
 		return err
 	}
@@ -361,16 +491,16 @@ func (c *CswClient) getRecordsRecursiveWithState(
 				// Reset results and restart from beginning with new baseline
 				*result = (*result)[:0]
 
-				return c.getRecordsRecursiveWithState(constraint, 1, result, matched, restarts+1)
+				return pageRecursive(getPage, getRecords, 1, result, matched, restarts+1)
 			}
 		}
 	}
 
-	*result = append(*result, resp.SearchResults.SummaryRecords...)
+	*result = append(*result, getRecords(&resp)...)
 
 	if nextOffset == 0 {
 		return nil
 	}
 
-	return c.getRecordsRecursiveWithState(constraint, nextOffset, result, baselineMatched, restarts)
+	return pageRecursive(getPage, getRecords, nextOffset, result, baselineMatched, restarts)
 }
@@ -1,10 +1,16 @@
 package client
 
 import (
+	"context"
+	"fmt"
+	"net/http"
 	"net/http/httptest"
 	"net/url"
 	"strconv"
+	"strings"
+	"sync/atomic"
 	"testing"
+	"time"
 
 	"github.com/pdok/pdok-metadata-tool/v2/pkg/model/csw"
 	"github.com/pdok/pdok-metadata-tool/v2/pkg/model/iso1911x"
@@ -60,7 +66,7 @@ func TestCswClient_GetRecords(t *testing.T) {
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			resp, err := cswClient.GetRecordPage(&tt.args.constraint, tt.args.offset)
+			resp, err := cswClient.GetRecordPage(t.Context(), &tt.args.constraint, tt.args.offset)
 			if !tt.wantErr {
 				require.NoError(t, err)
 			}
@@ -127,7 +133,7 @@ func TestCswClient_GetRecordsWithOGCFilter(t *testing.T) {
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			mdRecords, err := cswClient.GetRecordsWithOGCFilter(&tt.args.filter)
+			mdRecords, err := cswClient.GetRecordsWithOGCFilter(t.Context(), &tt.args.filter)
 			if !tt.wantErr {
 				require.NoError(t, err)
 			}
@@ -174,7 +180,7 @@ func TestCswClient_GetRecordById(t *testing.T) {
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			MDMetadata, err := cswClient.GetRecordByID(tt.args.id)
+			MDMetadata, err := cswClient.GetRecordByID(t.Context(), tt.args.id)
 			if !tt.wantErr {
 				require.NoError(t, err)
 			}
@@ -206,3 +212,223 @@ func getCswClient(t *testing.T, mockedNGRServer *httptest.Server) *CswClient {
 
 	return &cswClient
 }
+
+func TestCswClient_HarvestRecords(t *testing.T) {
+	files := map[string]string{
+		"dataset-1": "../../examples/ISO19115/5951efa2-1ff3-4763-a966-a2f5497679ee.xml",
+		"dataset-2": "../../examples/ISO19115/Voorbeeld_Metadata_Dataset_2022_max.xml",
+		"service-1": "../../examples/ISO19119/dae8f9e3-99af-4d21-9feb-29f2a1693077.xml",
+	}
+
+	// Earlier records respond slower, so workers finish in reverse order
+	delays := map[string]time.Duration{"dataset-1": 30 * time.Millisecond, "missing": 20 * time.Millisecond}
+
+	var requests atomic.Int32
+
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		requests.Add(1)
+
+		id := req.URL.Query().Get("id")
+		time.Sleep(delays[id])
+
+		metadataResponse, err := readFileToString(files[id])
+		if err != nil {
+			rw.WriteHeader(http.StatusNotFound)
+
+			return
+		}
+
+		_, _ = fmt.Fprint(rw, wrapAsGetRecordByIDResponse(metadataResponse))
+	}))
+	defer server.Close()
+
+	cswClient := getCswClient(t, server)
+	cswClient.SetConcurrency(4)
+	cswClient.SetCache(t.TempDir(), 1)
+
+	var progress []HarvestProgress
+
+	cswClient.SetProgressFunc(func(p HarvestProgress) { progress = append(progress, p) })
+
+	records := []csw.SummaryRecord{
+		{Identifier: "dataset-1"},
+		{Identifier: "missing", Title: "Deleted record"},
+		{Identifier: "dataset-2"},
+		{Identifier: "service-1"},
+	}
+
+	result, failures := cswClient.HarvestRecords(t.Context(), records)
+
+	var ids []string
+	for _, md := range result {
+		ids = append(ids, md.UUID)
+	}
+
+	assert.Equal(t, []string{
+		"5951efa2-1ff3-4763-a966-a2f5497679ee",
+		"C2DFBDBC-5092-11E0-BA8E-B62DE0D72085",
+		"dae8f9e3-99af-4d21-9feb-29f2a1693077",
+	}, ids)
+	require.Len(t, failures, 1)
+	assert.Equal(t, "missing", failures[0].Identifier)
+	assert.Equal(t, "Deleted record", failures[0].Title)
+	require.Error(t, failures[0].Err)
+
+	require.Len(t, progress, len(records))
+	last := progress[len(progress)-1]
+	assert.Equal(t, 4, last.Done)
+	assert.Equal(t, 1, last.Failed)
+	assert.Equal(t, time.Duration(0), last.ETA)
+
+	// A second harvest reads the successful records from the cache
+	_, failures = cswClient.HarvestRecords(t.Context(), records)
+	assert.Len(t, failures, 1)
+	assert.Equal(t, int32(len(records)+1), requests.Load())
+}
+
+func TestRateLimiter_Wait(t *testing.T) {
+	endpoint, err := url.Parse("http://rate-limit.test/csw")
+	require.NoError(t, err)
+
+	limiter := getEndpointLimiter(endpoint, 100)
+	assert.Same(t, limiter, getEndpointLimiter(endpoint, 100))
+
+	start := time.Now()
+	for range 5 {
+		require.NoError(t, limiter.wait(t.Context()))
+	}
+
+	// The first request is immediate, the next four are spaced 10ms apart
+	assert.GreaterOrEqual(t, time.Since(start), 40*time.Millisecond)
+
+	t.Run("Canceled", func(t *testing.T) {
+		slow := getEndpointLimiter(&url.URL{Host: "slow-rate-limit.test"}, 0.01)
+		require.NoError(t, slow.wait(t.Context()))
+
+		ctx, cancel := context.WithTimeout(t.Context(), 10*time.Millisecond)
+		defer cancel()
+
+		start := time.Now()
+		require.ErrorIs(t, slow.wait(ctx), context.DeadlineExceeded)
+		assert.Less(t, time.Since(start), time.Second, "the remaining delay of 100s is not awaited")
+	})
+
+	t.Run("Canceled waiters release their slots", func(t *testing.T) {
+		slow := getEndpointLimiter(&url.URL{Host: "release-rate-limit.test"}, 0.01)
+		require.NoError(t, slow.wait(t.Context()))
+
+		slow.mu.Lock()
+		next := slow.next
+		slow.mu.Unlock()
+
+		first, cancelFirst := context.WithCancel(t.Context())
+		second, cancelSecond := context.WithCancel(t.Context())
+		errs := make(chan error, 2)
+
+		go func() { errs <- slow.wait(first) }()
+
+		require.Eventually(t, func() bool {
+			slow.mu.Lock()
+			defer slow.mu.Unlock()
+
+			return slow.next.After(next)
+		}, time.Second, time.Millisecond)
+
+		go func() { errs <- slow.wait(second) }()
+
+		require.Eventually(t, func() bool {
+			slow.mu.Lock()
+			defer slow.mu.Unlock()
+
+			return slow.next.After(next.Add(slow.interval))
+		}, time.Second, time.Millisecond)
+
+		// The first slot is released after the second one, which is then no longer the last
+		cancelFirst()
+		require.ErrorIs(t, <-errs, context.Canceled)
+		cancelSecond()
+		require.ErrorIs(t, <-errs, context.Canceled)
+
+		slow.mu.Lock()
+		defer slow.mu.Unlock()
+
+		assert.Equal(t, next, slow.next)
+		assert.Empty(t, slow.released)
+	})
+}
+
+func TestCswClient_GetAllFullRecords(t *testing.T) {
+	files := []string{
+		"../../examples/ISO19115/5951efa2-1ff3-4763-a966-a2f5497679ee.xml",
+		"../../examples/ISO19115/Voorbeeld_Metadata_Dataset_2022_max.xml",
+		"../../examples/ISO19119/dae8f9e3-99af-4d21-9feb-29f2a1693077.xml",
+	}
+
+	var requests []string
+
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		requests = append(requests, req.URL.RawQuery)
+		query := req.URL.Query()
+
+		if query.Get("request") != "GetRecords" || query.Get("outputSchema") != "http://www.isotc211.org/2005/gmd" ||
+			query.Get("elementSetName") != "full" {
+			rw.WriteHeader(http.StatusNotFound)
+
+			return
+		}
+
+		start, _ := strconv.Atoi(query.Get("startPosition"))
+		pageSize, _ := strconv.Atoi(query.Get("maxRecords"))
+		end := min(start-1+pageSize, len(files))
+
+		nextRecord := end + 1
+		if end == len(files) {
+			nextRecord = 0
+		}
+
+		_, _ = fmt.Fprintf(rw, `<csw:GetRecordsResponse xmlns:csw="http://www.opengis.net/cat/csw/2.0.2">`+
+			`<csw:SearchResults numberOfRecordsMatched="%d" nextRecord="%d">`, len(files), nextRecord)
+
+		for _, file := range files[start-1 : end] {
+			record, err := readFileToString(file)
+			require.NoError(t, err)
+			_, _ = fmt.Fprint(rw, record[strings.Index(record, "<gmd:MD_Metadata"):])
+		}
+
+		_, _ = fmt.Fprint(rw, `</csw:SearchResults></csw:GetRecordsResponse>`)
+	}))
+	defer server.Close()
+
+	cswClient := getCswClient(t, server)
+	cswClient.SetCache(t.TempDir(), 1)
+	cswClient.SetPageSize(2)
+	cswClient.SetHarvestMode(HarvestByPage)
+
+	dataset := iso1911x.Dataset
+	mds, err := cswClient.HarvestByCQLConstraint(t.Context(), &csw.GetRecordsCQLConstraint{MetadataType: &dataset})
+	require.NoError(t, err)
+
+	var ids []string
+	for _, md := range mds {
+		ids = append(ids, md.UUID)
+	}
+
+	expectedIDs := []string{
+		"5951efa2-1ff3-4763-a966-a2f5497679ee",
+		"C2DFBDBC-5092-11E0-BA8E-B62DE0D72085",
+		"dae8f9e3-99af-4d21-9feb-29f2a1693077",
+	}
+	assert.Equal(t, expectedIDs, ids)
+	require.Len(t, requests, 2)
+	assert.Contains(t, requests[0], "startPosition=1&maxRecords=2")
+	assert.Contains(t, requests[1], "startPosition=3&maxRecords=2")
+
+	// Every record is now read from the cache, without requests to the server
+	for _, id := range expectedIDs {
+		md, err := cswClient.GetRecordByID(t.Context(), id)
+		require.NoError(t, err)
+		assert.Equal(t, id, md.UUID)
+	}
+
+	assert.Len(t, requests, 2)
+}
@@ -0,0 +1,73 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/pdok/pdok-metadata-tool/v2/internal/common"
+)
+
+const permFile0644 = 0o644
+
+// DownloadFile downloads a file from a URL, saves it to a local path and returns its content.
+//
+// The validators of the response (ETag, Last-Modified) are stored in a CacheEntryInfo sidecar next to the
+// file (<path>.info.json). When the file was downloaded before, a conditional request is sent; when the
+// server answers 304 Not Modified, the modification time of the file is refreshed instead of downloading
+// it again. The request is sent with the HTTP client, see NewHTTPClient.
+func DownloadFile(ctx context.Context, httpClient *http.Client, url string, path string) ([]byte, error) {
+	//nolint:gosec
+	previous, err := os.ReadFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+
+	var validators *CacheEntryInfo
+
+	if previous != nil {
+		info, err := readCacheEntryInfo(downloadInfoPath(path))
+		if err == nil && info != nil && info.SourceURL == url && info.SHA256 == hashRecord(previous) {
+			validators = info
+		}
+	}
+
+	body, header, notModified, err := getConditionalResponse(ctx, url, validators, *httpClient)
+	if err != nil {
+		return nil, err
+	}
+
+	info := newCacheEntryInfo(url, header)
+
+	if notModified {
+		now := time.Now()
+		if err := os.Chtimes(path, now, now); err != nil {
+			return nil, err
+		}
+
+		info = validators.refreshed(header)
+		body = previous
+	} else if err := common.WriteFileAtomic(path, body, permFile0644); err != nil {
+		return nil, err
+	}
+
+	info.FetchedAt = time.Now().UTC()
+	info.SHA256 = hashRecord(body)
+
+	sidecar, err := json.MarshalIndent(info, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+
+	if err := common.WriteFileAtomic(downloadInfoPath(path), sidecar, permFile0644); err != nil {
+		return nil, err
+	}
+
+	return body, nil
+}
+
+func downloadInfoPath(path string) string {
+	return path + ".info.json"
+}
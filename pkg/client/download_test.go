@@ -0,0 +1,89 @@
+package client
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDownloadFile(t *testing.T) {
+	const lastModified = "Mon, 02 Jun 2025 10:00:00 GMT"
+
+	content := "version 1"
+
+	var statuses []int
+
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		etag := `"` + content + `"`
+		if req.Header.Get("If-None-Match") == etag {
+			statuses = append(statuses, http.StatusNotModified)
+			rw.WriteHeader(http.StatusNotModified)
+
+			return
+		}
+
+		statuses = append(statuses, http.StatusOK)
+		rw.Header().Set("ETag", etag)
+		rw.Header().Set("Last-Modified", lastModified)
+		_, _ = rw.Write([]byte(content))
+	}))
+	defer server.Close()
+
+	path := filepath.Join(t.TempDir(), "thesaurus.rdf")
+
+	t.Run("First download", func(t *testing.T) {
+		body, err := DownloadFile(t.Context(), http.DefaultClient, server.URL, path)
+		require.NoError(t, err)
+		assert.Equal(t, "version 1", string(body))
+
+		info, err := readCacheEntryInfo(downloadInfoPath(path))
+		require.NoError(t, err)
+		require.NotNil(t, info)
+		assert.Equal(t, server.URL, info.SourceURL)
+		assert.Equal(t, `"version 1"`, info.ETag)
+		assert.Equal(t, lastModified, info.LastModified)
+	})
+
+	t.Run("Not modified", func(t *testing.T) {
+		old := time.Now().Add(-7 * 24 * time.Hour)
+		require.NoError(t, os.Chtimes(path, old, old))
+
+		body, err := DownloadFile(t.Context(), http.DefaultClient, server.URL, path)
+		require.NoError(t, err)
+		assert.Equal(t, "version 1", string(body))
+		assert.Equal(t, []int{http.StatusOK, http.StatusNotModified}, statuses)
+
+		fi, err := os.Stat(path)
+		require.NoError(t, err)
+		assert.WithinDuration(t, time.Now(), fi.ModTime(), time.Minute, "a 304 refreshes the file")
+	})
+
+	t.Run("Modified", func(t *testing.T) {
+		content = "version 2"
+
+		body, err := DownloadFile(t.Context(), http.DefaultClient, server.URL, path)
+		require.NoError(t, err)
+		assert.Equal(t, "version 2", string(body))
+
+		saved, err := os.ReadFile(path)
+		require.NoError(t, err)
+		assert.Equal(t, "version 2", string(saved))
+	})
+
+	t.Run("Changed local file is downloaded unconditionally", func(t *testing.T) {
+		statuses = nil
+
+		require.NoError(t, os.WriteFile(path, []byte("edited"), permFile0600))
+
+		body, err := DownloadFile(t.Context(), http.DefaultClient, server.URL, path)
+		require.NoError(t, err)
+		assert.Equal(t, "version 2", string(body))
+		assert.Equal(t, []int{http.StatusOK}, statuses)
+	})
+}
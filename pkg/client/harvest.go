@@ -0,0 +1,184 @@
+package client
+
+import (
+	"context"
+	"encoding/xml"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/pdok/pdok-metadata-tool/v2/pkg/model/csw"
+	"github.com/pdok/pdok-metadata-tool/v2/pkg/model/iso1911x"
+)
+
+// progressLogInterval is the minimum time between two progress log lines when no ProgressFunc is set.
+const progressLogInterval = 5 * time.Second
+
+// HarvestProgress holds the progress of a harvest.
+type HarvestProgress struct {
+	Done             int // Records that are processed, including failures
+	Failed           int
+	Total            int
+	Elapsed          time.Duration
+	RecordsPerSecond float64
+	ETA              time.Duration
+}
+
+// String returns the progress as a single line, i.e. for logging.
+func (p HarvestProgress) String() string {
+	return fmt.Sprintf("%d/%d records (%d failed), %.1f rec/s, ETA %s",
+		p.Done, p.Total, p.Failed, p.RecordsPerSecond, p.ETA.Round(time.Second))
+}
+
+// ProgressFunc is called after each harvested record. It is never called concurrently.
+type ProgressFunc func(progress HarvestProgress)
+
+// HarvestFailure holds a record that could not be harvested.
+type HarvestFailure struct {
+	Identifier string
+	Title      string
+	Err        error
+}
+
+// HarvestRecords retrieves the full records of the given summary records, using a pool of workers
+// (see SetConcurrency). The records are returned in the order of the summary records. Records that cannot
+// be retrieved or unmarshalled are left out and returned as failures, also in the order of the summary records,
+// so the outcome does not depend on the order in which the workers finish.
+// When the context is done, the remaining records fail with the error of the context.
+func (c *CswClient) HarvestRecords(
+	ctx context.Context,
+	records []csw.SummaryRecord,
+) (result []iso1911x.MDMetadata, failures []HarvestFailure) {
+	return c.harvestRecords(ctx, records, nil)
+}
+
+// harvestRecords implements HarvestRecords. When given, onDone is called after each record
+// from a single goroutine, in the order in which the records are finished.
+func (c *CswClient) harvestRecords(
+	ctx context.Context,
+	records []csw.SummaryRecord,
+	onDone func(record csw.SummaryRecord, err error),
+) (result []iso1911x.MDMetadata, failures []HarvestFailure) {
+	type outcome struct {
+		md  iso1911x.MDMetadata
+		err error
+	}
+
+	outcomes := make([]outcome, len(records))
+	jobs := make(chan int)
+	done := make(chan int)
+
+	var wg sync.WaitGroup
+
+	for range max(1, c.concurrency) {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			for i := range jobs {
+				if err := ctx.Err(); err != nil {
+					outcomes[i].err = err
+				} else {
+					outcomes[i].md, outcomes[i].err = c.harvestRecord(ctx, records[i].Identifier)
+				}
+				done <- i
+			}
+		}()
+	}
+
+	go func() {
+		for i := range records {
+			jobs <- i
+		}
+
+		close(jobs)
+		wg.Wait()
+		close(done)
+	}()
+
+	progress := newProgressTracker(len(records), c.progressFunc)
+	for i := range done {
+		progress.update(outcomes[i].err != nil)
+
+		if onDone != nil {
+			onDone(records[i], outcomes[i].err)
+		}
+	}
+
+	for i, o := range outcomes {
+		if o.err != nil {
+			failures = append(failures, HarvestFailure{
+				Identifier: records[i].Identifier,
+				Title:      records[i].Title,
+				Err:        o.err,
+			})
+
+			continue
+		}
+
+		result = append(result, o.md)
+	}
+
+	return result, failures
+}
+
+// harvestRecord retrieves and unmarshals a single record.
+func (c *CswClient) harvestRecord(ctx context.Context, identifier string) (iso1911x.MDMetadata, error) {
+	raw, err := c.GetRawRecordByID(ctx, identifier)
+	if err != nil {
+		return iso1911x.MDMetadata{}, err
+	}
+
+	cswResponse := csw.GetRecordByIDResponse{}
+	if err := xml.Unmarshal(raw, &cswResponse); err != nil { //nolint:musttag // model types contain tags
+		return iso1911x.MDMetadata{}, fmt.Errorf("error unmarshalling record %s: %w", identifier, err)
+	}
+
+	return cswResponse.MDMetadata, nil
+}
+
+// progressTracker computes the harvest progress. When no ProgressFunc is given, the progress is logged.
+type progressTracker struct {
+	progress HarvestProgress
+	start    time.Time
+	lastLog  time.Time
+	report   ProgressFunc
+}
+
+func newProgressTracker(total int, report ProgressFunc) *progressTracker {
+	now := time.Now()
+
+	return &progressTracker{
+		progress: HarvestProgress{Total: total},
+		start:    now,
+		lastLog:  now,
+		report:   report,
+	}
+}
+
+func (t *progressTracker) update(failed bool) {
+	t.progress.Done++
+	if failed {
+		t.progress.Failed++
+	}
+
+	t.progress.Elapsed = time.Since(t.start)
+	if seconds := t.progress.Elapsed.Seconds(); seconds > 0 {
+		t.progress.RecordsPerSecond = float64(t.progress.Done) / seconds
+		remaining := float64(t.progress.Total - t.progress.Done)
+		t.progress.ETA = time.Duration(remaining / t.progress.RecordsPerSecond * float64(time.Second))
+	}
+
+	if t.report != nil {
+		t.report(t.progress)
+
+		return
+	}
+
+	if t.progress.Done == t.progress.Total || time.Since(t.lastLog) >= progressLogInterval {
+		t.lastLog = time.Now()
+		slog.Info("Harvest progress", "progress", t.progress.String())
+	}
+}
@@ -0,0 +1,333 @@
+package client
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"math/rand/v2"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pdok/pdok-metadata-tool/v2/internal/common"
+)
+
+// DefaultHTTPTimeout is the timeout of a single HTTP request (attempt), including reading the response body.
+const DefaultHTTPTimeout = 20 * time.Second
+
+const (
+	defaultMaxRetries   = 3
+	defaultRetryWait    = 500 * time.Millisecond
+	defaultMaxRetryWait = 30 * time.Second
+	httpErrorBodyLimit  = 512
+)
+
+// HTTPConfig configures the HTTP client that is shared by the clients and repositories, see NewHTTPClient.
+type HTTPConfig struct {
+	Timeout      time.Duration // Timeout of a single attempt; 0 disables the timeout
+	Proxy        *url.URL      // Proxy for all requests; when nil the proxy is taken from the environment (HTTPS_PROXY etc.)
+	CACertFile   string        // PEM file with CA certificates that are trusted in addition to the system pool
+	MaxRetries   int           // Number of retries of a failed request; 0 disables retries
+	RetryWait    time.Duration // Base of the exponential backoff between retries
+	MaxRetryWait time.Duration // Maximum wait between retries, also for Retry-After
+}
+
+// HTTPError is returned when a server responds with an unsuccessful HTTP status.
+type HTTPError struct {
+	Method     string
+	URL        string
+	StatusCode int
+	Body       string // The start of the response body
+}
+
+func (e *HTTPError) Error() string {
+	msg := fmt.Sprintf("error while calling %s %s: http status is %d", e.Method, e.URL, e.StatusCode)
+	if e.Body != "" {
+		msg += ": " + e.Body
+	}
+
+	return msg
+}
+
+// DefaultHTTPConfig returns the default HTTP configuration: the default timeout and three retries.
+func DefaultHTTPConfig() HTTPConfig {
+	return HTTPConfig{
+		Timeout:      DefaultHTTPTimeout,
+		MaxRetries:   defaultMaxRetries,
+		RetryWait:    defaultRetryWait,
+		MaxRetryWait: defaultMaxRetryWait,
+	}
+}
+
+// NewHTTPClient returns an HTTP client for the configuration.
+//
+// Requests that fail with a network error or a 5xx status are retried with jittered exponential backoff,
+// when they are idempotent (e.g. GET, PUT and DELETE, or with an Idempotency-Key header, see http.Transport).
+// Requests that fail with 429 Too Many Requests or 503 Service Unavailable were not processed and are always
+// retried. A Retry-After header is honoured. Waiting stops when the context of the request is done.
+func NewHTTPClient(config HTTPConfig) (*http.Client, error) {
+	transport, ok := http.DefaultTransport.(*http.Transport)
+	if !ok {
+		return nil, errors.New("default HTTP transport is not an *http.Transport")
+	}
+
+	transport = transport.Clone()
+
+	if config.Proxy != nil {
+		transport.Proxy = http.ProxyURL(config.Proxy)
+	}
+
+	if config.CACertFile != "" {
+		pool, err := loadCACertPool(config.CACertFile)
+		if err != nil {
+			return nil, err
+		}
+
+		transport.TLSClientConfig = &tls.Config{RootCAs: pool, MinVersion: tls.VersionTLS12}
+	}
+
+	return &http.Client{
+		Transport: &retryTransport{
+			base:         transport,
+			timeout:      config.Timeout,
+			maxRetries:   max(0, config.MaxRetries),
+			retryWait:    config.RetryWait,
+			maxRetryWait: config.MaxRetryWait,
+		},
+	}, nil
+}
+
+// DefaultHTTPClient returns a new HTTP client with DefaultHTTPConfig.
+func DefaultHTTPClient() *http.Client {
+	client, err := NewHTTPClient(DefaultHTTPConfig())
+	if err != nil {
+		return &http.Client{Timeout: DefaultHTTPTimeout}
+	}
+
+	return client
+}
+
+// newHTTPError returns the HTTPError of a response of which the body was not read yet.
+func newHTTPError(resp *http.Response) *HTTPError {
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, httpErrorBodyLimit))
+
+	return newHTTPErrorWithBody(resp, body)
+}
+
+// newHTTPErrorWithBody returns the HTTPError of a response with the body that was read, of which the start is kept.
+func newHTTPErrorWithBody(resp *http.Response, body []byte) *HTTPError {
+	if len(body) > httpErrorBodyLimit {
+		body = body[:httpErrorBodyLimit]
+	}
+
+	return &HTTPError{
+		Method:     resp.Request.Method,
+		URL:        resp.Request.URL.String(),
+		StatusCode: resp.StatusCode,
+		Body:       strings.TrimSpace(string(body)),
+	}
+}
+
+func loadCACertPool(path string) (*x509.CertPool, error) {
+	//nolint:gosec
+	pem, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("cannot read CA certificates: %w", err)
+	}
+
+	pool, err := x509.SystemCertPool()
+	if err != nil {
+		pool = x509.NewCertPool()
+	}
+
+	if !pool.AppendCertsFromPEM(pem) {
+		return nil, fmt.Errorf("no CA certificates found in %s", path)
+	}
+
+	return pool, nil
+}
+
+// retryTransport retries failed requests, see NewHTTPClient. The timeout applies to every attempt,
+// until the response body is closed.
+type retryTransport struct {
+	base         http.RoundTripper
+	timeout      time.Duration
+	maxRetries   int
+	retryWait    time.Duration
+	maxRetryWait time.Duration
+}
+
+func (t *retryTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	for attempt := 0; ; attempt++ {
+		attemptReq, cancel, err := t.newAttempt(req, attempt)
+		if err != nil {
+			return nil, err
+		}
+
+		resp, err := t.base.RoundTrip(attemptReq)
+
+		wait, retry := t.retryWaitFor(req, resp, err, attempt)
+		if !retry {
+			if err != nil {
+				cancel()
+
+				return nil, err
+			}
+
+			resp.Body = &cancelOnClose{ReadCloser: resp.Body, cancel: cancel}
+
+			return resp, nil
+		}
+
+		if resp != nil {
+			_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, httpErrorBodyLimit))
+			common.SafeClose(resp.Body)
+		}
+
+		cancel()
+
+		slog.Debug("Retrying HTTP request", "url", req.URL.String(), "attempt", attempt+1, "wait", wait,
+			"err", retryReason(resp, err))
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-req.Context().Done():
+			timer.Stop()
+
+			return nil, req.Context().Err()
+		case <-timer.C:
+		}
+	}
+}
+
+// newAttempt returns the request for an attempt, with the timeout and a fresh body.
+func (t *retryTransport) newAttempt(req *http.Request, attempt int) (*http.Request, context.CancelFunc, error) {
+	ctx, cancel := req.Context(), context.CancelFunc(func() {})
+	if t.timeout > 0 {
+		ctx, cancel = context.WithTimeout(ctx, t.timeout)
+	}
+
+	attemptReq := req.Clone(ctx)
+
+	if attempt > 0 && req.Body != nil && req.Body != http.NoBody {
+		body, err := req.GetBody()
+		if err != nil {
+			cancel()
+
+			return nil, nil, err
+		}
+
+		attemptReq.Body = body
+	}
+
+	return attemptReq, cancel, nil
+}
+
+// retryWaitFor returns whether the attempt is retried and how long to wait before the retry.
+func (t *retryTransport) retryWaitFor(
+	req *http.Request,
+	resp *http.Response,
+	err error,
+	attempt int,
+) (time.Duration, bool) {
+	if attempt >= t.maxRetries || req.Context().Err() != nil {
+		return 0, false
+	}
+
+	if req.Body != nil && req.Body != http.NoBody && req.GetBody == nil {
+		return 0, false // The body cannot be sent again
+	}
+
+	switch {
+	case err != nil:
+		if !isIdempotent(req) {
+			return 0, false
+		}
+	case resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode == http.StatusServiceUnavailable:
+		if wait, ok := parseRetryAfter(resp.Header.Get("Retry-After"), time.Now()); ok {
+			return min(wait, t.maxRetryWait), true
+		}
+	case resp.StatusCode >= http.StatusInternalServerError:
+		if !isIdempotent(req) {
+			return 0, false
+		}
+	default:
+		return 0, false
+	}
+
+	return t.backoff(attempt), true
+}
+
+// backoff returns a wait between half and all of the exponential backoff of the attempt.
+func (t *retryTransport) backoff(attempt int) time.Duration {
+	backoff := t.maxRetryWait
+	if attempt < 32 && t.retryWait<<attempt < t.maxRetryWait { //nolint:mnd // avoid overflowing the shift
+		backoff = t.retryWait << attempt
+	}
+
+	if backoff <= 0 {
+		return 0
+	}
+
+	return backoff/2 + rand.N(backoff/2+1) //nolint:gosec // jitter does not need a secure random number
+}
+
+// isIdempotent reports whether a request can be sent again, as in http.Transport.
+func isIdempotent(req *http.Request) bool {
+	switch req.Method {
+	case "", http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodTrace, http.MethodPut, http.MethodDelete:
+		return true
+	}
+
+	_, ok := req.Header["Idempotency-Key"]
+	if !ok {
+		_, ok = req.Header["X-Idempotency-Key"]
+	}
+
+	return ok
+}
+
+// parseRetryAfter parses a Retry-After header in seconds or as an HTTP date.
+func parseRetryAfter(value string, now time.Time) (time.Duration, bool) {
+	if value == "" {
+		return 0, false
+	}
+
+	if seconds, err := strconv.Atoi(value); err == nil && seconds >= 0 {
+		return time.Duration(seconds) * time.Second, true
+	}
+
+	if date, err := http.ParseTime(value); err == nil {
+		return max(0, date.Sub(now)), true
+	}
+
+	return 0, false
+}
+
+func retryReason(resp *http.Response, err error) string {
+	if err != nil {
+		return err.Error()
+	}
+
+	return resp.Status
+}
+
+// cancelOnClose cancels the context of an attempt when the response body is closed.
+type cancelOnClose struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (b *cancelOnClose) Close() error {
+	err := b.ReadCloser.Close()
+	b.cancel()
+
+	return err
+}
@@ -0,0 +1,305 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/pdok/pdok-metadata-tool/v2/pkg/model/csw"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newTestHTTPClient returns an HTTP client that retries without noticeable waits.
+func newTestHTTPClient(t *testing.T, maxRetries int) *http.Client {
+	t.Helper()
+
+	httpClient, err := NewHTTPClient(HTTPConfig{
+		Timeout:      5 * time.Second,
+		MaxRetries:   maxRetries,
+		RetryWait:    time.Millisecond,
+		MaxRetryWait: 10 * time.Millisecond,
+	})
+	require.NoError(t, err)
+
+	return httpClient
+}
+
+// failingServer answers the first failures requests with the status, and then with the record.
+func failingServer(failures int32, status int, header http.Header) (*httptest.Server, *atomic.Int32) {
+	var requests atomic.Int32
+
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, _ *http.Request) {
+		if requests.Add(1) <= failures {
+			for key, values := range header {
+				rw.Header()[key] = values
+			}
+
+			rw.WriteHeader(status)
+			_, _ = rw.Write([]byte("catalogue is having a bad day"))
+
+			return
+		}
+
+		_, _ = rw.Write([]byte(wrapAsGetRecordByIDResponse("<gmd:MD_Metadata/>")))
+	}))
+
+	return server, &requests
+}
+
+func TestNewHTTPClient_Retries(t *testing.T) {
+	tests := []struct {
+		name         string
+		failures     int32
+		status       int
+		header       http.Header
+		maxRetries   int
+		wantErr      bool
+		wantRequests int32
+	}{
+		{name: "Server error is retried", failures: 2, status: http.StatusInternalServerError, maxRetries: 3, wantRequests: 3},
+		{name: "Service unavailable with Retry-After is retried", failures: 1, status: http.StatusServiceUnavailable,
+			header: http.Header{"Retry-After": []string{"0"}}, maxRetries: 3, wantRequests: 2},
+		{name: "Too many requests is retried", failures: 1, status: http.StatusTooManyRequests, maxRetries: 3, wantRequests: 2},
+		{name: "Retries are exhausted", failures: 5, status: http.StatusBadGateway, maxRetries: 2, wantErr: true, wantRequests: 3},
+		{name: "Client error is not retried", failures: 1, status: http.StatusNotFound, maxRetries: 3, wantErr: true, wantRequests: 1},
+		{name: "Retries are disabled", failures: 1, status: http.StatusInternalServerError, maxRetries: 0, wantErr: true, wantRequests: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server, requests := failingServer(tt.failures, tt.status, tt.header)
+			defer server.Close()
+
+			cswClient := getCswClient(t, server)
+			cswClient.SetHTTPClient(newTestHTTPClient(t, tt.maxRetries))
+
+			_, err := cswClient.GetRawRecordByID(t.Context(), testDatasetUUID)
+			if tt.wantErr {
+				var httpErr *HTTPError
+				require.ErrorAs(t, err, &httpErr)
+				assert.Equal(t, tt.status, httpErr.StatusCode)
+				assert.Equal(t, http.MethodGet, httpErr.Method)
+				assert.Equal(t, "catalogue is having a bad day", httpErr.Body)
+			} else {
+				require.NoError(t, err)
+			}
+
+			assert.Equal(t, tt.wantRequests, requests.Load())
+		})
+	}
+}
+
+func TestNewHTTPClient_TransactionIsNotRetried(t *testing.T) {
+	tests := []struct {
+		name         string
+		status       int
+		wantRequests int32
+	}{
+		{name: "Server error may have been processed", status: http.StatusInternalServerError, wantRequests: 1},
+		{name: "Service unavailable was not processed", status: http.StatusServiceUnavailable, wantRequests: 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var requests atomic.Int32
+
+			server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+				body := make([]byte, req.ContentLength)
+				_, _ = req.Body.Read(body)
+
+				if requests.Add(1) == 1 {
+					rw.WriteHeader(tt.status)
+
+					return
+				}
+
+				assert.Contains(t, string(body), "csw:Delete", "the request body is sent again")
+				_, _ = rw.Write([]byte(`<csw:TransactionResponse xmlns:csw="http://www.opengis.net/cat/csw/2.0.2">
+  <csw:TransactionSummary><csw:totalDeleted>1</csw:totalDeleted></csw:TransactionSummary>
+</csw:TransactionResponse>`))
+			}))
+			defer server.Close()
+
+			cswClient := getCswClient(t, server)
+			cswClient.SetHTTPClient(newTestHTTPClient(t, 3))
+
+			_, err := cswClient.DeleteRecords(t.Context(),
+				csw.Comparison{Property: "Identifier", Operator: csw.Equal, Value: testDatasetUUID})
+			if tt.wantRequests == 1 {
+				var httpErr *HTTPError
+				require.ErrorAs(t, err, &httpErr)
+				assert.Equal(t, http.MethodPost, httpErr.Method)
+			} else {
+				require.NoError(t, err)
+			}
+
+			assert.Equal(t, tt.wantRequests, requests.Load())
+		})
+	}
+}
+
+func TestNewHTTPClient_OnlyQueryPostIsRetried(t *testing.T) {
+	tests := []struct {
+		name         string
+		post         func(ctx context.Context, url string, client http.Client) error
+		wantRequests int32
+	}{
+		{
+			name: "Query",
+			post: func(ctx context.Context, url string, client http.Client) error {
+				var response csw.GetRecordByIDResponse
+
+				return getUnmarshalledXMLQueryResponse(ctx, &response, url, "<csw:GetRecords/>", client)
+			},
+			wantRequests: 2,
+		},
+		{
+			name: "Other request",
+			post: func(ctx context.Context, url string, client http.Client) error {
+				body := "<record/>"
+				_, err := getResponseBody(ctx, url, http.MethodPost, &body, client)
+
+				return err
+			},
+			wantRequests: 1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server, requests := failingServer(1, http.StatusInternalServerError, nil)
+			defer server.Close()
+
+			err := tt.post(t.Context(), server.URL, *newTestHTTPClient(t, 3))
+			if tt.wantRequests == 1 {
+				require.Error(t, err)
+			} else {
+				require.NoError(t, err)
+			}
+
+			assert.Equal(t, tt.wantRequests, requests.Load())
+		})
+	}
+}
+
+func TestNewHTTPClient_Cancel(t *testing.T) {
+	server, requests := failingServer(10, http.StatusServiceUnavailable, http.Header{"Retry-After": []string{"60"}})
+	defer server.Close()
+
+	httpClient, err := NewHTTPClient(HTTPConfig{MaxRetries: 3, RetryWait: time.Millisecond, MaxRetryWait: time.Minute})
+	require.NoError(t, err)
+
+	cswClient := getCswClient(t, server)
+	cswClient.SetHTTPClient(httpClient)
+
+	ctx, cancel := context.WithTimeout(t.Context(), 100*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	_, err = cswClient.GetRawRecordByID(ctx, testDatasetUUID)
+
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.Less(t, time.Since(start), 10*time.Second, "waiting for the Retry-After stops when the context is done")
+	assert.Equal(t, int32(1), requests.Load())
+}
+
+func TestNewHTTPClient_Timeout(t *testing.T) {
+	var requests atomic.Int32
+
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		if requests.Add(1) == 1 {
+			<-req.Context().Done() // The first attempt hangs
+
+			return
+		}
+
+		_, _ = rw.Write([]byte(wrapAsGetRecordByIDResponse("<gmd:MD_Metadata/>")))
+	}))
+	defer server.Close()
+
+	httpClient, err := NewHTTPClient(HTTPConfig{
+		Timeout: 100 * time.Millisecond, MaxRetries: 1, RetryWait: time.Millisecond, MaxRetryWait: time.Millisecond,
+	})
+	require.NoError(t, err)
+
+	cswClient := getCswClient(t, server)
+	cswClient.SetHTTPClient(httpClient)
+
+	_, err = cswClient.GetRawRecordByID(t.Context(), testDatasetUUID)
+	require.NoError(t, err, "the attempt that timed out is retried")
+	assert.Equal(t, int32(2), requests.Load())
+}
+
+func TestGetResponseBody_Errors(t *testing.T) {
+	httpClient, err := NewHTTPClient(HTTPConfig{})
+	require.NoError(t, err)
+
+	_, err = getResponseBody(t.Context(), "http://example.com/%zz", http.MethodGet, nil, *httpClient)
+	require.ErrorContains(t, err, "invalid request to http://example.com/%zz")
+
+	_, err = getResponseBody(t.Context(), "http://127.0.0.1:0/csw", http.MethodGet, nil, *httpClient)
+	require.ErrorContains(t, err, "request to http://127.0.0.1:0/csw failed")
+}
+
+func TestNewHTTPClient_CACertFile(t *testing.T) {
+	_, err := NewHTTPClient(HTTPConfig{CACertFile: filepath.Join(t.TempDir(), "missing.pem")})
+	require.ErrorContains(t, err, "cannot read CA certificates")
+
+	invalid := filepath.Join(t.TempDir(), "invalid.pem")
+	require.NoError(t, os.WriteFile(invalid, []byte("not a certificate"), permFile0600))
+
+	_, err = NewHTTPClient(HTTPConfig{CACertFile: invalid})
+	require.ErrorContains(t, err, "no CA certificates found")
+}
+
+func TestHTTPError(t *testing.T) {
+	err := error(&HTTPError{Method: http.MethodGet, URL: "https://example.com/csw", StatusCode: http.StatusBadGateway,
+		Body: "Bad Gateway"})
+
+	assert.Equal(t, "error while calling GET https://example.com/csw: http status is 502: Bad Gateway", err.Error())
+
+	wrapped := errors.Join(errors.New("harvest failed"), err)
+
+	var httpErr *HTTPError
+	require.ErrorAs(t, wrapped, &httpErr)
+	assert.Equal(t, http.StatusBadGateway, httpErr.StatusCode)
+
+	long := newHTTPErrorWithBody(&http.Response{
+		Request:    httptest.NewRequest(http.MethodGet, "https://example.com/csw", nil),
+		StatusCode: http.StatusInternalServerError,
+	}, []byte(strings.Repeat("x", 2*httpErrorBodyLimit)))
+	assert.Len(t, long.Body, httpErrorBodyLimit)
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	now := time.Date(2025, 6, 2, 10, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		value  string
+		want   time.Duration
+		wantOK bool
+	}{
+		{value: "", wantOK: false},
+		{value: "120", want: 2 * time.Minute, wantOK: true},
+		{value: "-1", wantOK: false},
+		{value: "Mon, 02 Jun 2025 10:00:30 GMT", want: 30 * time.Second, wantOK: true},
+		{value: "Mon, 02 Jun 2025 09:00:00 GMT", want: 0, wantOK: true},
+		{value: "soon", wantOK: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			got, ok := parseRetryAfter(tt.value, now)
+			assert.Equal(t, tt.wantOK, ok)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
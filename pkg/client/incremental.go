@@ -0,0 +1,349 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"slices"
+	"time"
+
+	"github.com/pdok/pdok-metadata-tool/v2/internal/common"
+	"github.com/pdok/pdok-metadata-tool/v2/pkg/model/csw"
+	"github.com/pdok/pdok-metadata-tool/v2/pkg/model/iso1911x"
+)
+
+// incrementalOverlap is subtracted from the last harvest time, so records are not missed
+// due to clock differences between the client and the CSW endpoint.
+const incrementalOverlap = time.Hour
+
+// HarvestState holds the last successful harvest per endpoint and constraint.
+type HarvestState struct {
+	Entries map[string]HarvestStateEntry `json:"entries"` // By endpoint and constraint, see harvestStateKey
+}
+
+// HarvestStateEntry holds the time and the identifiers of the last successful harvest.
+type HarvestStateEntry struct {
+	Endpoint    string    `json:"endpoint"`
+	Constraint  string    `json:"constraint"`
+	LastHarvest time.Time `json:"lastHarvest"`
+	Identifiers []string  `json:"identifiers"`
+}
+
+// IncrementalHarvestResult holds the outcome of an incremental harvest.
+type IncrementalHarvestResult struct {
+	Incremental bool                  // False when all records were harvested
+	Since       time.Time             // The modification date used to select records, when incremental
+	Records     []iso1911x.MDMetadata // The records that were retrieved in this harvest
+	Added       []string
+	Updated     []string
+	Deleted     []string // Records that no longer match this harvest
+	Removed     []string // Deleted records that no longer exist at the endpoint, which are removed from the cache
+	Unchanged   int
+	Failures    []HarvestFailure
+}
+
+// LoadHarvestState reads the harvest state from a JSON file. A missing file results in an empty state.
+func LoadHarvestState(path string) (*HarvestState, error) {
+	state := &HarvestState{Entries: map[string]HarvestStateEntry{}}
+
+	//nolint:gosec
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return state, nil
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	if err := json.Unmarshal(data, state); err != nil {
+		return nil, fmt.Errorf("failed to parse harvest state %s: %w", path, err)
+	}
+
+	if state.Entries == nil {
+		state.Entries = map[string]HarvestStateEntry{}
+	}
+
+	return state, nil
+}
+
+// Save writes the harvest state to a JSON file. The file is replaced atomically, so an interrupted harvest
+// never leaves a truncated state.
+func (s *HarvestState) Save(path string) error {
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), permDir0750); err != nil {
+		return err
+	}
+
+	return common.WriteFileAtomic(path, data, permFile0600)
+}
+
+// HarvestIncremental harvests the records that match the constraint, using the state of the last successful
+// harvest for this endpoint and constraint. Only records modified since the last harvest and records that are
+// new to the identifier set are retrieved, replacing them in the cache. Records that no longer match are
+// reported as deleted, but are only removed from the cache when they no longer exist at the endpoint, as other
+// harvests of the endpoint share the cache. Without a previous harvest, or when full is set,
+// all records are harvested. The state is only updated when all records were retrieved.
+func (c *CswClient) HarvestIncremental(
+	ctx context.Context,
+	constraint *csw.GetRecordsCQLConstraint,
+	state *HarvestState,
+	full bool,
+) (IncrementalHarvestResult, error) {
+	var result IncrementalHarvestResult
+
+	if constraint == nil {
+		constraint = &csw.GetRecordsCQLConstraint{}
+	}
+
+	if c.selector != nil && c.harvestMode == HarvestByPage {
+		return result, errRecordSelectorByPage
+	}
+
+	start := time.Now()
+	key := c.harvestStateKey(constraint)
+	previous, hasPrevious := state.Entries[key]
+
+	checkpoint := c.newCheckpoint(constraint)
+
+	summaries, err := c.getAllRecordsWithCheckpoint(ctx, constraint, checkpoint)
+	if err != nil {
+		return result, err
+	}
+
+	current := make([]string, 0, len(summaries))
+	for _, summary := range summaries {
+		current = append(current, summary.Identifier)
+	}
+
+	previousSet := toSet(previous.Identifiers)
+	currentSet := toSet(current)
+
+	for _, id := range previous.Identifiers {
+		if !currentSet[id] {
+			result.Deleted = append(result.Deleted, id)
+		}
+	}
+
+	if result.Removed, err = c.removeDeletedRecords(ctx, result.Deleted); err != nil {
+		return result, err
+	}
+
+	if full || !hasPrevious {
+		if err := c.harvestAll(ctx, constraint, summaries, checkpoint, &result); err != nil {
+			return result, err
+		}
+
+		for _, id := range current {
+			if !previousSet[id] {
+				result.Added = append(result.Added, id)
+			}
+		}
+	} else {
+		result.Incremental = true
+		result.Since = previous.LastHarvest.Add(-incrementalOverlap)
+
+		if err := c.harvestChanges(ctx, constraint, summaries, previousSet, checkpoint, &result); err != nil {
+			return result, err
+		}
+	}
+
+	result.Unchanged = len(current) - len(result.Added) - len(result.Updated)
+
+	if len(result.Failures) > 0 {
+		slog.Warn("Not all records could be harvested; the harvest state is not updated",
+			"failed", len(result.Failures))
+
+		return result, nil
+	}
+
+	slices.Sort(current)
+	state.Entries[key] = HarvestStateEntry{
+		Endpoint:    c.endpoint.String(),
+		Constraint:  constraint.ToQueryParameter() + c.selectorKey,
+		LastHarvest: start,
+		Identifiers: current,
+	}
+
+	return result, nil
+}
+
+// harvestAll retrieves all records, using the harvest mode of the client.
+func (c *CswClient) harvestAll(
+	ctx context.Context,
+	constraint *csw.GetRecordsCQLConstraint,
+	summaries []csw.SummaryRecord,
+	checkpoint *HarvestCheckpoint,
+	result *IncrementalHarvestResult,
+) (err error) {
+	if c.harvestMode == HarvestByPage {
+		result.Records, err = c.GetAllFullRecords(ctx, constraint)
+
+		return err
+	}
+
+	result.Records, result.Failures, err = c.harvestWithCheckpoint(ctx, checkpoint, summaries)
+
+	return err
+}
+
+// removeDeletedRecords removes the deleted records that no longer exist at the endpoint from the cache and
+// returns them. A record that only stops matching the constraint, e.g. after a change of organisation, may
+// still match the constraints of other harvests, so the records are checked against all records of the
+// endpoint. This lists all records, but only when records were deleted.
+func (c *CswClient) removeDeletedRecords(ctx context.Context, deleted []string) ([]string, error) {
+	if len(deleted) == 0 {
+		return nil, nil
+	}
+
+	all, err := c.GetAllRecords(ctx, &csw.GetRecordsCQLConstraint{})
+	if err != nil {
+		return nil, err
+	}
+
+	existing := make(map[string]bool, len(all))
+	for _, summary := range all {
+		existing[summary.Identifier] = true
+	}
+
+	var removed []string
+
+	for _, id := range deleted {
+		if !existing[id] {
+			removed = append(removed, id)
+			c.cache.remove(id)
+		}
+	}
+
+	return removed, nil
+}
+
+// harvestChanges retrieves the records that were modified since result.Since and the records that are new
+// to the identifier set, bypassing the cache.
+func (c *CswClient) harvestChanges(
+	ctx context.Context,
+	constraint *csw.GetRecordsCQLConstraint,
+	summaries []csw.SummaryRecord,
+	previous map[string]bool,
+	checkpoint *HarvestCheckpoint,
+	result *IncrementalHarvestResult,
+) (err error) {
+	modifiedConstraint := *constraint
+	modifiedConstraint.ModifiedSince = &result.Since
+
+	if c.harvestMode == HarvestByPage {
+		return c.harvestChangesByPage(ctx, &modifiedConstraint, summaries, previous, result)
+	}
+
+	modified, err := c.GetAllRecords(ctx, &modifiedConstraint)
+	if err != nil {
+		return err
+	}
+
+	modifiedSet := map[string]bool{}
+	for _, summary := range modified {
+		modifiedSet[summary.Identifier] = true
+	}
+
+	var toHarvest []csw.SummaryRecord
+
+	for _, summary := range summaries {
+		switch {
+		case !previous[summary.Identifier]:
+			result.Added = append(result.Added, summary.Identifier)
+		case modifiedSet[summary.Identifier]:
+			result.Updated = append(result.Updated, summary.Identifier)
+		default:
+			continue
+		}
+
+		c.cache.remove(summary.Identifier)
+		toHarvest = append(toHarvest, summary)
+	}
+
+	if checkpoint != nil {
+		// A resumed harvest only retrieves the changed records
+		checkpoint.Records = toHarvest
+	}
+
+	result.Records, result.Failures, err = c.harvestWithCheckpoint(ctx, checkpoint, toHarvest)
+
+	return err
+}
+
+// harvestStateKey identifies the harvest state of this endpoint, constraint and record selection.
+func (c *CswClient) harvestStateKey(constraint *csw.GetRecordsCQLConstraint) string {
+	return c.endpoint.String() + constraint.ToQueryParameter() + c.selectorKey
+}
+
+func toSet(values []string) map[string]bool {
+	set := make(map[string]bool, len(values))
+	for _, v := range values {
+		set[v] = true
+	}
+
+	return set
+}
+
+// harvestChangesByPage is like harvestChanges, but pages through the modified records with GetFullRecordPage.
+// Records that are new to the identifier set without being modified are retrieved by ID.
+func (c *CswClient) harvestChangesByPage(
+	ctx context.Context,
+	modifiedConstraint *csw.GetRecordsCQLConstraint,
+	summaries []csw.SummaryRecord,
+	previous map[string]bool,
+	result *IncrementalHarvestResult,
+) error {
+	modified, err := c.GetAllFullRecords(ctx, modifiedConstraint)
+	if err != nil {
+		return err
+	}
+
+	current := make(map[string]bool, len(summaries))
+	for _, summary := range summaries {
+		current[summary.Identifier] = true
+	}
+
+	harvested := map[string]bool{}
+
+	for _, md := range modified {
+		uuid := iso1911x.NormalizeXMLText(md.UUID)
+		if !current[uuid] || harvested[uuid] {
+			continue // Modified after the summary records were listed, or listed twice while paging
+		}
+
+		if previous[uuid] {
+			result.Updated = append(result.Updated, uuid)
+		} else {
+			result.Added = append(result.Added, uuid)
+		}
+
+		harvested[uuid] = true
+		result.Records = append(result.Records, md)
+	}
+
+	var toHarvest []csw.SummaryRecord
+
+	for _, summary := range summaries {
+		if !previous[summary.Identifier] && !harvested[summary.Identifier] {
+			result.Added = append(result.Added, summary.Identifier)
+
+			c.cache.remove(summary.Identifier)
+			toHarvest = append(toHarvest, summary)
+		}
+	}
+
+	records, failures, err := c.harvestWithCheckpoint(ctx, nil, toHarvest)
+	result.Records = append(result.Records, records...)
+	result.Failures = failures
+
+	return err
+}
@@ -0,0 +1,233 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/pdok/pdok-metadata-tool/v2/pkg/model/csw"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// mockIncrementalRecord is a minimal full record, as returned in a GetRecords page.
+const mockIncrementalRecord = `<gmd:MD_Metadata xmlns:gmd="http://www.isotc211.org/2005/gmd" ` +
+	`xmlns:gco="http://www.isotc211.org/2005/gco"><gmd:fileIdentifier><gco:CharacterString>%s` +
+	`</gco:CharacterString></gmd:fileIdentifier></gmd:MD_Metadata>`
+
+// buildMockWebserverIncremental serves the summary or full records of *ids, only *modified for a Modified
+// constraint and *all, when not nil, without a constraint. Any record can be retrieved by ID.
+func buildMockWebserverIncremental(ids, modified, all, recordRequests *[]string) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		query := req.URL.Query()
+
+		switch query.Get("request") {
+		case "GetRecords":
+			matched := *ids
+			if strings.Contains(query.Get("constraint"), "Modified>=") {
+				matched = *modified
+			} else if query.Get("constraint") == "" && all != nil {
+				matched = *all
+			}
+
+			_, _ = fmt.Fprintf(rw, `<csw:GetRecordsResponse xmlns:csw="http://www.opengis.net/cat/csw/2.0.2" `+
+				`xmlns:dc="http://purl.org/dc/elements/1.1/"><csw:SearchResults numberOfRecordsMatched="%d" nextRecord="0">`,
+				len(matched))
+
+			for _, id := range matched {
+				if query.Get("elementSetName") == "full" {
+					_, _ = fmt.Fprintf(rw, mockIncrementalRecord, id)
+				} else {
+					_, _ = fmt.Fprintf(rw, `<csw:SummaryRecord><dc:identifier>%s</dc:identifier></csw:SummaryRecord>`, id)
+				}
+			}
+
+			_, _ = fmt.Fprint(rw, `</csw:SearchResults></csw:GetRecordsResponse>`)
+		case "GetRecordById":
+			*recordRequests = append(*recordRequests, query.Get("id"))
+
+			record, _ := readFileToString("../../examples/ISO19115/5951efa2-1ff3-4763-a966-a2f5497679ee.xml")
+			_, _ = fmt.Fprint(rw, wrapAsGetRecordByIDResponse(record[strings.Index(record, "<gmd:MD_Metadata"):]))
+		default:
+			rw.WriteHeader(http.StatusNotFound)
+		}
+	}))
+}
+
+func TestCswClient_HarvestIncremental(t *testing.T) {
+	ids := []string{"a", "b", "c"}
+
+	var modified, recordRequests []string
+
+	server := buildMockWebserverIncremental(&ids, &modified, nil, &recordRequests)
+	defer server.Close()
+
+	cswClient := getCswClient(t, server)
+	cswClient.SetCache(t.TempDir(), 1)
+
+	statePath := filepath.Join(t.TempDir(), "harvest-state.json")
+	state, err := LoadHarvestState(statePath)
+	require.NoError(t, err)
+
+	// The first harvest retrieves all records
+	result, err := cswClient.HarvestIncremental(t.Context(), &csw.GetRecordsCQLConstraint{}, state, false)
+	require.NoError(t, err)
+	assert.False(t, result.Incremental)
+	assert.Equal(t, []string{"a", "b", "c"}, result.Added)
+	assert.Len(t, result.Records, 3)
+	require.NoError(t, state.Save(statePath))
+
+	// Then b is deleted, d is added and a is modified
+	ids = []string{"a", "c", "d"}
+	modified = []string{"a"}
+	recordRequests = nil
+
+	state, err = LoadHarvestState(statePath)
+	require.NoError(t, err)
+
+	result, err = cswClient.HarvestIncremental(t.Context(), &csw.GetRecordsCQLConstraint{}, state, false)
+	require.NoError(t, err)
+	assert.True(t, result.Incremental)
+	assert.Equal(t, []string{"d"}, result.Added)
+	assert.Equal(t, []string{"a"}, result.Updated)
+	assert.Equal(t, []string{"b"}, result.Deleted)
+	assert.Equal(t, []string{"b"}, result.Removed)
+	assert.Equal(t, 1, result.Unchanged)
+	assert.Empty(t, result.Failures)
+	// Modified records are retrieved again, even though they are still fresh in the cache
+	assert.Equal(t, []string{"a", "d"}, recordRequests)
+
+	for _, entry := range state.Entries {
+		assert.Equal(t, []string{"a", "c", "d"}, entry.Identifiers)
+	}
+
+	// A full harvest ignores the state, but uses the cache
+	recordRequests = nil
+
+	result, err = cswClient.HarvestIncremental(t.Context(), &csw.GetRecordsCQLConstraint{}, state, true)
+	require.NoError(t, err)
+	assert.False(t, result.Incremental)
+	assert.Empty(t, result.Added)
+	assert.Len(t, result.Records, 3)
+	assert.Empty(t, recordRequests)
+}
+
+func TestCswClient_HarvestIncremental_Constraint(t *testing.T) {
+	ids := []string{"a", "b", "c"}
+	all := []string{"a", "b", "c", "x"}
+
+	var modified, recordRequests []string
+
+	server := buildMockWebserverIncremental(&ids, &modified, &all, &recordRequests)
+	defer server.Close()
+
+	cacheDir := t.TempDir()
+	cswClient := getCswClient(t, server)
+	cswClient.SetCache(cacheDir, 1)
+
+	organisation := "Org A"
+	constraint := &csw.GetRecordsCQLConstraint{OrganisationName: &organisation}
+	state := &HarvestState{Entries: map[string]HarvestStateEntry{}}
+
+	_, err := cswClient.HarvestIncremental(t.Context(), constraint, state, false)
+	require.NoError(t, err)
+
+	// Then b moves to another organisation and c is deleted from the endpoint
+	ids = []string{"a"}
+	all = []string{"a", "b", "x"}
+
+	result, err := cswClient.HarvestIncremental(t.Context(), constraint, state, false)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"b", "c"}, result.Deleted, "both no longer match the constraint")
+	assert.Equal(t, []string{"c"}, result.Removed, "only c no longer exists at the endpoint")
+
+	namespace := filepath.Join(cacheDir, CacheNamespace(cswClient.endpoint))
+	assert.FileExists(t, filepath.Join(namespace, "b.xml"))
+	assert.NoFileExists(t, filepath.Join(namespace, "c.xml"))
+}
+
+func TestCswClient_HarvestIncremental_ByPage(t *testing.T) {
+	ids := []string{"a", "b", "c"}
+
+	var modified, recordRequests []string
+
+	server := buildMockWebserverIncremental(&ids, &modified, nil, &recordRequests)
+	defer server.Close()
+
+	cswClient := getCswClient(t, server)
+	cswClient.SetCache(t.TempDir(), 1)
+	cswClient.SetHarvestMode(HarvestByPage)
+
+	state := &HarvestState{Entries: map[string]HarvestStateEntry{}}
+
+	result, err := cswClient.HarvestIncremental(t.Context(), &csw.GetRecordsCQLConstraint{}, state, false)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"a", "b", "c"}, result.Added)
+
+	// Then a is modified and d is added, so the modified records are paged
+	ids = []string{"a", "b", "c", "d"}
+	modified = []string{"a", "d"}
+
+	result, err = cswClient.HarvestIncremental(t.Context(), &csw.GetRecordsCQLConstraint{}, state, false)
+	require.NoError(t, err)
+	assert.True(t, result.Incremental)
+	assert.Equal(t, []string{"d"}, result.Added)
+	assert.Equal(t, []string{"a"}, result.Updated)
+	assert.Len(t, result.Records, 2)
+	assert.Empty(t, recordRequests, "no records are retrieved by ID")
+}
+
+func TestCswClient_HarvestIncremental_RecordSelector(t *testing.T) {
+	ids := []string{"a", "b", "c"}
+	selected := map[string]bool{"a": true, "c": true}
+
+	var modified, recordRequests []string
+
+	server := buildMockWebserverIncremental(&ids, &modified, nil, &recordRequests)
+	defer server.Close()
+
+	cswClient := getCswClient(t, server)
+	cswClient.SetRecordSelector("&tag=1", func(_ context.Context, records []csw.SummaryRecord) ([]csw.SummaryRecord, error) {
+		return slices.DeleteFunc(records, func(record csw.SummaryRecord) bool { return !selected[record.Identifier] }), nil
+	})
+
+	state := &HarvestState{Entries: map[string]HarvestStateEntry{}}
+
+	result, err := cswClient.HarvestIncremental(t.Context(), &csw.GetRecordsCQLConstraint{}, state, false)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"a", "c"}, result.Added)
+	assert.Equal(t, []string{"a", "c"}, recordRequests, "only the selected records are retrieved")
+
+	entry, ok := state.Entries[server.URL+(&csw.GetRecordsCQLConstraint{}).ToQueryParameter()+"&tag=1"]
+	require.True(t, ok, "the selection is part of the key of the harvest state")
+	assert.Equal(t, []string{"a", "c"}, entry.Identifiers)
+
+	// A record that is no longer selected is deleted
+	delete(selected, "c")
+
+	result, err = cswClient.HarvestIncremental(t.Context(), &csw.GetRecordsCQLConstraint{}, state, false)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"c"}, result.Deleted)
+
+	t.Run("Selection fails", func(t *testing.T) {
+		cswClient.SetRecordSelector("&tag=1", func(context.Context, []csw.SummaryRecord) ([]csw.SummaryRecord, error) {
+			return nil, errors.New("tags are unavailable")
+		})
+
+		_, err := cswClient.HarvestIncremental(t.Context(), &csw.GetRecordsCQLConstraint{}, state, true)
+		require.ErrorContains(t, err, "cannot select records (&tag=1): tags are unavailable")
+	})
+
+	t.Run("Harvest by page", func(t *testing.T) {
+		cswClient.SetHarvestMode(HarvestByPage)
+
+		_, err := cswClient.HarvestIncremental(t.Context(), &csw.GetRecordsCQLConstraint{}, state, true)
+		require.ErrorIs(t, err, errRecordSelectorByPage)
+	})
+}
@@ -0,0 +1,245 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/pdok/pdok-metadata-tool/v2/pkg/mef"
+	"github.com/pdok/pdok-metadata-tool/v2/pkg/model/csw"
+	"github.com/pdok/pdok-metadata-tool/v2/pkg/model/ngr"
+)
+
+// ngrReservedGroups are the groups that GeoNetwork does not list by default, by name.
+var ngrReservedGroups = map[string]int{"all": ngr.GroupAll, "intranet": 0, "guest": -1}
+
+// ExportMEF returns the records with their categories, privileges and attachments, for a MEF archive.
+func (c *NgrClient) ExportMEF(ctx context.Context, uuids []string) ([]mef.Record, error) {
+	groups, err := c.GetGroups(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	groupNames := map[int]string{}
+	for name, id := range ngrReservedGroups {
+		groupNames[id] = name
+	}
+
+	for _, group := range groups {
+		groupNames[group.ID] = group.Name
+	}
+
+	records := make([]mef.Record, 0, len(uuids))
+
+	for _, uuid := range uuids {
+		record, err := c.exportMEFRecord(ctx, uuid, groupNames)
+		if err != nil {
+			return nil, fmt.Errorf("failed to export %s: %w", uuid, err)
+		}
+
+		records = append(records, record)
+	}
+
+	return records, nil
+}
+
+// ImportMEF creates or updates the records in NGR and restores their categories, privileges and attachments.
+// When groupID is set, the group becomes the owner of the records. Categories and groups are matched by name;
+// those that do not exist in NGR are skipped with a warning.
+func (c *NgrClient) ImportMEF(ctx context.Context, records []mef.Record, groupID *string) error {
+	groups, err := c.GetGroups(ctx)
+	if err != nil {
+		return err
+	}
+
+	categories, err := c.GetCategories(ctx)
+	if err != nil {
+		return err
+	}
+
+	for _, record := range records {
+		if err := c.importMEFRecord(ctx, record, groupID, groups, categories); err != nil {
+			return fmt.Errorf("failed to import %s: %w", record.UUID(), err)
+		}
+	}
+
+	return nil
+}
+
+// CachedMEFRecord returns a record of the cache for a MEF archive. Cached records have no categories,
+// privileges or attachments.
+func CachedMEFRecord(cacheDir string, record CachedRecord) (mef.Record, error) {
+	//nolint:gosec
+	data, err := os.ReadFile(filepath.Join(cacheDir, record.File))
+	if err != nil {
+		return mef.Record{}, err
+	}
+
+	metadata, err := csw.UnwrapRecord(data)
+	if err != nil {
+		return mef.Record{}, fmt.Errorf("%s: %w", record.File, err)
+	}
+
+	return mef.Record{
+		Metadata: metadata,
+		Info: mef.Info{General: mef.General{
+			ChangeDate: record.FetchedAt.UTC().Format("2006-01-02T15:04:05"),
+			Schema:     mef.SchemaISO19139,
+			IsTemplate: "false",
+			Format:     mef.FormatFull,
+			UUID:       record.UUID,
+		}},
+	}, nil
+}
+
+func (c *NgrClient) exportMEFRecord(ctx context.Context, uuid string, groupNames map[int]string) (mef.Record, error) {
+	metadata, err := c.GetRecord(ctx, uuid)
+	if err != nil {
+		return mef.Record{}, err
+	}
+
+	record := mef.Record{
+		Metadata: []byte(metadata),
+		Info: mef.Info{General: mef.General{
+			Schema:     mef.SchemaISO19139,
+			IsTemplate: "false",
+			Format:     mef.FormatFull,
+			UUID:       uuid,
+		}},
+	}
+
+	// The tags are requested in the session, because GetRecordTags cannot read the tags of unpublished records
+	var tags ngr.RecordTagsResponse
+
+	tagsURL := fmt.Sprintf("%s%s/%s/tags", *c.NgrConfig.NgrUrl, API_RECORDS_TEMPLATE, uuid)
+	if err := c.getJSON(ctx, tagsURL, &tags); err != nil {
+		return mef.Record{}, err
+	}
+
+	for _, tag := range tags {
+		record.Info.Categories = append(record.Info.Categories, mef.Category{Name: tag.Name})
+	}
+
+	sharing, err := c.GetSharing(ctx, uuid)
+	if err != nil {
+		return mef.Record{}, err
+	}
+
+	for _, privileges := range sharing.Privileges {
+		group := mef.Group{Name: groupNames[privileges.Group]}
+		if group.Name == "" {
+			slog.Warn("Skipping privileges of unknown group", "uuid", uuid, "group", privileges.Group)
+
+			continue
+		}
+
+		for _, operation := range slices.Sorted(maps.Keys(privileges.Operations)) {
+			if privileges.Operations[operation] {
+				group.Operations = append(group.Operations, mef.Operation{Name: string(operation)})
+			}
+		}
+
+		if len(group.Operations) > 0 {
+			record.Info.Privileges = append(record.Info.Privileges, group)
+		}
+	}
+
+	attachments, err := c.GetAttachments(ctx, uuid)
+	if err != nil {
+		return mef.Record{}, err
+	}
+
+	for _, attachment := range attachments {
+		content, err := c.DownloadAttachment(ctx, uuid, attachment.Filename)
+		if err != nil {
+			return mef.Record{}, err
+		}
+
+		file := mef.File{Name: attachment.Filename, ChangeDate: attachment.LastModification, Content: content}
+		if strings.EqualFold(string(attachment.Visibility), string(ngr.VisibilityPrivate)) {
+			record.Private = append(record.Private, file)
+		} else {
+			record.Public = append(record.Public, file)
+		}
+	}
+
+	return record, nil
+}
+
+func (c *NgrClient) importMEFRecord(
+	ctx context.Context,
+	record mef.Record,
+	groupID *string,
+	groups []ngr.Group,
+	categories []ngr.Tag,
+) error {
+	uuid := record.UUID()
+
+	if err := c.CreateOrUpdateServiceMetadataRecord(ctx, string(record.Metadata), nil, groupID, false); err != nil {
+		return err
+	}
+
+	for _, category := range record.Info.Categories {
+		index := findByName(categories, category.Name, func(t ngr.Tag) (string, map[string]string) {
+			return t.Name, t.Label
+		})
+		if index < 0 {
+			slog.Warn("Skipping unknown category", "uuid", uuid, "category", category.Name)
+
+			continue
+		}
+
+		if err := c.AddTagToRecord(ctx, uuid, categories[index].ID); err != nil {
+			return err
+		}
+	}
+
+	var privileges []ngr.GroupPrivileges
+
+	for _, group := range record.Info.Privileges {
+		id, ok := ngrReservedGroups[group.Name]
+		if index := findByName(groups, group.Name, func(g ngr.Group) (string, map[string]string) {
+			return g.Name, g.Label
+		}); index >= 0 {
+			id, ok = groups[index].ID, true
+		}
+
+		if !ok {
+			slog.Warn("Skipping privileges of unknown group", "uuid", uuid, "group", group.Name)
+
+			continue
+		}
+
+		operations := map[ngr.Operation]bool{}
+		for _, operation := range group.Operations {
+			operations[ngr.Operation(operation.Name)] = true
+		}
+
+		privileges = append(privileges, ngr.GroupPrivileges{Group: id, Operations: operations})
+	}
+
+	if len(privileges) > 0 {
+		if err := c.SetPrivileges(ctx, uuid, privileges, true); err != nil {
+			return err
+		}
+	}
+
+	for _, file := range record.Public {
+		if _, err := c.UploadAttachment(ctx, uuid, file.Name, file.Content, ngr.VisibilityPublic); err != nil {
+			return err
+		}
+	}
+
+	for _, file := range record.Private {
+		if _, err := c.UploadAttachment(ctx, uuid, file.Name, file.Content, ngr.VisibilityPrivate); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,172 @@
+package client
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/pdok/pdok-metadata-tool/v2/pkg/mef"
+	"github.com/pdok/pdok-metadata-tool/v2/pkg/model/ngr"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testMEFRecord = `<gmd:MD_Metadata xmlns:gmd="http://www.isotc211.org/2005/gmd"/>`
+
+// buildMockWebserverNgrMEF is a GeoNetwork stand-in with one record, which has the inspire tag, privileges
+// for the groups all, pdok and an unknown group, and a public and a private attachment. It records the
+// requests that change records, other than the login.
+func buildMockWebserverNgrMEF(t *testing.T) (*httptest.Server, *[]string) {
+	t.Helper()
+
+	var (
+		mu      sync.Mutex
+		changes []string
+	)
+
+	record := API_RECORDS_TEMPLATE + "/" + testServiceUUID
+
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		if req.URL.String() == API_LOGIN_PART {
+			writeForbiddenResponse(rw, ContentTypeJSON)
+
+			return
+		}
+
+		if req.Method != http.MethodGet {
+			change := req.Method + " " + req.URL.Path
+			if req.Method == http.MethodPost {
+				_, header, err := req.FormFile("file")
+				require.NoError(t, err)
+
+				change += " " + header.Filename + " " + req.URL.Query().Get("visibility")
+			} else if body, _ := io.ReadAll(req.Body); req.URL.Path == record+"/sharing" {
+				change += " " + string(body)
+			} else if req.URL.Path == record+"/tags" {
+				change += " " + req.URL.Query().Get("id")
+			}
+
+			mu.Lock()
+			changes = append(changes, change)
+			mu.Unlock()
+
+			if req.Method == http.MethodPost {
+				_, _ = rw.Write([]byte(`{}`))
+			}
+
+			return
+		}
+
+		switch req.URL.Path {
+		case API_GROUPS:
+			_, _ = rw.Write([]byte(`[{"id":2,"name":"sample"},{"id":5,"name":"pdok"}]`))
+		case API_TAGS, record + "/tags":
+			writeOkResponse("./testdata/API_Records_Tags_Inspire.json", rw, ContentTypeJSON)
+		case record:
+			_, _ = rw.Write([]byte(testMEFRecord))
+		case record + "/sharing":
+			_, _ = rw.Write([]byte(`{"privileges":[` +
+				`{"group":1,"operations":{"view":true,"download":false,"dynamic":true}},` +
+				`{"group":5,"operations":{"view":true,"editing":true}},` +
+				`{"group":9,"operations":{"view":true}},` +
+				`{"group":2,"operations":{"view":false}}]}`))
+		case record + "/attachments":
+			_, _ = rw.Write([]byte(`[{"filename":"thumb.png","visibility":"PUBLIC","lastModification":"2024-05-01"},` +
+				`{"filename":"notes.pdf","visibility":"PRIVATE"}]`))
+		case record + "/attachments/thumb.png":
+			_, _ = rw.Write(testPNG)
+		case record + "/attachments/notes.pdf":
+			_, _ = rw.Write([]byte("%PDF"))
+		default:
+			rw.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	t.Cleanup(server.Close)
+
+	return server, &changes
+}
+
+func TestNgrClient_MEF(t *testing.T) {
+	server, changes := buildMockWebserverNgrMEF(t)
+	ngrClient := getNgrClient(server)
+
+	records, err := ngrClient.ExportMEF(t.Context(), []string{testServiceUUID})
+	require.NoError(t, err)
+	require.Len(t, records, 1)
+
+	record := records[0]
+	assert.Equal(t, testServiceUUID, record.UUID())
+	assert.Equal(t, testMEFRecord, string(record.Metadata))
+	assert.Equal(t, []mef.Category{{Name: "inspire"}}, record.Info.Categories)
+	assert.Equal(t, []mef.Group{
+		{Name: "all", Operations: []mef.Operation{{Name: "dynamic"}, {Name: "view"}}},
+		{Name: "pdok", Operations: []mef.Operation{{Name: "editing"}, {Name: "view"}}},
+	}, record.Info.Privileges, "unknown groups and groups without operations are left out")
+	assert.Equal(t, []mef.File{{Name: "thumb.png", ChangeDate: "2024-05-01", Content: testPNG}}, record.Public)
+	assert.Equal(t, []mef.File{{Name: "notes.pdf", Content: []byte("%PDF")}}, record.Private)
+	assert.Empty(t, *changes)
+
+	// Import the exported records via an archive
+	var buf bytes.Buffer
+
+	require.NoError(t, mef.Write(&buf, records))
+
+	records, err = mef.Read(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	require.NoError(t, err)
+
+	records[0].Info.Categories = append(records[0].Info.Categories, mef.Category{Name: "unknown"})
+
+	groupID := "5"
+	require.NoError(t, ngrClient.ImportMEF(t.Context(), records, &groupID))
+
+	require.Len(t, *changes, 5)
+	assert.True(t, strings.HasPrefix((*changes)[0], http.MethodPut+" "+API_RECORDS_TEMPLATE+"/"))
+	assert.Equal(t, http.MethodPut+" "+API_RECORDS_TEMPLATE+"/"+testServiceUUID+"/tags 224342", (*changes)[1])
+
+	sharing := strings.TrimPrefix((*changes)[2], http.MethodPut+" "+API_RECORDS_TEMPLATE+"/"+testServiceUUID+"/sharing ")
+
+	var parameter ngr.SharingParameter
+	require.NoError(t, json.Unmarshal([]byte(sharing), &parameter))
+	assert.Equal(t, ngr.SharingParameter{Clear: true, Privileges: []ngr.GroupPrivileges{
+		{Group: ngr.GroupAll, Operations: map[ngr.Operation]bool{ngr.OperationDynamic: true, ngr.OperationView: true}},
+		{Group: 5, Operations: map[ngr.Operation]bool{ngr.OperationEditing: true, ngr.OperationView: true}},
+	}}, parameter)
+
+	attachments := http.MethodPost + " " + API_RECORDS_TEMPLATE + "/" + testServiceUUID + "/attachments "
+	assert.Equal(t, attachments+"thumb.png public", (*changes)[3])
+	assert.Equal(t, attachments+"notes.pdf private", (*changes)[4])
+}
+
+func TestCachedMEFRecord(t *testing.T) {
+	cacheDir := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(cacheDir, "ngr"), 0o750))
+	require.NoError(t, os.WriteFile(filepath.Join(cacheDir, "ngr", testDatasetUUID+".xml"), []byte(
+		`<csw:GetRecordByIdResponse xmlns:csw="http://www.opengis.net/cat/csw/2.0.2">`+testMEFRecord+
+			`</csw:GetRecordByIdResponse>`), 0o600))
+
+	fetchedAt := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+
+	record, err := CachedMEFRecord(cacheDir, CachedRecord{
+		Namespace: "ngr",
+		UUID:      testDatasetUUID,
+		File:      filepath.Join("ngr", testDatasetUUID+".xml"),
+		FetchedAt: fetchedAt,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, testMEFRecord, string(record.Metadata))
+	assert.Equal(t, mef.General{
+		ChangeDate: "2024-05-01T12:00:00",
+		Schema:     mef.SchemaISO19139,
+		IsTemplate: "false",
+		Format:     mef.FormatFull,
+		UUID:       testDatasetUUID,
+	}, record.Info.General)
+}
@@ -1,50 +1,75 @@
 package client
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"sync"
 	"time"
 
-	"github.com/pdok/pdok-metadata-tool/v2/internal/common"
+	"github.com/pdok/pdok-metadata-tool/v2/pkg/model/csw"
 	"github.com/pdok/pdok-metadata-tool/v2/pkg/model/ngr"
 )
 
+// NgrClient is a client for the GeoNetwork API of NGR. The client reuses its session with NGR, so it does not
+// log in for every request, and is safe for concurrent use. Create it with NewNgrClient.
 type NgrClient struct { //nolint:recvcheck
 	NgrClient *http.Client
 	NgrConfig *NgrConfig
+
+	session      *ngrSession
+	pollInterval time.Duration // Interval of polling for reports of operations that run in the background
 }
 
+// NgrConfig contains the location of NGR and the credentials. When NgrToken is set, requests are authenticated
+// with the token as bearer token instead of with the user name and password.
 type NgrConfig struct {
 	NgrUrl      *string
 	NgrUserName *string
 	NgrPassword *string
+	NgrToken    *string
 }
 
 const API_RECORDS_TEMPLATE = "/geonetwork/srv/api/records"
 const API_LOGIN_PART = "/geonetwork/srv/dut/info?type=me"
 const INSPIRE_TAG = 224342
-const NGR_CLIENT_TIMEOUT = 20 * time.Second
 
-func NewNgrClient(config NgrConfig) NgrClient {
-	client := &http.Client{
-		Timeout: NGR_CLIENT_TIMEOUT,
-	}
+// ngrTagConcurrency is the number of concurrent requests of RecordsWithTag.
+const ngrTagConcurrency = 8
+
+// ngrPollInterval is the default interval of polling for the reports of operations that NGR runs in the background.
+const ngrPollInterval = 2 * time.Second
 
+// Deprecated: use HTTPConfig.Timeout.
+const NGR_CLIENT_TIMEOUT = DefaultHTTPTimeout
+
+// NewNgrClient creates a new instance of NgrClient, with an HTTP client with DefaultHTTPConfig.
+// Use SetHTTPClient for another configuration.
+func NewNgrClient(config NgrConfig) NgrClient {
 	return NgrClient{
-		NgrConfig: &config,
-		NgrClient: client,
+		NgrConfig:    &config,
+		NgrClient:    DefaultHTTPClient(),
+		session:      newNgrSession(),
+		pollInterval: ngrPollInterval,
 	}
 }
 
-// TODO Use this for harvesting only INSPIRE service metadata in ETF-validator-go
+// SetHTTPClient sets the HTTP client, see NewHTTPClient.
+func (c *NgrClient) SetHTTPClient(httpClient *http.Client) {
+	c.NgrClient = httpClient
+}
+
 // GetRecordTags does a GET request on NGR to get the Tags for a record.
-func (c *NgrClient) GetRecordTags(uuid string) (ngr.RecordTagsResponse, error) {
+func (c *NgrClient) GetRecordTags(ctx context.Context, uuid string) (ngr.RecordTagsResponse, error) {
 	mdTagUrl := fmt.Sprintf("%s/geonetwork/srv/api/records/%s/tags", *c.NgrConfig.NgrUrl, uuid)
 
 	recordTagsResponse := ngr.RecordTagsResponse{}
 
-	err := getUnmarshalledJSONResponse(&recordTagsResponse, mdTagUrl, *c.NgrClient)
+	err := getUnmarshalledJSONResponse(ctx, &recordTagsResponse, mdTagUrl, *c.NgrClient)
 	if err != nil {
 		return nil, err
 	}
@@ -53,7 +78,9 @@ func (c *NgrClient) GetRecordTags(uuid string) (ngr.RecordTagsResponse, error) {
 }
 
 // CreateOrUpdateServiceMetadataRecord does a PUT request on NGR to create or update a record.
+// The IDs of the group and the category can be looked up by name with GetGroupByName and GetCategoryByName.
 func (c *NgrClient) CreateOrUpdateServiceMetadataRecord(
+	ctx context.Context,
 	record string,
 	categoryId *string,
 	groupId *string,
@@ -82,20 +109,13 @@ func (c *NgrClient) CreateOrUpdateServiceMetadataRecord(
 		params,
 	)
 
-	_, err := getNgrResponseBody(
-		c.NgrConfig,
-		url,
-		http.MethodPut,
-		&record,
-		*c.NgrClient,
-		ContentTypeXML,
-	)
+	_, err := c.getResponseBody(ctx, url, http.MethodPut, &record, ContentTypeXML)
 
 	return err
 }
 
 // GetRecord does a GET request on NGR to get a record.
-func (c *NgrClient) GetRecord(uuid string) (string, error) {
+func (c *NgrClient) GetRecord(ctx context.Context, uuid string) (string, error) {
 	ngrUrl := fmt.Sprintf("%s%s/%s",
 		*c.NgrConfig.NgrUrl,
 		API_RECORDS_TEMPLATE,
@@ -104,14 +124,7 @@ func (c *NgrClient) GetRecord(uuid string) (string, error) {
 
 	var responseBodyString = ""
 
-	responseBodyByteArr, err := getNgrResponseBody(
-		c.NgrConfig,
-		ngrUrl,
-		http.MethodGet,
-		nil,
-		*c.NgrClient,
-		ContentTypeXML,
-	)
+	responseBodyByteArr, err := c.getResponseBody(ctx, ngrUrl, http.MethodGet, nil, ContentTypeXML)
 
 	if responseBodyByteArr != nil {
 		responseBodyString = string(responseBodyByteArr)
@@ -121,60 +134,125 @@ func (c *NgrClient) GetRecord(uuid string) (string, error) {
 }
 
 // DeleteRecord does  DELETE request on NGR to delete a record.
-func (c *NgrClient) DeleteRecord(uuid string) error {
+func (c *NgrClient) DeleteRecord(ctx context.Context, uuid string) error {
 	ngrUrl := fmt.Sprintf("%s%s/%s",
 		*c.NgrConfig.NgrUrl,
 		API_RECORDS_TEMPLATE,
 		uuid,
 	)
-	_, err := getNgrResponseBody(
-		c.NgrConfig,
-		ngrUrl,
-		http.MethodDelete,
-		nil,
-		*c.NgrClient,
-		ContentTypeXML,
-	)
+	_, err := c.getResponseBody(ctx, ngrUrl, http.MethodDelete, nil, ContentTypeXML)
 
 	return err
 }
 
 // AddTagToRecord does a PUT request on NGR to add a Tag to a record.
-func (c *NgrClient) AddTagToRecord(uuid string, tagId int) error {
+func (c *NgrClient) AddTagToRecord(ctx context.Context, uuid string, tagId int) error {
 	ngrUrl := fmt.Sprintf("%s%s/%s/tags?id=%d",
 		*c.NgrConfig.NgrUrl,
 		API_RECORDS_TEMPLATE,
 		uuid,
 		tagId,
 	)
-	_, err := getNgrResponseBody(
-		c.NgrConfig,
-		ngrUrl,
-		http.MethodPut,
-		nil,
-		*c.NgrClient,
-		ContentTypeXML,
+	_, err := c.getResponseBody(ctx, ngrUrl, http.MethodPut, nil, ContentTypeXML)
+
+	return err
+}
+
+// RemoveTagFromRecord does a DELETE request on NGR to remove a Tag from a record.
+func (c *NgrClient) RemoveTagFromRecord(ctx context.Context, uuid string, tagId int) error {
+	ngrUrl := fmt.Sprintf("%s%s/%s/tags?id=%d",
+		*c.NgrConfig.NgrUrl,
+		API_RECORDS_TEMPLATE,
+		uuid,
+		tagId,
 	)
+	_, err := c.getResponseBody(ctx, ngrUrl, http.MethodDelete, nil, ContentTypeXML)
 
 	return err
 }
 
+// AddTagToRecords does PUT requests on NGR to add a Tag to the records.
+func (c *NgrClient) AddTagToRecords(ctx context.Context, uuids []string, tagId int) (ProcessingReport, error) {
+	return c.processRecords(ctx, http.MethodPut, "tags", uuids, url.Values{"id": {strconv.Itoa(tagId)}})
+}
+
+// RemoveTagFromRecords does DELETE requests on NGR to remove a Tag from the records.
+func (c *NgrClient) RemoveTagFromRecords(ctx context.Context, uuids []string, tagId int) (ProcessingReport, error) {
+	return c.processRecords(ctx, http.MethodDelete, "tags", uuids, url.Values{"id": {strconv.Itoa(tagId)}})
+}
+
+// TagFilter returns the CSW filter on the GeoNetwork category (_cat) of a Tag, so the CSW endpoint of NGR
+// only returns the records with the Tag.
+func TagFilter(tag ngr.Tag) csw.Filter {
+	return csw.Comparison{Property: "_cat", Operator: csw.Equal, Value: tag.Name}
+}
+
+// RecordsWithTag returns the UUIDs of the records that have the Tag, using GetRecordTags for every record
+// with a few concurrent requests. The UUIDs are returned in the given order. This costs a request per record,
+// so narrow the records down with TagFilter first and use this only to verify them.
+func (c *NgrClient) RecordsWithTag(ctx context.Context, uuids []string, tagId int) ([]string, error) {
+	hasTag := make([]bool, len(uuids))
+	jobs := make(chan int)
+
+	ctx, cancel := context.WithCancelCause(ctx)
+	defer cancel(nil)
+
+	var wg sync.WaitGroup
+
+	for range min(ngrTagConcurrency, len(uuids)) {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			for i := range jobs {
+				tags, err := c.GetRecordTags(ctx, uuids[i])
+				if err != nil {
+					cancel(fmt.Errorf("cannot get the tags of %s: %w", uuids[i], err))
+
+					continue
+				}
+
+				hasTag[i] = slices.ContainsFunc(tags, func(tag ngr.Tag) bool { return tag.ID == tagId })
+			}
+		}()
+	}
+
+	for i := range uuids {
+		if ctx.Err() != nil {
+			break
+		}
+
+		jobs <- i
+	}
+
+	close(jobs)
+	wg.Wait()
+
+	if err := context.Cause(ctx); err != nil {
+		return nil, err
+	}
+
+	var result []string
+
+	for i, uuid := range uuids {
+		if hasTag[i] {
+			result = append(result, uuid)
+		}
+	}
+
+	return result, nil
+}
+
 // ValidateRecord does a PUT request on NGR to validate a record.
-func (c *NgrClient) ValidateRecord(uuid string) (ValidationResult, error) {
+func (c *NgrClient) ValidateRecord(ctx context.Context, uuid string) (ValidationResult, error) {
 	ngrUrl := fmt.Sprintf("%s%s/validate?uuids=%s",
 		*c.NgrConfig.NgrUrl,
 		API_RECORDS_TEMPLATE,
 		uuid,
 	)
 
-	response, err := getNgrResponseBody(
-		c.NgrConfig,
-		ngrUrl,
-		http.MethodPut,
-		nil,
-		*c.NgrClient,
-		ContentTypeJSON,
-	)
+	response, err := c.getResponseBody(ctx, ngrUrl, http.MethodPut, nil, ContentTypeJSON)
 	if err != nil {
 		return ValidationResult{}, err
 	}
@@ -187,36 +265,28 @@ func (c *NgrClient) ValidateRecord(uuid string) (ValidationResult, error) {
 	return ngrResponse, nil
 }
 
-func obtainXSRFToken(ngrConfig *NgrConfig) (string, error) {
-	// Build URL
-	url := *ngrConfig.NgrUrl + API_LOGIN_PART
-
-	// Create HTTP client and request
-	req, err := http.NewRequest(http.MethodPost, url, nil)
-	if err != nil {
-		return "", fmt.Errorf("cannot create request: %w", err)
-	}
-
-	username := ngrConfig.NgrUserName
-	password := ngrConfig.NgrPassword
-	req.SetBasicAuth(*username, *password)
-
-	client := &http.Client{}
-	//nolint:bodyclose // We use common.SafeClose to handle closing the response body
-	resp, err := client.Do(req)
-	if err != nil {
-		return "", fmt.Errorf("error executing request: %w", err)
+// getResponseBody sends an authenticated request to NGR in the session of the client.
+func (c *NgrClient) getResponseBody(
+	ctx context.Context,
+	url string,
+	method string,
+	requestBody *string,
+	contentType string,
+) ([]byte, error) {
+	session := c.session
+	if session == nil {
+		session = newNgrSession() // The client was not created with NewNgrClient, so there is no session to reuse
 	}
 
-	defer common.SafeClose(resp.Body)
-
-	// Look for 403 Forbidden
-	if resp.StatusCode == http.StatusForbidden { //nolint:nestif
-		// Parse cookies from headers
-		cookies := resp.Header.Values("Set-Cookie")
+	return session.getResponseBody(ctx, c.NgrConfig, c.NgrClient, url, method, requestBody, contentType)
+}
 
-		return getCookieValueByName(cookies, "XSRF-TOKEN")
+// setAuth authenticates a request with the token, or else with the user name and password.
+func (config *NgrConfig) setAuth(req *http.Request) {
+	switch {
+	case config.NgrToken != nil && *config.NgrToken != "":
+		req.Header.Set("Authorization", "Bearer "+*config.NgrToken)
+	case config.NgrUserName != nil && config.NgrPassword != nil:
+		req.SetBasicAuth(*config.NgrUserName, *config.NgrPassword)
 	}
-
-	return "", fmt.Errorf("unexpected status code: %d", resp.StatusCode)
 }
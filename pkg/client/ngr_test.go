@@ -8,6 +8,7 @@ import (
 	"testing"
 	"time"
 
+	"github.com/pdok/pdok-metadata-tool/v2/pkg/model/csw"
 	"github.com/pdok/pdok-metadata-tool/v2/pkg/model/ngr"
 	"github.com/stretchr/testify/assert"
 )
@@ -56,7 +57,7 @@ func TestNgrClient_GetRecordTags(t *testing.T) {
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			got, err := ngrClient.GetRecordTags(tt.args.uuid)
+			got, err := ngrClient.GetRecordTags(t.Context(), tt.args.uuid)
 
 			assert.NoError(t, err)
 			assert.Len(t, got, tt.wantNrOfTags)
@@ -97,14 +98,14 @@ func TestNgrClient_updateServiceMetadataRecord(t *testing.T) {
 
 			recordToBeCreated := string(dataToBeCreated)
 			// create unpublished record
-			err = ngrClient.CreateOrUpdateServiceMetadataRecord(
+			err = ngrClient.CreateOrUpdateServiceMetadataRecord(t.Context(),
 				recordToBeCreated,
 				tt.args.categoryId,
 				tt.args.groupId,
 				false,
 			)
 			assert.NoError(t, err)
-			recordCreated, err := ngrClient.GetRecord(tt.args.uuid)
+			recordCreated, err := ngrClient.GetRecord(t.Context(), tt.args.uuid)
 			assert.NoError(t, err)
 			assert.Contains(t, recordCreated, "NWB - wegen22222")
 
@@ -115,31 +116,31 @@ func TestNgrClient_updateServiceMetadataRecord(t *testing.T) {
 				"NWB - wegen22222",
 				"NWB - wegen33333",
 			)
-			err = ngrClient.CreateOrUpdateServiceMetadataRecord(
+			err = ngrClient.CreateOrUpdateServiceMetadataRecord(t.Context(),
 				recordToBeUpdated,
 				tt.args.categoryId,
 				tt.args.groupId,
 				true,
 			)
 			assert.NoError(t, err)
-			recordUpdated, err := ngrClient.GetRecord(tt.args.uuid)
+			recordUpdated, err := ngrClient.GetRecord(t.Context(), tt.args.uuid)
 			assert.NoError(t, err)
 			assert.Contains(t, recordUpdated, "NWB - wegen33333")
 
 			time.Sleep(10 * time.Second)
 
-			err = ngrClient.AddTagToRecord(tt.args.uuid, INSPIRE_TAG)
+			err = ngrClient.AddTagToRecord(t.Context(), tt.args.uuid, INSPIRE_TAG)
 			assert.NoError(t, err)
 
 			time.Sleep(10 * time.Second)
 
-			tagsList, err := ngrClient.GetRecordTags(tt.args.uuid)
+			tagsList, err := ngrClient.GetRecordTags(t.Context(), tt.args.uuid)
 			assert.NoError(t, err)
 			assert.Equal(t, 224342, tagsList[0].ID)
 
 			time.Sleep(10 * time.Second)
 
-			err = ngrClient.DeleteRecord(tt.args.uuid)
+			err = ngrClient.DeleteRecord(t.Context(), tt.args.uuid)
 			assert.NoError(t, err)
 		})
 	}
@@ -168,7 +169,7 @@ func TestNgrClient_createAndValidateServiceMetadataRecord(t *testing.T) {
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			validationResult, err := ngrClient.ValidateRecord(tt.args.uuid)
+			validationResult, err := ngrClient.ValidateRecord(t.Context(), tt.args.uuid)
 			assert.NoError(t, err)
 
 			assert.NotNil(t, validationResult)
@@ -205,3 +206,29 @@ func getNgrClient(mockedNGRServer *httptest.Server) *NgrClient {
 
 	return &ngrClient
 }
+
+func TestNgrClient_RecordsWithTag(t *testing.T) {
+	mockedNGRServer := preTestSetup()
+	defer mockedNGRServer.Close()
+
+	ngrClient := getNgrClient(mockedNGRServer)
+
+	inspire := "b4ae622c-6201-49d8-bd2e-f7fce9206a1e"
+	other := "c4bda1aa-d6e6-482c-a6f1-bd519e3202d4"
+
+	tagged, err := ngrClient.RecordsWithTag(t.Context(), []string{other, inspire, other}, INSPIRE_TAG)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{inspire}, tagged)
+
+	_, err = ngrClient.RecordsWithTag(t.Context(), []string{inspire, "unknown"}, INSPIRE_TAG)
+	assert.ErrorContains(t, err, "cannot get the tags of unknown")
+
+	tagged, err = ngrClient.RecordsWithTag(t.Context(), nil, INSPIRE_TAG)
+	assert.NoError(t, err)
+	assert.Empty(t, tagged)
+}
+
+func TestTagFilter(t *testing.T) {
+	constraint := csw.GetRecordsCQLConstraint{Filter: TagFilter(ngr.Tag{ID: INSPIRE_TAG, Name: "inspire"})}
+	assert.Contains(t, constraint.ToQueryParameter(), "&constraint=_cat+%3D+%27inspire%27")
+}
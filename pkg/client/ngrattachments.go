@@ -0,0 +1,248 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/textproto"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/pdok/pdok-metadata-tool/v2/pkg/model/ngr"
+)
+
+// MaxThumbnailSize is the maximum size in bytes of a thumbnail that is uploaded to NGR.
+const MaxThumbnailSize = 2 << 20
+
+// thumbnailContentTypes are the image types that are allowed for thumbnails.
+var thumbnailContentTypes = []string{"image/png", "image/jpeg", "image/gif"}
+
+// GetAttachments does a GET request on NGR to list the attachments of a record.
+func (c *NgrClient) GetAttachments(ctx context.Context, uuid string) ([]ngr.Attachment, error) {
+	var attachments []ngr.Attachment
+
+	err := c.getJSON(ctx, c.attachmentsURL(uuid), &attachments)
+
+	return attachments, err
+}
+
+// UploadAttachment does a POST request on NGR to attach a file to a record. An attachment with the same
+// file name is replaced.
+func (c *NgrClient) UploadAttachment(
+	ctx context.Context,
+	uuid string,
+	filename string,
+	content []byte,
+	visibility ngr.Visibility,
+) (ngr.Attachment, error) {
+	var body bytes.Buffer
+
+	writer := multipart.NewWriter(&body)
+
+	header := make(textproto.MIMEHeader)
+	header.Set("Content-Disposition", fmt.Sprintf(`form-data; name="file"; filename=%q`, filename))
+	header.Set("Content-Type", http.DetectContentType(content))
+
+	part, err := writer.CreatePart(header)
+	if err != nil {
+		return ngr.Attachment{}, err
+	}
+
+	if _, err = part.Write(content); err != nil {
+		return ngr.Attachment{}, err
+	}
+
+	if err = writer.Close(); err != nil {
+		return ngr.Attachment{}, err
+	}
+
+	requestBody := body.String()
+
+	response, err := c.getResponseBody(
+		ctx,
+		c.attachmentsURL(uuid)+"?"+url.Values{"visibility": {string(visibility)}}.Encode(),
+		http.MethodPost,
+		&requestBody,
+		writer.FormDataContentType(),
+	)
+	if err != nil {
+		return ngr.Attachment{}, fmt.Errorf("failed to upload %s to %s: %w", filename, uuid, err)
+	}
+
+	var attachment ngr.Attachment
+	if err := json.Unmarshal(response, &attachment); err != nil {
+		return ngr.Attachment{}, fmt.Errorf("error unmarshalling NGR attachment: %w", err)
+	}
+
+	return attachment, nil
+}
+
+// DownloadAttachment does a GET request on NGR to get the content of an attachment of a record.
+func (c *NgrClient) DownloadAttachment(ctx context.Context, uuid string, filename string) ([]byte, error) {
+	return c.getResponseBody(
+		ctx,
+		c.attachmentsURL(uuid)+"/"+url.PathEscape(filename),
+		http.MethodGet,
+		nil,
+		ContentTypeJSON,
+	)
+}
+
+// DeleteAttachment does a DELETE request on NGR to remove an attachment from a record.
+func (c *NgrClient) DeleteAttachment(ctx context.Context, uuid string, filename string) error {
+	_, err := c.getResponseBody(
+		ctx,
+		c.attachmentsURL(uuid)+"/"+url.PathEscape(filename),
+		http.MethodDelete,
+		nil,
+		ContentTypeJSON,
+	)
+
+	return err
+}
+
+// Thumbnail is a local file that a graphic overview of a record refers to.
+type Thumbnail struct {
+	Path     string
+	Content  []byte
+	fileName textRange // The file name in the record
+}
+
+// ReadThumbnails reads and checks the local files that the graphic overviews of a record refer to, so they can
+// be checked before the record is created. Relative paths are relative to baseDir. File names that are already
+// URLs are skipped.
+func ReadThumbnails(record string, baseDir string) ([]Thumbnail, error) {
+	fileNames, err := graphicOverviewFileNames(record)
+	if err != nil {
+		return nil, err
+	}
+
+	var thumbnails []Thumbnail
+
+	for _, fileName := range fileNames {
+		if u, err := url.Parse(fileName.value); err == nil && (u.Scheme == "http" || u.Scheme == "https") {
+			continue
+		}
+
+		path := fileName.value
+		if !filepath.IsAbs(path) {
+			path = filepath.Join(baseDir, path)
+		}
+
+		//nolint:gosec
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read thumbnail: %w", err)
+		}
+
+		if err := CheckThumbnail(path, content); err != nil {
+			return nil, err
+		}
+
+		thumbnails = append(thumbnails, Thumbnail{Path: path, Content: content, fileName: fileName})
+	}
+
+	return thumbnails, nil
+}
+
+// UploadThumbnails uploads the thumbnails of a record, as read by ReadThumbnails, as public attachments, and
+// returns the record with the file names replaced by the URLs of the attachments. The record must exist in NGR.
+func (c *NgrClient) UploadThumbnails(
+	ctx context.Context,
+	uuid string,
+	record string,
+	thumbnails []Thumbnail,
+) (string, error) {
+	// Replace from the end, so the offsets of the preceding file names stay valid
+	for _, thumbnail := range slices.Backward(thumbnails) {
+		attachment, err := c.UploadAttachment(ctx, uuid, filepath.Base(thumbnail.Path), thumbnail.Content,
+			ngr.VisibilityPublic)
+		if err != nil {
+			return "", err
+		}
+
+		var escaped strings.Builder
+		_ = xml.EscapeText(&escaped, []byte(attachment.URL)) // Writing to a strings.Builder does not fail
+
+		record = record[:thumbnail.fileName.start] + escaped.String() + record[thumbnail.fileName.end:]
+	}
+
+	return record, nil
+}
+
+// CheckThumbnail returns an error when a thumbnail is not a PNG, JPEG or GIF image, or is larger than
+// MaxThumbnailSize.
+func CheckThumbnail(filename string, content []byte) error {
+	if len(content) == 0 {
+		return fmt.Errorf("thumbnail %s is empty", filename)
+	}
+
+	if len(content) > MaxThumbnailSize {
+		return fmt.Errorf("thumbnail %s is %d bytes, which exceeds the maximum of %d bytes",
+			filename, len(content), MaxThumbnailSize)
+	}
+
+	if contentType := http.DetectContentType(content); !slices.Contains(thumbnailContentTypes, contentType) {
+		return fmt.Errorf("thumbnail %s has type %s (allowed: %s)",
+			filename, contentType, strings.Join(thumbnailContentTypes, ", "))
+	}
+
+	return nil
+}
+
+func (c *NgrClient) attachmentsURL(uuid string) string {
+	return fmt.Sprintf("%s%s/%s/attachments", *c.NgrConfig.NgrUrl, API_RECORDS_TEMPLATE, uuid)
+}
+
+// textRange is the text of an element at the byte offsets start to end of a document.
+type textRange struct {
+	start, end int64
+	value      string
+}
+
+// graphicOverviewFileNames returns the file names of the graphic overviews of a record, with their offsets.
+func graphicOverviewFileNames(record string) ([]textRange, error) {
+	fileNamePath := []string{"graphicOverview", "MD_BrowseGraphic", "fileName", "CharacterString"}
+
+	var (
+		fileNames []textRange
+		path      []string
+	)
+
+	decoder := xml.NewDecoder(strings.NewReader(record))
+
+	for {
+		start := decoder.InputOffset()
+
+		token, err := decoder.Token()
+		if errors.Is(err, io.EOF) {
+			return fileNames, nil
+		}
+
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse record: %w", err)
+		}
+
+		switch t := token.(type) {
+		case xml.StartElement:
+			path = append(path, t.Name.Local)
+		case xml.EndElement:
+			path = path[:len(path)-1]
+		case xml.CharData:
+			value := strings.TrimSpace(string(t))
+			if value != "" && len(path) >= len(fileNamePath) &&
+				slices.Equal(path[len(path)-len(fileNamePath):], fileNamePath) {
+				fileNames = append(fileNames, textRange{start: start, end: decoder.InputOffset(), value: value})
+			}
+		}
+	}
+}
@@ -0,0 +1,197 @@
+package client
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/pdok/pdok-metadata-tool/v2/pkg/model/ngr"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var testPNG = append([]byte("\x89PNG\r\n\x1a\n"), bytes.Repeat([]byte{0}, 32)...)
+
+// buildMockWebserverNgrAttachments is a GeoNetwork stand-in for the attachments API, which keeps the
+// uploaded files in memory.
+func buildMockWebserverNgrAttachments(t *testing.T) (*httptest.Server, map[string][]byte) {
+	t.Helper()
+
+	var mu sync.Mutex
+
+	files := map[string][]byte{}
+
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		if req.URL.String() == API_LOGIN_PART {
+			writeForbiddenResponse(rw, ContentTypeJSON)
+
+			return
+		}
+
+		mu.Lock()
+		defer mu.Unlock()
+
+		attachments := API_RECORDS_TEMPLATE + "/" + testServiceUUID + "/attachments"
+		rw.Header().Set("Content-Type", ContentTypeJSON)
+
+		switch {
+		case req.Method == http.MethodPost && req.URL.Path == attachments:
+			file, header, err := req.FormFile("file")
+			if err != nil {
+				rw.WriteHeader(http.StatusBadRequest)
+
+				return
+			}
+
+			content, _ := io.ReadAll(file)
+			files[header.Filename] = content
+
+			rw.WriteHeader(http.StatusCreated)
+			_, _ = fmt.Fprintf(rw, `{"id":"%s/attachments/%s","url":"%s%s/%s","filename":"%s","size":%d,"visibility":"%s"}`,
+				testServiceUUID, header.Filename, server.URL, attachments, header.Filename, header.Filename,
+				len(content), req.URL.Query().Get("visibility"))
+		case req.Method == http.MethodGet && req.URL.Path == attachments:
+			var list []string
+			for filename, content := range files {
+				list = append(list, fmt.Sprintf(`{"filename":"%s","size":%d}`, filename, len(content)))
+			}
+
+			_, _ = rw.Write([]byte("[" + strings.Join(list, ",") + "]"))
+		case req.Method == http.MethodDelete && strings.HasPrefix(req.URL.Path, attachments+"/"):
+			delete(files, strings.TrimPrefix(req.URL.Path, attachments+"/"))
+			rw.WriteHeader(http.StatusNoContent)
+		default:
+			rw.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	t.Cleanup(server.Close)
+
+	return server, files
+}
+
+func TestNgrClient_Attachments(t *testing.T) {
+	server, files := buildMockWebserverNgrAttachments(t)
+	ngrClient := getNgrClient(server)
+
+	attachment, err := ngrClient.UploadAttachment(t.Context(), testServiceUUID, "thumb.png", testPNG, ngr.VisibilityPublic)
+	require.NoError(t, err)
+	assert.Equal(t, "thumb.png", attachment.Filename)
+	assert.Equal(t, ngr.VisibilityPublic, attachment.Visibility)
+	assert.Equal(t, server.URL+API_RECORDS_TEMPLATE+"/"+testServiceUUID+"/attachments/thumb.png", attachment.URL)
+	assert.Equal(t, testPNG, files["thumb.png"])
+
+	attachments, err := ngrClient.GetAttachments(t.Context(), testServiceUUID)
+	require.NoError(t, err)
+	require.Len(t, attachments, 1)
+	assert.Equal(t, int64(len(testPNG)), attachments[0].Size)
+
+	require.NoError(t, ngrClient.DeleteAttachment(t.Context(), testServiceUUID, "thumb.png"))
+	assert.Empty(t, files)
+}
+
+func TestNgrClient_UploadThumbnails(t *testing.T) {
+	server, files := buildMockWebserverNgrAttachments(t)
+	ngrClient := getNgrClient(server)
+
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "thumb.png"), testPNG, 0o600))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "notes.txt"), []byte("not an image"), 0o600))
+
+	record := func(fileNames ...string) string {
+		var graphicOverviews string
+		for _, fileName := range fileNames {
+			graphicOverviews += `<gmd:graphicOverview><gmd:MD_BrowseGraphic><gmd:fileName><gco:CharacterString>` +
+				fileName + `</gco:CharacterString></gmd:fileName><gmd:fileDescription><gco:CharacterString>` +
+				`thumbnail</gco:CharacterString></gmd:fileDescription></gmd:MD_BrowseGraphic></gmd:graphicOverview>`
+		}
+
+		return `<gmd:MD_Metadata xmlns:gmd="http://www.isotc211.org/2005/gmd" ` +
+			`xmlns:gco="http://www.isotc211.org/2005/gco"><gmd:identificationInfo><srv:SV_ServiceIdentification>` +
+			graphicOverviews + `</srv:SV_ServiceIdentification></gmd:identificationInfo></gmd:MD_Metadata>`
+	}
+
+	attachmentURL := server.URL + API_RECORDS_TEMPLATE + "/" + testServiceUUID + "/attachments/thumb.png"
+
+	tests := []struct {
+		name    string
+		record  string
+		want    string
+		wantErr string
+	}{
+		{
+			name:   "Local files are uploaded",
+			record: record("https://example.nl/thumb.png", "thumb.png", filepath.Join(dir, "thumb.png")),
+			want:   record("https://example.nl/thumb.png", attachmentURL, attachmentURL),
+		},
+		{
+			name:   "URLs are left unchanged",
+			record: record("https://example.nl/a.png?width=200&amp;height=200"),
+			want:   record("https://example.nl/a.png?width=200&amp;height=200"),
+		},
+		{
+			name:    "Missing file",
+			record:  record("missing.png"),
+			wantErr: "failed to read thumbnail",
+		},
+		{
+			name:    "Not an image",
+			record:  record("notes.txt"),
+			wantErr: "has type text/plain; charset=utf-8",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			thumbnails, err := ReadThumbnails(tt.record, dir)
+			if tt.wantErr != "" {
+				require.ErrorContains(t, err, tt.wantErr)
+
+				return
+			}
+
+			require.NoError(t, err)
+
+			got, err := ngrClient.UploadThumbnails(t.Context(), testServiceUUID, tt.record, thumbnails)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+
+	assert.Len(t, files, 1, "nothing but the image is uploaded")
+}
+
+func TestCheckThumbnail(t *testing.T) {
+	tests := []struct {
+		name    string
+		content []byte
+		wantErr string
+	}{
+		{name: "PNG", content: testPNG},
+		{name: "JPEG", content: []byte("\xFF\xD8\xFF\xE0\x00\x10JFIF\x00")},
+		{name: "GIF", content: []byte("GIF89a\x01\x00\x01\x00")},
+		{name: "Empty", content: nil, wantErr: "is empty"},
+		{name: "SVG", content: []byte(`<svg xmlns="http://www.w3.org/2000/svg"/>`), wantErr: "has type text/plain"},
+		{name: "Too large", content: append(testPNG, make([]byte, MaxThumbnailSize)...), wantErr: "exceeds the maximum"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := CheckThumbnail("thumbnail", tt.content)
+			if tt.wantErr != "" {
+				require.ErrorContains(t, err, tt.wantErr)
+
+				return
+			}
+
+			require.NoError(t, err)
+		})
+	}
+}
@@ -0,0 +1,168 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/cookiejar"
+	"strings"
+	"sync"
+
+	"github.com/pdok/pdok-metadata-tool/v2/internal/common"
+)
+
+const xsrfTokenCookie = "XSRF-TOKEN"
+
+// ngrSession is the session of an NgrClient with NGR: the cookies that NGR sets (e.g. JSESSIONID) and the
+// XSRF token that NGR requires on every API request. The token is obtained once and reused until NGR
+// rejects it with 403 Forbidden. The session is shared by copies of the client and is safe for concurrent use.
+type ngrSession struct {
+	jar http.CookieJar
+
+	mu        sync.Mutex // Guards xsrfToken; held while logging in, so concurrent requests share one login
+	xsrfToken string
+}
+
+func newNgrSession() *ngrSession {
+	jar, _ := cookiejar.New(nil) // Only fails on invalid options
+
+	return &ngrSession{jar: jar}
+}
+
+// getResponseBody sends an authenticated API request in the session and returns the body of the response.
+// When NGR rejects the XSRF token, a new token is obtained and the request is sent once more.
+func (s *ngrSession) getResponseBody(
+	ctx context.Context,
+	ngrConfig *NgrConfig,
+	httpClient *http.Client,
+	url string,
+	method string,
+	requestBody *string,
+	contentType string,
+) ([]byte, error) {
+	for attempt := 0; ; attempt++ {
+		xsrfToken, err := s.token(ctx, ngrConfig, httpClient)
+		if err != nil {
+			return nil, fmt.Errorf("failed to obtain XSRF token: %w", err)
+		}
+
+		var body io.Reader
+		if requestBody != nil {
+			body = strings.NewReader(*requestBody)
+		}
+
+		req, err := http.NewRequestWithContext(ctx, method, url, body)
+		if err != nil {
+			return nil, fmt.Errorf("cannot create request: %w", err)
+		}
+
+		s.addCookies(req)
+		setXsrfToken(req, xsrfToken)
+		ngrConfig.setAuth(req)
+		req.Header.Set("User-Agent", "pdok.nl (pdok-metadata-tool)")
+		req.Header.Set("Accept", "*/*;q=0.8,application/signed-exchange")
+		req.Header.Set("Content-Type", contentType)
+
+		responseBody, retry, err := s.do(httpClient, req, xsrfToken, attempt == 0)
+		if !retry {
+			return responseBody, err
+		}
+	}
+}
+
+// do sends a request in the session. It reports whether the request should be sent again with a new
+// XSRF token, which is the case when the token was rejected on the first attempt.
+func (s *ngrSession) do(httpClient *http.Client, req *http.Request, xsrfToken string, first bool) ([]byte, bool, error) {
+	//nolint:bodyclose // We use common.SafeClose to handle closing the response body
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return nil, false, fmt.Errorf("error while calling NGR using url %s: %w", req.URL, err)
+	}
+	defer common.SafeClose(resp.Body)
+
+	s.jar.SetCookies(req.URL, resp.Cookies())
+
+	if resp.StatusCode == http.StatusForbidden && first {
+		s.invalidate(xsrfToken)
+
+		return nil, true, nil
+	}
+
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusCreated &&
+		resp.StatusCode != http.StatusNoContent {
+		return nil, false, newHTTPError(resp)
+	}
+
+	body, err := io.ReadAll(resp.Body)
+
+	return body, false, err
+}
+
+// token returns the XSRF token of the session, after logging in when there is none.
+func (s *ngrSession) token(ctx context.Context, ngrConfig *NgrConfig, httpClient *http.Client) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.xsrfToken != "" {
+		return s.xsrfToken, nil
+	}
+
+	xsrfToken, err := s.login(ctx, ngrConfig, httpClient)
+	if err != nil {
+		return "", err
+	}
+
+	s.xsrfToken = xsrfToken
+
+	return xsrfToken, nil
+}
+
+// invalidate forgets the XSRF token, unless another request already replaced it.
+func (s *ngrSession) invalidate(xsrfToken string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.xsrfToken == xsrfToken {
+		s.xsrfToken = ""
+	}
+}
+
+// login obtains a new XSRF token. NGR answers a request without a token with 403 Forbidden and sets
+// the token as a cookie.
+func (s *ngrSession) login(ctx context.Context, ngrConfig *NgrConfig, httpClient *http.Client) (string, error) {
+	url := *ngrConfig.NgrUrl + API_LOGIN_PART
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, nil)
+	if err != nil {
+		return "", fmt.Errorf("cannot create request: %w", err)
+	}
+
+	s.addCookies(req)
+	ngrConfig.setAuth(req)
+
+	//nolint:bodyclose // We use common.SafeClose to handle closing the response body
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("error executing request: %w", err)
+	}
+	defer common.SafeClose(resp.Body)
+
+	s.jar.SetCookies(req.URL, resp.Cookies())
+
+	if resp.StatusCode != http.StatusForbidden {
+		return "", fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+	}
+
+	return getCookieValueByName(resp.Header.Values("Set-Cookie"), xsrfTokenCookie)
+}
+
+// addCookies adds the cookies of the session, except the XSRF token, which is set by setXsrfToken.
+// Leaving out a rejected token makes NGR issue a new one.
+func (s *ngrSession) addCookies(req *http.Request) {
+	for _, cookie := range s.jar.Cookies(req.URL) {
+		if cookie.Name != xsrfTokenCookie {
+			req.AddCookie(cookie)
+		}
+	}
+}
@@ -0,0 +1,179 @@
+package client
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// ngrSessionServer is a GeoNetwork stand-in that issues an XSRF token and a session cookie on login and
+// rejects API requests without them with 403 Forbidden.
+type ngrSessionServer struct {
+	*httptest.Server
+
+	mu            sync.Mutex
+	xsrfToken     string
+	logins        atomic.Int32
+	requests      atomic.Int32
+	authorization atomic.Value
+}
+
+func newNgrSessionServer(t *testing.T) *ngrSessionServer {
+	t.Helper()
+
+	s := &ngrSessionServer{xsrfToken: "token-1"}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		s.authorization.Store(req.Header.Get("Authorization"))
+
+		s.mu.Lock()
+		xsrfToken := s.xsrfToken
+		s.mu.Unlock()
+
+		if req.URL.String() == API_LOGIN_PART {
+			s.logins.Add(1)
+			http.SetCookie(rw, &http.Cookie{Name: "JSESSIONID", Value: "session-1", Path: "/geonetwork"})
+			http.SetCookie(rw, &http.Cookie{Name: xsrfTokenCookie, Value: xsrfToken, Path: "/geonetwork"})
+			rw.WriteHeader(http.StatusForbidden)
+
+			return
+		}
+
+		s.requests.Add(1)
+
+		session, err := req.Cookie("JSESSIONID")
+		if err != nil || session.Value != "session-1" || req.Header.Get("X-Xsrf-Token") != xsrfToken {
+			rw.WriteHeader(http.StatusForbidden)
+
+			return
+		}
+
+		_, _ = rw.Write([]byte("<gmd:MD_Metadata/>"))
+	}))
+	t.Cleanup(s.Close)
+
+	return s
+}
+
+// rotate replaces the XSRF token, as NGR does when a session expires.
+func (s *ngrSessionServer) rotate(xsrfToken string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.xsrfToken = xsrfToken
+}
+
+func TestNgrClient_Session(t *testing.T) {
+	t.Run("Login is reused", func(t *testing.T) {
+		server := newNgrSessionServer(t)
+		ngrClient := getNgrClient(server.Server)
+
+		for range 5 {
+			_, err := ngrClient.GetRecord(t.Context(), testServiceUUID)
+			require.NoError(t, err)
+		}
+
+		assert.Equal(t, int32(1), server.logins.Load())
+		assert.Equal(t, int32(5), server.requests.Load())
+	})
+
+	t.Run("Rejected token is refreshed", func(t *testing.T) {
+		server := newNgrSessionServer(t)
+		ngrClient := getNgrClient(server.Server)
+
+		_, err := ngrClient.GetRecord(t.Context(), testServiceUUID)
+		require.NoError(t, err)
+
+		server.rotate("token-2")
+
+		_, err = ngrClient.GetRecord(t.Context(), testServiceUUID)
+		require.NoError(t, err)
+		assert.Equal(t, int32(2), server.logins.Load())
+		assert.Equal(t, int32(3), server.requests.Load(), "the rejected request is sent once more")
+	})
+
+	t.Run("Forbidden after refresh is an error", func(t *testing.T) {
+		var logins atomic.Int32
+
+		server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+			if req.URL.String() == API_LOGIN_PART {
+				logins.Add(1)
+				writeForbiddenResponse(rw, ContentTypeJSON)
+
+				return
+			}
+
+			rw.WriteHeader(http.StatusForbidden)
+		}))
+		defer server.Close()
+
+		ngrClient := getNgrClient(server)
+
+		err := ngrClient.DeleteRecord(t.Context(), testServiceUUID)
+
+		var httpErr *HTTPError
+		require.ErrorAs(t, err, &httpErr)
+		assert.Equal(t, http.StatusForbidden, httpErr.StatusCode)
+		assert.Equal(t, int32(2), logins.Load())
+	})
+
+	t.Run("Copies share the session", func(t *testing.T) {
+		server := newNgrSessionServer(t)
+		ngrClient := getNgrClient(server.Server)
+		ngrClientCopy := *ngrClient
+
+		_, err := ngrClient.GetRecord(t.Context(), testServiceUUID)
+		require.NoError(t, err)
+		_, err = ngrClientCopy.GetRecord(t.Context(), testServiceUUID)
+		require.NoError(t, err)
+
+		assert.Equal(t, int32(1), server.logins.Load())
+	})
+
+	t.Run("Concurrent requests", func(t *testing.T) {
+		server := newNgrSessionServer(t)
+		ngrClient := getNgrClient(server.Server)
+
+		var wg sync.WaitGroup
+		for range 20 {
+			wg.Add(1)
+
+			go func() {
+				defer wg.Done()
+
+				_, err := ngrClient.GetRecord(t.Context(), testServiceUUID)
+				assert.NoError(t, err)
+			}()
+		}
+
+		wg.Wait()
+
+		assert.Equal(t, int32(1), server.logins.Load())
+		assert.Equal(t, int32(20), server.requests.Load())
+	})
+}
+
+func TestNgrClient_Auth(t *testing.T) {
+	t.Run("Basic authentication", func(t *testing.T) {
+		server := newNgrSessionServer(t)
+		ngrClient := getNgrClient(server.Server)
+
+		_, err := ngrClient.GetRecord(t.Context(), testServiceUUID)
+		require.NoError(t, err)
+		assert.Equal(t, "Basic TkdSX1VTRVJfTkFNRTpOR1JfUEFTU1dPUkQ=", server.authorization.Load())
+	})
+
+	t.Run("Token authentication", func(t *testing.T) {
+		server := newNgrSessionServer(t)
+		ngrURL, token := server.URL, "api-token"
+		ngrClient := NewNgrClient(NgrConfig{NgrUrl: &ngrURL, NgrToken: &token})
+
+		_, err := ngrClient.GetRecord(t.Context(), testServiceUUID)
+		require.NoError(t, err)
+		assert.Equal(t, "Bearer api-token", server.authorization.Load())
+	})
+}
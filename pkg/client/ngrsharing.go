@@ -0,0 +1,201 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/pdok/pdok-metadata-tool/v2/pkg/model/ngr"
+)
+
+const API_GROUPS = "/geonetwork/srv/api/groups"
+const API_TAGS = "/geonetwork/srv/api/tags"
+
+// ngrBatchSize is the number of records per request of a bulk operation, which keeps the URL short enough.
+const ngrBatchSize = 100
+
+// GetGroups does a GET request on NGR to list the groups.
+func (c *NgrClient) GetGroups(ctx context.Context) ([]ngr.Group, error) {
+	var groups []ngr.Group
+
+	err := c.getJSON(ctx, *c.NgrConfig.NgrUrl+API_GROUPS, &groups)
+
+	return groups, err
+}
+
+// GetGroupByName returns the group with the name, or else with the name as label in any language.
+func (c *NgrClient) GetGroupByName(ctx context.Context, name string) (ngr.Group, error) {
+	groups, err := c.GetGroups(ctx)
+	if err != nil {
+		return ngr.Group{}, err
+	}
+
+	index := findByName(groups, name, func(g ngr.Group) (string, map[string]string) { return g.Name, g.Label })
+	if index < 0 {
+		return ngr.Group{}, fmt.Errorf("group %s not found in NGR", name)
+	}
+
+	return groups[index], nil
+}
+
+// GetCategories does a GET request on NGR to list the categories, which the GeoNetwork API calls tags.
+func (c *NgrClient) GetCategories(ctx context.Context) ([]ngr.Tag, error) {
+	var categories []ngr.Tag
+
+	err := c.getJSON(ctx, *c.NgrConfig.NgrUrl+API_TAGS, &categories)
+
+	return categories, err
+}
+
+// GetCategoryByName returns the category with the name, or else with the name as label in any language.
+func (c *NgrClient) GetCategoryByName(ctx context.Context, name string) (ngr.Tag, error) {
+	categories, err := c.GetCategories(ctx)
+	if err != nil {
+		return ngr.Tag{}, err
+	}
+
+	index := findByName(categories, name, func(t ngr.Tag) (string, map[string]string) { return t.Name, t.Label })
+	if index < 0 {
+		return ngr.Tag{}, fmt.Errorf("category %s not found in NGR", name)
+	}
+
+	return categories[index], nil
+}
+
+// GetSharing does a GET request on NGR to get the owner and the privileges of a record.
+func (c *NgrClient) GetSharing(ctx context.Context, uuid string) (ngr.Sharing, error) {
+	var sharing ngr.Sharing
+
+	err := c.getJSON(ctx, fmt.Sprintf("%s%s/%s/sharing", *c.NgrConfig.NgrUrl, API_RECORDS_TEMPLATE, uuid), &sharing)
+
+	return sharing, err
+}
+
+// SetPrivileges does a PUT request on NGR to set the privileges of groups on a record.
+// Only the given operations are changed, unless clear is set: then all other privileges are removed.
+func (c *NgrClient) SetPrivileges(ctx context.Context, uuid string, privileges []ngr.GroupPrivileges, clear bool) error {
+	body, err := json.Marshal(ngr.SharingParameter{Clear: clear, Privileges: privileges})
+	if err != nil {
+		return err
+	}
+
+	requestBody := string(body)
+	_, err = c.getResponseBody(
+		ctx,
+		fmt.Sprintf("%s%s/%s/sharing", *c.NgrConfig.NgrUrl, API_RECORDS_TEMPLATE, uuid),
+		http.MethodPut,
+		&requestBody,
+		ContentTypeJSON,
+	)
+
+	return err
+}
+
+// TransferOwnership does PUT requests on NGR to make the user and the group the owners of the records.
+func (c *NgrClient) TransferOwnership(ctx context.Context, uuids []string, userID int, groupID int) (ProcessingReport, error) {
+	return c.processRecords(ctx, http.MethodPut, "ownership", uuids, url.Values{
+		"userIdentifier":  {strconv.Itoa(userID)},
+		"groupIdentifier": {strconv.Itoa(groupID)},
+	})
+}
+
+// PublishRecords does PUT requests on NGR to publish the records, which allows everyone to view them.
+func (c *NgrClient) PublishRecords(ctx context.Context, uuids []string) (ProcessingReport, error) {
+	return c.processRecords(ctx, http.MethodPut, "publish", uuids, nil)
+}
+
+// UnpublishRecords does PUT requests on NGR to unpublish the records.
+func (c *NgrClient) UnpublishRecords(ctx context.Context, uuids []string) (ProcessingReport, error) {
+	return c.processRecords(ctx, http.MethodPut, "unpublish", uuids, nil)
+}
+
+// processRecords applies a bulk operation to the records in batches and returns the merged report.
+// Batches that NGR processes in the background are awaited. It stops at the first batch that fails.
+func (c *NgrClient) processRecords(
+	ctx context.Context,
+	method string,
+	operation string,
+	uuids []string,
+	params url.Values,
+) (ProcessingReport, error) {
+	var report ProcessingReport
+
+	for batch := range slices.Chunk(uuids, ngrBatchSize) {
+		query := url.Values{"uuids": batch}
+		for key, values := range params {
+			query[key] = values
+		}
+
+		response, err := c.getResponseBody(
+			ctx,
+			fmt.Sprintf("%s%s/%s?%s", *c.NgrConfig.NgrUrl, API_RECORDS_TEMPLATE, operation, query.Encode()),
+			method,
+			nil,
+			ContentTypeJSON,
+		)
+		if err != nil {
+			return report, err
+		}
+
+		var batchReport ProcessingReport
+		if len(response) > 0 {
+			if err := json.Unmarshal(response, &batchReport); err != nil {
+				return report, fmt.Errorf("error unmarshalling NGR %s report: %w", operation, err)
+			}
+		}
+
+		if batchReport, err = c.awaitReport(ctx, batchReport); err != nil {
+			return report, err
+		}
+
+		report.merge(batchReport)
+	}
+
+	return report, nil
+}
+
+// getJSON does an authenticated GET request on NGR and unmarshals the JSON response.
+func (c *NgrClient) getJSON(ctx context.Context, url string, result any) error {
+	response, err := c.getResponseBody(ctx, url, http.MethodGet, nil, ContentTypeJSON)
+	if err != nil {
+		return err
+	}
+
+	if err := json.Unmarshal(response, result); err != nil {
+		return fmt.Errorf("error unmarshalling NGR response from url %s: %w", url, err)
+	}
+
+	return nil
+}
+
+// findByName returns the index of the item with the name, or else of the first item with the name as label in
+// any language, ignoring case. It returns -1 when there is none.
+func findByName[T any](items []T, name string, names func(T) (string, map[string]string)) int {
+	if index := slices.IndexFunc(items, func(item T) bool {
+		itemName, _ := names(item)
+
+		return itemName == name
+	}); index >= 0 {
+		return index
+	}
+
+	return slices.IndexFunc(items, func(item T) bool {
+		itemName, labels := names(item)
+		if strings.EqualFold(itemName, name) {
+			return true
+		}
+
+		for _, label := range labels {
+			if strings.EqualFold(label, name) {
+				return true
+			}
+		}
+
+		return false
+	})
+}
@@ -0,0 +1,219 @@
+package client
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/pdok/pdok-metadata-tool/v2/pkg/model/ngr"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// buildMockWebserverNgrSharing is a GeoNetwork stand-in for groups, categories, sharing and bulk operations.
+// It records the requests other than the login.
+func buildMockWebserverNgrSharing(t *testing.T) (*httptest.Server, *[]*http.Request, *[]string) {
+	t.Helper()
+
+	var (
+		mu       sync.Mutex
+		requests []*http.Request
+		bodies   []string
+	)
+
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		if req.URL.String() == API_LOGIN_PART {
+			writeForbiddenResponse(rw, ContentTypeJSON)
+
+			return
+		}
+
+		body, _ := io.ReadAll(req.Body)
+
+		mu.Lock()
+		requests = append(requests, req)
+		bodies = append(bodies, string(body))
+		mu.Unlock()
+
+		rw.Header().Set("Content-Type", ContentTypeJSON)
+
+		switch {
+		case req.URL.Path == API_GROUPS:
+			_, _ = rw.Write([]byte(`[{"id":1,"name":"all","label":{"dut":"Iedereen","eng":"All"}},` +
+				`{"id":2,"name":"sample","label":{"dut":"Voorbeeldgroep","eng":"Sample group"}},` +
+				`{"id":5,"name":"pdok","label":{"dut":"PDOK","eng":"PDOK"}}]`))
+		case req.URL.Path == API_TAGS:
+			writeOkResponse("./testdata/API_Records_Tags_Inspire.json", rw, ContentTypeJSON)
+		case strings.HasSuffix(req.URL.Path, "/sharing") && req.Method == http.MethodGet:
+			_, _ = rw.Write([]byte(`{"owner":"3","groupOwner":"5","privileges":[` +
+				`{"group":1,"operations":{"view":true,"download":false,"dynamic":true,"editing":false}},` +
+				`{"group":5,"operations":{"view":true,"download":true,"dynamic":true,"editing":true}}]}`))
+		case strings.HasSuffix(req.URL.Path, "/sharing"):
+			rw.WriteHeader(http.StatusNoContent)
+		default:
+			uuids := req.URL.Query()["uuids"]
+			_, _ = fmt.Fprintf(rw, `{"numberOfRecords":%d,"numberOfRecordsProcessed":%d,"numberOfRecordNotFound":0}`,
+				len(uuids), len(uuids))
+		}
+	}))
+	t.Cleanup(server.Close)
+
+	return server, &requests, &bodies
+}
+
+func TestNgrClient_GetGroupByName(t *testing.T) {
+	server, _, _ := buildMockWebserverNgrSharing(t)
+	ngrClient := getNgrClient(server)
+
+	tests := []struct {
+		name    string
+		wantID  int
+		wantErr bool
+	}{
+		{name: "sample", wantID: 2},
+		{name: "Sample group", wantID: 2},
+		{name: "PDOK", wantID: 5},
+		{name: "unknown", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			group, err := ngrClient.GetGroupByName(t.Context(), tt.name)
+			if tt.wantErr {
+				require.ErrorContains(t, err, "not found")
+
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantID, group.ID)
+		})
+	}
+}
+
+func TestNgrClient_GetCategoryByName(t *testing.T) {
+	server, _, _ := buildMockWebserverNgrSharing(t)
+	ngrClient := getNgrClient(server)
+
+	category, err := ngrClient.GetCategoryByName(t.Context(), "Inspire")
+	require.NoError(t, err)
+	assert.Equal(t, INSPIRE_TAG, category.ID)
+
+	_, err = ngrClient.GetCategoryByName(t.Context(), "hvd")
+	require.Error(t, err)
+}
+
+func TestNgrClient_Sharing(t *testing.T) {
+	server, requests, bodies := buildMockWebserverNgrSharing(t)
+	ngrClient := getNgrClient(server)
+
+	sharing, err := ngrClient.GetSharing(t.Context(), testServiceUUID)
+	require.NoError(t, err)
+	assert.Equal(t, "5", sharing.GroupOwner)
+	require.Len(t, sharing.Privileges, 2)
+	assert.True(t, sharing.Privileges[0].Operations[ngr.OperationDynamic])
+	assert.False(t, sharing.Privileges[0].Operations[ngr.OperationDownload])
+
+	err = ngrClient.SetPrivileges(t.Context(), testServiceUUID, []ngr.GroupPrivileges{
+		{Group: 2, Operations: map[ngr.Operation]bool{ngr.OperationView: true, ngr.OperationDownload: false}},
+	}, false)
+	require.NoError(t, err)
+
+	last := (*requests)[len(*requests)-1]
+	assert.Equal(t, http.MethodPut, last.Method)
+	assert.Equal(t, API_RECORDS_TEMPLATE+"/"+testServiceUUID+"/sharing", last.URL.Path)
+
+	var parameter ngr.SharingParameter
+	require.NoError(t, json.Unmarshal([]byte((*bodies)[len(*bodies)-1]), &parameter))
+	assert.Equal(t, ngr.SharingParameter{Privileges: []ngr.GroupPrivileges{
+		{Group: 2, Operations: map[ngr.Operation]bool{ngr.OperationView: true, ngr.OperationDownload: false}},
+	}}, parameter)
+}
+
+func TestNgrClient_BulkOperations(t *testing.T) {
+	uuids := make([]string, 2*ngrBatchSize+1)
+	for i := range uuids {
+		uuids[i] = fmt.Sprintf("record-%03d", i)
+	}
+
+	t.Run("Transfer ownership", func(t *testing.T) {
+		server, requests, _ := buildMockWebserverNgrSharing(t)
+		ngrClient := getNgrClient(server)
+
+		report, err := ngrClient.TransferOwnership(t.Context(), uuids, 3, 5)
+		require.NoError(t, err)
+		assert.Equal(t, len(uuids), report.NumberOfRecords)
+		assert.Equal(t, len(uuids), report.NumberOfRecordsProcessed)
+
+		require.Len(t, *requests, 3, "the records are processed in batches")
+
+		for _, req := range *requests {
+			assert.Equal(t, API_RECORDS_TEMPLATE+"/ownership", req.URL.Path)
+			assert.Equal(t, "3", req.URL.Query().Get("userIdentifier"))
+			assert.Equal(t, "5", req.URL.Query().Get("groupIdentifier"))
+		}
+
+		assert.Len(t, (*requests)[2].URL.Query()["uuids"], 1)
+	})
+
+	t.Run("Publish and unpublish", func(t *testing.T) {
+		server, requests, _ := buildMockWebserverNgrSharing(t)
+		ngrClient := getNgrClient(server)
+
+		_, err := ngrClient.PublishRecords(t.Context(), uuids[:2])
+		require.NoError(t, err)
+		_, err = ngrClient.UnpublishRecords(t.Context(), uuids[:2])
+		require.NoError(t, err)
+
+		require.Len(t, *requests, 2)
+		assert.Equal(t, API_RECORDS_TEMPLATE+"/publish", (*requests)[0].URL.Path)
+		assert.Equal(t, API_RECORDS_TEMPLATE+"/unpublish", (*requests)[1].URL.Path)
+		assert.Equal(t, uuids[:2], (*requests)[1].URL.Query()["uuids"])
+	})
+}
+
+func TestProcessingReport_merge(t *testing.T) {
+	report := ProcessingReport{NumberOfRecords: 2, NumberOfRecordsProcessed: 1, NumberOfRecordsWithErrors: 1,
+		MetadataErrors: map[string][]MetadataError{"12": {{Message: "first"}}}}
+
+	report.merge(ProcessingReport{NumberOfRecords: 3, NumberOfRecordsProcessed: 2, NumberOfRecordNotFound: 1,
+		MetadataErrors: map[string][]MetadataError{"12": {{Message: "second"}}, "13": {{Message: "third"}}}})
+
+	assert.Equal(t, 5, report.NumberOfRecords)
+	assert.Equal(t, 3, report.NumberOfRecordsProcessed)
+	assert.Equal(t, 1, report.NumberOfRecordsWithErrors)
+	assert.Equal(t, 1, report.NumberOfRecordNotFound)
+	assert.Len(t, report.MetadataErrors["12"], 2)
+	assert.Len(t, report.MetadataErrors["13"], 1)
+}
+
+func TestNgrClient_Tags(t *testing.T) {
+	server, requests, _ := buildMockWebserverNgrSharing(t)
+	ngrClient := getNgrClient(server)
+
+	require.NoError(t, ngrClient.RemoveTagFromRecord(t.Context(), testServiceUUID, INSPIRE_TAG))
+
+	report, err := ngrClient.AddTagToRecords(t.Context(), []string{testServiceUUID, testDatasetUUID}, INSPIRE_TAG)
+	require.NoError(t, err)
+	assert.Equal(t, 2, report.NumberOfRecordsProcessed)
+
+	_, err = ngrClient.RemoveTagFromRecords(t.Context(), []string{testServiceUUID}, INSPIRE_TAG)
+	require.NoError(t, err)
+
+	require.Len(t, *requests, 3)
+
+	for i, want := range []struct{ method, path string }{
+		{http.MethodDelete, API_RECORDS_TEMPLATE + "/" + testServiceUUID + "/tags"},
+		{http.MethodPut, API_RECORDS_TEMPLATE + "/tags"},
+		{http.MethodDelete, API_RECORDS_TEMPLATE + "/tags"},
+	} {
+		assert.Equal(t, want.method, (*requests)[i].Method)
+		assert.Equal(t, want.path, (*requests)[i].URL.Path)
+		assert.Equal(t, "224342", (*requests)[i].URL.Query().Get("id"))
+	}
+}
@@ -0,0 +1,93 @@
+package client
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+)
+
+const API_PROCESS_REPORTS = "/geonetwork/srv/api/processes/reports"
+
+// ValidationIssue is an error of a record in a validation report: the rule that the record violates.
+type ValidationIssue struct {
+	UUID string `json:"uuid"`
+	Rule string `json:"rule"`
+}
+
+// ValidateRecords does PUT requests on NGR to validate the records in batches, and returns the merged report.
+// Batches that NGR validates in the background are awaited.
+func (c *NgrClient) ValidateRecords(ctx context.Context, uuids []string) (ValidationResult, error) {
+	return c.processRecords(ctx, http.MethodPut, "validate", uuids, nil)
+}
+
+// Issues returns the errors of the records in the result, ordered by record and rule, without duplicates.
+// The rule of an error is its message, without the UUID of the record that NGR puts in front of some messages.
+func (r *ValidationResult) Issues() []ValidationIssue {
+	var issues []ValidationIssue
+
+	for id, errs := range r.MetadataErrors {
+		for _, err := range errs {
+			issue := ValidationIssue{UUID: cmp.Or(err.UUID, id), Rule: strings.TrimSpace(err.Message)}
+			issue.Rule = strings.TrimSpace(strings.TrimPrefix(issue.Rule, "("+issue.UUID+")"))
+
+			issues = append(issues, issue)
+		}
+	}
+
+	slices.SortFunc(issues, compareValidationIssues)
+
+	return slices.Compact(issues)
+}
+
+// NewValidationIssues returns the issues that are not in the baseline, e.g. the issues of a previous validation.
+func NewValidationIssues(issues []ValidationIssue, baseline []ValidationIssue) []ValidationIssue {
+	known := make(map[ValidationIssue]bool, len(baseline))
+	for _, issue := range baseline {
+		known[issue] = true
+	}
+
+	var newIssues []ValidationIssue
+
+	for _, issue := range issues {
+		if !known[issue] {
+			newIssues = append(newIssues, issue)
+		}
+	}
+
+	return newIssues
+}
+
+// awaitReport polls the reports of the processes of NGR until the report is not running anymore.
+func (c *NgrClient) awaitReport(ctx context.Context, report ProcessingReport) (ProcessingReport, error) {
+	interval := cmp.Or(c.pollInterval, ngrPollInterval)
+
+	for report.Running {
+		select {
+		case <-ctx.Done():
+			return report, context.Cause(ctx)
+		case <-time.After(interval):
+		}
+
+		var reports []ProcessingReport
+		if err := c.getJSON(ctx, *c.NgrConfig.NgrUrl+API_PROCESS_REPORTS, &reports); err != nil {
+			return report, err
+		}
+
+		index := slices.IndexFunc(reports, func(r ProcessingReport) bool { return r.UUID == report.UUID })
+		if index < 0 {
+			return report, fmt.Errorf("report %s of NGR is not available anymore", report.UUID)
+		}
+
+		report = reports[index]
+	}
+
+	return report, nil
+}
+
+func compareValidationIssues(a, b ValidationIssue) int {
+	return cmp.Or(strings.Compare(a.UUID, b.UUID), strings.Compare(a.Rule, b.Rule))
+}
@@ -0,0 +1,164 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// buildMockWebserverNgrValidation is a GeoNetwork stand-in that validates the first batch of records in the
+// background: its report is running until it has been polled the given number of times. Then the report is
+// done, or removed when removeReport is set.
+func buildMockWebserverNgrValidation(t *testing.T, polls int32, removeReport bool) (*httptest.Server, *atomic.Int32) {
+	t.Helper()
+
+	var (
+		batches atomic.Int32
+		polled  atomic.Int32
+	)
+
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		if req.URL.String() == API_LOGIN_PART {
+			writeForbiddenResponse(rw, ContentTypeJSON)
+
+			return
+		}
+
+		rw.Header().Set("Content-Type", ContentTypeJSON)
+
+		switch req.URL.Path {
+		case API_RECORDS_TEMPLATE + "/validate":
+			uuids := req.URL.Query()["uuids"]
+			if batches.Add(1) == 1 {
+				_, _ = fmt.Fprintf(rw, `{"uuid":"report-1","numberOfRecords":%d,"running":true}`, len(uuids))
+
+				return
+			}
+
+			_, _ = fmt.Fprintf(rw, `{"uuid":"report-2","numberOfRecords":%d,"numberOfRecordsProcessed":%d,`+
+				`"numberOfRecordsWithErrors":1,"metadataErrors":{"7":[{"message":"(%s) Is invalid","uuid":"%s"}]}}`,
+				len(uuids), len(uuids), uuids[0], uuids[0])
+		case API_PROCESS_REPORTS:
+			n := polled.Add(1)
+
+			switch {
+			case n < polls:
+				_, _ = rw.Write([]byte(`[{"uuid":"other"},{"uuid":"report-1","running":true}]`))
+			case removeReport:
+				_, _ = rw.Write([]byte(`[]`))
+			default:
+				_, _ = rw.Write([]byte(`[{"uuid":"report-1","numberOfRecords":100,"numberOfRecordsProcessed":100,` +
+					`"numberOfRecordsWithErrors":1,"metadataErrors":{"3":[{"message":"Title is missing","uuid":"record-003"},` +
+					`{"message":"Abstract is missing","uuid":"record-003"}]},"running":false}]`))
+			}
+		default:
+			rw.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	t.Cleanup(server.Close)
+
+	return server, &polled
+}
+
+func TestNgrClient_ValidateRecords(t *testing.T) {
+	uuids := make([]string, ngrBatchSize+1)
+	for i := range uuids {
+		uuids[i] = fmt.Sprintf("record-%03d", i)
+	}
+
+	t.Run("Background validation is awaited", func(t *testing.T) {
+		server, polled := buildMockWebserverNgrValidation(t, 3, false)
+		ngrClient := getNgrClient(server)
+		ngrClient.pollInterval = time.Millisecond
+
+		report, err := ngrClient.ValidateRecords(t.Context(), uuids)
+		require.NoError(t, err)
+		assert.Equal(t, int32(3), polled.Load())
+		assert.False(t, report.Running)
+		assert.Equal(t, len(uuids), report.NumberOfRecords)
+		assert.Equal(t, len(uuids), report.NumberOfRecordsProcessed)
+		assert.Equal(t, 2, report.NumberOfRecordsWithErrors)
+
+		assert.Equal(t, []ValidationIssue{
+			{UUID: "record-003", Rule: "Abstract is missing"},
+			{UUID: "record-003", Rule: "Title is missing"},
+			{UUID: "record-100", Rule: "Is invalid"},
+		}, report.Issues())
+	})
+
+	t.Run("Removed report", func(t *testing.T) {
+		server, polled := buildMockWebserverNgrValidation(t, 2, true)
+		ngrClient := getNgrClient(server)
+		ngrClient.pollInterval = time.Millisecond
+
+		_, err := ngrClient.ValidateRecords(t.Context(), uuids[:1])
+		require.ErrorContains(t, err, "report report-1 of NGR is not available anymore")
+		assert.Equal(t, int32(2), polled.Load())
+	})
+
+	t.Run("Canceled while polling", func(t *testing.T) {
+		server, _ := buildMockWebserverNgrValidation(t, 1000, false)
+		ngrClient := getNgrClient(server)
+		ngrClient.pollInterval = time.Hour
+
+		ctx, cancel := context.WithTimeout(t.Context(), 10*time.Millisecond)
+		defer cancel()
+
+		_, err := ngrClient.ValidateRecords(ctx, uuids[:1])
+		require.ErrorIs(t, err, context.DeadlineExceeded)
+	})
+}
+
+func TestValidationResult_Issues(t *testing.T) {
+	data, err := os.ReadFile("./testdata/API_Records_Validate_nwbwegen.json")
+	require.NoError(t, err)
+
+	var result ValidationResult
+	require.NoError(t, json.Unmarshal(data, &result))
+
+	assert.Equal(t, []ValidationIssue{
+		{
+			UUID: "689c413e-a057-11f0-8de9-0242ac120002",
+			Rule: "E-mail van de verantwoordelijke organisatie van de service ontbreekt of is ongeldig",
+		},
+		{UUID: "689c413e-a057-11f0-8de9-0242ac120002", Rule: "Is invalid"},
+	}, result.Issues())
+}
+
+func TestNewValidationIssues(t *testing.T) {
+	baseline := []ValidationIssue{{UUID: "a", Rule: "Title is missing"}, {UUID: "b", Rule: "Is invalid"}}
+
+	tests := []struct {
+		name   string
+		issues []ValidationIssue
+		want   []ValidationIssue
+	}{
+		{name: "No issues", issues: nil, want: nil},
+		{name: "Known issues", issues: baseline[:1], want: nil},
+		{
+			name:   "New rule of a known record",
+			issues: []ValidationIssue{{UUID: "a", Rule: "Title is missing"}, {UUID: "a", Rule: "Is invalid"}},
+			want:   []ValidationIssue{{UUID: "a", Rule: "Is invalid"}},
+		},
+		{
+			name:   "New record",
+			issues: []ValidationIssue{{UUID: "c", Rule: "Title is missing"}},
+			want:   []ValidationIssue{{UUID: "c", Rule: "Title is missing"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, NewValidationIssues(tt.issues, baseline))
+		})
+	}
+}
@@ -0,0 +1,388 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"slices"
+	"sort"
+	"time"
+
+	"github.com/pdok/pdok-metadata-tool/v2/pkg/model/csw"
+	"github.com/pdok/pdok-metadata-tool/v2/pkg/model/iso1911x"
+	"github.com/pdok/pdok-metadata-tool/v2/pkg/model/oaipmh"
+)
+
+// OaiPmhClient is used as a client for harvesting ISO 19139 records from an OAI-PMH repository.
+// Records are stored in the same on-disk cache as records harvested with CswClient.
+type OaiPmhClient struct {
+	endpoint       *url.URL
+	client         *http.Client
+	cache          *recordCache // nil when caching is disabled
+	limiter        *rateLimiter
+	metadataPrefix string
+	granularity    string // Of the repository, retrieved with Identify when dates are used
+}
+
+// OaiPmhRecords holds the records of a ListRecords request.
+type OaiPmhRecords struct {
+	Records []iso1911x.MDMetadata
+	Deleted []string // Identifiers of the records that were deleted from the repository
+}
+
+// NewOaiPmhClient creates a new instance of OaiPmhClient, using metadata prefix oaipmh.DefaultMetadataPrefix
+// and an HTTP client with DefaultHTTPConfig.
+func NewOaiPmhClient(endpoint *url.URL) OaiPmhClient {
+	return OaiPmhClient{
+		endpoint:       endpoint,
+		client:         DefaultHTTPClient(),
+		metadataPrefix: oaipmh.DefaultMetadataPrefix,
+	}
+}
+
+// SetHTTPClient sets the HTTP client, see NewHTTPClient.
+func (c *OaiPmhClient) SetHTTPClient(httpClient *http.Client) {
+	c.client = httpClient
+}
+
+// SetCache enables on-disk caching of raw records. ttlHours is the time-to-live expressed in hours.
+func (c *OaiPmhClient) SetCache(cacheDir string, ttlHours int) {
+	c.cache = newRecordCache(cacheDir, ttlHours, c.endpoint)
+}
+
+// UnsetCache disables caching.
+func (c *OaiPmhClient) UnsetCache() {
+	c.cache = nil
+}
+
+// SetRateLimit limits the number of requests per second to the endpoint. The limit is shared with other
+// clients for the same host. A value of 0 or less disables rate limiting for this client.
+func (c *OaiPmhClient) SetRateLimit(requestsPerSecond float64) {
+	if requestsPerSecond <= 0 {
+		c.limiter = nil
+
+		return
+	}
+
+	c.limiter = getEndpointLimiter(c.endpoint, requestsPerSecond)
+}
+
+// SetMetadataPrefix sets the metadata prefix of the ISO 19139 format of the repository,
+// see ListMetadataFormats.
+func (c *OaiPmhClient) SetMetadataPrefix(metadataPrefix string) {
+	c.metadataPrefix = metadataPrefix
+}
+
+// Identify returns the description of the repository.
+func (c *OaiPmhClient) Identify(ctx context.Context) (oaipmh.Identify, error) {
+	resp, _, err := c.request(ctx, url.Values{"verb": {oaipmh.VerbIdentify}})
+	if err != nil {
+		return oaipmh.Identify{}, err
+	}
+
+	if resp.Identify == nil {
+		return oaipmh.Identify{}, errors.New("missing Identify in OAI-PMH response")
+	}
+
+	return *resp.Identify, nil
+}
+
+// ListMetadataFormats returns the metadata formats in which the repository provides records.
+func (c *OaiPmhClient) ListMetadataFormats(ctx context.Context) ([]oaipmh.MetadataFormat, error) {
+	resp, _, err := c.request(ctx, url.Values{"verb": {oaipmh.VerbListMetadataFormats}})
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.ListMetadataFormats == nil {
+		return nil, errors.New("missing ListMetadataFormats in OAI-PMH response")
+	}
+
+	return resp.ListMetadataFormats.MetadataFormats, nil
+}
+
+// GetRecord returns a record by its OAI-PMH identifier, using the cache when the identifier is the UUID
+// of a cached record.
+func (c *OaiPmhClient) GetRecord(ctx context.Context, identifier string) (iso1911x.MDMetadata, error) {
+	if cached, ok, err := c.cache.get(identifier); err == nil && ok {
+		return csw.UnmarshalMDMetadata(cached)
+	}
+
+	resp, _, err := c.request(ctx, c.getRecordParams(identifier))
+	if err != nil {
+		return iso1911x.MDMetadata{}, err
+	}
+
+	if resp.GetRecord == nil {
+		return iso1911x.MDMetadata{}, errors.New("missing GetRecord in OAI-PMH response")
+	}
+
+	if resp.GetRecord.Record.Header.IsDeleted() {
+		return iso1911x.MDMetadata{}, fmt.Errorf("record %s is deleted", identifier)
+	}
+
+	return c.extractRecord(&resp.GetRecord.Record, resp.Namespaces())
+}
+
+// ListRecords returns all records in the selection, following resumption tokens. Every record is stored
+// in the cache by the UUID of the record.
+func (c *OaiPmhClient) ListRecords(ctx context.Context, selection oaipmh.Selection) (OaiPmhRecords, error) {
+	var result OaiPmhRecords
+
+	if (selection.From != nil || selection.Until != nil) && c.granularity == "" {
+		identify, err := c.Identify(ctx)
+		if err != nil {
+			return result, err
+		}
+
+		c.granularity = identify.Granularity
+	}
+
+	params := selection.QueryParameters(c.metadataPrefix, c.granularity)
+	seenTokens := map[string]bool{}
+
+	for {
+		resp, oaiErr, err := c.request(ctx, params)
+		if oaiErr != nil && oaiErr.Code == oaipmh.ErrorNoRecordsMatch {
+			return result, nil
+		}
+
+		if err != nil {
+			return result, err
+		}
+
+		if resp.ListRecords == nil {
+			return result, errors.New("missing ListRecords in OAI-PMH response")
+		}
+
+		namespaces := resp.Namespaces()
+
+		for i := range resp.ListRecords.Records {
+			record := &resp.ListRecords.Records[i]
+			if record.Header.IsDeleted() {
+				result.Deleted = append(result.Deleted, record.Header.Identifier)
+
+				continue
+			}
+
+			md, err := c.extractRecord(record, namespaces)
+			if err != nil {
+				slog.Warn("Skipping OAI-PMH record", "identifier", record.Header.Identifier, "err", err)
+
+				continue
+			}
+
+			result.Records = append(result.Records, md)
+		}
+
+		token := resp.ListRecords.ResumptionToken
+		if token == nil || token.Token == "" {
+			return result, nil
+		}
+
+		if seenTokens[token.Token] {
+			return result, fmt.Errorf("resumptionToken %s was returned twice", token.Token)
+		}
+
+		seenTokens[token.Token] = true
+		params = url.Values{"verb": {oaipmh.VerbListRecords}, "resumptionToken": {token.Token}}
+	}
+}
+
+// HarvestIncremental harvests the records in the selection, using the state of the last successful harvest
+// for this endpoint, set and metadata prefix. After a first harvest, only records with a datestamp since the
+// last harvest are retrieved, and records reported as deleted are removed from the cache. This assumes that
+// the OAI-PMH identifiers are the UUIDs of the records, as in GeoNetwork and pycsw.
+// Without a previous harvest, or when full is set, all records are harvested. Records of a previous harvest
+// that are no longer listed are then deleted as well, unless the selection has a date range, which leaves
+// records out without them being deleted.
+func (c *OaiPmhClient) HarvestIncremental(
+	ctx context.Context,
+	selection oaipmh.Selection,
+	state *HarvestState,
+	full bool,
+) (IncrementalHarvestResult, error) {
+	var result IncrementalHarvestResult
+
+	start := time.Now()
+	bounded := selection.From != nil || selection.Until != nil
+	key, description := c.harvestStateKey(selection)
+	previous, hasPrevious := state.Entries[key]
+
+	if !full && hasPrevious {
+		since := previous.LastHarvest.Add(-incrementalOverlap)
+		if selection.From == nil || selection.From.Before(since) {
+			selection.From = &since
+		}
+
+		result.Incremental = true
+		result.Since = since
+	}
+
+	records, err := c.ListRecords(ctx, selection)
+	if err != nil {
+		return result, err
+	}
+
+	result.Records = records.Records
+	previousSet := toSet(previous.Identifiers)
+
+	current := map[string]bool{}
+	if result.Incremental || bounded {
+		current = toSet(previous.Identifiers)
+	}
+
+	for _, md := range records.Records {
+		uuid := iso1911x.NormalizeXMLText(md.UUID)
+		switch {
+		case !previousSet[uuid]:
+			result.Added = append(result.Added, uuid)
+		case result.Incremental:
+			result.Updated = append(result.Updated, uuid)
+		}
+
+		current[uuid] = true
+	}
+
+	deleted := records.Deleted
+	if !result.Incremental && !bounded {
+		// Records that are no longer listed were deleted, also when the repository does not keep track of deletions
+		deleted = nil
+
+		for _, id := range previous.Identifiers {
+			if !current[id] {
+				deleted = append(deleted, id)
+			}
+		}
+	}
+
+	for _, id := range deleted {
+		if previousSet[id] || current[id] {
+			result.Deleted = append(result.Deleted, id)
+			delete(current, id)
+		}
+
+		c.cache.remove(id)
+		result.Removed = append(result.Removed, id)
+	}
+
+	identifiers := make([]string, 0, len(current))
+	for id := range current {
+		identifiers = append(identifiers, id)
+	}
+
+	slices.Sort(identifiers)
+
+	result.Unchanged = len(identifiers) - len(result.Added) - len(result.Updated)
+	state.Entries[key] = HarvestStateEntry{
+		Endpoint:    c.endpoint.String(),
+		Constraint:  description,
+		LastHarvest: start,
+		Identifiers: identifiers,
+	}
+
+	return result, nil
+}
+
+// request executes an OAI-PMH request. An OAI-PMH error in the response is returned both as error
+// and separately, so callers can handle specific error codes.
+func (c *OaiPmhClient) request(ctx context.Context, params url.Values) (oaipmh.Response, *oaipmh.Error, error) {
+	requestURL := *c.endpoint
+	requestURL.RawQuery = params.Encode()
+
+	if err := c.limiter.wait(ctx); err != nil {
+		return oaipmh.Response{}, nil, err
+	}
+
+	body, err := getResponseBody(ctx, requestURL.String(), http.MethodGet, nil, *c.client)
+	if err != nil {
+		return oaipmh.Response{}, nil, err
+	}
+
+	var resp oaipmh.Response
+	if err := xml.Unmarshal(body, &resp); err != nil { //nolint:musttag
+		return oaipmh.Response{}, nil, fmt.Errorf("error unmarshalling OAI-PMH response from url %s: %w", requestURL.String(), err)
+	}
+
+	if len(resp.Errors) > 0 {
+		return resp, &resp.Errors[0], &resp.Errors[0]
+	}
+
+	return resp, nil, nil
+}
+
+// extractRecord unmarshals the ISO 19139 metadata of a record and stores it in the cache.
+func (c *OaiPmhClient) extractRecord(record *oaipmh.Record, namespaces map[string]string) (iso1911x.MDMetadata, error) {
+	if record.Metadata == nil {
+		return iso1911x.MDMetadata{}, errors.New("record has no metadata")
+	}
+
+	// Wrap the metadata with the namespace declarations of the response, which may be used in the record
+	var buf bytes.Buffer
+
+	prefixes := make([]string, 0, len(namespaces))
+	for prefix := range namespaces {
+		prefixes = append(prefixes, prefix)
+	}
+
+	sort.Strings(prefixes)
+	buf.WriteString("<metadata")
+
+	for _, prefix := range prefixes {
+		buf.WriteString(" xmlns:" + prefix + `="`)
+		_ = xml.EscapeText(&buf, []byte(namespaces[prefix]))
+		buf.WriteString(`"`)
+	}
+
+	buf.WriteString(">")
+	buf.Write(record.Metadata.Inner)
+	buf.WriteString("</metadata>")
+
+	raws, err := csw.ExtractRawRecords(buf.Bytes())
+	if err != nil {
+		return iso1911x.MDMetadata{}, err
+	}
+
+	if len(raws) != 1 {
+		return iso1911x.MDMetadata{}, fmt.Errorf(
+			"expected one gmd:MD_Metadata, found %d; check the metadata prefix (%s)", len(raws), c.metadataPrefix)
+	}
+
+	md, err := csw.UnmarshalMDMetadata(raws[0])
+	if err != nil {
+		return iso1911x.MDMetadata{}, err
+	}
+
+	if uuid := iso1911x.NormalizeXMLText(md.UUID); uuid != "" {
+		// The GetRecord URL is stored as source, also for records harvested with ListRecords
+		requestURL := *c.endpoint
+		requestURL.RawQuery = c.getRecordParams(record.Header.Identifier).Encode()
+
+		_ = c.cache.put(uuid, raws[0], CacheEntryInfo{SourceURL: requestURL.String()}) // best-effort caching
+	}
+
+	return md, nil
+}
+
+func (c *OaiPmhClient) getRecordParams(identifier string) url.Values {
+	return url.Values{
+		"verb":           {oaipmh.VerbGetRecord},
+		"identifier":     {identifier},
+		"metadataPrefix": {c.metadataPrefix},
+	}
+}
+
+// harvestStateKey identifies the harvest state of this endpoint, set and metadata prefix.
+func (c *OaiPmhClient) harvestStateKey(selection oaipmh.Selection) (key string, description string) {
+	description = "metadataPrefix=" + c.metadataPrefix
+	if selection.Set != "" {
+		description += "&set=" + selection.Set
+	}
+
+	return c.endpoint.String() + "?" + description, description
+}
@@ -0,0 +1,226 @@
+package client
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pdok/pdok-metadata-tool/v2/pkg/model/iso1911x"
+	"github.com/pdok/pdok-metadata-tool/v2/pkg/model/oaipmh"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const oaiPmhEnvelope = `<?xml version="1.0" encoding="UTF-8"?>
+<OAI-PMH xmlns="http://www.openarchives.org/OAI/2.0/" %s>
+  <responseDate>2025-01-01T00:00:00Z</responseDate>
+  %s
+</OAI-PMH>`
+
+// buildMockWebserverOaiPmh serves an OAI-PMH repository with the given ISO 19139 records. ListRecords returns
+// one record per page, followed by a deleted record. With a from argument, only the last record is listed.
+// The requests are recorded in requests.
+func buildMockWebserverOaiPmh(t *testing.T, records []string, requests *[]url.Values) *httptest.Server {
+	t.Helper()
+
+	writeResponse := func(w http.ResponseWriter, body string) {
+		w.Header().Set("Content-Type", "text/xml")
+		// The gmd namespace is declared on the response element, so the client has to copy it to the record
+		_, _ = fmt.Fprintf(w, oaiPmhEnvelope, `xmlns:gmd="http://www.isotc211.org/2005/gmd"`, body)
+	}
+	recordXML := func(record string) string {
+		return `<record><header><identifier>oai:test</identifier><datestamp>2025-01-01</datestamp></header>` +
+			`<metadata>` + record + `</metadata></record>`
+	}
+
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		query := r.URL.Query()
+		*requests = append(*requests, query)
+
+		switch query.Get("verb") {
+		case oaipmh.VerbIdentify:
+			writeResponse(w, `<Identify><repositoryName>Test</repositoryName><baseURL>http://localhost</baseURL>`+
+				`<protocolVersion>2.0</protocolVersion><deletedRecord>persistent</deletedRecord>`+
+				`<granularity>YYYY-MM-DDThh:mm:ssZ</granularity></Identify>`)
+		case oaipmh.VerbListMetadataFormats:
+			writeResponse(w, `<ListMetadataFormats><metadataFormat><metadataPrefix>iso19139</metadataPrefix>`+
+				`<schema>http://www.isotc211.org/2005/gmd/gmd.xsd</schema>`+
+				`<metadataNamespace>http://www.isotc211.org/2005/gmd</metadataNamespace></metadataFormat>`+
+				`</ListMetadataFormats>`)
+		case oaipmh.VerbGetRecord:
+			writeResponse(w, `<error code="idDoesNotExist">Unknown identifier</error>`)
+		case oaipmh.VerbListRecords:
+			if query.Get("set") == "empty" {
+				writeResponse(w, `<error code="noRecordsMatch">No records</error>`)
+
+				return
+			}
+
+			page := 0
+			if token := query.Get("resumptionToken"); token != "" {
+				_, _ = fmt.Sscanf(token, "page-%d", &page)
+			} else if query.Get("from") != "" {
+				page = len(records) - 1
+			}
+
+			switch {
+			case page < len(records):
+				writeResponse(w, fmt.Sprintf(`<ListRecords>%s<resumptionToken cursor="%d">page-%d</resumptionToken></ListRecords>`,
+					recordXML(records[page]), page, page+1))
+			default:
+				writeResponse(w, `<ListRecords><record><header status="deleted"><identifier>deleted-uuid</identifier>`+
+					`<datestamp>2025-01-01</datestamp></header></record><resumptionToken/></ListRecords>`)
+			}
+		}
+	}))
+}
+
+func readRecordsForOaiPmh(t *testing.T, paths ...string) []string {
+	t.Helper()
+
+	records := make([]string, 0, len(paths))
+
+	for _, path := range paths {
+		record, err := readFileToString(path)
+		require.NoError(t, err)
+
+		records = append(records, record[strings.Index(record, "<gmd:MD_Metadata"):])
+	}
+
+	return records
+}
+
+func TestOaiPmhClient(t *testing.T) {
+	records := readRecordsForOaiPmh(t,
+		"../../examples/ISO19115/5951efa2-1ff3-4763-a966-a2f5497679ee.xml",
+		"../../examples/ISO19119/dae8f9e3-99af-4d21-9feb-29f2a1693077.xml",
+	)
+
+	var requests []url.Values
+
+	server := buildMockWebserverOaiPmh(t, records, &requests)
+	defer server.Close()
+
+	endpoint, _ := url.Parse(server.URL)
+	oaiClient := NewOaiPmhClient(endpoint)
+
+	cacheDir := t.TempDir()
+	oaiClient.SetCache(cacheDir, 1)
+
+	t.Run("Identify", func(t *testing.T) {
+		identify, err := oaiClient.Identify(t.Context())
+		require.NoError(t, err)
+		assert.Equal(t, "Test", identify.RepositoryName)
+		assert.Equal(t, oaipmh.GranularitySecond, identify.Granularity)
+	})
+
+	t.Run("ListMetadataFormats", func(t *testing.T) {
+		formats, err := oaiClient.ListMetadataFormats(t.Context())
+		require.NoError(t, err)
+		require.Len(t, formats, 1)
+		assert.Equal(t, "iso19139", formats[0].MetadataPrefix)
+	})
+
+	t.Run("ListRecords", func(t *testing.T) {
+		requests = nil
+
+		result, err := oaiClient.ListRecords(t.Context(), oaipmh.Selection{Set: "datasets"})
+		require.NoError(t, err)
+		require.Len(t, result.Records, 2)
+		assert.Equal(t, "5951efa2-1ff3-4763-a966-a2f5497679ee", iso1911x.NormalizeXMLText(result.Records[0].UUID))
+		assert.NotNil(t, result.Records[1].IdentificationInfo.SVServiceIdentification)
+		assert.Equal(t, []string{"deleted-uuid"}, result.Deleted)
+
+		require.Len(t, requests, 3)
+		assert.Equal(t, "datasets", requests[0].Get("set"))
+		assert.Equal(t, "iso19139", requests[0].Get("metadataPrefix"))
+		assert.Equal(t, url.Values{"verb": {"ListRecords"}, "resumptionToken": {"page-1"}}, requests[1])
+
+		assert.FileExists(t, filepath.Join(cacheDir, CacheNamespace(endpoint), "5951efa2-1ff3-4763-a966-a2f5497679ee.xml"))
+	})
+
+	t.Run("ListRecords without matches", func(t *testing.T) {
+		result, err := oaiClient.ListRecords(t.Context(), oaipmh.Selection{Set: "empty"})
+		require.NoError(t, err)
+		assert.Empty(t, result.Records)
+	})
+
+	t.Run("GetRecord", func(t *testing.T) {
+		md, err := oaiClient.GetRecord(t.Context(), "5951efa2-1ff3-4763-a966-a2f5497679ee")
+		require.NoError(t, err, "record is read from the cache")
+		assert.NotNil(t, md.IdentificationInfo.MDDataIdentification)
+
+		_, err = oaiClient.GetRecord(t.Context(), "unknown")
+		require.EqualError(t, err, "OAI-PMH error idDoesNotExist: Unknown identifier")
+	})
+}
+
+func TestOaiPmhClient_HarvestIncremental(t *testing.T) {
+	records := readRecordsForOaiPmh(t,
+		"../../examples/ISO19115/5951efa2-1ff3-4763-a966-a2f5497679ee.xml",
+		"../../examples/ISO19119/dae8f9e3-99af-4d21-9feb-29f2a1693077.xml",
+	)
+
+	var requests []url.Values
+
+	server := buildMockWebserverOaiPmh(t, records, &requests)
+	defer server.Close()
+
+	endpoint, _ := url.Parse(server.URL)
+	oaiClient := NewOaiPmhClient(endpoint)
+	oaiClient.SetCache(t.TempDir(), 1)
+
+	lastHarvest := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	state := &HarvestState{Entries: map[string]HarvestStateEntry{}}
+
+	// First harvest: all records
+	result, err := oaiClient.HarvestIncremental(t.Context(), oaipmh.Selection{}, state, false)
+	require.NoError(t, err)
+	assert.False(t, result.Incremental)
+	assert.Len(t, result.Added, 2)
+	assert.Empty(t, result.Deleted)
+
+	key := server.URL + "?metadataPrefix=iso19139"
+	require.Contains(t, state.Entries, key)
+	assert.Equal(t, []string{"5951efa2-1ff3-4763-a966-a2f5497679ee", "dae8f9e3-99af-4d21-9feb-29f2a1693077"},
+		state.Entries[key].Identifiers)
+
+	// Incremental harvest: only the last record, and a deleted record that was harvested before
+	entry := state.Entries[key]
+	entry.LastHarvest = lastHarvest
+	entry.Identifiers = append(entry.Identifiers, "deleted-uuid")
+	state.Entries[key] = entry
+	requests = nil
+
+	result, err = oaiClient.HarvestIncremental(t.Context(), oaipmh.Selection{}, state, false)
+	require.NoError(t, err)
+	assert.True(t, result.Incremental)
+	assert.Equal(t, []string{"dae8f9e3-99af-4d21-9feb-29f2a1693077"}, result.Updated)
+	assert.Equal(t, []string{"deleted-uuid"}, result.Deleted)
+	assert.Equal(t, 1, result.Unchanged)
+	assert.Equal(t, "2025-01-01T11:00:00Z", requests[1].Get("from"), "from uses the granularity of Identify")
+	assert.NotContains(t, state.Entries[key].Identifiers, "deleted-uuid")
+
+	// A full harvest of a date range does not delete the records outside the range
+	from := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	result, err = oaiClient.HarvestIncremental(t.Context(), oaipmh.Selection{From: &from}, state, true)
+	require.NoError(t, err)
+	assert.False(t, result.Incremental)
+	assert.Empty(t, result.Deleted)
+	assert.Equal(t, []string{"5951efa2-1ff3-4763-a966-a2f5497679ee", "dae8f9e3-99af-4d21-9feb-29f2a1693077"},
+		state.Entries[key].Identifiers)
+
+	// The state can be written and read again
+	statePath := filepath.Join(t.TempDir(), "state.json")
+	require.NoError(t, state.Save(statePath))
+
+	loaded, err := LoadHarvestState(statePath)
+	require.NoError(t, err)
+	assert.Len(t, loaded.Entries[key].Identifiers, 2)
+}
@@ -0,0 +1,227 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/pdok/pdok-metadata-tool/v2/pkg/model/csw"
+	"github.com/pdok/pdok-metadata-tool/v2/pkg/model/iso1911x"
+	"github.com/pdok/pdok-metadata-tool/v2/pkg/model/ogcrecords"
+)
+
+// ErrNoISOLink is returned when a record has no alternate link to its ISO 19139 XML.
+var ErrNoISOLink = errors.New("record has no ISO 19139 alternate link")
+
+// OgcRecordsClient is used as a client for an OGC API Records collection. ISO 19139 records that are
+// retrieved via alternate links are stored in the same on-disk cache as records harvested with CswClient.
+type OgcRecordsClient struct {
+	endpoint   *url.URL // Landing page of the API
+	collection string
+	client     *http.Client
+	cache      *recordCache // nil when caching is disabled
+	limiter    *rateLimiter
+}
+
+// OgcRecordsHarvestResult holds the outcome of harvesting ISO 19139 records from an OGC API Records collection.
+type OgcRecordsHarvestResult struct {
+	Records    []iso1911x.MDMetadata // The ISO records that were retrieved via alternate links
+	WithoutISO []ogcrecords.Record   // Records without an ISO 19139 alternate link
+	Failures   []HarvestFailure
+}
+
+// NewOgcRecordsClient creates a new instance of OgcRecordsClient for a collection of the API at the endpoint,
+// with an HTTP client with DefaultHTTPConfig.
+func NewOgcRecordsClient(endpoint *url.URL, collection string) OgcRecordsClient {
+	return OgcRecordsClient{
+		endpoint:   endpoint,
+		collection: collection,
+		client:     DefaultHTTPClient(),
+	}
+}
+
+// SetHTTPClient sets the HTTP client, see NewHTTPClient.
+func (c *OgcRecordsClient) SetHTTPClient(httpClient *http.Client) {
+	c.client = httpClient
+}
+
+// SetCache enables on-disk caching of ISO 19139 records. ttlHours is the time-to-live expressed in hours.
+func (c *OgcRecordsClient) SetCache(cacheDir string, ttlHours int) {
+	c.cache = newRecordCache(cacheDir, ttlHours, c.endpoint)
+}
+
+// UnsetCache disables caching.
+func (c *OgcRecordsClient) UnsetCache() {
+	c.cache = nil
+}
+
+// SetRateLimit limits the number of requests per second to the endpoint. The limit is shared with other
+// clients for the same host. A value of 0 or less disables rate limiting for this client.
+func (c *OgcRecordsClient) SetRateLimit(requestsPerSecond float64) {
+	if requestsPerSecond <= 0 {
+		c.limiter = nil
+
+		return
+	}
+
+	c.limiter = getEndpointLimiter(c.endpoint, requestsPerSecond)
+}
+
+// GetItemsPage returns the first page of records that match the query.
+func (c *OgcRecordsClient) GetItemsPage(ctx context.Context, query ogcrecords.ItemsQuery) (ogcrecords.ItemCollection, error) {
+	return c.getPage(ctx, c.itemsURL(query))
+}
+
+// GetAllItems returns all records that match the query, following the next links.
+func (c *OgcRecordsClient) GetAllItems(ctx context.Context, query ogcrecords.ItemsQuery) ([]ogcrecords.Record, error) {
+	var result []ogcrecords.Record
+
+	seen := map[string]bool{}
+
+	for pageURL := c.itemsURL(query); pageURL != ""; {
+		if seen[pageURL] {
+			return nil, fmt.Errorf("next link %s was returned twice", pageURL)
+		}
+
+		seen[pageURL] = true
+
+		page, err := c.getPage(ctx, pageURL)
+		if err != nil {
+			return nil, err
+		}
+
+		result = append(result, page.Features...)
+
+		if len(page.Features) == 0 {
+			break
+		}
+
+		pageURL = page.NextLink()
+	}
+
+	return result, nil
+}
+
+// GetISORecord returns the ISO 19139 XML of the record, retrieved via its alternate link.
+// ErrNoISOLink is returned when the record has no such link. The record is cached under its id, which
+// is known before the ISO 19139 XML is retrieved, rather than under the UUID of the XML.
+func (c *OgcRecordsClient) GetISORecord(ctx context.Context, record *ogcrecords.Record) (iso1911x.MDMetadata, error) {
+	if cached, ok, err := c.cache.get(record.ID); err == nil && ok {
+		return csw.UnmarshalMDMetadata(cached)
+	}
+
+	link := record.ISOLink()
+	if link == nil {
+		return iso1911x.MDMetadata{}, ErrNoISOLink
+	}
+
+	href, err := c.endpoint.Parse(link.Href)
+	if err != nil {
+		return iso1911x.MDMetadata{}, err
+	}
+
+	// An expired record is revalidated with a conditional request
+	stale, validators, _ := c.cache.stale(record.ID)
+
+	if err := c.limiter.wait(ctx); err != nil {
+		return iso1911x.MDMetadata{}, err
+	}
+
+	body, header, notModified, err := getConditionalResponse(ctx, href.String(), validators, *c.client)
+	if err != nil {
+		return iso1911x.MDMetadata{}, err
+	}
+
+	if notModified {
+		_ = c.cache.refresh(record.ID, validators, header) // best-effort caching
+
+		return csw.UnmarshalMDMetadata(stale)
+	}
+
+	raws, err := csw.ExtractRawRecords(body)
+	if err != nil {
+		return iso1911x.MDMetadata{}, err
+	}
+
+	if len(raws) != 1 {
+		return iso1911x.MDMetadata{}, fmt.Errorf("expected one gmd:MD_Metadata at %s, found %d", href, len(raws))
+	}
+
+	md, err := csw.UnmarshalMDMetadata(raws[0])
+	if err != nil {
+		return iso1911x.MDMetadata{}, err
+	}
+
+	_ = c.cache.put(record.ID, raws[0], newCacheEntryInfo(href.String(), header)) // best-effort caching
+
+	return md, nil
+}
+
+// HarvestISORecords retrieves the ISO 19139 XML of all records that match the query. Records without
+// an ISO 19139 alternate link are returned separately, so they can be mapped from their core properties.
+func (c *OgcRecordsClient) HarvestISORecords(ctx context.Context, query ogcrecords.ItemsQuery) (OgcRecordsHarvestResult, error) {
+	var result OgcRecordsHarvestResult
+
+	records, err := c.GetAllItems(ctx, query)
+	if err != nil {
+		return result, err
+	}
+
+	for i := range records {
+		if err := ctx.Err(); err != nil {
+			return result, err
+		}
+
+		record := &records[i]
+
+		md, err := c.GetISORecord(ctx, record)
+
+		switch {
+		case errors.Is(err, ErrNoISOLink):
+			result.WithoutISO = append(result.WithoutISO, *record)
+		case err != nil:
+			result.Failures = append(result.Failures, HarvestFailure{
+				Identifier: record.ID,
+				Title:      record.Properties.Title,
+				Err:        err,
+			})
+		default:
+			result.Records = append(result.Records, md)
+		}
+	}
+
+	return result, nil
+}
+
+func (c *OgcRecordsClient) getPage(ctx context.Context, pageURL string) (ogcrecords.ItemCollection, error) {
+	var page ogcrecords.ItemCollection
+
+	if err := c.limiter.wait(ctx); err != nil {
+		return page, err
+	}
+
+	if err := getUnmarshalledJSONResponse(ctx, &page, pageURL, *c.client); err != nil {
+		return page, err
+	}
+
+	return page, nil
+}
+
+// itemsURL returns the URL of the items of the collection. Responses are requested as JSON with f=json,
+// unless another format is given in the extra parameters of the query.
+func (c *OgcRecordsClient) itemsURL(query ogcrecords.ItemsQuery) string {
+	itemsURL := *c.endpoint
+	itemsURL.Path = strings.TrimSuffix(itemsURL.Path, "/") + "/collections/" + url.PathEscape(c.collection) + "/items"
+
+	params := query.QueryParameters()
+	if !params.Has("f") {
+		params.Set("f", "json")
+	}
+
+	itemsURL.RawQuery = params.Encode()
+
+	return itemsURL.String()
+}
@@ -0,0 +1,156 @@
+package client
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"path/filepath"
+	"testing"
+
+	"github.com/pdok/pdok-metadata-tool/v2/pkg/model/iso1911x"
+	"github.com/pdok/pdok-metadata-tool/v2/pkg/model/ogcrecords"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// buildMockWebserverOgcRecords serves the items of collection "main" in pages of one record, using next links.
+// The first record has an ISO 19139 alternate link, the second record only has its core properties.
+func buildMockWebserverOgcRecords(t *testing.T, requests *[]url.Values) *httptest.Server {
+	t.Helper()
+
+	isoRecord, err := readFileToString("../../examples/ISO19115/5951efa2-1ff3-4763-a966-a2f5497679ee.xml")
+	require.NoError(t, err)
+
+	var server *httptest.Server
+
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api/collections/main/items":
+			*requests = append(*requests, r.URL.Query())
+
+			records := []ogcrecords.Record{
+				{
+					ID:         "5951efa2-1ff3-4763-a966-a2f5497679ee",
+					Properties: ogcrecords.RecordProperties{Type: "dataset", Title: "With ISO"},
+					Links: []ogcrecords.Link{
+						{Href: server.URL + "/api/records/1.html", Rel: "alternate", Type: "text/html"},
+						{Href: "/api/records/1.xml", Rel: "alternate", Type: "application/vnd.iso.19139+xml"},
+					},
+				},
+				{
+					ID:         "core-only",
+					Properties: ogcrecords.RecordProperties{Type: "dataset", Title: "Without ISO"},
+				},
+			}
+
+			page := 0
+			if r.URL.Query().Get("page") == "2" {
+				page = 1
+			}
+
+			collection := ogcrecords.ItemCollection{Type: "FeatureCollection", Features: records[page : page+1]}
+			if page == 0 {
+				collection.Links = []ogcrecords.Link{{Href: server.URL + "/api/collections/main/items?f=json&page=2", Rel: "next"}}
+			}
+
+			w.Header().Set("Content-Type", "application/geo+json")
+			_ = json.NewEncoder(w).Encode(collection)
+		case "/api/records/1.xml":
+			w.Header().Set("Content-Type", "application/xml")
+			_, _ = fmt.Fprint(w, isoRecord)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+
+	return server
+}
+
+func TestOgcRecordsClient(t *testing.T) {
+	var requests []url.Values
+
+	server := buildMockWebserverOgcRecords(t, &requests)
+	defer server.Close()
+
+	endpoint, _ := url.Parse(server.URL + "/api/")
+	recordsClient := NewOgcRecordsClient(endpoint, "main")
+
+	cacheDir := t.TempDir()
+	recordsClient.SetCache(cacheDir, 1)
+
+	query := ogcrecords.ItemsQuery{
+		Limit:    1,
+		BBox:     &[4]float64{3.3, 50.7, 7.2, 53.6},
+		Datetime: "2024-01-01T00:00:00Z/..",
+		Filter:   "title LIKE '%kaart%'",
+	}
+
+	t.Run("GetAllItems", func(t *testing.T) {
+		requests = nil
+
+		records, err := recordsClient.GetAllItems(t.Context(), query)
+		require.NoError(t, err)
+		require.Len(t, records, 2)
+		assert.Equal(t, "core-only", records[1].ID)
+
+		require.Len(t, requests, 2)
+		assert.Equal(t, url.Values{
+			"f":           {"json"},
+			"limit":       {"1"},
+			"bbox":        {"3.3,50.7,7.2,53.6"},
+			"datetime":    {"2024-01-01T00:00:00Z/.."},
+			"filter":      {"title LIKE '%kaart%'"},
+			"filter-lang": {"cql2-text"},
+		}, requests[0])
+		assert.Equal(t, url.Values{"f": {"json"}, "page": {"2"}}, requests[1], "next link is followed as is")
+	})
+
+	t.Run("HarvestISORecords", func(t *testing.T) {
+		result, err := recordsClient.HarvestISORecords(t.Context(), query)
+		require.NoError(t, err)
+		require.Len(t, result.Records, 1)
+		assert.Equal(t, "5951efa2-1ff3-4763-a966-a2f5497679ee", iso1911x.NormalizeXMLText(result.Records[0].UUID))
+		require.Len(t, result.WithoutISO, 1)
+		assert.Equal(t, "Without ISO", result.WithoutISO[0].Properties.Title)
+		assert.Empty(t, result.Failures)
+
+		assert.FileExists(t, filepath.Join(cacheDir, CacheNamespace(endpoint), "5951efa2-1ff3-4763-a966-a2f5497679ee.xml"))
+	})
+}
+
+func TestOgcRecordsClient_GetISORecord_CachedByID(t *testing.T) {
+	isoRecord, err := readFileToString("../../examples/ISO19115/5951efa2-1ff3-4763-a966-a2f5497679ee.xml")
+	require.NoError(t, err)
+
+	isoRequests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		isoRequests++
+
+		w.Header().Set("Content-Type", "application/xml")
+		_, _ = fmt.Fprint(w, isoRecord)
+	}))
+	defer server.Close()
+
+	endpoint, _ := url.Parse(server.URL + "/api/")
+	recordsClient := NewOgcRecordsClient(endpoint, "main")
+
+	cacheDir := t.TempDir()
+	recordsClient.SetCache(cacheDir, 1)
+
+	// The id of the record differs from the UUID in its ISO 19139 XML
+	record := &ogcrecords.Record{
+		ID:    "record-1",
+		Links: []ogcrecords.Link{{Href: "/api/records/1.xml", Rel: "alternate", Type: "application/vnd.iso.19139+xml"}},
+	}
+
+	for range 2 {
+		md, err := recordsClient.GetISORecord(t.Context(), record)
+		require.NoError(t, err)
+		assert.Equal(t, "5951efa2-1ff3-4763-a966-a2f5497679ee", iso1911x.NormalizeXMLText(md.UUID))
+	}
+
+	assert.Equal(t, 1, isoRequests, "second call is served from the cache")
+	assert.FileExists(t, filepath.Join(cacheDir, CacheNamespace(endpoint), "record-1.xml"))
+}
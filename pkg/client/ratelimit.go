@@ -0,0 +1,102 @@
+package client
+
+import (
+	"context"
+	"net/url"
+	"slices"
+	"sync"
+	"time"
+)
+
+// rateLimiter spaces requests to an endpoint at a minimum interval.
+type rateLimiter struct {
+	mu       sync.Mutex
+	interval time.Duration
+	next     time.Time
+	released []slot
+}
+
+// slot is the period reserved for one request.
+type slot struct {
+	start, end time.Time
+}
+
+// endpointLimiters holds one rateLimiter per endpoint host, shared by all clients in this process.
+var endpointLimiters = struct {
+	sync.Mutex
+	byHost map[string]*rateLimiter
+}{byHost: map[string]*rateLimiter{}}
+
+// getEndpointLimiter returns the shared rateLimiter for the host of the endpoint,
+// allowing at most requestsPerSecond requests.
+func getEndpointLimiter(endpoint *url.URL, requestsPerSecond float64) *rateLimiter {
+	endpointLimiters.Lock()
+	defer endpointLimiters.Unlock()
+
+	limiter, ok := endpointLimiters.byHost[endpoint.Host]
+	if !ok {
+		limiter = &rateLimiter{}
+		endpointLimiters.byHost[endpoint.Host] = limiter
+	}
+
+	limiter.mu.Lock()
+	limiter.interval = time.Duration(float64(time.Second) / requestsPerSecond)
+	limiter.mu.Unlock()
+
+	return limiter
+}
+
+// wait blocks until the next request to the endpoint is allowed, or until the context is done, in which case
+// the error of the context is returned. A nil limiter does not wait.
+func (l *rateLimiter) wait(ctx context.Context) error {
+	if l == nil {
+		return ctx.Err()
+	}
+
+	l.mu.Lock()
+
+	now := time.Now()
+	if l.next.Before(now) {
+		l.next = now
+		l.released = nil
+	}
+
+	reserved := slot{start: l.next, end: l.next.Add(l.interval)}
+	l.next = reserved.end
+
+	l.mu.Unlock()
+
+	timer := time.NewTimer(reserved.start.Sub(now))
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		l.release(reserved)
+
+		return ctx.Err()
+	}
+}
+
+// release gives back the slot of a request that was not sent. Only the last slot can be given back, since
+// the slots after it are already promised to other requests. Other slots are kept until the slots after
+// them are released as well.
+func (l *rateLimiter) release(reserved slot) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.released = append(l.released, reserved)
+
+	for i := 0; i < len(l.released); {
+		if l.released[i].end.Equal(l.next) {
+			l.next = l.released[i].start
+			l.released = slices.Delete(l.released, i, i+1)
+			i = 0
+
+			continue
+		}
+
+		i++
+	}
+}
@@ -0,0 +1,181 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/pdok/pdok-metadata-tool/v2/internal/common"
+	"github.com/pdok/pdok-metadata-tool/v2/pkg/model/csw"
+)
+
+// SetTransactionEndpoint sets the endpoint for CSW Transaction requests, for catalogues that expose
+// transactions on a separate (publication) endpoint. By default the CSW endpoint is used.
+func (c *CswClient) SetTransactionEndpoint(endpoint *url.URL) {
+	c.transactionEndpoint = endpoint
+}
+
+// SetCredentials sets the username and password that are sent with basic authentication
+// in CSW Transaction requests.
+func (c *CswClient) SetCredentials(username string, password string) {
+	c.username = username
+	c.password = password
+}
+
+// Transaction executes a CSW Transaction. An ExceptionReport of the catalogue is returned
+// as *csw.ExceptionReport error, other unsuccessful responses as *HTTPError.
+//
+// Transactions are not idempotent, so they are only retried when the catalogue answers 429 or 503.
+func (c *CswClient) Transaction(ctx context.Context, transaction *csw.Transaction) (csw.TransactionResponse, error) {
+	requestBody, err := transaction.ToRequestBody()
+	if err != nil {
+		return csw.TransactionResponse{}, err
+	}
+
+	endpoint := c.endpoint
+	if c.transactionEndpoint != nil {
+		endpoint = c.transactionEndpoint
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint.String(), strings.NewReader(requestBody))
+	if err != nil {
+		return csw.TransactionResponse{}, err
+	}
+
+	req.Header.Set("User-Agent", "pdok.nl (pdok-metadata-tool)")
+	req.Header.Set("Content-Type", "application/xml")
+
+	if c.username != "" {
+		req.SetBasicAuth(c.username, c.password)
+	}
+
+	if err := c.limiter.wait(ctx); err != nil {
+		return csw.TransactionResponse{}, err
+	}
+
+	//nolint:bodyclose // We use common.SafeClose to handle closing the response body
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return csw.TransactionResponse{}, fmt.Errorf("error while calling CSW using url %s: %w", endpoint, err)
+	}
+	defer common.SafeClose(resp.Body)
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return csw.TransactionResponse{}, err
+	}
+
+	response, err := csw.ParseTransactionResponse(body)
+
+	var report *csw.ExceptionReport
+	if resp.StatusCode != http.StatusOK && !errors.As(err, &report) {
+		return csw.TransactionResponse{}, newHTTPErrorWithBody(resp, body)
+	}
+
+	return response, err
+}
+
+// InsertRecords inserts records (raw MD_Metadata XML documents) and returns the inserted identifiers.
+func (c *CswClient) InsertRecords(ctx context.Context, records ...[]byte) ([]string, error) {
+	response, err := c.Transaction(ctx, &csw.Transaction{Actions: []csw.TransactionAction{
+		csw.Insert{Records: records},
+	}})
+	if err != nil {
+		return nil, err
+	}
+
+	if response.Summary.TotalInserted != len(records) {
+		return response.Identifiers(), fmt.Errorf(
+			"inserted %d of %d records", response.Summary.TotalInserted, len(records))
+	}
+
+	return response.Identifiers(), nil
+}
+
+// UpdateRecord replaces a record (raw MD_Metadata XML document) with the same identifier.
+// The cached copy of the record is removed.
+func (c *CswClient) UpdateRecord(ctx context.Context, record []byte) error {
+	md, err := csw.UnmarshalMDMetadata(record)
+	if err != nil {
+		return fmt.Errorf("failed to read record: %w", err)
+	}
+
+	response, err := c.Transaction(ctx, &csw.Transaction{Actions: []csw.TransactionAction{
+		csw.Update{Record: record},
+	}})
+	if err != nil {
+		return err
+	}
+
+	c.cache.remove(md.UUID)
+
+	if response.Summary.TotalUpdated != 1 {
+		return fmt.Errorf("record %s was not updated", md.UUID)
+	}
+
+	return nil
+}
+
+// UpdateRecordProperties sets properties of the records that match the filter and returns
+// the number of updated records. The cached copies of the records are removed.
+func (c *CswClient) UpdateRecordProperties(ctx context.Context, filter csw.Filter, properties ...csw.RecordProperty) (int, error) {
+	cached, err := c.matchingCachedRecords(ctx, filter)
+	if err != nil {
+		return 0, err
+	}
+
+	response, err := c.Transaction(ctx, &csw.Transaction{Actions: []csw.TransactionAction{
+		csw.UpdateProperties{Properties: properties, Filter: filter},
+	}})
+	c.removeFromCache(cached)
+
+	return response.Summary.TotalUpdated, err
+}
+
+// DeleteRecords deletes the records that match the filter and returns the number of deleted records.
+// The cached copies of the records are removed.
+func (c *CswClient) DeleteRecords(ctx context.Context, filter csw.Filter) (int, error) {
+	cached, err := c.matchingCachedRecords(ctx, filter)
+	if err != nil {
+		return 0, err
+	}
+
+	response, err := c.Transaction(ctx, &csw.Transaction{Actions: []csw.TransactionAction{
+		csw.Delete{Filter: filter},
+	}})
+	c.removeFromCache(cached)
+
+	return response.Summary.TotalDeleted, err
+}
+
+// matchingCachedRecords returns the identifiers of the records that match the filter, so their cached copies
+// can be removed after a transaction. Without a cache, the records are not listed.
+func (c *CswClient) matchingCachedRecords(ctx context.Context, filter csw.Filter) ([]string, error) {
+	if c.cache == nil {
+		return nil, nil
+	}
+
+	records, err := c.GetAllRecordsByFilter(ctx, filter)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list the records to remove from the cache: %w", err)
+	}
+
+	identifiers := make([]string, 0, len(records))
+	for _, record := range records {
+		identifiers = append(identifiers, record.Identifier)
+	}
+
+	return identifiers, nil
+}
+
+// removeFromCache removes the cached copies of the records. This is also done after a failed transaction,
+// as some of the records may have been changed.
+func (c *CswClient) removeFromCache(identifiers []string) {
+	for _, id := range identifiers {
+		c.cache.remove(id)
+	}
+}
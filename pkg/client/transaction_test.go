@@ -0,0 +1,241 @@
+package client
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/pdok/pdok-metadata-tool/v2/pkg/model/csw"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// transactionRequest is the part of a CSW Transaction that the stand-in catalogue understands.
+type transactionRequest struct {
+	Inserts []struct {
+		Records []struct {
+			UUID string `xml:"fileIdentifier>CharacterString"`
+		} `xml:"MD_Metadata"`
+	} `xml:"Insert"`
+	Updates []struct {
+		Record *struct {
+			UUID string `xml:"fileIdentifier>CharacterString"`
+		} `xml:"MD_Metadata"`
+		Properties []string `xml:"RecordProperty>Name"`
+		Identifier string   `xml:"Constraint>Filter>PropertyIsEqualTo>Literal"`
+	} `xml:"Update"`
+	Deletes []struct {
+		Identifier string `xml:"Constraint>Filter>PropertyIsEqualTo>Literal"`
+	} `xml:"Delete"`
+}
+
+// buildMockWebserverTransaction is an in-memory catalogue with the given records that supports CSW Transactions
+// with basic authentication and GetRecords. Filters are only supported on the identifier.
+func buildMockWebserverTransaction(records map[string]bool) *httptest.Server {
+	writeSummary := func(w http.ResponseWriter, inserted []string, updated, deleted int) {
+		w.Header().Set("Content-Type", "application/xml")
+
+		briefRecords := ""
+		for _, id := range inserted {
+			briefRecords += fmt.Sprintf(`<csw:BriefRecord><dc:identifier>%s</dc:identifier></csw:BriefRecord>`, id)
+		}
+
+		_, _ = fmt.Fprintf(w, `<?xml version="1.0" encoding="UTF-8"?>
+<csw:TransactionResponse xmlns:csw="http://www.opengis.net/cat/csw/2.0.2" xmlns:dc="http://purl.org/dc/elements/1.1/" version="2.0.2">
+  <csw:TransactionSummary>
+    <csw:totalInserted>%d</csw:totalInserted>
+    <csw:totalUpdated>%d</csw:totalUpdated>
+    <csw:totalDeleted>%d</csw:totalDeleted>
+  </csw:TransactionSummary>
+  <csw:InsertResult>%s</csw:InsertResult>
+</csw:TransactionResponse>`, len(inserted), updated, deleted, briefRecords)
+	}
+	writeException := func(w http.ResponseWriter, status int, code string, text string) {
+		w.WriteHeader(status)
+		_, _ = fmt.Fprintf(w, `<ows:ExceptionReport xmlns:ows="http://www.opengis.net/ows" version="1.2.0">
+  <ows:Exception exceptionCode="%s" locator="transaction"><ows:ExceptionText>%s</ows:ExceptionText></ows:Exception>
+</ows:ExceptionReport>`, code, text)
+	}
+
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if query := r.URL.Query(); query.Get("request") == "GetRecords" {
+			var matched string
+			for id := range records {
+				if strings.Contains(query.Get("constraint"), id) {
+					matched += fmt.Sprintf(`<csw:SummaryRecord><dc:identifier>%s</dc:identifier></csw:SummaryRecord>`, id)
+				}
+			}
+
+			_, _ = fmt.Fprintf(w, `<csw:GetRecordsResponse xmlns:csw="http://www.opengis.net/cat/csw/2.0.2" `+
+				`xmlns:dc="http://purl.org/dc/elements/1.1/"><csw:SearchResults numberOfRecordsMatched="%d" `+
+				`nextRecord="0">%s</csw:SearchResults></csw:GetRecordsResponse>`, strings.Count(matched, "<csw:Summ"), matched)
+
+			return
+		}
+
+		if user, password, ok := r.BasicAuth(); !ok || user != "editor" || password != "secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+
+			return
+		}
+
+		body, _ := io.ReadAll(r.Body)
+
+		var request transactionRequest
+		if err := xml.Unmarshal(body, &request); err != nil { //nolint:musttag
+			writeException(w, http.StatusBadRequest, "InvalidParameterValue", err.Error())
+
+			return
+		}
+
+		var (
+			inserted         []string
+			updated, deleted int
+		)
+
+		for _, insert := range request.Inserts {
+			for _, record := range insert.Records {
+				if records[record.UUID] {
+					writeException(w, http.StatusOK, "NoApplicableCode", "duplicate record "+record.UUID)
+
+					return
+				}
+
+				records[record.UUID] = true
+				inserted = append(inserted, record.UUID)
+			}
+		}
+
+		for _, update := range request.Updates {
+			id := update.Identifier
+			if update.Record != nil {
+				id = update.Record.UUID
+			}
+
+			if records[id] {
+				updated++
+			}
+		}
+
+		for _, del := range request.Deletes {
+			if records[del.Identifier] {
+				delete(records, del.Identifier)
+				deleted++
+			}
+		}
+
+		writeSummary(w, inserted, updated, deleted)
+	}))
+}
+
+func TestCswClient_Transaction(t *testing.T) {
+	const (
+		existingID = "5951efa2-1ff3-4763-a966-a2f5497679ee"
+		missingID  = "00000000-0000-0000-0000-000000000000"
+	)
+
+	record, err := os.ReadFile("../../examples/ISO19115/5951efa2-1ff3-4763-a966-a2f5497679ee.xml")
+	require.NoError(t, err)
+
+	newRecord, err := os.ReadFile("../../examples/ISO19119/dae8f9e3-99af-4d21-9feb-29f2a1693077.xml")
+	require.NoError(t, err)
+
+	byID := func(id string) csw.Filter {
+		return csw.Comparison{Property: "Identifier", Operator: csw.Equal, Value: id}
+	}
+
+	t.Run("Insert", func(t *testing.T) {
+		server := buildMockWebserverTransaction(map[string]bool{existingID: true})
+		defer server.Close()
+
+		cswClient := getCswClient(t, server)
+		cswClient.SetCredentials("editor", "secret")
+
+		identifiers, err := cswClient.InsertRecords(t.Context(), newRecord)
+		require.NoError(t, err)
+		assert.Equal(t, []string{"dae8f9e3-99af-4d21-9feb-29f2a1693077"}, identifiers)
+
+		_, err = cswClient.InsertRecords(t.Context(), record)
+
+		var report *csw.ExceptionReport
+		require.ErrorAs(t, err, &report)
+		assert.Equal(t, "NoApplicableCode", report.Exceptions[0].Code)
+		assert.EqualError(t, err, "CSW exception: NoApplicableCode (transaction): duplicate record "+existingID)
+	})
+
+	t.Run("Update full record", func(t *testing.T) {
+		server := buildMockWebserverTransaction(map[string]bool{existingID: true})
+		defer server.Close()
+
+		cswClient := getCswClient(t, server)
+		cswClient.SetCredentials("editor", "secret")
+		cswClient.SetCache(t.TempDir(), 1)
+		require.NoError(t, cswClient.cache.put(existingID, record, CacheEntryInfo{}))
+
+		require.NoError(t, cswClient.UpdateRecord(t.Context(), record))
+		assert.NoFileExists(t, cswClient.cache.path(existingID))
+		assert.NoFileExists(t, cswClient.cache.infoPath(existingID))
+
+		require.EqualError(t, cswClient.UpdateRecord(t.Context(), newRecord),
+			"record dae8f9e3-99af-4d21-9feb-29f2a1693077 was not updated")
+	})
+
+	t.Run("Update record property", func(t *testing.T) {
+		server := buildMockWebserverTransaction(map[string]bool{existingID: true})
+		defer server.Close()
+
+		cswClient := getCswClient(t, server)
+		cswClient.SetCredentials("editor", "secret")
+		cswClient.SetCache(t.TempDir(), 1)
+		require.NoError(t, cswClient.cache.put(existingID, record, CacheEntryInfo{}))
+
+		updated, err := cswClient.UpdateRecordProperties(t.Context(), byID(existingID),
+			csw.RecordProperty{Name: "dc:title", Value: "New title"})
+		require.NoError(t, err)
+		assert.Equal(t, 1, updated)
+		assert.NoFileExists(t, cswClient.cache.path(existingID))
+
+		_, err = cswClient.UpdateRecordProperties(t.Context(), byID(existingID))
+		require.EqualError(t, err, "update has no properties")
+	})
+
+	t.Run("Delete with filter", func(t *testing.T) {
+		records := map[string]bool{existingID: true}
+		server := buildMockWebserverTransaction(records)
+		defer server.Close()
+
+		cswClient := getCswClient(t, server)
+		cswClient.SetCredentials("editor", "secret")
+		cswClient.SetCache(t.TempDir(), 1)
+		require.NoError(t, cswClient.cache.put(existingID, record, CacheEntryInfo{}))
+
+		deleted, err := cswClient.DeleteRecords(t.Context(), byID(missingID))
+		require.NoError(t, err)
+		assert.Equal(t, 0, deleted)
+		assert.FileExists(t, cswClient.cache.path(existingID))
+
+		deleted, err = cswClient.DeleteRecords(t.Context(), byID(existingID))
+		require.NoError(t, err)
+		assert.Equal(t, 1, deleted)
+		assert.Empty(t, records)
+		assert.NoFileExists(t, cswClient.cache.path(existingID))
+	})
+
+	t.Run("Separate transaction endpoint without credentials", func(t *testing.T) {
+		server := buildMockWebserverTransaction(map[string]bool{})
+		defer server.Close()
+
+		cswClient := NewCswClient(&url.URL{Scheme: "http", Host: "csw.invalid"})
+		transactionURL, _ := url.Parse(server.URL)
+		cswClient.SetTransactionEndpoint(transactionURL)
+
+		_, err := cswClient.DeleteRecords(t.Context(), byID(existingID))
+		require.ErrorContains(t, err, "http status is 401")
+	})
+}
@@ -1,7 +1,7 @@
 package client
 
 import (
-	"bytes"
+	"context"
 	"encoding/json"
 	"encoding/xml"
 	"errors"
@@ -19,13 +19,14 @@ const (
 )
 
 func getUnmarshalledXMLResponse(
+	ctx context.Context,
 	resultStruct any,
 	url string,
 	method string,
 	requestBody *string,
 	client http.Client,
 ) error {
-	responseBody, err := getResponseBody(url, method, requestBody, client)
+	responseBody, err := getResponseBody(ctx, url, method, requestBody, client)
 	if err != nil {
 		return err
 	}
@@ -38,8 +39,39 @@ func getUnmarshalledXMLResponse(
 	return nil
 }
 
-func getUnmarshalledJSONResponse(resultStruct any, url string, client http.Client) error {
-	responseBody, err := getResponseBody(url, "GET", nil, client)
+// getUnmarshalledXMLQueryResponse is getUnmarshalledXMLResponse for a POST request that only queries, like a
+// CSW GetRecords request. Unlike other POST requests, it is marked as idempotent, so it is retried after
+// network errors and 5xx responses.
+func getUnmarshalledXMLQueryResponse(
+	ctx context.Context,
+	resultStruct any,
+	url string,
+	requestBody string,
+	client http.Client,
+) error {
+	req, err := newRequest(ctx, url, http.MethodPost, &requestBody)
+	if err != nil {
+		return err
+	}
+
+	// A nil Idempotency-Key marks the request as idempotent without sending the header
+	req.Header["Idempotency-Key"] = nil
+
+	responseBody, _, _, err := sendRequest(req, client)
+	if err != nil {
+		return err
+	}
+
+	err = xml.Unmarshal(responseBody, resultStruct)
+	if err != nil {
+		return fmt.Errorf("error unmarshalling NGR response from url %s: %w", url, err)
+	}
+
+	return nil
+}
+
+func getUnmarshalledJSONResponse(ctx context.Context, resultStruct any, url string, client http.Client) error {
+	responseBody, err := getResponseBody(ctx, url, "GET", nil, client)
 	if err != nil {
 		return err
 	}
@@ -53,92 +85,104 @@ func getUnmarshalledJSONResponse(resultStruct any, url string, client http.Clien
 }
 
 func getResponseBody(
+	ctx context.Context,
 	url string,
 	method string,
 	requestBody *string,
 	client http.Client,
 ) ([]byte, error) {
-	var body io.Reader
-	if method == "POST" && requestBody != nil {
-		body = strings.NewReader(*requestBody)
+	body, _, err := getResponse(ctx, url, method, requestBody, client)
+
+	return body, err
+}
+
+// getResponse returns the body and the headers of a successful response.
+func getResponse(
+	ctx context.Context,
+	url string,
+	method string,
+	requestBody *string,
+	client http.Client,
+) ([]byte, http.Header, error) {
+	req, err := newRequest(ctx, url, method, requestBody)
+	if err != nil {
+		return nil, nil, err
 	}
 
-	req, _ := http.NewRequest(method, url, body)
+	body, header, _, err := sendRequest(req, client)
 
-	req.Header.Set("User-Agent", "pdok.nl (pdok-metadata-tool)")
-	req.Header.Set("Accept", "*/*;q=0.8,application/signed-exchange")
-	req.Header.Set("Content-Type", "application/xml")
+	return body, header, err
+}
 
-	//nolint:bodyclose // We use common.SafeClose to handle closing the response body
-	resp, err := client.Do(req)
+// getConditionalResponse is getResponse for a GET request with the validators (ETag, Last-Modified) of a
+// previous response, when given. notModified is true when the server answers 304 Not Modified; the body
+// is then empty and the previous response is still valid.
+func getConditionalResponse(
+	ctx context.Context,
+	url string,
+	validators *CacheEntryInfo,
+	client http.Client,
+) (body []byte, header http.Header, notModified bool, err error) {
+	req, err := newRequest(ctx, url, http.MethodGet, nil)
 	if err != nil {
-		return nil, fmt.Errorf("error while calling NGR using url %s: %w", url, err)
+		return nil, nil, false, err
 	}
-	defer common.SafeClose(resp.Body)
-
-	if resp.StatusCode != http.StatusOK {
-		bodyBytes, _ := io.ReadAll(resp.Body)
-		bodyStr := string(bodyBytes)
 
-		fmt.Println(bodyStr)
+	if validators != nil {
+		if validators.ETag != "" {
+			req.Header.Set("If-None-Match", validators.ETag)
+		}
 
-		return nil, fmt.Errorf(
-			"error while calling NGR using url %s\nhttp status is %d",
-			url,
-			resp.StatusCode,
-		)
+		if validators.LastModified != "" {
+			req.Header.Set("If-Modified-Since", validators.LastModified)
+		}
 	}
 
-	return io.ReadAll(resp.Body)
+	return sendRequest(req, client)
 }
 
-func getNgrResponseBody(
-	ngrConfig *NgrConfig,
-	url string,
-	method string,
-	requestBody *string,
-	client http.Client,
-	contentType string,
-) ([]byte, error) {
-	var req *http.Request
-	if requestBody != nil {
-		req, _ = http.NewRequest(method, url, bytes.NewBufferString(*requestBody))
-	} else {
-		req, _ = http.NewRequest(method, url, nil)
+func newRequest(ctx context.Context, url string, method string, requestBody *string) (*http.Request, error) {
+	var body io.Reader
+	if method == "POST" && requestBody != nil {
+		body = strings.NewReader(*requestBody)
 	}
 
-	xsrfToken, err := obtainXSRFToken(ngrConfig)
+	req, err := http.NewRequestWithContext(ctx, method, url, body)
 	if err != nil {
-		return nil, fmt.Errorf("failed to obtain XSRF token: %w", err)
+		return nil, fmt.Errorf("invalid request to %s: %w", url, err)
 	}
 
-	setXsrfToken(req, xsrfToken)
-
-	username := ngrConfig.NgrUserName
-	password := ngrConfig.NgrPassword
-	req.SetBasicAuth(*username, *password)
-
 	req.Header.Set("User-Agent", "pdok.nl (pdok-metadata-tool)")
 	req.Header.Set("Accept", "*/*;q=0.8,application/signed-exchange")
-	req.Header.Set("Content-Type", contentType)
+	req.Header.Set("Content-Type", "application/xml")
+
+	return req, nil
+}
+
+// sendRequest returns the body and headers of a successful response. A 304 Not Modified response to a
+// conditional request is successful too (notModified). Other responses result in an *HTTPError.
+func sendRequest(req *http.Request, client http.Client) (body []byte, header http.Header, notModified bool, err error) {
+	url := req.URL.String()
 
 	//nolint:bodyclose // We use common.SafeClose to handle closing the response body
 	resp, err := client.Do(req)
 	if err != nil {
-		return nil, fmt.Errorf("error while calling NGR using url %s: %w", url, err)
+		return nil, nil, false, fmt.Errorf("request to %s failed: %w", url, err)
 	}
 	defer common.SafeClose(resp.Body)
 
-	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusCreated &&
-		resp.StatusCode != http.StatusNoContent {
-		return nil, fmt.Errorf(
-			"error while calling NGR using url %s\nhttp status is %d",
-			url,
-			resp.StatusCode,
-		)
+	conditional := req.Header.Get("If-None-Match") != "" || req.Header.Get("If-Modified-Since") != ""
+	if resp.StatusCode == http.StatusNotModified && conditional {
+		return nil, resp.Header, true, nil
 	}
 
-	return io.ReadAll(resp.Body)
+	if resp.StatusCode != http.StatusOK {
+		return nil, nil, false, newHTTPError(resp)
+	}
+
+	body, err = io.ReadAll(resp.Body)
+
+	return body, resp.Header, false, err
 }
 
 func setXsrfToken(req *http.Request, xsrfToken string) {
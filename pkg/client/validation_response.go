@@ -34,3 +34,27 @@ type MetadataError struct {
 	Date     time.Time `json:"date"`
 	Stack    string    `json:"stack"`
 }
+
+// ProcessingReport is the report of NGR on an operation on a batch of records, such as a validation or a
+// change of the owner or privileges.
+type ProcessingReport = ValidationResult
+
+// merge adds the counts and errors of the report of another batch.
+func (r *ValidationResult) merge(other ValidationResult) {
+	r.Metadata = append(r.Metadata, other.Metadata...)
+	r.NumberOfNullRecords += other.NumberOfNullRecords
+	r.NumberOfRecordsProcessed += other.NumberOfRecordsProcessed
+	r.NumberOfRecordsUnchanged += other.NumberOfRecordsUnchanged
+	r.NumberOfRecordsWithErrors += other.NumberOfRecordsWithErrors
+	r.NumberOfRecordNotFound += other.NumberOfRecordNotFound
+	r.NumberOfRecordsNotEditable += other.NumberOfRecordsNotEditable
+	r.NumberOfRecords += other.NumberOfRecords
+
+	for id, errs := range other.MetadataErrors {
+		if r.MetadataErrors == nil {
+			r.MetadataErrors = map[string][]MetadataError{}
+		}
+
+		r.MetadataErrors[id] = append(r.MetadataErrors[id], errs...)
+	}
+}
@@ -2,6 +2,7 @@
 package iso19119
 
 import (
+	"context"
 	"fmt"
 	"net/url"
 	"strings"
@@ -536,7 +537,7 @@ func (g *Generator) setIdentificationInfo() error {
 
 		var keywordTags []iso1911x.KeywordTag
 
-		filteredHvdCategories, err := g.HVDRepository.GetFilteredHvdCategories(hvdCategories)
+		filteredHvdCategories, err := g.HVDRepository.GetFilteredHvdCategories(context.Background(), hvdCategories)
 		if err != nil {
 			return err
 		}
@@ -0,0 +1,325 @@
+// Package mef reads and writes MEF (Metadata Exchange Format) archives, the zip archives that GeoNetwork uses
+// for backups and migrations of metadata records. Archives are written as MEF version 2, with a directory per
+// record; version 1 archives, with a single record in the root, can be read as well.
+package mef
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"slices"
+	"strings"
+	"time"
+)
+
+// Layout of a record in an archive. In MEF version 1, the record directory is the root of the archive.
+const (
+	MetadataFile = "metadata.xml"
+	InfoFile     = "info.xml"
+	metadataDir  = "metadata"
+	publicDir    = "public"
+	privateDir   = "private"
+)
+
+// InfoVersion is the version of the info.xml files that are written.
+const InfoVersion = "1.1"
+
+// Values of the general information of a record.
+const (
+	SchemaISO19139 = "iso19139"
+	FormatFull     = "full"
+)
+
+// Record is a metadata record in a MEF archive, with its attachments.
+type Record struct {
+	Metadata []byte // The metadata record as XML
+	Info     Info
+	Public   []File // Attachments that everyone can download, such as thumbnails
+	Private  []File // Attachments that only users with download privileges can download
+}
+
+// File is an attachment of a record.
+type File struct {
+	Name       string
+	ChangeDate string
+	Content    []byte
+}
+
+// Info is the info.xml of a record: general information, the categories and the privileges of groups.
+type Info struct {
+	XMLName    xml.Name   `xml:"info"`
+	Version    string     `xml:"version,attr"`
+	General    General    `xml:"general"`
+	Categories []Category `xml:"categories>category"`
+	Privileges []Group    `xml:"privileges>group"`
+	Public     []FileInfo `xml:"public>file"`
+	Private    []FileInfo `xml:"private>file"`
+}
+
+// General is the general information of a record in info.xml.
+type General struct {
+	CreateDate string `xml:"createDate,omitempty"`
+	ChangeDate string `xml:"changeDate,omitempty"`
+	Schema     string `xml:"schema"`
+	IsTemplate string `xml:"isTemplate,omitempty"`
+	LocalID    string `xml:"localId,omitempty"`
+	Format     string `xml:"format"`
+	UUID       string `xml:"uuid"`
+	SiteID     string `xml:"siteId,omitempty"`
+	SiteName   string `xml:"siteName,omitempty"`
+}
+
+// Category is a category of a record, by name.
+type Category struct {
+	Name string `xml:"name,attr"`
+}
+
+// Group holds the operations that a group, by name, is allowed to do on a record.
+type Group struct {
+	Name       string      `xml:"name,attr"`
+	Operations []Operation `xml:"operation"`
+}
+
+// Operation is an operation on a record, by name, e.g. view or download.
+type Operation struct {
+	Name string `xml:"name,attr"`
+}
+
+// FileInfo describes an attachment in info.xml.
+type FileInfo struct {
+	Name       string `xml:"name,attr"`
+	ChangeDate string `xml:"changeDate,attr,omitempty"`
+}
+
+// UUID returns the UUID of the record.
+func (r *Record) UUID() string {
+	return r.Info.General.UUID
+}
+
+// Write writes the records to a MEF version 2 archive. The file list of info.xml is derived from the attachments.
+func Write(w io.Writer, records []Record) error {
+	zw := zip.NewWriter(w)
+
+	for _, record := range records {
+		if err := writeRecord(zw, record); err != nil {
+			return errors.Join(err, zw.Close())
+		}
+	}
+
+	return zw.Close()
+}
+
+// WriteFile writes the records to a MEF version 2 archive at the path.
+func WriteFile(filename string, records []Record) (err error) {
+	file, err := os.Create(filename)
+	if err != nil {
+		return err
+	}
+
+	defer func() {
+		if closeErr := file.Close(); err == nil {
+			err = closeErr
+		}
+	}()
+
+	return Write(file, records)
+}
+
+// Read reads the records of a MEF archive of version 1 or 2.
+func Read(r io.ReaderAt, size int64) ([]Record, error) {
+	zr, err := zip.NewReader(r, size)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read MEF archive: %w", err)
+	}
+
+	// Group the files by record directory; in version 1, that is the root
+	var dirs []string
+
+	files := map[string][]archiveFile{}
+
+	for _, file := range zr.File {
+		if file.FileInfo().IsDir() {
+			continue
+		}
+
+		name := path.Clean(strings.TrimPrefix(file.Name, "/"))
+
+		dir, rest, found := strings.Cut(name, "/")
+		if !found || dir == metadataDir || dir == publicDir || dir == privateDir {
+			dir, rest = "", name
+		}
+
+		if _, ok := files[dir]; !ok {
+			dirs = append(dirs, dir)
+		}
+
+		files[dir] = append(files[dir], archiveFile{name: rest, file: file})
+	}
+
+	records := make([]Record, 0, len(dirs))
+
+	for _, dir := range dirs {
+		record, err := readRecord(dir, files[dir])
+		if err != nil {
+			return nil, err
+		}
+
+		records = append(records, record)
+	}
+
+	return records, nil
+}
+
+// ReadFile reads the records of a MEF archive at the path.
+func ReadFile(filename string) ([]Record, error) {
+	//nolint:gosec
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	return Read(bytes.NewReader(data), int64(len(data)))
+}
+
+func writeRecord(zw *zip.Writer, record Record) error {
+	uuid := record.UUID()
+	if uuid == "" {
+		return errors.New("record without UUID in info")
+	}
+
+	info := record.Info
+	info.Version = InfoVersion
+	info.Public = fileInfos(record.Public)
+	info.Private = fileInfos(record.Private)
+
+	infoXML, err := xml.MarshalIndent(info, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	if err := writeZipFile(zw, path.Join(uuid, metadataDir, MetadataFile), record.Metadata); err != nil {
+		return err
+	}
+
+	if err := writeZipFile(zw, path.Join(uuid, InfoFile), append([]byte(xml.Header), infoXML...)); err != nil {
+		return err
+	}
+
+	for _, file := range record.Public {
+		if err := writeZipFile(zw, path.Join(uuid, publicDir, path.Base(file.Name)), file.Content); err != nil {
+			return err
+		}
+	}
+
+	for _, file := range record.Private {
+		if err := writeZipFile(zw, path.Join(uuid, privateDir, path.Base(file.Name)), file.Content); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// archiveFile is a file in an archive, with its name relative to the directory of its record.
+type archiveFile struct {
+	name string
+	file *zip.File
+}
+
+// readRecord reads a record from the files in its directory.
+func readRecord(dir string, files []archiveFile) (Record, error) {
+	var (
+		record   Record
+		metadata []byte
+		fallback []byte // A metadata file of another schema, e.g. metadata/metadata.iso19139.xml
+		infoXML  []byte
+	)
+
+	changeDates := map[string]string{}
+
+	for _, file := range files {
+		content, err := readZipFile(file.file)
+		if err != nil {
+			return Record{}, err
+		}
+
+		switch directory, base := path.Split(file.name); {
+		case file.name == InfoFile:
+			infoXML = content
+		case file.name == MetadataFile || file.name == path.Join(metadataDir, MetadataFile):
+			metadata = content
+		case directory == metadataDir+"/" && path.Ext(base) == ".xml":
+			fallback = content
+		case directory == publicDir+"/":
+			record.Public = append(record.Public, File{Name: base, Content: content})
+		case directory == privateDir+"/":
+			record.Private = append(record.Private, File{Name: base, Content: content})
+		}
+	}
+
+	if infoXML == nil {
+		return Record{}, fmt.Errorf("record %s in MEF archive has no %s", dir, InfoFile)
+	}
+
+	if err := xml.Unmarshal(infoXML, &record.Info); err != nil {
+		return Record{}, fmt.Errorf("failed to parse %s of record %s: %w", InfoFile, dir, err)
+	}
+
+	if metadata == nil {
+		metadata = fallback
+	}
+
+	if metadata == nil {
+		return Record{}, fmt.Errorf("record %s in MEF archive has no %s", record.UUID(), MetadataFile)
+	}
+
+	record.Metadata = metadata
+
+	for _, file := range slices.Concat(record.Info.Public, record.Info.Private) {
+		changeDates[file.Name] = file.ChangeDate
+	}
+
+	for _, attachments := range [][]File{record.Public, record.Private} {
+		for i := range attachments {
+			attachments[i].ChangeDate = changeDates[attachments[i].Name]
+		}
+	}
+
+	return record, nil
+}
+
+func writeZipFile(zw *zip.Writer, name string, content []byte) error {
+	fw, err := zw.CreateHeader(&zip.FileHeader{Name: name, Method: zip.Deflate, Modified: time.Now()})
+	if err != nil {
+		return err
+	}
+
+	_, err = fw.Write(content)
+
+	return err
+}
+
+func readZipFile(file *zip.File) ([]byte, error) {
+	rc, err := file.Open()
+	if err != nil {
+		return nil, err
+	}
+
+	content, err := io.ReadAll(rc)
+
+	return content, errors.Join(err, rc.Close())
+}
+
+func fileInfos(files []File) []FileInfo {
+	var infos []FileInfo
+	for _, file := range files {
+		infos = append(infos, FileInfo{Name: file.Name, ChangeDate: file.ChangeDate})
+	}
+
+	return infos
+}
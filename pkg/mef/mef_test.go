@@ -0,0 +1,177 @@
+package mef
+
+import (
+	"archive/zip"
+	"bytes"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testMetadata = `<gmd:MD_Metadata xmlns:gmd="http://www.isotc211.org/2005/gmd"/>`
+
+func testRecords() []Record {
+	return []Record{
+		{
+			Metadata: []byte(testMetadata),
+			Info: Info{
+				General: General{
+					ChangeDate: "2024-05-01T12:00:00",
+					Schema:     SchemaISO19139,
+					IsTemplate: "false",
+					Format:     FormatFull,
+					UUID:       "00000000-0000-0000-0000-000000000001",
+				},
+				Categories: []Category{{Name: "inspire"}, {Name: "hvd"}},
+				Privileges: []Group{
+					{Name: "all", Operations: []Operation{{Name: "view"}, {Name: "dynamic"}}},
+					{Name: "pdok", Operations: []Operation{{Name: "view"}, {Name: "download"}, {Name: "editing"}}},
+				},
+			},
+			Public:  []File{{Name: "thumb.png", ChangeDate: "2024-05-01T12:00:00", Content: []byte("png")}},
+			Private: []File{{Name: "notes.pdf", Content: []byte("pdf")}},
+		},
+		{
+			Metadata: []byte(testMetadata),
+			Info: Info{
+				General: General{Schema: SchemaISO19139, Format: FormatFull, UUID: "00000000-0000-0000-0000-000000000002"},
+			},
+		},
+	}
+}
+
+func TestWriteRead(t *testing.T) {
+	var buf bytes.Buffer
+
+	require.NoError(t, Write(&buf, testRecords()))
+
+	records, err := Read(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	require.NoError(t, err)
+	require.Len(t, records, 2)
+
+	for i, want := range testRecords() {
+		got := records[i]
+		assert.Equal(t, want.Metadata, got.Metadata)
+		assert.Equal(t, want.Public, got.Public)
+		assert.Equal(t, want.Private, got.Private)
+		assert.Equal(t, want.Info.General, got.Info.General)
+		assert.Equal(t, want.Info.Categories, got.Info.Categories)
+		assert.Equal(t, want.Info.Privileges, got.Info.Privileges)
+		assert.Equal(t, InfoVersion, got.Info.Version)
+	}
+
+	assert.Equal(t, []FileInfo{{Name: "thumb.png", ChangeDate: "2024-05-01T12:00:00"}}, records[0].Info.Public)
+	assert.Equal(t, []FileInfo{{Name: "notes.pdf"}}, records[0].Info.Private)
+
+	t.Run("Layout of version 2", func(t *testing.T) {
+		zr, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+		require.NoError(t, err)
+
+		var names []string
+		for _, file := range zr.File {
+			names = append(names, file.Name)
+		}
+
+		assert.Equal(t, []string{
+			"00000000-0000-0000-0000-000000000001/metadata/metadata.xml",
+			"00000000-0000-0000-0000-000000000001/info.xml",
+			"00000000-0000-0000-0000-000000000001/public/thumb.png",
+			"00000000-0000-0000-0000-000000000001/private/notes.pdf",
+			"00000000-0000-0000-0000-000000000002/metadata/metadata.xml",
+			"00000000-0000-0000-0000-000000000002/info.xml",
+		}, names)
+	})
+}
+
+func TestWriteFileReadFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "export.mef")
+
+	require.NoError(t, WriteFile(path, testRecords()[:1]))
+
+	records, err := ReadFile(path)
+	require.NoError(t, err)
+	require.Len(t, records, 1)
+	assert.Equal(t, "00000000-0000-0000-0000-000000000001", records[0].UUID())
+}
+
+func TestRead(t *testing.T) {
+	archive := func(files map[string]string) []byte {
+		var buf bytes.Buffer
+
+		zw := zip.NewWriter(&buf)
+		for name, content := range files {
+			fw, err := zw.Create(name)
+			require.NoError(t, err)
+			_, err = fw.Write([]byte(content))
+			require.NoError(t, err)
+		}
+
+		require.NoError(t, zw.Close())
+
+		return buf.Bytes()
+	}
+
+	info := `<info version="1.1"><general><schema>iso19139</schema><format>full</format>` +
+		`<uuid>00000000-0000-0000-0000-000000000003</uuid></general>` +
+		`<public><file name="thumb.png" changeDate="2023-01-01T00:00:00"/></public></info>`
+
+	tests := []struct {
+		name     string
+		files    map[string]string
+		wantErr  string
+		wantFile string
+	}{
+		{
+			name:     "Version 1",
+			files:    map[string]string{"metadata.xml": testMetadata, "info.xml": info, "public/thumb.png": "png"},
+			wantFile: "thumb.png",
+		},
+		{
+			name: "Version 2 with metadata of another schema",
+			files: map[string]string{
+				"00000000-0000-0000-0000-000000000003/metadata/metadata.iso19139.xml": testMetadata,
+				"00000000-0000-0000-0000-000000000003/info.xml":                       info,
+				"00000000-0000-0000-0000-000000000003/public/thumb.png":               "png",
+			},
+			wantFile: "thumb.png",
+		},
+		{
+			name:    "Missing info",
+			files:   map[string]string{"metadata.xml": testMetadata},
+			wantErr: "has no info.xml",
+		},
+		{
+			name:    "Missing metadata",
+			files:   map[string]string{"info.xml": info},
+			wantErr: "has no metadata.xml",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data := archive(tt.files)
+
+			records, err := Read(bytes.NewReader(data), int64(len(data)))
+			if tt.wantErr != "" {
+				require.ErrorContains(t, err, tt.wantErr)
+
+				return
+			}
+
+			require.NoError(t, err)
+			require.Len(t, records, 1)
+			assert.Equal(t, "00000000-0000-0000-0000-000000000003", records[0].UUID())
+			assert.Equal(t, testMetadata, string(records[0].Metadata))
+			require.Len(t, records[0].Public, 1)
+			assert.Equal(t, File{Name: tt.wantFile, ChangeDate: "2023-01-01T00:00:00", Content: []byte("png")},
+				records[0].Public[0])
+		})
+	}
+
+	t.Run("Not a zip archive", func(t *testing.T) {
+		_, err := Read(bytes.NewReader([]byte("no zip")), 6)
+		require.ErrorContains(t, err, "failed to read MEF archive")
+	})
+}
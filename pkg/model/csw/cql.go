@@ -0,0 +1,457 @@
+package csw
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// openDate marks an open end of a DURING period, e.g. '2024-01-01/..'.
+const openDate = ".."
+
+type cqlTokenKind int
+
+const (
+	tokenEOF cqlTokenKind = iota
+	tokenIdent
+	tokenString
+	tokenNumber
+	tokenOperator
+	tokenLeftParen
+	tokenRightParen
+	tokenComma
+)
+
+type cqlToken struct {
+	kind  cqlTokenKind
+	text  string
+	value float64
+	pos   int
+}
+
+type cqlParser struct {
+	tokens []cqlToken
+	pos    int
+}
+
+// ParseCQL parses a CQL_TEXT expression into a Filter. Supported are AND, OR, NOT and parentheses,
+// comparisons (=, <>, <, <=, >, >=), [NOT] LIKE, [NOT] BETWEEN ... AND ..., DURING 'from/to'
+// (use .. for an open end) and BBOX(property, west, south, east, north[, 'crs']).
+// String literals are enclosed in single quotes; a single quote in a literal is doubled.
+// A comparison on keyword (or Subject) results in a Keyword filter, on AnyText in an AnyText filter.
+func ParseCQL(input string) (Filter, error) {
+	tokens, err := tokenizeCQL(input)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &cqlParser{tokens: tokens}
+
+	filter, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+
+	if t := p.peek(); t.kind != tokenEOF {
+		return nil, p.errorf(t, "unexpected %q", t.text)
+	}
+
+	return filter, nil
+}
+
+func (p *cqlParser) parseOr() (Filter, error) {
+	return p.parseJunction("OR", p.parseAnd, func(filters []Filter) Filter { return Or{Filters: filters} })
+}
+
+func (p *cqlParser) parseAnd() (Filter, error) {
+	return p.parseJunction("AND", p.parseNot, func(filters []Filter) Filter { return And{Filters: filters} })
+}
+
+func (p *cqlParser) parseJunction(
+	keyword string,
+	parseOperand func() (Filter, error),
+	combine func([]Filter) Filter,
+) (Filter, error) {
+	first, err := parseOperand()
+	if err != nil {
+		return nil, err
+	}
+
+	filters := []Filter{first}
+
+	for p.acceptKeyword(keyword) {
+		next, err := parseOperand()
+		if err != nil {
+			return nil, err
+		}
+
+		filters = append(filters, next)
+	}
+
+	if len(filters) == 1 {
+		return first, nil
+	}
+
+	return combine(filters), nil
+}
+
+func (p *cqlParser) parseNot() (Filter, error) {
+	if p.acceptKeyword("NOT") {
+		filter, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+
+		return Not{Filter: filter}, nil
+	}
+
+	return p.parsePrimary()
+}
+
+func (p *cqlParser) parsePrimary() (Filter, error) {
+	t := p.next()
+
+	switch {
+	case t.kind == tokenLeftParen:
+		filter, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+
+		if err := p.expect(tokenRightParen, ")"); err != nil {
+			return nil, err
+		}
+
+		return filter, nil
+	case t.kind == tokenIdent && strings.EqualFold(t.text, "BBOX") && p.peek().kind == tokenLeftParen:
+		return p.parseBBox()
+	case t.kind == tokenIdent:
+		if err := ValidatePropertyName(t.text); err != nil {
+			return nil, p.errorf(t, "%s", err)
+		}
+
+		return p.parsePredicate(t.text)
+	default:
+		return nil, p.errorf(t, "expected a property name or '(', got %q", t.text)
+	}
+}
+
+func (p *cqlParser) parsePredicate(property string) (Filter, error) {
+	negate := p.acceptKeyword("NOT")
+
+	var (
+		filter Filter
+		err    error
+	)
+
+	t := p.next()
+
+	switch {
+	case t.kind == tokenOperator && !negate:
+		filter, err = p.parseComparison(property, Operator(t.text))
+	case isKeyword(t, "LIKE"):
+		var pattern cqlToken
+
+		pattern, err = p.expectString()
+		filter = Like{Property: property, Pattern: pattern.text}
+	case isKeyword(t, "BETWEEN"):
+		filter, err = p.parseBetween(property)
+	case isKeyword(t, "DURING") && !negate:
+		filter, err = p.parseDuring(property)
+	default:
+		return nil, p.errorf(t, "expected an operator after %s, got %q", property, t.text)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	if negate {
+		return Not{Filter: filter}, nil
+	}
+
+	return filter, nil
+}
+
+func (p *cqlParser) parseComparison(property string, operator Operator) (Filter, error) {
+	value, err := p.parseLiteral()
+	if err != nil {
+		return nil, err
+	}
+
+	if text, ok := value.(string); ok && operator == Equal {
+		switch {
+		case strings.EqualFold(property, "keyword"), strings.EqualFold(property, KeywordProperty):
+			return Keyword{Value: text}, nil
+		case strings.EqualFold(property, AnyTextProperty):
+			return AnyText{Value: text}, nil
+		}
+	}
+
+	return Comparison{Property: property, Operator: operator, Value: value}, nil
+}
+
+func (p *cqlParser) parseBetween(property string) (Filter, error) {
+	lower, err := p.parseLiteral()
+	if err != nil {
+		return nil, err
+	}
+
+	if t := p.next(); !isKeyword(t, "AND") {
+		return nil, p.errorf(t, "expected AND in BETWEEN, got %q", t.text)
+	}
+
+	upper, err := p.parseLiteral()
+	if err != nil {
+		return nil, err
+	}
+
+	return Between{Property: property, Lower: lower, Upper: upper}, nil
+}
+
+func (p *cqlParser) parseDuring(property string) (Filter, error) {
+	t, err := p.expectString()
+	if err != nil {
+		return nil, err
+	}
+
+	from, to, found := strings.Cut(t.text, "/")
+	if !found {
+		return nil, p.errorf(t, "expected a period 'from/to', got %q", t.text)
+	}
+
+	dateRange := DateRange{Property: property}
+
+	if dateRange.From, err = parseOpenDate(from); err != nil {
+		return nil, p.errorf(t, "%s", err)
+	}
+
+	if dateRange.To, err = parseOpenDate(to); err != nil {
+		return nil, p.errorf(t, "%s", err)
+	}
+
+	if dateRange.From == nil && dateRange.To == nil {
+		return nil, p.errorf(t, "a period needs at least one date")
+	}
+
+	return dateRange, nil
+}
+
+func (p *cqlParser) parseBBox() (Filter, error) {
+	p.next() // (
+
+	property := p.next()
+	if property.kind != tokenIdent {
+		return nil, p.errorf(property, "expected a property name in BBOX, got %q", property.text)
+	}
+
+	var coordinates [4]float64
+
+	for i := range coordinates {
+		if err := p.expect(tokenComma, ","); err != nil {
+			return nil, err
+		}
+
+		t := p.next()
+		if t.kind != tokenNumber {
+			return nil, p.errorf(t, "expected a number in BBOX, got %q", t.text)
+		}
+
+		coordinates[i] = t.value
+	}
+
+	bbox := BBox{West: coordinates[0], South: coordinates[1], East: coordinates[2], North: coordinates[3]}
+
+	if p.peek().kind == tokenComma {
+		p.next()
+
+		crs, err := p.expectString()
+		if err != nil {
+			return nil, err
+		}
+
+		bbox.CRS = crs.text
+	}
+
+	if err := p.expect(tokenRightParen, ")"); err != nil {
+		return nil, err
+	}
+
+	if bbox.West > bbox.East || bbox.South > bbox.North {
+		return nil, errors.New("invalid BBOX: west must not exceed east and south must not exceed north")
+	}
+
+	return bbox, nil
+}
+
+func (p *cqlParser) parseLiteral() (any, error) {
+	t := p.next()
+
+	switch t.kind {
+	case tokenString:
+		return t.text, nil
+	case tokenNumber:
+		return t.value, nil
+	default:
+		return nil, p.errorf(t, "expected a value, got %q", t.text)
+	}
+}
+
+func (p *cqlParser) peek() cqlToken {
+	return p.tokens[p.pos]
+}
+
+func (p *cqlParser) next() cqlToken {
+	t := p.tokens[p.pos]
+	if t.kind != tokenEOF {
+		p.pos++
+	}
+
+	return t
+}
+
+func (p *cqlParser) acceptKeyword(keyword string) bool {
+	if isKeyword(p.peek(), keyword) {
+		p.pos++
+
+		return true
+	}
+
+	return false
+}
+
+func (p *cqlParser) expect(kind cqlTokenKind, text string) error {
+	if t := p.next(); t.kind != kind {
+		return p.errorf(t, "expected %q, got %q", text, t.text)
+	}
+
+	return nil
+}
+
+func (p *cqlParser) expectString() (cqlToken, error) {
+	t := p.next()
+	if t.kind != tokenString {
+		return t, p.errorf(t, "expected a quoted string, got %q", t.text)
+	}
+
+	return t, nil
+}
+
+func (p *cqlParser) errorf(t cqlToken, format string, args ...any) error {
+	return fmt.Errorf("invalid CQL at position %d: %s", t.pos+1, fmt.Sprintf(format, args...))
+}
+
+func isKeyword(t cqlToken, keyword string) bool {
+	return t.kind == tokenIdent && strings.EqualFold(t.text, keyword)
+}
+
+// parseOpenDate parses a date (2006-01-02) or date-time (RFC 3339); .. or an empty string results in nil.
+func parseOpenDate(value string) (*time.Time, error) {
+	value = strings.TrimSpace(value)
+	if value == "" || value == openDate {
+		return nil, nil //nolint:nilnil
+	}
+
+	for _, layout := range []string{time.RFC3339, time.DateOnly} {
+		if t, err := time.Parse(layout, value); err == nil {
+			return &t, nil
+		}
+	}
+
+	return nil, fmt.Errorf("invalid date %q (expected YYYY-MM-DD or RFC 3339)", value)
+}
+
+//nolint:cyclop,funlen
+func tokenizeCQL(input string) ([]cqlToken, error) {
+	var tokens []cqlToken
+
+	runes := []rune(input)
+
+	for i := 0; i < len(runes); {
+		r := runes[i]
+		start := i
+
+		switch {
+		case unicode.IsSpace(r):
+			i++
+
+			continue
+		case r == '(':
+			tokens = append(tokens, cqlToken{kind: tokenLeftParen, text: "(", pos: start})
+			i++
+		case r == ')':
+			tokens = append(tokens, cqlToken{kind: tokenRightParen, text: ")", pos: start})
+			i++
+		case r == ',':
+			tokens = append(tokens, cqlToken{kind: tokenComma, text: ",", pos: start})
+			i++
+		case r == '=':
+			tokens = append(tokens, cqlToken{kind: tokenOperator, text: "=", pos: start})
+			i++
+		case r == '<' || r == '>':
+			op := string(r)
+			if i+1 < len(runes) && (runes[i+1] == '=' || (r == '<' && runes[i+1] == '>')) {
+				op += string(runes[i+1])
+			}
+
+			tokens = append(tokens, cqlToken{kind: tokenOperator, text: op, pos: start})
+			i += len(op)
+		case r == '\'':
+			var sb strings.Builder
+
+			closed := false
+
+			for i++; i < len(runes); i++ {
+				if runes[i] != '\'' {
+					sb.WriteRune(runes[i])
+
+					continue
+				}
+
+				if i+1 < len(runes) && runes[i+1] == '\'' {
+					sb.WriteRune('\'')
+					i++
+
+					continue
+				}
+
+				closed = true
+				i++
+
+				break
+			}
+
+			if !closed {
+				return nil, fmt.Errorf("invalid CQL at position %d: unterminated string", start+1)
+			}
+
+			tokens = append(tokens, cqlToken{kind: tokenString, text: sb.String(), pos: start})
+		case r == '-' || r == '+' || r == '.' || unicode.IsDigit(r):
+			for i++; i < len(runes) && (unicode.IsDigit(runes[i]) || runes[i] == '.' ||
+				runes[i] == 'e' || runes[i] == 'E'); i++ {
+			}
+
+			text := string(runes[start:i])
+
+			value, err := strconv.ParseFloat(text, 64)
+			if err != nil {
+				return nil, fmt.Errorf("invalid CQL at position %d: invalid number %q", start+1, text)
+			}
+
+			tokens = append(tokens, cqlToken{kind: tokenNumber, text: text, value: value, pos: start})
+		case unicode.IsLetter(r) || r == '_':
+			for i++; i < len(runes) && (unicode.IsLetter(runes[i]) || unicode.IsDigit(runes[i]) ||
+				strings.ContainsRune("_.:-", runes[i])); i++ {
+			}
+
+			tokens = append(tokens, cqlToken{kind: tokenIdent, text: string(runes[start:i]), pos: start})
+		default:
+			return nil, fmt.Errorf("invalid CQL at position %d: unexpected character %q", start+1, r)
+		}
+	}
+
+	return append(tokens, cqlToken{kind: tokenEOF, text: "end of input", pos: len(runes)}), nil
+}
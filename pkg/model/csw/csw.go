@@ -2,10 +2,16 @@
 package csw
 
 import (
+	"bytes"
+	"encoding/json"
 	"encoding/xml"
+	"errors"
 	"fmt"
+	"io"
 	"net/url"
+	"sort"
 	"strings"
+	"time"
 
 	"github.com/pdok/pdok-metadata-tool/v2/pkg/model/iso1911x"
 )
@@ -20,20 +26,251 @@ type GetRecordByIDResponse struct {
 	MDMetadata iso1911x.MDMetadata `xml:"MD_Metadata"`
 }
 
+// UnmarshalMDMetadata unmarshalls a metadata record that is either wrapped in a CSW GetRecordByIdResponse
+// (i.e. a cached record) or stored as a plain MD_Metadata document (i.e. generated output).
+func UnmarshalMDMetadata(data []byte) (iso1911x.MDMetadata, error) {
+	decoder := xml.NewDecoder(bytes.NewReader(data))
+
+	for {
+		token, err := decoder.Token()
+		if err != nil {
+			return iso1911x.MDMetadata{}, fmt.Errorf("no root element found: %w", err)
+		}
+
+		root, ok := token.(xml.StartElement)
+		if !ok {
+			continue
+		}
+
+		switch root.Name.Local {
+		case "GetRecordByIdResponse":
+			response := GetRecordByIDResponse{}
+			if err := xml.Unmarshal(data, &response); err != nil { //nolint:musttag
+				return iso1911x.MDMetadata{}, err
+			}
+
+			return response.MDMetadata, nil
+		case "MD_Metadata":
+			md := iso1911x.MDMetadata{}
+			if err := xml.Unmarshal(data, &md); err != nil { //nolint:musttag
+				return iso1911x.MDMetadata{}, err
+			}
+
+			return md, nil
+		default:
+			return iso1911x.MDMetadata{}, fmt.Errorf("unexpected root element: %s", root.Name.Local)
+		}
+	}
+}
+
 // GetRecordsResponse struct for unmarshalling a CSW GetRecords response.
+// Records is filled when the response uses outputSchema gmd, SummaryRecords otherwise.
 type GetRecordsResponse struct {
 	XMLName       xml.Name `xml:"GetRecordsResponse"`
 	SearchResults struct {
-		NumberOfRecordsMatched string          `xml:"numberOfRecordsMatched,attr"`
-		NextRecord             string          `xml:"nextRecord,attr"`
-		SummaryRecords         []SummaryRecord `xml:"SummaryRecord"`
+		NumberOfRecordsMatched string                `xml:"numberOfRecordsMatched,attr"`
+		NextRecord             string                `xml:"nextRecord,attr"`
+		SummaryRecords         []SummaryRecord       `xml:"SummaryRecord"`
+		Records                []iso1911x.MDMetadata `xml:"MD_Metadata"`
 	} `xml:"SearchResults"`
 }
 
+// ExtractRawRecords returns the MD_Metadata elements of a GetRecords response, each wrapped in a
+// GetRecordByIdResponse as if retrieved by ID. Namespace declarations of the enclosing elements
+// are copied to the wrapper, so every record can be unmarshalled on its own.
+func ExtractRawRecords(data []byte) ([][]byte, error) {
+	decoder := xml.NewDecoder(bytes.NewReader(data))
+	namespaces := map[string]string{}
+
+	var records [][]byte
+
+	for {
+		start := decoder.InputOffset()
+
+		token, err := decoder.RawToken()
+		if errors.Is(err, io.EOF) {
+			return records, nil
+		}
+
+		if err != nil {
+			return nil, err
+		}
+
+		element, ok := token.(xml.StartElement)
+		if !ok {
+			continue
+		}
+
+		if element.Name.Local != "MD_Metadata" {
+			for _, attr := range element.Attr {
+				if attr.Name.Space == "xmlns" && attr.Name.Local != "csw" {
+					if _, exists := namespaces[attr.Name.Local]; !exists {
+						namespaces[attr.Name.Local] = attr.Value
+					}
+				}
+			}
+
+			continue
+		}
+
+		if err := skipElement(decoder); err != nil {
+			return nil, err
+		}
+
+		records = append(records, wrapAsGetRecordByIDResponse(data[start:decoder.InputOffset()], namespaces))
+	}
+}
+
+// UnwrapRecord returns the MD_Metadata element of a record that is wrapped in a CSW GetRecordByIdResponse
+// (i.e. a cached record) as a document of its own. Namespace declarations of the enclosing elements are copied
+// to the MD_Metadata element. A plain MD_Metadata document is returned unchanged.
+func UnwrapRecord(data []byte) ([]byte, error) {
+	decoder := xml.NewDecoder(bytes.NewReader(data))
+	namespaces := map[string]string{}
+	root := true
+
+	for {
+		start := decoder.InputOffset()
+
+		token, err := decoder.RawToken()
+		if err != nil {
+			return nil, fmt.Errorf("no MD_Metadata element found: %w", err)
+		}
+
+		element, ok := token.(xml.StartElement)
+		if !ok {
+			continue
+		}
+
+		if element.Name.Local != "MD_Metadata" {
+			for prefix, uri := range namespaceDeclarations(element) {
+				if _, exists := namespaces[prefix]; !exists && prefix != "csw" {
+					namespaces[prefix] = uri
+				}
+			}
+
+			root = false
+
+			continue
+		}
+
+		if root {
+			return data, nil
+		}
+
+		for prefix := range namespaceDeclarations(element) {
+			delete(namespaces, prefix)
+		}
+
+		if err := skipElement(decoder); err != nil {
+			return nil, err
+		}
+
+		record := data[start:decoder.InputOffset()]
+
+		name := element.Name.Local
+		if element.Name.Space != "" {
+			name = element.Name.Space + ":" + name
+		}
+
+		prefixes := make([]string, 0, len(namespaces))
+		for prefix := range namespaces {
+			prefixes = append(prefixes, prefix)
+		}
+
+		sort.Strings(prefixes)
+
+		var buf bytes.Buffer
+
+		buf.Write(record[:1+len(name)])
+
+		for _, prefix := range prefixes {
+			if prefix == "" {
+				buf.WriteString(` xmlns="`)
+			} else {
+				buf.WriteString(" xmlns:" + prefix + `="`)
+			}
+
+			_ = xml.EscapeText(&buf, []byte(namespaces[prefix]))
+			buf.WriteString(`"`)
+		}
+
+		buf.Write(record[1+len(name):])
+
+		return buf.Bytes(), nil
+	}
+}
+
+// namespaceDeclarations returns the namespaces that an element declares by prefix; the default namespace has
+// an empty prefix.
+func namespaceDeclarations(element xml.StartElement) map[string]string {
+	namespaces := map[string]string{}
+
+	for _, attr := range element.Attr {
+		switch {
+		case attr.Name.Space == "xmlns":
+			namespaces[attr.Name.Local] = attr.Value
+		case attr.Name.Space == "" && attr.Name.Local == "xmlns":
+			namespaces[""] = attr.Value
+		}
+	}
+
+	return namespaces
+}
+
 // GetRecordsCQLConstraint struct for creating a CQL constraint.
 type GetRecordsCQLConstraint struct {
-	MetadataType     *iso1911x.MetadataType
-	OrganisationName *string
+	MetadataType     *iso1911x.MetadataType `json:"metadataType,omitempty"`
+	OrganisationName *string                `json:"organisationName,omitempty"`
+	ModifiedSince    *time.Time             `json:"modifiedSince,omitempty"` // Only records modified at or after this time
+	Filter           Filter                 `json:"-"`                       // Additional filter, combined with AND
+}
+
+// cqlConstraintJSON is the JSON representation of GetRecordsCQLConstraint, with the filter as CQL text.
+type cqlConstraintJSON struct {
+	MetadataType     *iso1911x.MetadataType `json:"metadataType,omitempty"`
+	OrganisationName *string                `json:"organisationName,omitempty"`
+	ModifiedSince    *time.Time             `json:"modifiedSince,omitempty"`
+	Filter           string                 `json:"filter,omitempty"`
+}
+
+// MarshalJSON encodes the constraint as JSON, with the filter as CQL text.
+func (c GetRecordsCQLConstraint) MarshalJSON() ([]byte, error) {
+	data := cqlConstraintJSON{
+		MetadataType:     c.MetadataType,
+		OrganisationName: c.OrganisationName,
+		ModifiedSince:    c.ModifiedSince,
+	}
+	if c.Filter != nil {
+		data.Filter = c.Filter.ToCQL()
+	}
+
+	return json.Marshal(data)
+}
+
+// UnmarshalJSON decodes the constraint from JSON, parsing the filter from CQL text.
+func (c *GetRecordsCQLConstraint) UnmarshalJSON(b []byte) error {
+	var data cqlConstraintJSON
+	if err := json.Unmarshal(b, &data); err != nil {
+		return err
+	}
+
+	*c = GetRecordsCQLConstraint{
+		MetadataType:     data.MetadataType,
+		OrganisationName: data.OrganisationName,
+		ModifiedSince:    data.ModifiedSince,
+	}
+
+	if data.Filter != "" {
+		filter, err := ParseCQL(data.Filter)
+		if err != nil {
+			return err
+		}
+
+		c.Filter = filter
+	}
+
+	return nil
 }
 
 // ToQueryParameter returns a query parameter string based on the constraints.
@@ -41,11 +278,24 @@ func (c *GetRecordsCQLConstraint) ToQueryParameter() (constraint string) {
 	var constraints []string
 
 	if c.MetadataType != nil {
-		constraints = append(constraints, fmt.Sprintf("type='%s'", *c.MetadataType))
+		constraints = append(constraints, "type="+cqlLiteral(c.MetadataType.String()))
 	}
 
 	if c.OrganisationName != nil {
-		constraints = append(constraints, fmt.Sprintf("OrganisationName='%s'", *c.OrganisationName))
+		constraints = append(constraints, "OrganisationName="+cqlLiteral(*c.OrganisationName))
+	}
+
+	if c.ModifiedSince != nil {
+		constraints = append(constraints, fmt.Sprintf("Modified>='%s'", c.ModifiedSince.UTC().Format(time.RFC3339)))
+	}
+
+	if c.Filter != nil {
+		cql := c.Filter.ToCQL()
+		if len(constraints) > 0 && isOr(c.Filter) {
+			cql = "(" + cql + ")"
+		}
+
+		constraints = append(constraints, cql)
 	}
 
 	if len(constraints) == 0 {
@@ -81,6 +331,7 @@ type GetRecordsOgcFilter struct {
 	MetadataType iso1911x.MetadataType
 	Title        *string
 	Identifier   *string
+	Filter       Filter // Additional filter, combined with AND
 }
 
 // ToRequestBody Returns a request body string for a CSW GetRecords request.
@@ -120,11 +371,59 @@ func (f *GetRecordsOgcFilter) ToRequestBody() (ogcFilter string, err error) {
 		)
 	}
 
+	if f.Filter != nil {
+		filter = wrapFiltersInAndOperator([]string{filter, f.Filter.ToOGC()})
+	}
+
 	requestBody := fmt.Sprintf(template, filter)
 
 	return requestBody, nil
 }
 
+// skipElement reads raw tokens until the end of the current element.
+func skipElement(decoder *xml.Decoder) error {
+	for depth := 1; depth > 0; {
+		token, err := decoder.RawToken()
+		if err != nil {
+			return err
+		}
+
+		switch token.(type) {
+		case xml.StartElement:
+			depth++
+		case xml.EndElement:
+			depth--
+		}
+	}
+
+	return nil
+}
+
+func wrapAsGetRecordByIDResponse(record []byte, namespaces map[string]string) []byte {
+	prefixes := make([]string, 0, len(namespaces))
+	for prefix := range namespaces {
+		prefixes = append(prefixes, prefix)
+	}
+
+	sort.Strings(prefixes)
+
+	var buf bytes.Buffer
+
+	buf.WriteString(`<csw:GetRecordByIdResponse xmlns:csw="http://www.opengis.net/cat/csw/2.0.2"`)
+
+	for _, prefix := range prefixes {
+		buf.WriteString(" xmlns:" + prefix + `="`)
+		_ = xml.EscapeText(&buf, []byte(namespaces[prefix]))
+		buf.WriteString(`"`)
+	}
+
+	buf.WriteString(">")
+	buf.Write(record)
+	buf.WriteString("</csw:GetRecordByIdResponse>")
+
+	return buf.Bytes()
+}
+
 func wrapFiltersInOrOperator(filters []string) string {
 	template := `<ogc:Or>
     %s
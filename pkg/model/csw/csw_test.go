@@ -1,7 +1,10 @@
 package csw
 
 import (
+	"encoding/xml"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/pdok/pdok-metadata-tool/v2/pkg/model/iso1911x"
 	"github.com/stretchr/testify/assert"
@@ -69,3 +72,114 @@ func TestGetRecordsOgcFilter_getPropertyIsEqualToClause(t *testing.T) {
 	clause := filter.getPropertyIsEqualToClause("property", "value")
 	assert.Equal(t, expectedClause, clause)
 }
+
+func TestExtractRawRecords(t *testing.T) {
+	response := `<?xml version="1.0" encoding="UTF-8"?>
+<csw:GetRecordsResponse xmlns:csw="http://www.opengis.net/cat/csw/2.0.2" xmlns:gco="http://www.isotc211.org/2005/gco">
+  <csw:SearchStatus timestamp="2025-01-01T00:00:00"/>
+  <csw:SearchResults numberOfRecordsMatched="3" numberOfRecordsReturned="2" nextRecord="3">
+    <gmd:MD_Metadata xmlns:gmd="http://www.isotc211.org/2005/gmd">
+      <gmd:fileIdentifier><gco:CharacterString>record-1</gco:CharacterString></gmd:fileIdentifier>
+      <gmd:hierarchyLevel/>
+    </gmd:MD_Metadata>
+    <gmd:MD_Metadata xmlns:gmd="http://www.isotc211.org/2005/gmd">
+      <gmd:fileIdentifier><gco:CharacterString>record-2</gco:CharacterString></gmd:fileIdentifier>
+    </gmd:MD_Metadata>
+  </csw:SearchResults>
+</csw:GetRecordsResponse>`
+
+	var parsed GetRecordsResponse
+
+	require.NoError(t, xml.Unmarshal([]byte(response), &parsed))
+	require.Len(t, parsed.SearchResults.Records, 2)
+	assert.Equal(t, "record-1", parsed.SearchResults.Records[0].UUID)
+	assert.Equal(t, "3", parsed.SearchResults.NextRecord)
+
+	raw, err := ExtractRawRecords([]byte(response))
+	require.NoError(t, err)
+	require.Len(t, raw, 2)
+
+	for i, id := range []string{"record-1", "record-2"} {
+		assert.True(t, strings.HasPrefix(string(raw[i]),
+			`<csw:GetRecordByIdResponse xmlns:csw="http://www.opengis.net/cat/csw/2.0.2" `+
+				`xmlns:gco="http://www.isotc211.org/2005/gco"><gmd:MD_Metadata`))
+
+		md, err := UnmarshalMDMetadata(raw[i])
+		require.NoError(t, err)
+		assert.Equal(t, id, md.UUID)
+	}
+}
+
+func TestUnwrapRecord(t *testing.T) {
+	record := `<gmd:MD_Metadata xmlns:gmd="http://www.isotc211.org/2005/gmd">` +
+		`<gmd:fileIdentifier><gco:CharacterString>record-1</gco:CharacterString></gmd:fileIdentifier>` +
+		`</gmd:MD_Metadata>`
+
+	t.Run("Cached record", func(t *testing.T) {
+		cached := `<?xml version="1.0" encoding="UTF-8"?>` + "\n" +
+			`<csw:GetRecordByIdResponse xmlns:csw="http://www.opengis.net/cat/csw/2.0.2" ` +
+			`xmlns:gco="http://www.isotc211.org/2005/gco" xmlns:gmd="http://www.isotc211.org/2005/gmd">` +
+			record + `</csw:GetRecordByIdResponse>`
+
+		unwrapped, err := UnwrapRecord([]byte(cached))
+		require.NoError(t, err)
+		assert.Equal(t, `<gmd:MD_Metadata xmlns:gco="http://www.isotc211.org/2005/gco" `+record[len("<gmd:MD_Metadata "):],
+			string(unwrapped))
+
+		md, err := UnmarshalMDMetadata(unwrapped)
+		require.NoError(t, err)
+		assert.Equal(t, "record-1", md.UUID)
+	})
+
+	t.Run("Plain record", func(t *testing.T) {
+		unwrapped, err := UnwrapRecord([]byte(record))
+		require.NoError(t, err)
+		assert.Equal(t, record, string(unwrapped))
+	})
+
+	t.Run("No record", func(t *testing.T) {
+		_, err := UnwrapRecord([]byte(`<csw:GetRecordByIdResponse/>`))
+		require.ErrorContains(t, err, "no MD_Metadata element found")
+	})
+}
+
+func TestGetRecordsCQLConstraint_ToQueryParameter(t *testing.T) {
+	dataset := iso1911x.Dataset
+	org := "Beheer PDOK"
+	since := time.Date(2025, 1, 2, 3, 4, 5, 0, time.FixedZone("CET", 3600))
+
+	tests := []struct {
+		name       string
+		constraint GetRecordsCQLConstraint
+		want       string
+	}{
+		{name: "No constraint", want: ""},
+		{
+			name:       "Type and organisation",
+			constraint: GetRecordsCQLConstraint{MetadataType: &dataset, OrganisationName: &org},
+			want: "&constraintLanguage=CQL_TEXT&constraint_language_version=1.1.0" +
+				"&constraint=type%3D%27dataset%27+AND+OrganisationName%3D%27Beheer+PDOK%27",
+		},
+		{
+			name:       "Modified since",
+			constraint: GetRecordsCQLConstraint{MetadataType: &dataset, ModifiedSince: &since},
+			want: "&constraintLanguage=CQL_TEXT&constraint_language_version=1.1.0" +
+				"&constraint=type%3D%27dataset%27+AND+Modified%3E%3D%272025-01-02T02%3A04%3A05Z%27",
+		},
+		{
+			name: "Filter",
+			constraint: GetRecordsCQLConstraint{
+				OrganisationName: &org,
+				Filter:           Or{Filters: []Filter{Keyword{Value: "inspire"}, Keyword{Value: "hvd"}}},
+			},
+			want: "&constraintLanguage=CQL_TEXT&constraint_language_version=1.1.0" +
+				"&constraint=OrganisationName%3D%27Beheer+PDOK%27+AND+%28Subject+%3D+%27inspire%27+OR+Subject+%3D+%27hvd%27%29",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, tt.constraint.ToQueryParameter())
+		})
+	}
+}
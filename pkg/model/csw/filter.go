@@ -0,0 +1,333 @@
+package csw
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Filter is a node of a typed query that can be encoded as CQL_TEXT and as OGC Filter 1.1 XML.
+// Use ParseCQL to create a Filter from (user supplied) CQL text.
+type Filter interface {
+	// ToCQL encodes the filter as CQL_TEXT.
+	ToCQL() string
+	// ToOGC encodes the filter as OGC Filter 1.1 XML, without the enclosing ogc:Filter element.
+	ToOGC() string
+}
+
+// Operator holds the binary comparison operators.
+type Operator string
+
+// Values for Operator.
+const (
+	Equal              Operator = "="
+	NotEqual           Operator = "<>"
+	LessThan           Operator = "<"
+	LessThanOrEqual    Operator = "<="
+	GreaterThan        Operator = ">"
+	GreaterThanOrEqual Operator = ">="
+)
+
+// ogcOperators maps the comparison operators to OGC Filter elements.
+var ogcOperators = map[Operator]string{
+	Equal:              "PropertyIsEqualTo",
+	NotEqual:           "PropertyIsNotEqualTo",
+	LessThan:           "PropertyIsLessThan",
+	LessThanOrEqual:    "PropertyIsLessThanOrEqualTo",
+	GreaterThan:        "PropertyIsGreaterThan",
+	GreaterThanOrEqual: "PropertyIsGreaterThanOrEqualTo",
+}
+
+const (
+	// KeywordProperty is the queryable used for keywords.
+	KeywordProperty = "Subject"
+	// AnyTextProperty is the queryable used for full text search.
+	AnyTextProperty = "AnyText"
+	// BBoxProperty is the queryable used for the bounding box.
+	BBoxProperty = "ows:BoundingBox"
+	// DefaultCRS is the CRS of a BBox without CRS, with coordinates in longitude/latitude order.
+	DefaultCRS = "EPSG:4326"
+)
+
+var propertyNameRegex = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_.:-]*$`)
+
+// And matches when all filters match.
+type And struct {
+	Filters []Filter
+}
+
+// Or matches when any of the filters matches.
+type Or struct {
+	Filters []Filter
+}
+
+// Not matches when the filter does not match.
+type Not struct {
+	Filter Filter
+}
+
+// Comparison compares a property to a literal value, which is a string, number or time.Time.
+type Comparison struct {
+	Property string
+	Operator Operator
+	Value    any
+}
+
+// Like matches a property against a pattern, where % matches any text and _ a single character.
+// Use \ to match a literal %, _ or \.
+type Like struct {
+	Property string
+	Pattern  string
+}
+
+// Between matches when the property is between the lower and upper value (inclusive).
+type Between struct {
+	Property string
+	Lower    any
+	Upper    any
+}
+
+// DateRange matches when the date property is within the range (inclusive). Either end may be nil.
+type DateRange struct {
+	Property string
+	From     *time.Time
+	To       *time.Time
+}
+
+// Keyword matches records with the given keyword.
+type Keyword struct {
+	Value string
+}
+
+// AnyText matches records that contain the given text in any of their fields.
+type AnyText struct {
+	Value string
+}
+
+// BBox matches records of which the bounding box intersects the envelope. An empty CRS means DefaultCRS.
+type BBox struct {
+	West  float64
+	South float64
+	East  float64
+	North float64
+	CRS   string
+}
+
+// ToCQL encodes the filter as CQL_TEXT.
+func (f And) ToCQL() string { return joinCQL(f.Filters, " AND ") }
+
+// ToOGC encodes the filter as OGC Filter XML.
+func (f And) ToOGC() string { return joinOGC("And", f.Filters) }
+
+// ToCQL encodes the filter as CQL_TEXT.
+func (f Or) ToCQL() string { return joinCQL(f.Filters, " OR ") }
+
+// ToOGC encodes the filter as OGC Filter XML.
+func (f Or) ToOGC() string { return joinOGC("Or", f.Filters) }
+
+// ToCQL encodes the filter as CQL_TEXT.
+func (f Not) ToCQL() string { return "NOT (" + f.Filter.ToCQL() + ")" }
+
+// ToOGC encodes the filter as OGC Filter XML.
+func (f Not) ToOGC() string { return "<ogc:Not>" + f.Filter.ToOGC() + "</ogc:Not>" }
+
+// ToCQL encodes the filter as CQL_TEXT.
+func (f Comparison) ToCQL() string {
+	return f.Property + " " + string(f.Operator) + " " + cqlLiteral(f.Value)
+}
+
+// ToOGC encodes the filter as OGC Filter XML.
+func (f Comparison) ToOGC() string {
+	element := ogcOperators[f.Operator]
+
+	return "<ogc:" + element + ">" + ogcProperty(f.Property) + ogcLiteral(f.Value) + "</ogc:" + element + ">"
+}
+
+// ToCQL encodes the filter as CQL_TEXT.
+func (f Like) ToCQL() string {
+	return f.Property + " LIKE " + cqlLiteral(f.Pattern)
+}
+
+// ToOGC encodes the filter as OGC Filter XML.
+func (f Like) ToOGC() string {
+	return `<ogc:PropertyIsLike wildCard="%" singleChar="_" escapeChar="\">` +
+		ogcProperty(f.Property) + ogcLiteral(f.Pattern) + "</ogc:PropertyIsLike>"
+}
+
+// ToCQL encodes the filter as CQL_TEXT.
+func (f Between) ToCQL() string {
+	return f.Property + " BETWEEN " + cqlLiteral(f.Lower) + " AND " + cqlLiteral(f.Upper)
+}
+
+// ToOGC encodes the filter as OGC Filter XML.
+func (f Between) ToOGC() string {
+	return "<ogc:PropertyIsBetween>" + ogcProperty(f.Property) +
+		"<ogc:LowerBoundary>" + ogcLiteral(f.Lower) + "</ogc:LowerBoundary>" +
+		"<ogc:UpperBoundary>" + ogcLiteral(f.Upper) + "</ogc:UpperBoundary>" +
+		"</ogc:PropertyIsBetween>"
+}
+
+// ToCQL encodes the filter as CQL_TEXT.
+func (f DateRange) ToCQL() string { return f.toComparisons().ToCQL() }
+
+// ToOGC encodes the filter as OGC Filter XML.
+func (f DateRange) ToOGC() string { return f.toComparisons().ToOGC() }
+
+// ToCQL encodes the filter as CQL_TEXT.
+func (f Keyword) ToCQL() string { return Comparison{KeywordProperty, Equal, f.Value}.ToCQL() }
+
+// ToOGC encodes the filter as OGC Filter XML.
+func (f Keyword) ToOGC() string { return Comparison{KeywordProperty, Equal, f.Value}.ToOGC() }
+
+// ToCQL encodes the filter as CQL_TEXT.
+func (f AnyText) ToCQL() string { return f.toLike().ToCQL() }
+
+// ToOGC encodes the filter as OGC Filter XML.
+func (f AnyText) ToOGC() string { return f.toLike().ToOGC() }
+
+// ToCQL encodes the filter as CQL_TEXT.
+func (f BBox) ToCQL() string {
+	cql := fmt.Sprintf("BBOX(%s, %s, %s, %s, %s", BBoxProperty,
+		formatNumber(f.West), formatNumber(f.South), formatNumber(f.East), formatNumber(f.North))
+	if f.CRS != "" {
+		cql += ", " + cqlLiteral(f.CRS)
+	}
+
+	return cql + ")"
+}
+
+// ToOGC encodes the filter as OGC Filter XML.
+func (f BBox) ToOGC() string {
+	crs := f.CRS
+	if crs == "" {
+		crs = DefaultCRS
+	}
+
+	return "<ogc:BBOX>" + ogcProperty(BBoxProperty) +
+		`<gml:Envelope xmlns:gml="http://www.opengis.net/gml" srsName="` + escapeXML(crs) + `">` +
+		"<gml:lowerCorner>" + formatNumber(f.West) + " " + formatNumber(f.South) + "</gml:lowerCorner>" +
+		"<gml:upperCorner>" + formatNumber(f.East) + " " + formatNumber(f.North) + "</gml:upperCorner>" +
+		"</gml:Envelope></ogc:BBOX>"
+}
+
+// ValidatePropertyName returns an error when the name cannot be used as a property name in CQL or XML.
+func ValidatePropertyName(name string) error {
+	if !propertyNameRegex.MatchString(name) {
+		return fmt.Errorf("invalid property name: %q", name)
+	}
+
+	return nil
+}
+
+func (f DateRange) toComparisons() Filter {
+	var filters []Filter
+	if f.From != nil {
+		filters = append(filters, Comparison{f.Property, GreaterThanOrEqual, *f.From})
+	}
+
+	if f.To != nil {
+		filters = append(filters, Comparison{f.Property, LessThanOrEqual, *f.To})
+	}
+
+	if len(filters) == 1 {
+		return filters[0]
+	}
+
+	return And{Filters: filters}
+}
+
+func (f AnyText) toLike() Like {
+	return Like{Property: AnyTextProperty, Pattern: "%" + EscapeLikePattern(f.Value) + "%"}
+}
+
+// EscapeLikePattern escapes the wildcards in a value, so it can be used literally in a Like pattern.
+func EscapeLikePattern(value string) string {
+	return strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(value)
+}
+
+func joinCQL(filters []Filter, separator string) string {
+	parts := make([]string, 0, len(filters))
+	for _, f := range filters {
+		cql := f.ToCQL()
+		if _, ok := f.(And); ok || isOr(f) {
+			cql = "(" + cql + ")"
+		}
+
+		parts = append(parts, cql)
+	}
+
+	return strings.Join(parts, separator)
+}
+
+func isOr(f Filter) bool {
+	_, ok := f.(Or)
+
+	return ok
+}
+
+func joinOGC(operator string, filters []Filter) string {
+	if len(filters) == 1 {
+		return filters[0].ToOGC()
+	}
+
+	var sb strings.Builder
+
+	sb.WriteString("<ogc:" + operator + ">")
+
+	for _, f := range filters {
+		sb.WriteString(f.ToOGC())
+	}
+
+	sb.WriteString("</ogc:" + operator + ">")
+
+	return sb.String()
+}
+
+// cqlLiteral encodes a value as CQL literal. Strings are quoted, with single quotes doubled.
+func cqlLiteral(value any) string {
+	switch v := value.(type) {
+	case float64:
+		return formatNumber(v)
+	case int:
+		return strconv.Itoa(v)
+	case time.Time:
+		return "'" + v.UTC().Format(time.RFC3339) + "'"
+	default:
+		return "'" + strings.ReplaceAll(fmt.Sprint(v), "'", "''") + "'"
+	}
+}
+
+func ogcLiteral(value any) string {
+	var text string
+
+	switch v := value.(type) {
+	case float64:
+		text = formatNumber(v)
+	case time.Time:
+		text = v.UTC().Format(time.RFC3339)
+	default:
+		text = fmt.Sprint(v)
+	}
+
+	return "<ogc:Literal>" + escapeXML(text) + "</ogc:Literal>"
+}
+
+func ogcProperty(property string) string {
+	return "<ogc:PropertyName>" + escapeXML(property) + "</ogc:PropertyName>"
+}
+
+func formatNumber(v float64) string {
+	return strconv.FormatFloat(v, 'f', -1, 64)
+}
+
+func escapeXML(s string) string {
+	var buf bytes.Buffer
+
+	_ = xml.EscapeText(&buf, []byte(s))
+
+	return buf.String()
+}
@@ -0,0 +1,149 @@
+package csw
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseCQL(t *testing.T) {
+	from := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name    string
+		cql     string
+		want    Filter
+		wantCQL string
+		wantErr string
+	}{
+		{
+			name:    "Equality",
+			cql:     "OrganisationName = 'Beheer PDOK'",
+			want:    Comparison{Property: "OrganisationName", Operator: Equal, Value: "Beheer PDOK"},
+			wantCQL: "OrganisationName = 'Beheer PDOK'",
+		},
+		{
+			name:    "Escaped quote",
+			cql:     "Title='O''Brien'",
+			want:    Comparison{Property: "Title", Operator: Equal, Value: "O'Brien"},
+			wantCQL: "Title = 'O''Brien'",
+		},
+		{
+			name:    "Keyword and AnyText",
+			cql:     "keyword = 'inspire' and anytext = 'water'",
+			want:    And{Filters: []Filter{Keyword{Value: "inspire"}, AnyText{Value: "water"}}},
+			wantCQL: "Subject = 'inspire' AND AnyText LIKE '%water%'",
+		},
+		{
+			name: "Precedence",
+			cql:  "a = 1 OR b <> 2 AND NOT c >= 3.5",
+			want: Or{Filters: []Filter{
+				Comparison{Property: "a", Operator: Equal, Value: 1.0},
+				And{Filters: []Filter{
+					Comparison{Property: "b", Operator: NotEqual, Value: 2.0},
+					Not{Filter: Comparison{Property: "c", Operator: GreaterThanOrEqual, Value: 3.5}},
+				}},
+			}},
+			wantCQL: "a = 1 OR (b <> 2 AND NOT (c >= 3.5))",
+		},
+		{
+			name:    "Parentheses, LIKE and BETWEEN",
+			cql:     "(Title LIKE '%kaart%' OR Title NOT LIKE 'x_') AND Denominator BETWEEN 1000 AND 5000",
+			wantCQL: "(Title LIKE '%kaart%' OR NOT (Title LIKE 'x_')) AND Denominator BETWEEN 1000 AND 5000",
+		},
+		{
+			name:    "Date range",
+			cql:     "Modified DURING '2024-01-01/..'",
+			want:    DateRange{Property: "Modified", From: &from},
+			wantCQL: "Modified >= '2024-01-01T00:00:00Z'",
+		},
+		{
+			name:    "BBOX",
+			cql:     "BBOX(ows:BoundingBox, 3.3, 50.7, 7.2, 53.6)",
+			want:    BBox{West: 3.3, South: 50.7, East: 7.2, North: 53.6},
+			wantCQL: "BBOX(ows:BoundingBox, 3.3, 50.7, 7.2, 53.6)",
+		},
+		{name: "Unterminated string", cql: "Title = 'abc", wantErr: "position 9: unterminated string"},
+		{name: "Missing value", cql: "Title =", wantErr: `expected a value, got "end of input"`},
+		{name: "Trailing input", cql: "Title = 'a' 'b'", wantErr: `unexpected "b"`},
+		{name: "Injection", cql: "Title = 'a'; DROP", wantErr: "unexpected character ';'"},
+		{name: "Invalid period", cql: "Modified DURING 'yesterday/..'", wantErr: `invalid date "yesterday"`},
+		{name: "Invalid BBOX", cql: "BBOX(ows:BoundingBox, 7, 50, 3, 53)", wantErr: "invalid BBOX"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			filter, err := ParseCQL(tt.cql)
+			if tt.wantErr != "" {
+				require.ErrorContains(t, err, tt.wantErr)
+
+				return
+			}
+
+			require.NoError(t, err)
+
+			if tt.want != nil {
+				assert.Equal(t, tt.want, filter)
+			}
+
+			assert.Equal(t, tt.wantCQL, filter.ToCQL())
+
+			// The encoded CQL parses to the same encoding
+			reparsed, err := ParseCQL(filter.ToCQL())
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantCQL, reparsed.ToCQL())
+		})
+	}
+}
+
+func TestFilter_ToOGC(t *testing.T) {
+	filter := And{Filters: []Filter{
+		Keyword{Value: "a<b & 'c'"},
+		AnyText{Value: "100%"},
+		Not{Filter: Between{Property: "Denominator", Lower: 1000.0, Upper: 5000.0}},
+		BBox{West: 3.3, South: 50.7, East: 7.2, North: 53.6},
+	}}
+
+	want := `<ogc:And>` +
+		`<ogc:PropertyIsEqualTo><ogc:PropertyName>Subject</ogc:PropertyName>` +
+		`<ogc:Literal>a&lt;b &amp; &#39;c&#39;</ogc:Literal></ogc:PropertyIsEqualTo>` +
+		`<ogc:PropertyIsLike wildCard="%" singleChar="_" escapeChar="\">` +
+		`<ogc:PropertyName>AnyText</ogc:PropertyName><ogc:Literal>%100\%%</ogc:Literal></ogc:PropertyIsLike>` +
+		`<ogc:Not><ogc:PropertyIsBetween><ogc:PropertyName>Denominator</ogc:PropertyName>` +
+		`<ogc:LowerBoundary><ogc:Literal>1000</ogc:Literal></ogc:LowerBoundary>` +
+		`<ogc:UpperBoundary><ogc:Literal>5000</ogc:Literal></ogc:UpperBoundary></ogc:PropertyIsBetween></ogc:Not>` +
+		`<ogc:BBOX><ogc:PropertyName>ows:BoundingBox</ogc:PropertyName>` +
+		`<gml:Envelope xmlns:gml="http://www.opengis.net/gml" srsName="EPSG:4326">` +
+		`<gml:lowerCorner>3.3 50.7</gml:lowerCorner><gml:upperCorner>7.2 53.6</gml:upperCorner>` +
+		`</gml:Envelope></ogc:BBOX>` +
+		`</ogc:And>`
+
+	assert.Equal(t, want, filter.ToOGC())
+
+	// The request body with the filter is well-formed XML
+	body, err := (&GetRecordsOgcFilter{MetadataType: "dataset", Filter: filter}).ToRequestBody()
+	require.NoError(t, err)
+	require.NoError(t, xml.Unmarshal([]byte(body), new(struct{})))
+}
+
+func TestGetRecordsCQLConstraint_JSON(t *testing.T) {
+	filter, err := ParseCQL("keyword = 'inspire' AND Title LIKE 'a''s%'")
+	require.NoError(t, err)
+
+	org := "Beheer PDOK"
+	constraint := GetRecordsCQLConstraint{OrganisationName: &org, Filter: filter}
+
+	data, err := json.Marshal(constraint)
+	require.NoError(t, err)
+	assert.JSONEq(t,
+		`{"organisationName":"Beheer PDOK","filter":"Subject = 'inspire' AND Title LIKE 'a''s%'"}`,
+		string(data))
+
+	var decoded GetRecordsCQLConstraint
+	require.NoError(t, json.Unmarshal(data, &decoded))
+	assert.Equal(t, constraint, decoded)
+}
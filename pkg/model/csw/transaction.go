@@ -0,0 +1,244 @@
+package csw
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// RecordTypeName is the type name used for Delete and RecordProperty Update actions.
+const RecordTypeName = "csw:Record"
+
+// TransactionAction is an Insert, Update, UpdateProperties or Delete action of a CSW Transaction.
+type TransactionAction interface {
+	toXML() (string, error)
+}
+
+// Transaction is a CSW 2.0.2 Transaction request with one or more actions.
+type Transaction struct {
+	Actions []TransactionAction
+}
+
+// Insert adds records (raw MD_Metadata XML documents) to the catalogue.
+type Insert struct {
+	Records [][]byte
+}
+
+// Update replaces a record (raw MD_Metadata XML document) with the same identifier.
+type Update struct {
+	Record []byte
+}
+
+// UpdateProperties sets properties of the records that match the filter.
+type UpdateProperties struct {
+	Properties []RecordProperty
+	Filter     Filter
+}
+
+// RecordProperty is a property to set in an UpdateProperties action. An empty Value removes the property.
+type RecordProperty struct {
+	Name  string
+	Value string
+}
+
+// Delete removes the records that match the filter.
+type Delete struct {
+	Filter Filter
+}
+
+// TransactionResponse struct for unmarshalling a CSW TransactionResponse.
+type TransactionResponse struct {
+	XMLName       xml.Name           `xml:"TransactionResponse"`
+	Summary       TransactionSummary `xml:"TransactionSummary"`
+	InsertResults []InsertResult     `xml:"InsertResult"`
+}
+
+// TransactionSummary holds the number of records affected by a transaction.
+type TransactionSummary struct {
+	TotalInserted int `xml:"totalInserted"`
+	TotalUpdated  int `xml:"totalUpdated"`
+	TotalDeleted  int `xml:"totalDeleted"`
+}
+
+// InsertResult holds the records that were inserted.
+type InsertResult struct {
+	BriefRecords []BriefRecord `xml:"BriefRecord"`
+}
+
+// BriefRecord holds the identifier and title of an inserted record.
+type BriefRecord struct {
+	Identifier string `xml:"identifier"`
+	Title      string `xml:"title"`
+}
+
+// ExceptionReport struct for unmarshalling an OWS ExceptionReport, which is returned when a request fails.
+type ExceptionReport struct {
+	XMLName    xml.Name    `xml:"ExceptionReport"`
+	Exceptions []Exception `xml:"Exception"`
+}
+
+// Exception is a single exception of an ExceptionReport.
+type Exception struct {
+	Code    string   `xml:"exceptionCode,attr"`
+	Locator string   `xml:"locator,attr"`
+	Texts   []string `xml:"ExceptionText"`
+}
+
+// ToRequestBody returns the request body for the transaction.
+func (t *Transaction) ToRequestBody() (string, error) {
+	if len(t.Actions) == 0 {
+		return "", errors.New("transaction has no actions")
+	}
+
+	var sb strings.Builder
+
+	sb.WriteString(`<?xml version="1.0" encoding="UTF-8"?>` + "\n")
+	sb.WriteString(`<csw:Transaction xmlns:csw="http://www.opengis.net/cat/csw/2.0.2" ` +
+		`xmlns:ogc="http://www.opengis.net/ogc" service="CSW" version="2.0.2">`)
+
+	for _, action := range t.Actions {
+		x, err := action.toXML()
+		if err != nil {
+			return "", err
+		}
+
+		sb.WriteString(x)
+	}
+
+	sb.WriteString(`</csw:Transaction>`)
+
+	return sb.String(), nil
+}
+
+// Identifiers returns the identifiers of the inserted records.
+func (r *TransactionResponse) Identifiers() []string {
+	var identifiers []string
+
+	for _, result := range r.InsertResults {
+		for _, record := range result.BriefRecords {
+			identifiers = append(identifiers, record.Identifier)
+		}
+	}
+
+	return identifiers
+}
+
+// Error returns the exceptions of the report as a single message.
+func (r *ExceptionReport) Error() string {
+	messages := make([]string, 0, len(r.Exceptions))
+
+	for _, e := range r.Exceptions {
+		message := e.Code
+		if e.Locator != "" {
+			message += " (" + e.Locator + ")"
+		}
+
+		if len(e.Texts) > 0 {
+			message += ": " + strings.TrimSpace(strings.Join(e.Texts, "; "))
+		}
+
+		messages = append(messages, message)
+	}
+
+	return "CSW exception: " + strings.Join(messages, ", ")
+}
+
+// ParseTransactionResponse parses a TransactionResponse. An ExceptionReport is returned as error.
+func ParseTransactionResponse(data []byte) (TransactionResponse, error) {
+	var report ExceptionReport
+	if err := xml.Unmarshal(data, &report); err == nil { //nolint:musttag
+		return TransactionResponse{}, &report
+	}
+
+	var response TransactionResponse
+	if err := xml.Unmarshal(data, &response); err != nil { //nolint:musttag
+		return TransactionResponse{}, fmt.Errorf("failed to parse TransactionResponse: %w", err)
+	}
+
+	return response, nil
+}
+
+func (a Insert) toXML() (string, error) {
+	if len(a.Records) == 0 {
+		return "", errors.New("insert has no records")
+	}
+
+	var sb strings.Builder
+
+	sb.WriteString("<csw:Insert>")
+
+	for _, record := range a.Records {
+		sb.Write(stripXMLDeclaration(record))
+	}
+
+	sb.WriteString("</csw:Insert>")
+
+	return sb.String(), nil
+}
+
+func (a Update) toXML() (string, error) {
+	if len(a.Record) == 0 {
+		return "", errors.New("update has no record")
+	}
+
+	return "<csw:Update>" + string(stripXMLDeclaration(a.Record)) + "</csw:Update>", nil
+}
+
+func (a UpdateProperties) toXML() (string, error) {
+	if len(a.Properties) == 0 {
+		return "", errors.New("update has no properties")
+	}
+
+	if a.Filter == nil {
+		return "", errors.New("update of properties requires a filter")
+	}
+
+	var sb strings.Builder
+
+	sb.WriteString(`<csw:Update>`)
+
+	for _, p := range a.Properties {
+		if err := ValidatePropertyName(p.Name); err != nil {
+			return "", err
+		}
+
+		sb.WriteString("<csw:RecordProperty><csw:Name>" + escapeXML(p.Name) + "</csw:Name>")
+
+		if p.Value != "" {
+			sb.WriteString("<csw:Value>" + escapeXML(p.Value) + "</csw:Value>")
+		}
+
+		sb.WriteString("</csw:RecordProperty>")
+	}
+
+	sb.WriteString(constraintXML(a.Filter))
+	sb.WriteString(`</csw:Update>`)
+
+	return sb.String(), nil
+}
+
+func (a Delete) toXML() (string, error) {
+	if a.Filter == nil {
+		return "", errors.New("delete requires a filter")
+	}
+
+	return `<csw:Delete typeName="` + RecordTypeName + `">` + constraintXML(a.Filter) + `</csw:Delete>`, nil
+}
+
+func constraintXML(filter Filter) string {
+	return `<csw:Constraint version="1.1.0"><ogc:Filter>` + filter.ToOGC() + `</ogc:Filter></csw:Constraint>`
+}
+
+// stripXMLDeclaration removes the XML declaration, so a document can be embedded in a request.
+func stripXMLDeclaration(record []byte) []byte {
+	record = bytes.TrimSpace(record)
+	if bytes.HasPrefix(record, []byte("<?xml")) {
+		if end := bytes.Index(record, []byte("?>")); end >= 0 {
+			record = bytes.TrimSpace(record[end+2:])
+		}
+	}
+
+	return record
+}
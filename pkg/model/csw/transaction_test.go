@@ -0,0 +1,55 @@
+package csw
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTransaction_ToRequestBody(t *testing.T) {
+	byID := Comparison{Property: "Identifier", Operator: Equal, Value: "abc"}
+
+	transaction := Transaction{Actions: []TransactionAction{
+		Insert{Records: [][]byte{[]byte(`<?xml version="1.0"?>` + "\n<gmd:MD_Metadata/>")}},
+		UpdateProperties{
+			Properties: []RecordProperty{{Name: "dc:title", Value: "A & B"}, {Name: "dc:subject"}},
+			Filter:     byID,
+		},
+		Delete{Filter: byID},
+	}}
+
+	body, err := transaction.ToRequestBody()
+	require.NoError(t, err)
+
+	filter := `<csw:Constraint version="1.1.0"><ogc:Filter><ogc:PropertyIsEqualTo>` +
+		`<ogc:PropertyName>Identifier</ogc:PropertyName><ogc:Literal>abc</ogc:Literal>` +
+		`</ogc:PropertyIsEqualTo></ogc:Filter></csw:Constraint>`
+	assert.Equal(t, `<?xml version="1.0" encoding="UTF-8"?>`+"\n"+
+		`<csw:Transaction xmlns:csw="http://www.opengis.net/cat/csw/2.0.2" xmlns:ogc="http://www.opengis.net/ogc" `+
+		`service="CSW" version="2.0.2">`+
+		`<csw:Insert><gmd:MD_Metadata/></csw:Insert>`+
+		`<csw:Update><csw:RecordProperty><csw:Name>dc:title</csw:Name><csw:Value>A &amp; B</csw:Value></csw:RecordProperty>`+
+		`<csw:RecordProperty><csw:Name>dc:subject</csw:Name></csw:RecordProperty>`+filter+`</csw:Update>`+
+		`<csw:Delete typeName="csw:Record">`+filter+`</csw:Delete>`+
+		`</csw:Transaction>`, body)
+
+	_, err = (&Transaction{Actions: []TransactionAction{Delete{}}}).ToRequestBody()
+	require.EqualError(t, err, "delete requires a filter")
+}
+
+func TestParseTransactionResponse(t *testing.T) {
+	response, err := ParseTransactionResponse([]byte(`<csw:TransactionResponse xmlns:csw="http://www.opengis.net/cat/csw/2.0.2">
+  <csw:TransactionSummary><csw:totalInserted>2</csw:totalInserted><csw:totalUpdated>0</csw:totalUpdated>
+  <csw:totalDeleted>0</csw:totalDeleted></csw:TransactionSummary>
+  <csw:InsertResult><csw:BriefRecord><dc:identifier xmlns:dc="http://purl.org/dc/elements/1.1/">a</dc:identifier></csw:BriefRecord>
+  <csw:BriefRecord><dc:identifier xmlns:dc="http://purl.org/dc/elements/1.1/">b</dc:identifier></csw:BriefRecord></csw:InsertResult>
+</csw:TransactionResponse>`))
+	require.NoError(t, err)
+	assert.Equal(t, 2, response.Summary.TotalInserted)
+	assert.Equal(t, []string{"a", "b"}, response.Identifiers())
+
+	_, err = ParseTransactionResponse([]byte(`<ExceptionReport><Exception exceptionCode="OperationNotSupported">` +
+		`<ExceptionText>Transactions are disabled</ExceptionText></Exception></ExceptionReport>`))
+	require.EqualError(t, err, "CSW exception: OperationNotSupported: Transactions are disabled")
+}
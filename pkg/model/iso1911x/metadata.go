@@ -41,6 +41,9 @@ const (
 	hvdThesaurusTitleAnchorHttps     = "https://publications.europa.eu/resource/dataset/high-value-dataset-category"
 	hvdRegulationImplementation      = "http://data.europa.eu/eli/reg_impl/2023/138/oj"
 	hvdRegulationImplementationHttps = "https://data.europa.eu/eli/reg_impl/2023/138/oj"
+
+	inspireLimitationsOnPublicAccessCodelist      = "http://inspire.ec.europa.eu/metadata-codelist/LimitationsOnPublicAccess/"
+	inspireLimitationsOnPublicAccessCodelistHttps = "https://inspire.ec.europa.eu/metadata-codelist/LimitationsOnPublicAccess/"
 )
 
 // String returns the string representation of the MetadataType.
@@ -62,13 +65,21 @@ type MDMetadata struct {
 			Title               string                  `xml:"citation>CI_Citation>title>CharacterString"`
 			Abstract            string                  `xml:"abstract>CharacterString"`
 			ResponsibleParty    *CSWResponsibleParty    `xml:"pointOfContact>CI_ResponsibleParty>organisationName"`
+			ContactEmail        string                  `xml:"pointOfContact>CI_ResponsibleParty>contactInfo>CI_Contact>address>CI_Address>electronicMailAddress>CharacterString"`
+			ContactURL          string                  `xml:"pointOfContact>CI_ResponsibleParty>contactInfo>CI_Contact>onlineResource>CI_OnlineResource>linkage>URL"`
 			GraphicOverview     *CSWGraphicOverview     `xml:"graphicOverview"`
 			DescriptiveKeywords []CSWDescriptiveKeyword `xml:"descriptiveKeywords"`
 			ServiceType         string                  `xml:"serviceType>LocalName"`
 			LicenseURL          []CSWAnchor             `xml:"resourceConstraints>MD_LegalConstraints>otherConstraints>Anchor"`
 			UseLimitation       string                  `xml:"resourceConstraints>MD_Constraints>useLimitation>CharacterString"`
 			Dates               []CSWDate               `xml:"citation>CI_Citation>date"`
-			OperatesOn          []struct {
+			Extent              struct {
+				WestBoundLongitude string `xml:"westBoundLongitude>Decimal"`
+				EastBoundLongitude string `xml:"eastBoundLongitude>Decimal"`
+				SouthBoundLatitude string `xml:"southBoundLatitude>Decimal"`
+				NorthBoundLatitude string `xml:"northBoundLatitude>Decimal"`
+			} `xml:"extent>EX_Extent>geographicElement>EX_GeographicBoundingBox"`
+			OperatesOn []struct {
 				Uuidref string `xml:"uuidref,attr"`
 				Href    string `xml:"href,attr"`
 			} `xml:"operatesOn"`
@@ -85,7 +96,7 @@ type MDMetadata struct {
 			LicenseURL          []CSWAnchor             `xml:"resourceConstraints>MD_LegalConstraints>otherConstraints>Anchor"`
 			UseLimitation       string                  `xml:"resourceConstraints>MD_Constraints>useLimitation>CharacterString"`
 			Dates               []CSWDate               `xml:"citation>CI_Citation>date"`
-			ResponsibleParty    *CSWResponsibleParty    `xml:"pointOfContact>CI_ResponsibleParty>OrganisationName"`
+			ResponsibleParty    *CSWResponsibleParty    `xml:"pointOfContact>CI_ResponsibleParty>organisationName"`
 			Extent              struct {
 				WestBoundLongitude string `xml:"westBoundLongitude>Decimal"`
 				EastBoundLongitude string `xml:"eastBoundLongitude>Decimal"`
@@ -94,13 +105,20 @@ type MDMetadata struct {
 			} `xml:"extent>EX_Extent>geographicElement>EX_GeographicBoundingBox"`
 		} `xml:"MD_DataIdentification"`
 	} `xml:"identificationInfo"`
-	// todo: also implement transferOptions>MD_DigitalTransferOptions>onLine for datasets
 	OnLine []struct {
 		URL      string `xml:"CI_OnlineResource>linkage>URL"`
 		Protocol struct {
 			Anchor CSWAnchor `xml:"Anchor"`
 		} `xml:"CI_OnlineResource>protocol"`
+		Name        string `xml:"CI_OnlineResource>name>CharacterString"`
+		Description struct {
+			CharacterString string    `xml:"CharacterString"`
+			Anchor          CSWAnchor `xml:"Anchor"`
+		} `xml:"CI_OnlineResource>description"`
 	} `xml:"distributionInfo>MD_Distribution>transferOptions>MD_DigitalTransferOptions>onLine"`
+	DistributorOnLine []struct {
+		URL string `xml:"CI_OnlineResource>linkage>URL"`
+	} `xml:"distributionInfo>MD_Distribution>distributor>MD_Distributor>distributorTransferOptions>MD_DigitalTransferOptions>onLine"`
 	DQDataQuality struct {
 		Report []struct {
 			ConsistencyResult []struct {
@@ -201,6 +219,21 @@ type ServiceEndpoint struct {
 	Protocol string
 }
 
+// OnlineResource represents a CI_OnlineResource from the distribution info.
+type OnlineResource struct {
+	URL         string
+	Protocol    string
+	Name        string
+	Description string
+}
+
+// ConformityStatement represents a DQ_ConformanceResult from the data quality section.
+type ConformityStatement struct {
+	Title string
+	Href  string
+	Pass  string
+}
+
 // NormalizeXMLText removes leading and trailing whitespace from XML text nodes.
 func NormalizeXMLText(s string) string {
 	return strings.Join(strings.Fields(s), " ")
@@ -531,6 +564,8 @@ func (m *MDMetadata) GetOperatesOnForService() (result []string) {
 	for _, val := range m.IdentificationInfo.SVServiceIdentification.OperatesOn {
 		unescapedHref := html.UnescapeString(val.Href)
 
+		found := false
+
 		hrefUrl, err := url.Parse(unescapedHref)
 		if err == nil {
 			for _, key := range []string{"id", "ID"} {
@@ -540,9 +575,13 @@ func (m *MDMetadata) GetOperatesOnForService() (result []string) {
 					id = strings.ReplaceAll(id, " ", "")
 
 					result = append(result, id)
+					found = true
 				}
 			}
-		} else {
+		}
+
+		// Fall back to the uuidref when the href does not hold an id, i.e. when only a uuidref is given
+		if !found && val.Uuidref != "" {
 			result = append(result, strings.ReplaceAll(val.Uuidref, " ", ""))
 		}
 	}
@@ -563,6 +602,24 @@ func (m *MDMetadata) GetServiceEndpointsForService() (result []ServiceEndpoint)
 	return
 }
 
+// GetResourceLocatorsForDataset returns the URLs of the online resources of a dataset, given in the transfer
+// options of the distribution or of its distributors.
+func (m *MDMetadata) GetResourceLocatorsForDataset() (result []string) {
+	for _, ol := range m.OnLine {
+		if link := NormalizeXMLText(ol.URL); link != "" {
+			result = append(result, link)
+		}
+	}
+
+	for _, ol := range m.DistributorOnLine {
+		if link := NormalizeXMLText(ol.URL); link != "" {
+			result = append(result, link)
+		}
+	}
+
+	return
+}
+
 func (m *MDMetadata) GetServiceContactForService() string {
 	if m.IdentificationInfo.SVServiceIdentification.ResponsibleParty != nil {
 		if m.IdentificationInfo.SVServiceIdentification.ResponsibleParty.Char != "" {
@@ -587,6 +644,197 @@ func (m *MDMetadata) GetRevisionDate() string {
 	return m.getDateByType("revision")
 }
 
+// GetOrganisationName returns the organisation name of the point of contact for either dataset or service.
+// Falls back to the organisation name of the metadata contact when the point of contact has none.
+func (m *MDMetadata) GetOrganisationName() string {
+	var party *CSWResponsibleParty
+
+	switch m.GetMetaDataType() {
+	case Service:
+		if m.IdentificationInfo.SVServiceIdentification != nil {
+			party = m.IdentificationInfo.SVServiceIdentification.ResponsibleParty
+		}
+	case Dataset:
+		if m.IdentificationInfo.MDDataIdentification != nil {
+			party = m.IdentificationInfo.MDDataIdentification.ResponsibleParty
+		}
+	}
+
+	for _, p := range []*CSWResponsibleParty{party, m.ResponsibleParty} {
+		if p == nil {
+			continue
+		}
+
+		if name := NormalizeXMLText(p.Char); name != "" {
+			return name
+		}
+
+		if name := NormalizeXMLText(p.Anchor); name != "" {
+			return name
+		}
+	}
+
+	return ""
+}
+
+// GetTitle returns the title of either dataset or service metadata.
+func (m *MDMetadata) GetTitle() string {
+	switch m.GetMetaDataType() {
+	case Service:
+		if m.IdentificationInfo.SVServiceIdentification != nil {
+			return NormalizeXMLText(m.IdentificationInfo.SVServiceIdentification.Title)
+		}
+	case Dataset:
+		if m.IdentificationInfo.MDDataIdentification != nil {
+			return NormalizeXMLText(m.IdentificationInfo.MDDataIdentification.Title)
+		}
+	}
+
+	return ""
+}
+
+// GetConformityStatements returns all conformity statements from the data quality section.
+func (m *MDMetadata) GetConformityStatements() (result []ConformityStatement) {
+	for _, report := range m.DQDataQuality.Report {
+		for _, cr := range report.ConsistencyResult {
+			title := cr.Specification.CharacterString
+			if title == "" {
+				title = cr.Specification.Anchor.Text
+			}
+
+			result = append(result, ConformityStatement{
+				Title: NormalizeXMLText(title),
+				Href:  NormalizeXMLText(cr.Specification.Anchor.Href),
+				Pass:  NormalizeXMLText(cr.Pass),
+			})
+		}
+	}
+
+	return result
+}
+
+// GetSpatialDataServiceCategories returns the INSPIRE spatial data service categories
+// (i.e. infoMapAccessService) that are used as keywords.
+func (m *MDMetadata) GetSpatialDataServiceCategories() (categories []string) {
+	for _, dk := range m.getDescriptiveKeywords() {
+		for _, kw := range dk.MDKeywords.Keyword {
+			if !m.isInspireSpatialDataServiceCategory(kw) {
+				continue
+			}
+
+			href := NormalizeXMLText(kw.Anchor.Href)
+			categories = append(categories, href[strings.LastIndex(href, "/")+1:])
+		}
+	}
+
+	return categories
+}
+
+// GetLimitationsOnPublicAccess returns the codes from the INSPIRE LimitationsOnPublicAccess codelist
+// that are used in the legal constraints, i.e. noLimitations.
+func (m *MDMetadata) GetLimitationsOnPublicAccess() (limitations []string) {
+	for _, oc := range m.GetOtherConstraints() {
+		href := NormalizeXMLText(oc.Href)
+		if strings.HasPrefix(href, inspireLimitationsOnPublicAccessCodelist) ||
+			strings.HasPrefix(href, inspireLimitationsOnPublicAccessCodelistHttps) {
+			limitations = append(limitations, href[strings.LastIndex(href, "/")+1:])
+		}
+	}
+
+	return limitations
+}
+
+// GetOtherConstraints returns the otherConstraints anchors of the legal constraints for either dataset or service.
+func (m *MDMetadata) GetOtherConstraints() []CSWAnchor {
+	switch m.GetMetaDataType() {
+	case Service:
+		if m.IdentificationInfo.SVServiceIdentification != nil {
+			return m.IdentificationInfo.SVServiceIdentification.LicenseURL
+		}
+	case Dataset:
+		if m.IdentificationInfo.MDDataIdentification != nil {
+			return m.IdentificationInfo.MDDataIdentification.LicenseURL
+		}
+	}
+
+	return nil
+}
+
+// GetContactEmail returns the e-mail address of the point of contact for either dataset or service.
+func (m *MDMetadata) GetContactEmail() string {
+	switch m.GetMetaDataType() {
+	case Service:
+		if m.IdentificationInfo.SVServiceIdentification != nil {
+			return NormalizeXMLText(m.IdentificationInfo.SVServiceIdentification.ContactEmail)
+		}
+	case Dataset:
+		if m.IdentificationInfo.MDDataIdentification != nil {
+			return NormalizeXMLText(m.IdentificationInfo.MDDataIdentification.ContactEmail)
+		}
+	}
+
+	return ""
+}
+
+// GetContactURL returns the online resource URL of the point of contact for either dataset or service.
+func (m *MDMetadata) GetContactURL() string {
+	switch m.GetMetaDataType() {
+	case Service:
+		if m.IdentificationInfo.SVServiceIdentification != nil {
+			return NormalizeXMLText(m.IdentificationInfo.SVServiceIdentification.ContactURL)
+		}
+	case Dataset:
+		if m.IdentificationInfo.MDDataIdentification != nil {
+			return NormalizeXMLText(m.IdentificationInfo.MDDataIdentification.ContactURL)
+		}
+	}
+
+	return ""
+}
+
+// GetOnlineResources returns all online resources from the distribution info,
+// including name and description which are omitted by GetServiceEndpointsForService.
+func (m *MDMetadata) GetOnlineResources() (result []OnlineResource) {
+	for _, ol := range m.OnLine {
+		description := ol.Description.CharacterString
+		if description == "" {
+			description = ol.Description.Anchor.Href
+		}
+
+		result = append(result, OnlineResource{
+			URL:         NormalizeXMLText(ol.URL),
+			Protocol:    NormalizeXMLText(ol.Protocol.Anchor.Text),
+			Name:        NormalizeXMLText(ol.Name),
+			Description: NormalizeXMLText(description),
+		})
+	}
+
+	return result
+}
+
+// CountControlledKeywords returns the number of keywords and the number of those that come from a controlled
+// vocabulary, i.e. keywords in a group with a thesaurus name or keywords given as an Anchor.
+func (m *MDMetadata) CountControlledKeywords() (total int, controlled int) {
+	for _, dk := range m.getDescriptiveKeywords() {
+		hasThesaurus := NormalizeXMLText(dk.MDKeywords.Thesaurus.CharacterString) != "" ||
+			NormalizeXMLText(dk.MDKeywords.Thesaurus.Anchor.Text) != ""
+
+		for _, kw := range dk.MDKeywords.Keyword {
+			if NormalizeXMLText(kw.CharacterString) == "" && NormalizeXMLText(kw.Anchor.Text) == "" {
+				continue
+			}
+
+			total++
+
+			if hasThesaurus || NormalizeXMLText(kw.Anchor.Href) != "" {
+				controlled++
+			}
+		}
+	}
+
+	return total, controlled
+}
+
 func (m *MDMetadata) isInspireGroup(dk CSWDescriptiveKeyword) bool {
 	th := dk.MDKeywords.Thesaurus
 	if NormalizeXMLText(th.CharacterString) == inspireThesaurusName ||
@@ -648,6 +896,21 @@ func (m *MDMetadata) isHVDImplementingRegulation(kw CSWKeywordEntry) bool {
 	return false
 }
 
+func (m *MDMetadata) getDescriptiveKeywords() []CSWDescriptiveKeyword {
+	switch m.GetMetaDataType() {
+	case Service:
+		if m.IdentificationInfo.SVServiceIdentification != nil {
+			return m.IdentificationInfo.SVServiceIdentification.DescriptiveKeywords
+		}
+	case Dataset:
+		if m.IdentificationInfo.MDDataIdentification != nil {
+			return m.IdentificationInfo.MDDataIdentification.DescriptiveKeywords
+		}
+	}
+
+	return nil
+}
+
 func (m *MDMetadata) getDateByType(dateType string) string {
 	var dates []CSWDate
 
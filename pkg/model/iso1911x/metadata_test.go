@@ -1,11 +1,11 @@
-package iso1911x
+package iso1911x_test
 
 import (
-	"encoding/xml"
-	"os"
 	"path/filepath"
 	"reflect"
 	"testing"
+
+	"github.com/pdok/pdok-metadata-tool/v2/internal/testutil"
 )
 
 const serviceMetadataStandard = "ISO19119"
@@ -48,7 +48,7 @@ func TestMDMetadata_GetKeywords(t *testing.T) {
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			filename := filepath.Join("..", "..", "..", "examples", tt.standard, tt.filename)
-			md := loadMDMetadataFromXML(t, filename)
+			md := testutil.LoadMDMetadata(t, filename)
 
 			if gotKeywords := md.GetKeywords(); !reflect.DeepEqual(gotKeywords, tt.wantKeywords) {
 				t.Errorf("GetKeywords() = %v, want %v", gotKeywords, tt.wantKeywords)
@@ -57,23 +57,6 @@ func TestMDMetadata_GetKeywords(t *testing.T) {
 	}
 }
 
-func loadMDMetadataFromXML(t *testing.T, path string) MDMetadata {
-	t.Helper()
-
-	data, err := os.ReadFile(path)
-	if err != nil {
-		t.Fatalf("reading %s: %v", path, err)
-	}
-
-	var md MDMetadata
-	//nolint:musttag
-	if err := xml.Unmarshal(data, &md); err != nil {
-		t.Fatalf("unmarshal %s: %v", path, err)
-	}
-
-	return md
-}
-
 func TestMDMetadata_GetLicenseURL(t *testing.T) {
 	tests := []struct {
 		name           string
@@ -109,7 +92,7 @@ func TestMDMetadata_GetLicenseURL(t *testing.T) {
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			filename := filepath.Join("..", "..", "..", "examples", tt.standard, tt.filename)
-			md := loadMDMetadataFromXML(t, filename)
+			md := testutil.LoadMDMetadata(t, filename)
 
 			if gotLicenseURL := md.GetLicenseURL(); !reflect.DeepEqual(
 				gotLicenseURL,
@@ -120,3 +103,36 @@ func TestMDMetadata_GetLicenseURL(t *testing.T) {
 		})
 	}
 }
+
+func TestMDMetadata_GetResourceLocatorsForDataset(t *testing.T) {
+	tests := []struct {
+		name         string
+		filename     string
+		wantLocators []string
+	}{
+		{
+			name:     "Dataset with view, download and atom services",
+			filename: "Wetlands_INSPIRE_geharmoniseerd.xml",
+			wantLocators: []string{
+				"https://service.pdok.nl/rvo/beschermdegebieden/wetlands/wms/v1_0?request=GetCapabilities&service=WMS",
+				"https://service.pdok.nl/rvo/beschermdegebieden/wetlands/wfs/v1_0?request=GetCapabilities&service=WFS",
+				"https://service.pdok.nl/rvo/beschermdegebieden/wetlands/atom/index.xml",
+			},
+		},
+		{
+			name:         "Dataset with empty transfer options",
+			filename:     "Invasieve_Exoten_INSPIRE_geharmoniseerd.xml",
+			wantLocators: nil,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			filename := filepath.Join("..", "..", "..", "examples", datasetMetadataStandard, tt.filename)
+			md := testutil.LoadMDMetadata(t, filename)
+
+			if gotLocators := md.GetResourceLocatorsForDataset(); !reflect.DeepEqual(gotLocators, tt.wantLocators) {
+				t.Errorf("GetResourceLocatorsForDataset() = %v, want %v", gotLocators, tt.wantLocators)
+			}
+		})
+	}
+}
@@ -9,22 +9,23 @@ import (
 
 // NLDatasetMetadata is used for retrieving the relevant fields from dataset metadata.
 type NLDatasetMetadata struct {
-	MetadataID     string
-	SourceID       string
-	Title          string
-	Abstract       string
-	ContactName    string
-	ContactEmail   string
-	ContactURL     string
-	Keywords       []string
-	LicenceURL     string
-	UseLimitation  string
-	ThumbnailURL   string
-	InspireVariant inspire.InspireVariant
-	InspireThemes  []string
-	HVDCategories  []hvd.HVDCategory
-	BoundingBox    *BoundingBox
-	CreationDate   string
+	MetadataID       string
+	SourceID         string
+	Title            string
+	Abstract         string
+	OrganisationName string
+	ContactName      string
+	ContactEmail     string
+	ContactURL       string
+	Keywords         []string
+	LicenceURL       string
+	UseLimitation    string
+	ThumbnailURL     string
+	InspireVariant   inspire.InspireVariant
+	InspireThemes    []string
+	HVDCategories    []hvd.HVDCategory
+	BoundingBox      *BoundingBox
+	CreationDate     string
 }
 
 // NewNLDatasetMetadataFromMDMetadata creates a new instance based on dataset metadata from a CSW response.
@@ -47,6 +48,7 @@ func NewNLDatasetMetadataFromMDMetadataWithHVDRepo(
 		Abstract: iso1911x.NormalizeXMLText(
 			m.IdentificationInfo.MDDataIdentification.Abstract,
 		),
+		OrganisationName: m.GetOrganisationName(),
 		ContactName: iso1911x.NormalizeXMLText(
 			m.IdentificationInfo.MDDataIdentification.ContactName,
 		),
@@ -27,13 +27,14 @@ func TestNewNLDatasetMetadataFromMDMetadataWithHVDRepo_StaticExamples(t *testing
 		{
 			File: filepath.Join(examples, "500d396f-5ec6-4e4b-a151-5fb3cddd8082.xml"),
 			Metadata: NLDatasetMetadata{
-				MetadataID:   "500d396f-5ec6-4e4b-a151-5fb3cddd8082",
-				SourceID:     "440c4a06-6924-4f9c-a9e2-6f61340f711b",
-				Title:        "Gemeten Zwaveldioxide concentraties in buitenlucht.",
-				Abstract:     "Ruwe ongevalideerde uurwaarden zwaveldioxide (SO2) op grondniveau in de buitenlucht gemeten in het Landelijk Meetnet Luchtkwaliteit (LML). Zwaveldioxide is een kleurloos gas. Het wordt voornamelijk gevormd het gebruik van zwavelhoudende brandstoffen. Belangrijke bronnen zijn kolengestookte energiecentrales, raffinaderijen en het verkeer (de laatste jaren is voornamelijk de internationale scheepvaart van belang). De concentraties zwaveldioxide zijn in Nederland sterk gedaald door maatregelen op de belangrijkste bronnen. Sinds de jaren 90 van de vorige eeuw zijn er geen normoverschrijdingen meer geweest. Bij hoge concentraties heeft zwaveldioxide negatieve effecten op de menselijke gezondheid en draagt het bij aan de verzuring van ecosystemen. Zwaveldioxide wordt in de lucht gedeeltelijk omgezet in sulfaatdeeltjes en heeft zo een bijdrage aan fijn stof.",
-				ContactName:  "",
-				ContactEmail: "geodata@rivm.nl",
-				ContactURL:   "",
+				MetadataID:       "500d396f-5ec6-4e4b-a151-5fb3cddd8082",
+				SourceID:         "440c4a06-6924-4f9c-a9e2-6f61340f711b",
+				Title:            "Gemeten Zwaveldioxide concentraties in buitenlucht.",
+				Abstract:         "Ruwe ongevalideerde uurwaarden zwaveldioxide (SO2) op grondniveau in de buitenlucht gemeten in het Landelijk Meetnet Luchtkwaliteit (LML). Zwaveldioxide is een kleurloos gas. Het wordt voornamelijk gevormd het gebruik van zwavelhoudende brandstoffen. Belangrijke bronnen zijn kolengestookte energiecentrales, raffinaderijen en het verkeer (de laatste jaren is voornamelijk de internationale scheepvaart van belang). De concentraties zwaveldioxide zijn in Nederland sterk gedaald door maatregelen op de belangrijkste bronnen. Sinds de jaren 90 van de vorige eeuw zijn er geen normoverschrijdingen meer geweest. Bij hoge concentraties heeft zwaveldioxide negatieve effecten op de menselijke gezondheid en draagt het bij aan de verzuring van ecosystemen. Zwaveldioxide wordt in de lucht gedeeltelijk omgezet in sulfaatdeeltjes en heeft zo een bijdrage aan fijn stof.",
+				OrganisationName: "RIVM",
+				ContactName:      "",
+				ContactEmail:     "geodata@rivm.nl",
+				ContactURL:       "",
 				Keywords: []string{
 					"Zwaveldioxide",
 					"Vegetatie",
@@ -217,6 +218,10 @@ func TestNewNLDatasetMetadataFromMDMetadataWithHVDRepo_StaticExamples(t *testing
 			assert.Equal(t, tc.Metadata.Title, flat.Title)
 			assert.Equal(t, tc.Metadata.Abstract, flat.Abstract)
 			assert.Equal(t, tc.Metadata.ContactName, flat.ContactName)
+
+			if tc.Metadata.OrganisationName != "" {
+				assert.Equal(t, tc.Metadata.OrganisationName, flat.OrganisationName)
+			}
 			assert.Equal(t, tc.Metadata.ContactEmail, flat.ContactEmail)
 			assert.Equal(t, tc.Metadata.ContactURL, flat.ContactURL)
 			assert.Equal(t, tc.Metadata.Keywords, flat.Keywords)
@@ -1,8 +1,79 @@
 package metadata
 
+import (
+	"errors"
+	"strconv"
+	"strings"
+)
+
+// bboxTolerance is the margin in degrees used when comparing bounding boxes, to allow for rounding.
+const bboxTolerance = 1e-6
+
+// BoundingBox holds a geographic bounding box in decimal degrees as found in the metadata.
 type BoundingBox struct {
 	WestBoundLongitude string
 	EastBoundLongitude string
 	SouthBoundLatitude string
 	NorthBoundLatitude string
 }
+
+// IsEmpty returns true when none of the bounds are given.
+func (b *BoundingBox) IsEmpty() bool {
+	return b == nil || (strings.TrimSpace(b.WestBoundLongitude) == "" &&
+		strings.TrimSpace(b.EastBoundLongitude) == "" &&
+		strings.TrimSpace(b.SouthBoundLatitude) == "" &&
+		strings.TrimSpace(b.NorthBoundLatitude) == "")
+}
+
+// Bounds returns the bounds as west, south, east, north.
+func (b *BoundingBox) Bounds() (west, south, east, north float64, err error) {
+	if b.IsEmpty() {
+		return 0, 0, 0, 0, errors.New("bounding box is empty")
+	}
+
+	values := make([]float64, 4) //nolint:mnd
+
+	for i, s := range []string{
+		b.WestBoundLongitude, b.SouthBoundLatitude, b.EastBoundLongitude, b.NorthBoundLatitude,
+	} {
+		values[i], err = strconv.ParseFloat(strings.TrimSpace(s), 64)
+		if err != nil {
+			return 0, 0, 0, 0, err
+		}
+	}
+
+	return values[0], values[1], values[2], values[3], nil
+}
+
+// Covers returns true when the other bounding box lies within this bounding box.
+func (b *BoundingBox) Covers(other *BoundingBox) (bool, error) {
+	west, south, east, north, err := b.Bounds()
+	if err != nil {
+		return false, err
+	}
+
+	otherWest, otherSouth, otherEast, otherNorth, err := other.Bounds()
+	if err != nil {
+		return false, err
+	}
+
+	return otherWest >= west-bboxTolerance &&
+		otherSouth >= south-bboxTolerance &&
+		otherEast <= east+bboxTolerance &&
+		otherNorth <= north+bboxTolerance, nil
+}
+
+// Intersects returns true when the other bounding box overlaps with this bounding box.
+func (b *BoundingBox) Intersects(other *BoundingBox) (bool, error) {
+	west, south, east, north, err := b.Bounds()
+	if err != nil {
+		return false, err
+	}
+
+	otherWest, otherSouth, otherEast, otherNorth, err := other.Bounds()
+	if err != nil {
+		return false, err
+	}
+
+	return otherWest <= east && otherEast >= west && otherSouth <= north && otherNorth >= south, nil
+}
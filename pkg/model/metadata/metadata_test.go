@@ -0,0 +1,98 @@
+package metadata
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBoundingBox_Covers(t *testing.T) {
+	netherlands := &BoundingBox{
+		WestBoundLongitude: "3.2",
+		EastBoundLongitude: "7.22",
+		SouthBoundLatitude: "50.75",
+		NorthBoundLatitude: "53.7",
+	}
+
+	tests := []struct {
+		name           string
+		other          *BoundingBox
+		wantCovers     bool
+		wantIntersects bool
+		wantErr        bool
+	}{
+		{
+			name:           "Same bounding box",
+			other:          netherlands,
+			wantCovers:     true,
+			wantIntersects: true,
+		},
+		{
+			name: "Utrecht lies within",
+			other: &BoundingBox{
+				WestBoundLongitude: " 4.9 ",
+				EastBoundLongitude: "5.3",
+				SouthBoundLatitude: "51.9",
+				NorthBoundLatitude: "52.2",
+			},
+			wantCovers:     true,
+			wantIntersects: true,
+		},
+		{
+			name: "Extends beyond the east bound",
+			other: &BoundingBox{
+				WestBoundLongitude: "6",
+				EastBoundLongitude: "9",
+				SouthBoundLatitude: "51",
+				NorthBoundLatitude: "52",
+			},
+			wantCovers:     false,
+			wantIntersects: true,
+		},
+		{
+			name: "Disjoint",
+			other: &BoundingBox{
+				WestBoundLongitude: "-73.3",
+				EastBoundLongitude: "-60",
+				SouthBoundLatitude: "22.8",
+				NorthBoundLatitude: "30",
+			},
+			wantCovers:     false,
+			wantIntersects: false,
+		},
+		{
+			name:    "Empty",
+			other:   &BoundingBox{},
+			wantErr: true,
+		},
+		{
+			name: "Not a number",
+			other: &BoundingBox{
+				WestBoundLongitude: "west",
+				EastBoundLongitude: "5.3",
+				SouthBoundLatitude: "51.9",
+				NorthBoundLatitude: "52.2",
+			},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			covers, err := netherlands.Covers(tt.other)
+			if tt.wantErr {
+				require.Error(t, err)
+
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantCovers, covers)
+
+			intersects, err := netherlands.Intersects(tt.other)
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantIntersects, intersects)
+		})
+	}
+}
@@ -0,0 +1,113 @@
+package metadata
+
+import (
+	"net/url"
+	"strconv"
+
+	"github.com/pdok/pdok-metadata-tool/v2/pkg/model/ogcrecords"
+)
+
+// NewNLDatasetMetadataFromRecord creates a new instance based on an OGC API Records record.
+// Records only have the core properties, so INSPIRE and HVD fields are left empty; retrieve
+// the ISO XML of the record for a complete model.
+func NewNLDatasetMetadataFromRecord(r *ogcrecords.Record) *NLDatasetMetadata {
+	dm := &NLDatasetMetadata{
+		MetadataID:    r.ID,
+		Title:         r.Properties.Title,
+		Abstract:      r.Properties.Description,
+		Keywords:      r.AllKeywords(),
+		LicenceURL:    recordLicenceURL(r),
+		UseLimitation: r.Properties.Rights,
+		ThumbnailURL:  r.LinkByRel(ogcrecords.RelPreview),
+		BoundingBox:   recordBoundingBox(r),
+		CreationDate:  r.Properties.Created,
+	}
+
+	if contact := recordContact(r); contact != nil {
+		dm.OrganisationName = contact.Organization
+		dm.ContactName = contact.Name
+
+		if len(contact.Emails) > 0 {
+			dm.ContactEmail = contact.Emails[0].Value
+		}
+
+		if len(contact.Links) > 0 {
+			dm.ContactURL = contact.Links[0].Href
+		}
+	}
+
+	return dm
+}
+
+// NewNLServiceMetadataFromRecord creates a new instance based on an OGC API Records record.
+// Records only have the core properties, so service type, coupled datasets and endpoints are left empty;
+// retrieve the ISO XML of the record for a complete model.
+func NewNLServiceMetadataFromRecord(r *ogcrecords.Record) *NLServiceMetadata {
+	sm := &NLServiceMetadata{
+		MetadataID:    r.ID,
+		Title:         r.Properties.Title,
+		Abstract:      r.Properties.Description,
+		Keywords:      r.AllKeywords(),
+		LicenceURL:    recordLicenceURL(r),
+		UseLimitation: r.Properties.Rights,
+		ThumbnailURL:  r.LinkByRel(ogcrecords.RelPreview),
+		BoundingBox:   recordBoundingBox(r),
+		CreationDate:  r.Properties.Created,
+		RevisionDate:  r.Properties.Updated,
+	}
+
+	if contact := recordContact(r); contact != nil {
+		sm.OrganisationName = contact.Organization
+	}
+
+	return sm
+}
+
+// recordContact returns the point of contact of the record, or else the first contact with an organization.
+func recordContact(r *ogcrecords.Record) *ogcrecords.Contact {
+	var first *ogcrecords.Contact
+
+	for i := range r.Properties.Contacts {
+		contact := &r.Properties.Contacts[i]
+		for _, role := range contact.Roles {
+			if role == "pointOfContact" {
+				return contact
+			}
+		}
+
+		if first == nil && contact.Organization != "" {
+			first = contact
+		}
+	}
+
+	return first
+}
+
+// recordLicenceURL returns the license link, or the license property when it is a URL.
+func recordLicenceURL(r *ogcrecords.Record) string {
+	if href := r.LinkByRel(ogcrecords.RelLicense); href != "" {
+		return href
+	}
+
+	if u, err := url.Parse(r.Properties.License); err == nil && u.Scheme != "" && u.Host != "" {
+		return r.Properties.License
+	}
+
+	return ""
+}
+
+func recordBoundingBox(r *ogcrecords.Record) *BoundingBox {
+	west, south, east, north, ok := r.Extent()
+	if !ok {
+		return nil
+	}
+
+	format := func(v float64) string { return strconv.FormatFloat(v, 'f', -1, 64) }
+
+	return &BoundingBox{
+		WestBoundLongitude: format(west),
+		EastBoundLongitude: format(east),
+		SouthBoundLatitude: format(south),
+		NorthBoundLatitude: format(north),
+	}
+}
@@ -0,0 +1,56 @@
+package metadata
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/pdok/pdok-metadata-tool/v2/pkg/model/ogcrecords"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewNLDatasetMetadataFromRecord(t *testing.T) {
+	var record ogcrecords.Record
+
+	err := json.Unmarshal([]byte(`{
+		"id": "abc",
+		"type": "Feature",
+		"geometry": {"type": "Polygon", "coordinates": [[[3.2, 50.75], [7.22, 50.75], [7.22, 53.7], [3.2, 53.7], [3.2, 50.75]]]},
+		"properties": {
+			"type": "dataset",
+			"title": "Kadastrale kaart",
+			"description": "De kadastrale kaart",
+			"keywords": ["kadaster", "percelen"],
+			"themes": [{"concepts": [{"id": "cp", "title": "Kadastrale percelen"}, {"id": "kadaster"}], "scheme": "https://www.eionet.europa.eu/gemet/nl/inspire-themes/"}],
+			"contacts": [
+				{"name": "Helpdesk", "organization": "PDOK", "roles": ["distributor"]},
+				{"name": "Klantcontact", "organization": "Kadaster", "emails": [{"value": "info@kadaster.nl"}], "roles": ["pointOfContact"]}
+			],
+			"license": "http://creativecommons.org/publicdomain/mark/1.0/deed.nl",
+			"rights": "Geen beperkingen",
+			"created": "2024-01-01"
+		},
+		"links": [{"href": "https://example.com/thumbnail.png", "rel": "preview", "type": "image/png"}]
+	}`), &record)
+	require.NoError(t, err)
+
+	assert.Equal(t, &NLDatasetMetadata{
+		MetadataID:       "abc",
+		Title:            "Kadastrale kaart",
+		Abstract:         "De kadastrale kaart",
+		Keywords:         []string{"kadaster", "percelen", "Kadastrale percelen"},
+		LicenceURL:       "http://creativecommons.org/publicdomain/mark/1.0/deed.nl",
+		UseLimitation:    "Geen beperkingen",
+		ThumbnailURL:     "https://example.com/thumbnail.png",
+		CreationDate:     "2024-01-01",
+		OrganisationName: "Kadaster",
+		ContactName:      "Klantcontact",
+		ContactEmail:     "info@kadaster.nl",
+		BoundingBox: &BoundingBox{
+			WestBoundLongitude: "3.2",
+			EastBoundLongitude: "7.22",
+			SouthBoundLatitude: "50.75",
+			NorthBoundLatitude: "53.7",
+		},
+	}, NewNLDatasetMetadataFromRecord(&record))
+}
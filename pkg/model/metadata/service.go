@@ -23,6 +23,7 @@ type NLServiceMetadata struct {
 	//	InspireVariant *inspire.InspireVariant // A service does not have an inspire variant. Datasets have. The service can be conform inspire or not. But when a dataset is as-is the service is still 100% conform inspire. Thus, the conformity of a service is separate from the dataset it serves. This is our current interpretation. In the future we might call this field conformInspire. But this only reflects if the service is conform inspire and not if the dataset in the service is conform.
 	InspireThemes []string
 	HVDCategories []hvd.HVDCategory
+	BoundingBox   *BoundingBox // Nil when the service has no extent
 	CreationDate  string
 	RevisionDate  string
 }
@@ -62,7 +63,17 @@ func NewNLServiceMetadataFromMDMetadataWithHVDRepo(
 		RevisionDate:  m.GetRevisionDate(),
 	}
 
-	// Organisation (point of contact organisation name)
+	extent := m.IdentificationInfo.SVServiceIdentification.Extent
+	bbox := &BoundingBox{
+		WestBoundLongitude: iso1911x.NormalizeXMLText(extent.WestBoundLongitude),
+		EastBoundLongitude: iso1911x.NormalizeXMLText(extent.EastBoundLongitude),
+		SouthBoundLatitude: iso1911x.NormalizeXMLText(extent.SouthBoundLatitude),
+		NorthBoundLatitude: iso1911x.NormalizeXMLText(extent.NorthBoundLatitude),
+	}
+
+	if !bbox.IsEmpty() {
+		sm.BoundingBox = bbox
+	}
 
 	return sm
 }
@@ -52,6 +52,12 @@ func TestNewNLServiceMetadataFromMDMetadataWithHVDRepo_StaticExamples(t *testing
 				ThumbnailURL:  "https://www.nationaalgeoregister.nl/geonetwork/srv/api/records/39d03482-fef0-4706-8f66-16ffb2617155/attachments/map%20(1).png",
 				CreationDate:  "2024-04-25",
 				RevisionDate:  "2024-11-22",
+				BoundingBox: &BoundingBox{
+					WestBoundLongitude: "-0.1124",
+					EastBoundLongitude: "9.3353",
+					SouthBoundLatitude: "50.3808",
+					NorthBoundLatitude: "55.9755",
+				},
 				InspireThemes: []string{
 					"am",
 				},
@@ -207,6 +213,10 @@ func TestNewNLServiceMetadataFromMDMetadataWithHVDRepo_StaticExamples(t *testing
 			assert.Equal(t, tc.Metadata.RevisionDate, flat.RevisionDate)
 			assert.Equal(t, tc.Metadata.InspireThemes, flat.InspireThemes)
 
+			if tc.Metadata.BoundingBox != nil {
+				assert.Equal(t, tc.Metadata.BoundingBox, flat.BoundingBox)
+			}
+
 			if tc.Metadata.HVDCategories != nil {
 				assert.NotEmpty(t, flat.HVDCategories)
 				assert.Equal(t, tc.Metadata.HVDCategories, flat.HVDCategories)
@@ -3,12 +3,80 @@ package ngr
 
 const NgrEndpoint = "https://nationaalgeoregister.nl/geonetwork/srv/dut/csw"
 
+// NgrURL is the base URL of NGR, for the GeoNetwork API.
+const NgrURL = "https://nationaalgeoregister.nl"
+
 // RecordTagsResponse for retrieving tags from NGR.
 type RecordTagsResponse []Tag
 
-// Tag struct for retrieving tags from NGR.
+// Tag struct for retrieving tags from NGR. In the GeoNetwork API, the categories of records are called tags.
 type Tag struct {
 	ID    int               `json:"id"`
 	Name  string            `json:"name"`
 	Label map[string]string `json:"label"`
 }
+
+// Group struct for retrieving groups from NGR.
+type Group struct {
+	ID          int               `json:"id"`
+	Name        string            `json:"name"`
+	Label       map[string]string `json:"label"`
+	Description string            `json:"description"`
+	Email       string            `json:"email"`
+}
+
+// GroupAll is the group of all users, including anonymous users. A record is published when this group may view it.
+const GroupAll = 1
+
+// Operation is an operation on a record that is allowed to a group.
+type Operation string
+
+// Operations on a record.
+const (
+	OperationView     Operation = "view"
+	OperationDownload Operation = "download"
+	OperationDynamic  Operation = "dynamic"
+	OperationEditing  Operation = "editing"
+	OperationNotify   Operation = "notify"
+	OperationFeatured Operation = "featured"
+)
+
+// GroupPrivileges are the operations on a record that are allowed to a group.
+type GroupPrivileges struct {
+	Group      int                `json:"group"`
+	Operations map[Operation]bool `json:"operations"`
+}
+
+// Sharing struct for retrieving the owner and privileges of a record from NGR.
+type Sharing struct {
+	Owner      string            `json:"owner"`
+	GroupOwner string            `json:"groupOwner"`
+	Privileges []GroupPrivileges `json:"privileges"`
+}
+
+// SharingParameter struct for setting the privileges of a record in NGR. Only the given operations are changed,
+// unless Clear is set: then all privileges that are not given are removed.
+type SharingParameter struct {
+	Clear      bool              `json:"clear"`
+	Privileges []GroupPrivileges `json:"privileges"`
+}
+
+// Visibility of an attachment of a record.
+type Visibility string
+
+// Visibilities of attachments. Public attachments, such as thumbnails, can be downloaded by everyone.
+const (
+	VisibilityPublic  Visibility = "public"
+	VisibilityPrivate Visibility = "private"
+)
+
+// Attachment struct for retrieving the attachments (files) of a record from NGR.
+type Attachment struct {
+	ID               string     `json:"id"`
+	URL              string     `json:"url"`
+	Filename         string     `json:"filename"`
+	Size             int64      `json:"size"`
+	LastModification string     `json:"lastModification"`
+	Visibility       Visibility `json:"visibility"`
+	Approved         bool       `json:"approved"`
+}
@@ -0,0 +1,168 @@
+// Package oaipmh holds models for handling OAI-PMH 2.0 requests.
+package oaipmh
+
+import (
+	"encoding/xml"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// Verbs of the OAI-PMH protocol.
+const (
+	VerbIdentify            = "Identify"
+	VerbListMetadataFormats = "ListMetadataFormats"
+	VerbListRecords         = "ListRecords"
+	VerbGetRecord           = "GetRecord"
+)
+
+// Error codes of the OAI-PMH protocol that are handled by the client.
+const (
+	ErrorNoRecordsMatch = "noRecordsMatch"
+	ErrorIDDoesNotExist = "idDoesNotExist"
+)
+
+// Values for Identify.Granularity.
+const (
+	GranularityDay    = "YYYY-MM-DD"
+	GranularitySecond = "YYYY-MM-DDThh:mm:ssZ"
+)
+
+// DefaultMetadataPrefix is the metadata prefix for ISO 19139 records, as used by GeoNetwork and pycsw.
+const DefaultMetadataPrefix = "iso19139"
+
+// Response struct for unmarshalling an OAI-PMH response. Only the element of the requested verb is filled.
+type Response struct {
+	XMLName             xml.Name             `xml:"OAI-PMH"`
+	Attrs               []xml.Attr           `xml:",any,attr"` // Includes the namespace declarations
+	ResponseDate        string               `xml:"responseDate"`
+	Errors              []Error              `xml:"error"`
+	Identify            *Identify            `xml:"Identify"`
+	ListMetadataFormats *ListMetadataFormats `xml:"ListMetadataFormats"`
+	ListRecords         *ListRecords         `xml:"ListRecords"`
+	GetRecord           *GetRecord           `xml:"GetRecord"`
+}
+
+// Error is an OAI-PMH error.
+type Error struct {
+	Code    string `xml:"code,attr"`
+	Message string `xml:",chardata"`
+}
+
+// Identify holds the description of the repository.
+type Identify struct {
+	RepositoryName    string   `xml:"repositoryName"`
+	BaseURL           string   `xml:"baseURL"`
+	ProtocolVersion   string   `xml:"protocolVersion"`
+	AdminEmails       []string `xml:"adminEmail"`
+	EarliestDatestamp string   `xml:"earliestDatestamp"`
+	DeletedRecord     string   `xml:"deletedRecord"` // no, persistent or transient
+	Granularity       string   `xml:"granularity"`
+}
+
+// ListMetadataFormats holds the metadata formats of the repository.
+type ListMetadataFormats struct {
+	MetadataFormats []MetadataFormat `xml:"metadataFormat"`
+}
+
+// MetadataFormat is a metadata format in which records can be retrieved.
+type MetadataFormat struct {
+	MetadataPrefix    string `xml:"metadataPrefix"`
+	Schema            string `xml:"schema"`
+	MetadataNamespace string `xml:"metadataNamespace"`
+}
+
+// ListRecords holds a page of records.
+type ListRecords struct {
+	Records         []Record         `xml:"record"`
+	ResumptionToken *ResumptionToken `xml:"resumptionToken"`
+}
+
+// GetRecord holds a single record.
+type GetRecord struct {
+	Record Record `xml:"record"`
+}
+
+// ResumptionToken is used to retrieve the next page of a list. An empty token marks the last page.
+type ResumptionToken struct {
+	Token            string `xml:",chardata"`
+	CompleteListSize string `xml:"completeListSize,attr"`
+	Cursor           string `xml:"cursor,attr"`
+}
+
+// Record is a record of the repository. The metadata is left raw; deleted records have no metadata.
+type Record struct {
+	Header   Header    `xml:"header"`
+	Metadata *Metadata `xml:"metadata"`
+}
+
+// Metadata holds the raw XML of the metadata of a record.
+type Metadata struct {
+	Inner []byte `xml:",innerxml"`
+}
+
+// Header holds the identifier, datestamp, sets and status of a record.
+type Header struct {
+	Status     string   `xml:"status,attr"`
+	Identifier string   `xml:"identifier"`
+	Datestamp  string   `xml:"datestamp"`
+	SetSpecs   []string `xml:"setSpec"`
+}
+
+// Selection holds the arguments for selective harvesting with ListRecords.
+type Selection struct {
+	Set   string     `json:"set,omitempty"`
+	From  *time.Time `json:"from,omitempty"`
+	Until *time.Time `json:"until,omitempty"`
+}
+
+// Namespaces returns the namespace declarations of the response element, so the raw metadata of a record
+// can be unmarshalled on its own.
+func (r *Response) Namespaces() map[string]string {
+	namespaces := map[string]string{}
+
+	for _, attr := range r.Attrs {
+		if attr.Name.Space == "xmlns" {
+			namespaces[attr.Name.Local] = attr.Value
+		}
+	}
+
+	return namespaces
+}
+
+// Error returns the code and message of the error.
+func (e *Error) Error() string {
+	return "OAI-PMH error " + e.Code + ": " + strings.TrimSpace(e.Message)
+}
+
+// IsDeleted reports whether the record was deleted from the repository.
+func (h *Header) IsDeleted() bool {
+	return h.Status == "deleted"
+}
+
+// QueryParameters returns the query parameters of a ListRecords request. Dates are formatted
+// using the granularity of the repository.
+func (s *Selection) QueryParameters(metadataPrefix string, granularity string) url.Values {
+	layout := time.DateOnly
+	if granularity == GranularitySecond {
+		layout = time.RFC3339
+	}
+
+	values := url.Values{}
+	values.Set("verb", VerbListRecords)
+	values.Set("metadataPrefix", metadataPrefix)
+
+	if s.Set != "" {
+		values.Set("set", s.Set)
+	}
+
+	if s.From != nil {
+		values.Set("from", s.From.UTC().Format(layout))
+	}
+
+	if s.Until != nil {
+		values.Set("until", s.Until.UTC().Format(layout))
+	}
+
+	return values
+}
@@ -0,0 +1,273 @@
+// Package ogcrecords holds models for handling OGC API Records requests.
+package ogcrecords
+
+import (
+	"encoding/json"
+	"math"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// Link relations used by the client.
+const (
+	RelNext      = "next"
+	RelAlternate = "alternate"
+	RelLicense   = "license"
+	RelPreview   = "preview"
+)
+
+// Record types of the record property type.
+const (
+	TypeDataset = "dataset"
+	TypeService = "service"
+)
+
+// isoMediaTypes are media types of ISO 19139 representations in the order of preference.
+var isoMediaTypes = []string{"application/vnd.iso.19139+xml", "application/iso19139+xml", "application/xml", "text/xml"}
+
+// ItemCollection is a page of records of the /collections/{collectionId}/items endpoint (GeoJSON).
+type ItemCollection struct {
+	Type           string   `json:"type"`
+	NumberMatched  *int     `json:"numberMatched,omitempty"`
+	NumberReturned *int     `json:"numberReturned,omitempty"`
+	Features       []Record `json:"features"`
+	Links          []Link   `json:"links"`
+}
+
+// Record is a catalogue record (GeoJSON feature).
+type Record struct {
+	ID         string           `json:"id"`
+	Type       string           `json:"type"`
+	Geometry   *Geometry        `json:"geometry"`
+	Properties RecordProperties `json:"properties"`
+	Links      []Link           `json:"links"`
+}
+
+// Geometry is a GeoJSON geometry. Only the coordinates are used, to determine the bounding box.
+type Geometry struct {
+	Type        string          `json:"type"`
+	Coordinates json.RawMessage `json:"coordinates"`
+}
+
+// RecordProperties holds the core properties of a record.
+type RecordProperties struct {
+	Type        string    `json:"type"`
+	Title       string    `json:"title"`
+	Description string    `json:"description"`
+	Keywords    []string  `json:"keywords"`
+	Themes      []Theme   `json:"themes"`
+	Contacts    []Contact `json:"contacts"`
+	License     string    `json:"license"`
+	Rights      string    `json:"rights"`
+	Created     string    `json:"created"`
+	Updated     string    `json:"updated"`
+}
+
+// Theme holds concepts of a knowledge organization system (scheme).
+type Theme struct {
+	Concepts []Concept `json:"concepts"`
+	Scheme   string    `json:"scheme"`
+}
+
+// Concept is a concept of a theme.
+type Concept struct {
+	ID    string `json:"id"`
+	Title string `json:"title"`
+	URL   string `json:"url"`
+}
+
+// Contact is a party related to the record.
+type Contact struct {
+	Name         string   `json:"name"`
+	Organization string   `json:"organization"`
+	Emails       []Email  `json:"emails"`
+	Links        []Link   `json:"links"`
+	Roles        []string `json:"roles"`
+}
+
+// Email is an email address of a contact.
+type Email struct {
+	Value string `json:"value"`
+}
+
+// Link is a link to a related resource.
+type Link struct {
+	Href  string `json:"href"`
+	Rel   string `json:"rel"`
+	Type  string `json:"type,omitempty"`
+	Title string `json:"title,omitempty"`
+}
+
+// ItemsQuery holds the query parameters of an items request.
+type ItemsQuery struct {
+	Limit    int         // Number of records per page; 0 uses the default of the server
+	BBox     *[4]float64 // West, south, east, north in longitude/latitude
+	Datetime string      // Instant or interval, e.g. 2024-01-01T00:00:00Z/..
+	Filter   string      // CQL2 text filter
+	Type     string      // Record type, e.g. dataset or service
+	Extra    url.Values  // Additional query parameters
+}
+
+// QueryParameters returns the query parameters of the items request.
+func (q *ItemsQuery) QueryParameters() url.Values {
+	values := url.Values{}
+	for key, v := range q.Extra {
+		values[key] = slices.Clone(v)
+	}
+
+	if q.Limit > 0 {
+		values.Set("limit", strconv.Itoa(q.Limit))
+	}
+
+	if q.BBox != nil {
+		coordinates := make([]string, 0, len(q.BBox))
+		for _, c := range q.BBox {
+			coordinates = append(coordinates, strconv.FormatFloat(c, 'f', -1, 64))
+		}
+
+		values.Set("bbox", strings.Join(coordinates, ","))
+	}
+
+	if q.Datetime != "" {
+		values.Set("datetime", q.Datetime)
+	}
+
+	if q.Filter != "" {
+		values.Set("filter", q.Filter)
+		values.Set("filter-lang", "cql2-text")
+	}
+
+	if q.Type != "" {
+		values.Set("type", q.Type)
+	}
+
+	return values
+}
+
+// NextLink returns the href of the next page, or an empty string on the last page.
+func (c *ItemCollection) NextLink() string {
+	for _, link := range c.Links {
+		if link.Rel == RelNext {
+			return link.Href
+		}
+	}
+
+	return ""
+}
+
+// ISOLink returns the alternate link to the ISO 19139 XML of the record, or nil when there is none.
+// Links of which the type or href mentions ISO 19139 are preferred over other XML links.
+func (r *Record) ISOLink() *Link {
+	var best *Link
+
+	bestRank := len(isoMediaTypes) + 1
+
+	for i := range r.Links {
+		link := &r.Links[i]
+		if link.Rel != RelAlternate {
+			continue
+		}
+
+		rank := slices.Index(isoMediaTypes, strings.ToLower(strings.TrimSpace(strings.Split(link.Type, ";")[0])))
+		if rank < 0 {
+			continue
+		}
+
+		if strings.Contains(strings.ToLower(link.Href+link.Title), "19139") {
+			rank = -1
+		}
+
+		if rank < bestRank {
+			best, bestRank = link, rank
+		}
+	}
+
+	return best
+}
+
+// LinkByRel returns the href of the first link with the relation, or an empty string.
+func (r *Record) LinkByRel(rel string) string {
+	for _, link := range r.Links {
+		if link.Rel == rel {
+			return link.Href
+		}
+	}
+
+	return ""
+}
+
+// AllKeywords returns the keywords and the theme concepts of the record, without duplicates.
+func (r *Record) AllKeywords() []string {
+	var keywords []string
+
+	add := func(keyword string) {
+		if keyword = strings.TrimSpace(keyword); keyword != "" && !slices.Contains(keywords, keyword) {
+			keywords = append(keywords, keyword)
+		}
+	}
+
+	for _, keyword := range r.Properties.Keywords {
+		add(keyword)
+	}
+
+	for _, theme := range r.Properties.Themes {
+		for _, concept := range theme.Concepts {
+			if concept.Title != "" {
+				add(concept.Title)
+			} else {
+				add(concept.ID)
+			}
+		}
+	}
+
+	return keywords
+}
+
+// Extent returns the west, south, east and north bound of the geometry, or false when there is no geometry.
+func (r *Record) Extent() (west, south, east, north float64, ok bool) {
+	if r.Geometry == nil || len(r.Geometry.Coordinates) == 0 {
+		return 0, 0, 0, 0, false
+	}
+
+	var coordinates any
+	if err := json.Unmarshal(r.Geometry.Coordinates, &coordinates); err != nil {
+		return 0, 0, 0, 0, false
+	}
+
+	west, south = math.Inf(1), math.Inf(1)
+	east, north = math.Inf(-1), math.Inf(-1)
+
+	var walk func(v any)
+
+	walk = func(v any) {
+		values, isArray := v.([]any)
+		if !isArray {
+			return
+		}
+
+		if len(values) >= 2 {
+			x, xOK := values[0].(float64)
+			y, yOK := values[1].(float64)
+
+			if xOK && yOK {
+				west, east = math.Min(west, x), math.Max(east, x)
+				south, north = math.Min(south, y), math.Max(north, y)
+
+				return
+			}
+		}
+
+		for _, value := range values {
+			walk(value)
+		}
+	}
+	walk(coordinates)
+
+	if math.IsInf(west, 1) {
+		return 0, 0, 0, 0, false
+	}
+
+	return west, south, east, north, true
+}
@@ -2,16 +2,16 @@
 package repository
 
 import (
+	"context"
 	"encoding/xml"
 	"fmt"
-	"io"
 	"net/http"
 	"os"
 	"sort"
 	"strings"
 	"time"
 
-	"github.com/pdok/pdok-metadata-tool/v2/internal/common"
+	"github.com/pdok/pdok-metadata-tool/v2/pkg/client"
 	model "github.com/pdok/pdok-metadata-tool/v2/pkg/model/hvd"
 )
 
@@ -19,40 +19,30 @@ import (
 type HVDRepository struct {
 	thesaurusEndpoint       string
 	thesaurusLocalCachePath string
+	httpClient              *http.Client
 }
 
-// NewHVDRepository creates a new instance of an HVDRepository.
+// NewHVDRepository creates a new instance of an HVDRepository, with an HTTP client with client.DefaultHTTPConfig.
 func NewHVDRepository(thesaurusEndpoint string, thesaurusLocalCachePath string) *HVDRepository {
 	return &HVDRepository{
 		thesaurusEndpoint:       thesaurusEndpoint,
 		thesaurusLocalCachePath: thesaurusLocalCachePath,
+		httpClient:              client.DefaultHTTPClient(),
 	}
 }
 
+// SetHTTPClient sets the HTTP client, see client.NewHTTPClient.
+func (hvd *HVDRepository) SetHTTPClient(httpClient *http.Client) {
+	hvd.httpClient = httpClient
+}
+
 // Download the thesaurus from thesaurusEndpoint and store it in thesaurusLocalCachePath.
-func (hvd *HVDRepository) Download() ([]byte, error) {
-	//nolint:bodyclose // We use common.SafeClose to handle closing the response body
-	resp, err := http.Get(hvd.thesaurusEndpoint)
+// When the thesaurus was downloaded before, it is only downloaded again when it was modified.
+func (hvd *HVDRepository) Download(ctx context.Context) ([]byte, error) {
+	body, err := downloadFile(ctx, hvd.httpClient, hvd.thesaurusEndpoint, hvd.thesaurusLocalCachePath)
 	if err != nil {
 		return nil, fmt.Errorf("failed to download thesaurus: %w", err)
 	}
-	defer common.SafeClose(resp.Body)
-
-	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("failed to download thesaurus: status code %d", resp.StatusCode)
-	}
-
-	// Read all bytes from response body
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return nil, fmt.Errorf("failed to read thesaurus response body: %w", err)
-	}
-
-	// Write to the cache file
-	perm := 0644
-	if err := os.WriteFile(hvd.thesaurusLocalCachePath, body, os.FileMode(perm)); err != nil {
-		return nil, fmt.Errorf("failed to write thesaurus to local cache: %w", err)
-	}
 
 	return body, nil
 }
@@ -69,8 +59,8 @@ func GetAllHVDCategoriesFromContent(content []byte) ([]model.HVDCategory, error)
 }
 
 // GetAllHVDCategories retrieves all HVD categories.
-func (hvd *HVDRepository) GetAllHVDCategories() (result []model.HVDCategory, err error) {
-	rdf, err := hvd.parseThesaurus()
+func (hvd *HVDRepository) GetAllHVDCategories(ctx context.Context) (result []model.HVDCategory, err error) {
+	rdf, err := hvd.parseThesaurus(ctx)
 	if err != nil {
 		return nil, err
 	}
@@ -80,9 +70,11 @@ func (hvd *HVDRepository) GetAllHVDCategories() (result []model.HVDCategory, err
 	return result, nil
 }
 
-// GetHVDCategoryByCode retrieves a single HVD category by its code.
+// GetHVDCategoryByCode retrieves a single HVD category by its code. It implements hvd.CategoryProvider,
+// which has no context: when the thesaurus must be downloaded, the download cannot be cancelled.
+// Use GetAllHVDCategories to download the thesaurus with a context first.
 func (hvd *HVDRepository) GetHVDCategoryByCode(code string) (*model.HVDCategory, error) {
-	allCategories, err := hvd.GetAllHVDCategories()
+	allCategories, err := hvd.GetAllHVDCategories(context.Background())
 	if err != nil {
 		return nil, err
 	}
@@ -100,9 +92,10 @@ func (hvd *HVDRepository) GetHVDCategoryByCode(code string) (*model.HVDCategory,
 // For each code in the filter, parent codes are also added.
 // It is ensured that the filtered categories in the result keep their original order, this is a requirement!
 func (hvd *HVDRepository) GetFilteredHvdCategories(
+	ctx context.Context,
 	filterCategories []string,
 ) ([]model.HVDCategory, error) {
-	allCategories, err := hvd.GetAllHVDCategories()
+	allCategories, err := hvd.GetAllHVDCategories(ctx)
 	if err != nil {
 		return nil, err
 	}
@@ -152,13 +145,13 @@ func (hvd *HVDRepository) GetFilteredHvdCategories(
 	return result, nil
 }
 
-func (hvd *HVDRepository) getThesaurus() ([]byte, error) {
+func (hvd *HVDRepository) getThesaurus(ctx context.Context) ([]byte, error) {
 	// Check if thesaurusLocalCachePath exists and that it is not older than 3 days
 	fileInfo, err := os.Stat(hvd.thesaurusLocalCachePath)
 
 	// If file doesn't exist or there's an error, download it
 	if os.IsNotExist(err) || err != nil {
-		_, err = hvd.Download()
+		_, err = hvd.Download(ctx)
 		if err != nil {
 			return nil, fmt.Errorf("failed to download thesaurus: %w", err)
 		}
@@ -170,7 +163,7 @@ func (hvd *HVDRepository) getThesaurus() ([]byte, error) {
 
 		threeDays := time.Hour * hoursPerDay * days
 		if time.Since(fileInfo.ModTime()) > threeDays {
-			_, err = hvd.Download()
+			_, err = hvd.Download(ctx)
 			if err != nil {
 				return nil, fmt.Errorf("failed to download thesaurus: %w", err)
 			}
@@ -185,8 +178,8 @@ func (hvd *HVDRepository) getThesaurus() ([]byte, error) {
 	return content, nil
 }
 
-func (hvd *HVDRepository) parseThesaurus() (*model.RDF, error) {
-	content, err := hvd.getThesaurus()
+func (hvd *HVDRepository) parseThesaurus(ctx context.Context) (*model.RDF, error) {
+	content, err := hvd.getThesaurus(ctx)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get thesaurus: %w", err)
 	}
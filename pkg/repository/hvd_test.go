@@ -1,8 +1,13 @@
 package repository
 
 import (
+	"net/http"
+	"net/http/httptest"
+	"os"
 	"path"
+	"path/filepath"
 	"testing"
+	"time"
 
 	"github.com/pdok/pdok-metadata-tool/v2/internal/common"
 	"github.com/pdok/pdok-metadata-tool/v2/pkg/model/hvd"
@@ -13,7 +18,7 @@ import (
 func TestHvdRepository_GetAllHvdCategories(t *testing.T) {
 	hvdRepo := getNewHVDRepository()
 
-	result, err := hvdRepo.GetAllHVDCategories()
+	result, err := hvdRepo.GetAllHVDCategories(t.Context())
 
 	require.NoError(t, err)
 	assert.NotNil(t, result)
@@ -46,7 +51,7 @@ func TestHvdRepository_GetFilteredHvdCategories(t *testing.T) {
 	}
 	for _, test := range tests {
 		hvdRepo := getNewHVDRepository()
-		filteredCategories, err := hvdRepo.GetFilteredHvdCategories(test.filterCodes)
+		filteredCategories, err := hvdRepo.GetFilteredHvdCategories(t.Context(), test.filterCodes)
 		require.NoError(t, err)
 
 		for i, code := range test.expectedCodes {
@@ -60,3 +65,56 @@ func getNewHVDRepository() *HVDRepository {
 
 	return NewHVDRepository(hvd.HvdEndpoint, hvdCachePath)
 }
+
+func TestHvdRepository_ThesaurusRevalidation(t *testing.T) {
+	const thesaurus = `<rdf:RDF xmlns:rdf="http://www.w3.org/1999/02/22-rdf-syntax-ns#"
+	xmlns:skos="http://www.w3.org/2004/02/skos/core#">
+	<rdf:Description rdf:about="http://data.europa.eu/bna/c_dd313021">
+		<rdf:type rdf:resource="http://www.w3.org/2004/02/skos/core#Concept"/>
+		<skos:prefLabel xml:lang="nl">Aardobservatie en milieu</skos:prefLabel>
+		<skos:prefLabel xml:lang="en">Earth observation and environment</skos:prefLabel>
+	</rdf:Description>
+</rdf:RDF>`
+
+	var statuses []int
+
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		if req.Header.Get("If-Modified-Since") == "Wed, 02 Oct 2024 00:00:00 GMT" {
+			statuses = append(statuses, http.StatusNotModified)
+			rw.WriteHeader(http.StatusNotModified)
+
+			return
+		}
+
+		statuses = append(statuses, http.StatusOK)
+		rw.Header().Set("Last-Modified", "Wed, 02 Oct 2024 00:00:00 GMT")
+		_, _ = rw.Write([]byte(thesaurus))
+	}))
+	defer server.Close()
+
+	localPath := filepath.Join(t.TempDir(), "high-value-dataset-category.rdf")
+	hvdRepo := NewHVDRepository(server.URL, localPath)
+
+	categories, err := hvdRepo.GetAllHVDCategories(t.Context())
+	require.NoError(t, err)
+	require.Len(t, categories, 1)
+	assert.Equal(t, "c_dd313021", categories[0].ID)
+
+	// A fresh thesaurus is not requested again
+	_, err = hvdRepo.GetAllHVDCategories(t.Context())
+	require.NoError(t, err)
+	assert.Equal(t, []int{http.StatusOK}, statuses)
+
+	// An outdated thesaurus is revalidated and refreshed when not modified
+	old := time.Now().Add(-4 * 24 * time.Hour)
+	require.NoError(t, os.Chtimes(localPath, old, old))
+
+	categories, err = hvdRepo.GetAllHVDCategories(t.Context())
+	require.NoError(t, err)
+	require.Len(t, categories, 1)
+	assert.Equal(t, []int{http.StatusOK, http.StatusNotModified}, statuses)
+
+	fileInfo, err := os.Stat(localPath)
+	require.NoError(t, err)
+	assert.WithinDuration(t, time.Now(), fileInfo.ModTime(), time.Minute)
+}
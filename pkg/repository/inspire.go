@@ -1,33 +1,41 @@
 package repository
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
-	"io"
 	"net/http"
 	"os"
 	"path/filepath"
 	"strconv"
 	"time"
 
-	"github.com/pdok/pdok-metadata-tool/v2/internal/common"
+	"github.com/pdok/pdok-metadata-tool/v2/pkg/client"
 	"github.com/pdok/pdok-metadata-tool/v2/pkg/model/inspire"
 )
 
 // InspireRepository is used for retrieving INSPIRE related information.
 type InspireRepository struct {
 	localCachePath string
+	httpClient     *http.Client
 }
 
-// NewInspireRepository creates a new instance of an InspireRepository.
+// NewInspireRepository creates a new instance of an InspireRepository, with an HTTP client with
+// client.DefaultHTTPConfig.
 func NewInspireRepository(localCachePath string) *InspireRepository {
 	return &InspireRepository{
 		localCachePath: localCachePath,
+		httpClient:     client.DefaultHTTPClient(),
 	}
 }
 
+// SetHTTPClient sets the HTTP client, see client.NewHTTPClient.
+func (ir *InspireRepository) SetHTTPClient(httpClient *http.Client) {
+	ir.httpClient = httpClient
+}
+
 // Download will later download from the INSPIRE endpoints. These are not working correctly, so for now we use local json.
-func (ir *InspireRepository) Download(kind inspire.InspireRegisterKind) error {
+func (ir *InspireRepository) Download(ctx context.Context, kind inspire.InspireRegisterKind) error {
 	dutchURL := inspire.GetInspireEndpoint(kind, inspire.Dutch)
 	englishURL := inspire.GetInspireEndpoint(kind, inspire.English)
 
@@ -43,11 +51,11 @@ func (ir *InspireRepository) Download(kind inspire.InspireRegisterKind) error {
 		return fmt.Errorf("failed to create directory: %w", err)
 	}
 
-	if err := downloadFile(dutchURL, dutchFullPath); err != nil {
+	if _, err := downloadFile(ctx, ir.httpClient, dutchURL, dutchFullPath); err != nil {
 		return fmt.Errorf("failed to download Dutch file: %w", err)
 	}
 
-	if err := downloadFile(englishURL, englishFullPath); err != nil {
+	if _, err := downloadFile(ctx, ir.httpClient, englishURL, englishFullPath); err != nil {
 		return fmt.Errorf("failed to download English file: %w", err)
 	}
 
@@ -55,8 +63,8 @@ func (ir *InspireRepository) Download(kind inspire.InspireRegisterKind) error {
 }
 
 // GetThemes retrieves the INSPIRE themes.
-func (ir *InspireRepository) GetThemes() ([]inspire.InspireTheme, error) {
-	englishData, dutchData, err := ir.getKind(inspire.Theme)
+func (ir *InspireRepository) GetThemes(ctx context.Context) ([]inspire.InspireTheme, error) {
+	englishData, dutchData, err := ir.getKind(ctx, inspire.Theme)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get theme data: %w", err)
 	}
@@ -65,8 +73,8 @@ func (ir *InspireRepository) GetThemes() ([]inspire.InspireTheme, error) {
 }
 
 // GetLayers retrieves the INSPIRE layers.
-func (ir *InspireRepository) GetLayers() ([]inspire.InspireLayer, error) {
-	englishData, dutchData, err := ir.getKind(inspire.Layer)
+func (ir *InspireRepository) GetLayers(ctx context.Context) ([]inspire.InspireLayer, error) {
+	englishData, dutchData, err := ir.getKind(ctx, inspire.Layer)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get layer data: %w", err)
 	}
@@ -74,33 +82,16 @@ func (ir *InspireRepository) GetLayers() ([]inspire.InspireLayer, error) {
 	return ir.parseLayers(englishData, dutchData)
 }
 
-// downloadFile downloads a file from a URL and saves it to a local path.
-func downloadFile(url string, filepath string) error {
-	//nolint:gosec
-	out, err := os.Create(filepath)
-	if err != nil {
-		return err
-	}
-	defer common.SafeClose(out)
-
-	//nolint:gosec,bodyclose // We use common.SafeClose to handle closing the response body
-	resp, err := http.Get(url)
-	if err != nil {
-		return err
-	}
-	defer common.SafeClose(resp.Body)
-
-	if resp.StatusCode != http.StatusOK {
-		return fmt.Errorf("bad status: %s", resp.Status)
-	}
-
-	// Write the body to file
-	_, err = io.Copy(out, resp.Body)
-
-	return err
+// downloadFile downloads a file from a URL and saves it to a local path. The validators of the response
+// are stored next to the file, so the file is only downloaded again when it was modified.
+func downloadFile(ctx context.Context, httpClient *http.Client, url string, filepath string) ([]byte, error) {
+	return client.DownloadFile(ctx, httpClient, url, filepath)
 }
 
-func (ir *InspireRepository) getKind(kind inspire.InspireRegisterKind) ([]byte, []byte, error) {
+func (ir *InspireRepository) getKind(
+	ctx context.Context,
+	kind inspire.InspireRegisterKind,
+) ([]byte, []byte, error) {
 	dutchFilePath := inspire.GetInspirePath(kind, inspire.Dutch)
 	englishFilePath := inspire.GetInspirePath(kind, inspire.English)
 
@@ -127,14 +118,14 @@ func (ir *InspireRepository) getKind(kind inspire.InspireRegisterKind) ([]byte,
 
 	if dutchNeedsDownload {
 		dutchURL := inspire.GetInspireEndpoint(kind, inspire.Dutch)
-		if err := downloadFile(dutchURL, dutchFullPath); err != nil {
+		if _, err := downloadFile(ctx, ir.httpClient, dutchURL, dutchFullPath); err != nil {
 			return nil, nil, fmt.Errorf("failed to download Dutch file: %w", err)
 		}
 	}
 
 	if englishNeedsDownload {
 		englishURL := inspire.GetInspireEndpoint(kind, inspire.English)
-		if err := downloadFile(englishURL, englishFullPath); err != nil {
+		if _, err := downloadFile(ctx, ir.httpClient, englishURL, englishFullPath); err != nil {
 			return nil, nil, fmt.Errorf("failed to download English file: %w", err)
 		}
 	}
@@ -1,6 +1,7 @@
 package repository
 
 import (
+	"context"
 	"errors"
 	"net/url"
 
@@ -33,9 +34,10 @@ func NewMetadataRepository(cswEndpoint string) (*MetadataRepository, error) {
 
 // GetDatasetMetadataByID retrieves dataset metadata by id.
 func (mr *MetadataRepository) GetDatasetMetadataByID(
+	ctx context.Context,
 	id string,
 ) (datasetMetadata *metadata.NLDatasetMetadata, err error) {
-	mdMetadata, err := mr.CswClient.GetRecordByID(id)
+	mdMetadata, err := mr.CswClient.GetRecordByID(ctx, id)
 	if err != nil {
 		return
 	}
@@ -52,6 +54,7 @@ func (mr *MetadataRepository) GetDatasetMetadataByID(
 
 // SearchDatasetMetadata searches for dataset metadata by title or id.
 func (mr *MetadataRepository) SearchDatasetMetadata(
+	ctx context.Context,
 	title *string,
 	id *string,
 ) (records []csw.SummaryRecord, err error) {
@@ -60,11 +63,16 @@ func (mr *MetadataRepository) SearchDatasetMetadata(
 		Title:        title,
 		Identifier:   id,
 	}
-	records, err = mr.CswClient.GetRecordsWithOGCFilter(&filter)
+	records, err = mr.CswClient.GetRecordsWithOGCFilter(ctx, &filter)
 
 	return
 }
 
+// SearchMetadata searches for metadata of any type that matches the filter.
+func (mr *MetadataRepository) SearchMetadata(ctx context.Context, filter csw.Filter) ([]csw.SummaryRecord, error) {
+	return mr.CswClient.GetAllRecordsByFilter(ctx, filter)
+}
+
 // SetCache enables caching on the underlying CSW client.
 func (mr *MetadataRepository) SetCache(cacheDir string, ttlHours int) {
 	if mr != nil && mr.CswClient != nil {
@@ -81,9 +89,10 @@ func (mr *MetadataRepository) UnsetCache() {
 
 // HarvestByCQLConstraint is a generic harvester that returns flat models based on the MetadataType in the constraint.
 // Usage:
-//   - For services: repo.HarvestByCQLConstraint[metadata.NLServiceMetadata](constraintWithTypeService)
-//   - For datasets: repo.HarvestByCQLConstraint[metadata.NLDatasetMetadata](constraintWithTypeDataset)
+//   - For services: repository.HarvestByCQLConstraint[metadata.NLServiceMetadata](ctx, repo, constraintWithTypeService)
+//   - For datasets: repository.HarvestByCQLConstraint[metadata.NLDatasetMetadata](ctx, repo, constraintWithTypeDataset)
 func HarvestByCQLConstraint[T any](
+	ctx context.Context,
 	mr *MetadataRepository,
 	constraint *csw.GetRecordsCQLConstraint,
 ) (result []T, err error) {
@@ -113,7 +122,7 @@ func HarvestByCQLConstraint[T any](
 
 	constraint.MetadataType = &metadataType
 
-	mds, err := mr.CswClient.HarvestByCQLConstraint(constraint)
+	mds, err := mr.CswClient.HarvestByCQLConstraint(ctx, constraint)
 	if err != nil {
 		return nil, err
 	}
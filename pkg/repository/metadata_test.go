@@ -146,7 +146,7 @@ func TestMetadataRepository_GetDatasetMetadataById(t *testing.T) {
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			metadataRecord, err := mr.GetDatasetMetadataByID(tt.args.id)
+			metadataRecord, err := mr.GetDatasetMetadataByID(t.Context(), tt.args.id)
 			require.NoError(t, err)
 
 			assert.Equal(t, tt.wantMetadataID, metadataRecord.MetadataID)
@@ -203,7 +203,7 @@ func TestMetadataRepository_SearchDatasetMetadata(t *testing.T) {
 	mr.CswClient = getCswClient(t, mockedNGRServer)
 
 	title := "ataset titl"
-	summaryRecords, err := mr.SearchDatasetMetadata(&title, nil)
+	summaryRecords, err := mr.SearchDatasetMetadata(t.Context(), &title, nil)
 
 	require.NoError(t, err)
 	assert.NotNil(t, summaryRecords)
@@ -0,0 +1,83 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+
+	"github.com/pdok/pdok-metadata-tool/v2/pkg/client"
+	"github.com/pdok/pdok-metadata-tool/v2/pkg/model/hvd"
+	"github.com/pdok/pdok-metadata-tool/v2/pkg/model/metadata"
+	"github.com/pdok/pdok-metadata-tool/v2/pkg/model/ogcrecords"
+)
+
+// HarvestOgcRecords is a generic harvester that returns flat models of an OGC API Records collection.
+// Records with an ISO 19139 alternate link are mapped from the ISO XML (enriched with the HVD repository,
+// if given), other records from their core properties. Records of another type are left out.
+// Usage:
+//   - For services: repository.HarvestOgcRecords[metadata.NLServiceMetadata](ctx, recordsClient, query, hvdRepo)
+//   - For datasets: repository.HarvestOgcRecords[metadata.NLDatasetMetadata](ctx, recordsClient, query, hvdRepo)
+func HarvestOgcRecords[T any](
+	ctx context.Context,
+	recordsClient *client.OgcRecordsClient,
+	query ogcrecords.ItemsQuery,
+	hvdRepo hvd.CategoryProvider,
+) (result []T, err error) {
+	var (
+		recordType string
+		zero       T
+	)
+
+	switch any(zero).(type) {
+	case metadata.NLServiceMetadata:
+		recordType = ogcrecords.TypeService
+	case metadata.NLDatasetMetadata:
+		recordType = ogcrecords.TypeDataset
+	default:
+		return nil, errors.New(
+			"unsupported type parameter T; must be NLServiceMetadata or NLDatasetMetadata",
+		)
+	}
+
+	records, err := recordsClient.GetAllItems(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range records {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+
+		record := &records[i]
+		if record.Properties.Type != "" && record.Properties.Type != recordType {
+			continue
+		}
+
+		md, err := recordsClient.GetISORecord(ctx, record)
+		if err != nil && !errors.Is(err, client.ErrNoISOLink) {
+			slog.Warn("Error retrieving ISO record; using the core properties", "identifier", record.ID, "err", err)
+		}
+
+		var v any
+
+		switch {
+		case err != nil && recordType == ogcrecords.TypeService:
+			v = *metadata.NewNLServiceMetadataFromRecord(record)
+		case err != nil:
+			v = *metadata.NewNLDatasetMetadataFromRecord(record)
+		case recordType == ogcrecords.TypeService && md.IdentificationInfo.SVServiceIdentification != nil:
+			v = *metadata.NewNLServiceMetadataFromMDMetadataWithHVDRepo(&md, hvdRepo)
+		case recordType == ogcrecords.TypeDataset && md.IdentificationInfo.MDDataIdentification != nil:
+			v = *metadata.NewNLDatasetMetadataFromMDMetadataWithHVDRepo(&md, hvdRepo)
+		default:
+			continue
+		}
+
+		if t, ok := v.(T); ok {
+			result = append(result, t)
+		}
+	}
+
+	return result, nil
+}
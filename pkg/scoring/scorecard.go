@@ -0,0 +1,51 @@
+package scoring
+
+import (
+	"embed"
+	"fmt"
+	"html/template"
+	"io"
+	"time"
+)
+
+//go:embed templates/scorecard.html.tmpl
+var templateFS embed.FS
+
+const (
+	goodScore = 80
+	fairScore = 50
+)
+
+var scorecardTemplate = template.Must(
+	template.New("scorecard.html.tmpl").Funcs(template.FuncMap{
+		"grade":      grade,
+		"percentage": func(score float64) string { return fmt.Sprintf("%.0f%%", score*maxScore) },
+	}).ParseFS(templateFS, "templates/scorecard.html.tmpl"),
+)
+
+// WriteHTML writes a scorecard with the organisation and record scores as an HTML page.
+func WriteHTML(w io.Writer, records []RecordScore, organisations []OrganisationScore) error {
+	return scorecardTemplate.Execute(w, struct {
+		GeneratedAt   string
+		Criteria      []Criterion
+		Records       []RecordScore
+		Organisations []OrganisationScore
+	}{
+		GeneratedAt:   time.Now().Format(time.DateTime),
+		Criteria:      criteria,
+		Records:       records,
+		Organisations: organisations,
+	})
+}
+
+// grade returns the CSS class for a total score.
+func grade(score float64) string {
+	switch {
+	case score >= goodScore:
+		return "good"
+	case score >= fairScore:
+		return "fair"
+	default:
+		return "poor"
+	}
+}
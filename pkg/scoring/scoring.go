@@ -0,0 +1,446 @@
+// Package scoring holds a completeness and quality score for metadata records and organisations.
+package scoring
+
+import (
+	"fmt"
+	"math"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/pdok/pdok-metadata-tool/v2/pkg/model/codelist"
+	"github.com/pdok/pdok-metadata-tool/v2/pkg/model/iso1911x"
+	"github.com/pdok/pdok-metadata-tool/v2/pkg/model/metadata"
+	"gopkg.in/yaml.v3"
+)
+
+// Criterion holds the aspects of a record that are scored.
+type Criterion string
+
+// Values for Criterion.
+const (
+	OptionalFields     Criterion = "optional-fields"
+	Thumbnail          Criterion = "thumbnail"
+	License            Criterion = "license"
+	ControlledKeywords Criterion = "controlled-keywords"
+	AbstractLength     Criterion = "abstract-length"
+	RevisionRecency    Criterion = "revision-recency"
+)
+
+// criteria holds all criteria in the order in which they are reported.
+var criteria = []Criterion{
+	OptionalFields, Thumbnail, License, ControlledKeywords, AbstractLength, RevisionRecency,
+}
+
+const (
+	defaultMinAbstractLength = 200
+	defaultFreshDays         = 365
+	defaultMaxAgeDays        = 5 * 365
+	hoursPerDay              = 24
+	maxScore                 = 100
+)
+
+// Weights holds the relative weight of each criterion in the total score.
+type Weights struct {
+	OptionalFields     float64 `json:"optionalFields"     yaml:"optionalFields"`
+	Thumbnail          float64 `json:"thumbnail"          yaml:"thumbnail"`
+	License            float64 `json:"license"            yaml:"license"`
+	ControlledKeywords float64 `json:"controlledKeywords" yaml:"controlledKeywords"`
+	AbstractLength     float64 `json:"abstractLength"     yaml:"abstractLength"`
+	RevisionRecency    float64 `json:"revisionRecency"    yaml:"revisionRecency"`
+}
+
+// Config holds the weights and thresholds used for scoring.
+type Config struct {
+	Weights Weights `json:"weights" yaml:"weights"`
+	// An abstract of at least this many characters gets the full score
+	MinAbstractLength int `json:"minAbstractLength" yaml:"minAbstractLength"`
+	// A revision date at most this many days ago gets the full score
+	FreshDays int `json:"freshDays" yaml:"freshDays"`
+	// A revision date at least this many days ago (or no revision date) gets no score
+	MaxAgeDays int `json:"maxAgeDays" yaml:"maxAgeDays"`
+}
+
+// DefaultConfig returns the config with equal weights for all criteria.
+func DefaultConfig() Config {
+	return Config{
+		Weights: Weights{
+			OptionalFields:     1,
+			Thumbnail:          1,
+			License:            1,
+			ControlledKeywords: 1,
+			AbstractLength:     1,
+			RevisionRecency:    1,
+		},
+		MinAbstractLength: defaultMinAbstractLength,
+		FreshDays:         defaultFreshDays,
+		MaxAgeDays:        defaultMaxAgeDays,
+	}
+}
+
+// LoadConfig reads a config from a YAML file. Values that are not given keep their default.
+func LoadConfig(path string) (Config, error) {
+	config := DefaultConfig()
+
+	//nolint:gosec
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return config, fmt.Errorf("failed to read scoring config: %w", err)
+	}
+
+	if err := yaml.Unmarshal(data, &config); err != nil {
+		return config, fmt.Errorf("failed to parse scoring config: %w", err)
+	}
+
+	if err := config.validate(); err != nil {
+		return config, err
+	}
+
+	return config, nil
+}
+
+// CriterionScore holds the score of a single criterion between 0 and 1.
+type CriterionScore struct {
+	Criterion Criterion `json:"criterion"`
+	Score     float64   `json:"score"`
+	Weight    float64   `json:"weight"`
+	Message   string    `json:"message,omitempty"`
+}
+
+// RecordScore holds the scores of a single metadata record. The total score is between 0 and 100.
+type RecordScore struct {
+	MetadataID       string                `json:"metadataId"`
+	Title            string                `json:"title"`
+	OrganisationName string                `json:"organisationName"`
+	MetadataType     iso1911x.MetadataType `json:"metadataType"`
+	Score            float64               `json:"score"`
+	Criteria         []CriterionScore      `json:"criteria"`
+}
+
+// OrganisationScore holds the aggregated scores of the records of an organisation.
+type OrganisationScore struct {
+	OrganisationName string                `json:"organisationName"`
+	Records          int                   `json:"records"`
+	Average          float64               `json:"average"`
+	Min              float64               `json:"min"`
+	Max              float64               `json:"max"`
+	Criteria         map[Criterion]float64 `json:"criteria"` // Average score per criterion
+}
+
+// Scorer scores metadata records.
+type Scorer struct {
+	Config   Config
+	Codelist *codelist.Codelist
+	Now      func() time.Time
+}
+
+// NewScorer creates a new Scorer with the given config.
+func NewScorer(config Config) (*Scorer, error) {
+	if err := config.validate(); err != nil {
+		return nil, err
+	}
+
+	cl, err := codelist.NewCodelist()
+	if err != nil {
+		return nil, err
+	}
+
+	return &Scorer{Config: config, Codelist: cl, Now: time.Now}, nil
+}
+
+// ScoreRecord scores a single dataset or service record.
+func (s *Scorer) ScoreRecord(md *iso1911x.MDMetadata) RecordScore {
+	record := RecordScore{
+		MetadataID:       iso1911x.NormalizeXMLText(md.UUID),
+		Title:            md.GetTitle(),
+		OrganisationName: md.GetOrganisationName(),
+		MetadataType:     md.GetMetaDataType(),
+	}
+
+	scores := map[Criterion]CriterionScore{
+		OptionalFields:     s.scoreOptionalFields(md),
+		Thumbnail:          s.scoreThumbnail(md),
+		License:            s.scoreLicense(md),
+		ControlledKeywords: s.scoreControlledKeywords(md),
+		AbstractLength:     s.scoreAbstractLength(md),
+		RevisionRecency:    s.scoreRevisionRecency(md),
+	}
+
+	var total, totalWeight float64
+
+	for _, criterion := range criteria {
+		score := scores[criterion]
+		score.Criterion = criterion
+		score.Weight = s.Config.Weights.get(criterion)
+		record.Criteria = append(record.Criteria, score)
+
+		total += score.Score * score.Weight
+		totalWeight += score.Weight
+	}
+
+	if totalWeight > 0 {
+		record.Score = round(maxScore * total / totalWeight)
+	}
+
+	return record
+}
+
+// ScoreAll scores all records.
+func (s *Scorer) ScoreAll(mds []iso1911x.MDMetadata) []RecordScore {
+	scores := make([]RecordScore, 0, len(mds))
+	for i := range mds {
+		scores = append(scores, s.ScoreRecord(&mds[i]))
+	}
+
+	return scores
+}
+
+// AggregateByOrganisation aggregates record scores per organisation, sorted by average score descending.
+func AggregateByOrganisation(records []RecordScore) []OrganisationScore {
+	byOrg := map[string]*OrganisationScore{}
+
+	var order []string
+
+	for _, record := range records {
+		org, ok := byOrg[record.OrganisationName]
+		if !ok {
+			org = &OrganisationScore{
+				OrganisationName: record.OrganisationName,
+				Min:              record.Score,
+				Max:              record.Score,
+				Criteria:         map[Criterion]float64{},
+			}
+			byOrg[record.OrganisationName] = org
+			order = append(order, record.OrganisationName)
+		}
+
+		org.Records++
+		org.Average += record.Score
+		org.Min = min(org.Min, record.Score)
+		org.Max = max(org.Max, record.Score)
+
+		for _, criterion := range record.Criteria {
+			org.Criteria[criterion.Criterion] += criterion.Score
+		}
+	}
+
+	result := make([]OrganisationScore, 0, len(order))
+
+	for _, name := range order {
+		org := byOrg[name]
+		org.Average = round(org.Average / float64(org.Records))
+
+		for criterion, total := range org.Criteria {
+			org.Criteria[criterion] = round(total / float64(org.Records))
+		}
+
+		result = append(result, *org)
+	}
+
+	sort.SliceStable(result, func(i, j int) bool {
+		return result[i].Average > result[j].Average
+	})
+
+	return result
+}
+
+func (c Config) validate() error {
+	for _, criterion := range criteria {
+		if c.Weights.get(criterion) < 0 {
+			return fmt.Errorf("invalid weight for %s: must not be negative", criterion)
+		}
+	}
+
+	if c.MinAbstractLength <= 0 {
+		return fmt.Errorf("invalid minAbstractLength: %d (must be positive)", c.MinAbstractLength)
+	}
+
+	if c.FreshDays < 0 || c.MaxAgeDays <= c.FreshDays {
+		return fmt.Errorf("invalid freshDays/maxAgeDays: %d/%d (maxAgeDays must exceed freshDays)",
+			c.FreshDays, c.MaxAgeDays)
+	}
+
+	return nil
+}
+
+func (w Weights) get(criterion Criterion) float64 {
+	switch criterion {
+	case OptionalFields:
+		return w.OptionalFields
+	case Thumbnail:
+		return w.Thumbnail
+	case License:
+		return w.License
+	case ControlledKeywords:
+		return w.ControlledKeywords
+	case AbstractLength:
+		return w.AbstractLength
+	case RevisionRecency:
+		return w.RevisionRecency
+	}
+
+	return 0
+}
+
+// scoreOptionalFields scores the fraction of optional fields of the flat model that are filled.
+func (s *Scorer) scoreOptionalFields(md *iso1911x.MDMetadata) CriterionScore {
+	var fields map[string]bool
+
+	switch md.GetMetaDataType() {
+	case iso1911x.Dataset:
+		if md.IdentificationInfo.MDDataIdentification == nil {
+			return CriterionScore{Message: "no identification info"}
+		}
+
+		d := metadata.NewNLDatasetMetadataFromMDMetadata(md)
+		fields = map[string]bool{
+			"sourceId":      d.SourceID != "",
+			"contactName":   d.ContactName != "",
+			"contactEmail":  d.ContactEmail != "",
+			"contactUrl":    d.ContactURL != "",
+			"keywords":      len(d.Keywords) > 0,
+			"useLimitation": d.UseLimitation != "",
+			"boundingBox":   !d.BoundingBox.IsEmpty(),
+			"creationDate":  d.CreationDate != "",
+		}
+	case iso1911x.Service:
+		if md.IdentificationInfo.SVServiceIdentification == nil {
+			return CriterionScore{Message: "no identification info"}
+		}
+
+		sv := metadata.NewNLServiceMetadataFromMDMetadata(md)
+		fields = map[string]bool{
+			"contactEmail":  md.GetContactEmail() != "",
+			"contactUrl":    md.GetContactURL() != "",
+			"keywords":      len(sv.Keywords) > 0,
+			"useLimitation": sv.UseLimitation != "",
+			"boundingBox":   !sv.BoundingBox.IsEmpty(),
+			"operatesOn":    len(sv.OperatesOn) > 0,
+			"endpoints":     len(sv.Endpoints) > 0,
+			"creationDate":  sv.CreationDate != "",
+		}
+	default:
+		return CriterionScore{Message: "unknown metadata type"}
+	}
+
+	var missing []string
+
+	for field, filled := range fields {
+		if !filled {
+			missing = append(missing, field)
+		}
+	}
+
+	sort.Strings(missing)
+
+	score := CriterionScore{Score: round(float64(len(fields)-len(missing)) / float64(len(fields)))}
+	if len(missing) > 0 {
+		score.Message = "missing: " + strings.Join(missing, ", ")
+	}
+
+	return score
+}
+
+func (s *Scorer) scoreThumbnail(md *iso1911x.MDMetadata) CriterionScore {
+	if md.GetThumbnailURL() == "" {
+		return CriterionScore{Message: "no thumbnail"}
+	}
+
+	return CriterionScore{Score: 1}
+}
+
+// scoreLicense gives the full score when a license from the data licenses codelist is found.
+func (s *Scorer) scoreLicense(md *iso1911x.MDMetadata) CriterionScore {
+	for _, oc := range md.GetOtherConstraints() {
+		if license, ok := s.Codelist.GetDataLicenseByURI(iso1911x.NormalizeXMLText(oc.Href)); ok {
+			return CriterionScore{Score: 1, Message: license.Value}
+		}
+	}
+
+	return CriterionScore{Message: "no license from the data licenses codelist found"}
+}
+
+// scoreControlledKeywords scores the fraction of keywords that come from a controlled vocabulary.
+func (s *Scorer) scoreControlledKeywords(md *iso1911x.MDMetadata) CriterionScore {
+	total, controlled := md.CountControlledKeywords()
+	if total == 0 {
+		return CriterionScore{Message: "no keywords"}
+	}
+
+	return CriterionScore{
+		Score:   round(float64(controlled) / float64(total)),
+		Message: fmt.Sprintf("%d of %d keywords from a controlled vocabulary", controlled, total),
+	}
+}
+
+func (s *Scorer) scoreAbstractLength(md *iso1911x.MDMetadata) CriterionScore {
+	var abstract string
+
+	switch md.GetMetaDataType() {
+	case iso1911x.Dataset:
+		if md.IdentificationInfo.MDDataIdentification != nil {
+			abstract = md.IdentificationInfo.MDDataIdentification.Abstract
+		}
+	case iso1911x.Service:
+		if md.IdentificationInfo.SVServiceIdentification != nil {
+			abstract = md.IdentificationInfo.SVServiceIdentification.Abstract
+		}
+	}
+
+	length := len([]rune(iso1911x.NormalizeXMLText(abstract)))
+
+	return CriterionScore{
+		Score:   round(min(1, float64(length)/float64(s.Config.MinAbstractLength))),
+		Message: fmt.Sprintf("%d characters", length),
+	}
+}
+
+// scoreRevisionRecency gives the full score for a revision date within FreshDays,
+// decreasing linearly to no score at MaxAgeDays. The creation date is used when there is no revision date.
+func (s *Scorer) scoreRevisionRecency(md *iso1911x.MDMetadata) CriterionScore {
+	date := md.GetRevisionDate()
+	if date == "" {
+		date = md.GetCreationDate()
+	}
+
+	if date == "" {
+		return CriterionScore{Message: "no revision or creation date"}
+	}
+
+	parsed, err := parseDate(date)
+	if err != nil {
+		return CriterionScore{Message: "invalid date: " + date}
+	}
+
+	ageDays := s.Now().Sub(parsed).Hours() / hoursPerDay
+	fresh := float64(s.Config.FreshDays)
+	maxAge := float64(s.Config.MaxAgeDays)
+
+	var score float64
+
+	switch {
+	case ageDays <= fresh:
+		score = 1
+	case ageDays < maxAge:
+		score = (maxAge - ageDays) / (maxAge - fresh)
+	}
+
+	return CriterionScore{Score: round(score), Message: date}
+}
+
+func parseDate(value string) (time.Time, error) {
+	for _, layout := range []string{time.DateOnly, time.RFC3339, "2006-01-02T15:04:05"} {
+		if t, err := time.Parse(layout, value); err == nil {
+			return t, nil
+		}
+	}
+
+	return time.Time{}, fmt.Errorf("unsupported date format: %s", value)
+}
+
+// round rounds to two decimals.
+func round(v float64) float64 {
+	//nolint:mnd
+	return math.Round(v*100) / 100
+}
@@ -0,0 +1,161 @@
+package scoring
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/pdok/pdok-metadata-tool/v2/internal/common"
+	"github.com/pdok/pdok-metadata-tool/v2/internal/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestScorer_ScoreRecord(t *testing.T) {
+	examples := filepath.Join(common.GetProjectRoot(), "examples")
+
+	tests := []struct {
+		name      string
+		file      string
+		wantScore float64
+		want      map[Criterion]float64
+	}{
+		{
+			name:      "Dataset without dates",
+			file:      filepath.Join(examples, "ISO19115", "5951efa2-1ff3-4763-a966-a2f5497679ee.xml"),
+			wantScore: 70.67,
+			want: map[Criterion]float64{
+				OptionalFields:     0.88,
+				Thumbnail:          1,
+				License:            1,
+				ControlledKeywords: 0.36,
+				AbstractLength:     1,
+				RevisionRecency:    0,
+			},
+		},
+		{
+			name:      "Recently revised service",
+			file:      filepath.Join(examples, "ISO19119", "dae8f9e3-99af-4d21-9feb-29f2a1693077.xml"),
+			wantScore: 95.17,
+			want: map[Criterion]float64{
+				OptionalFields:     1,
+				Thumbnail:          1,
+				License:            1,
+				ControlledKeywords: 0.8,
+				AbstractLength:     0.91,
+				RevisionRecency:    1,
+			},
+		},
+	}
+
+	scorer := newTestScorer(t, DefaultConfig())
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			md := testutil.LoadMDMetadata(t, tt.file)
+			score := scorer.ScoreRecord(&md)
+
+			got := map[Criterion]float64{}
+			for _, criterion := range score.Criteria {
+				got[criterion.Criterion] = criterion.Score
+			}
+
+			assert.Equal(t, tt.want, got)
+			assert.InDelta(t, tt.wantScore, score.Score, 0.001)
+		})
+	}
+}
+
+func TestScorer_ScoreRevisionRecency(t *testing.T) {
+	md := testutil.LoadMDMetadata(t, filepath.Join(
+		common.GetProjectRoot(), "examples", "ISO19119", "dae8f9e3-99af-4d21-9feb-29f2a1693077.xml"))
+
+	tests := []struct {
+		name string
+		now  time.Time
+		want float64
+	}{
+		{name: "Fresh", now: time.Date(2026, 6, 1, 0, 0, 0, 0, time.UTC), want: 1},
+		{name: "Halfway", now: time.Date(2027, 12, 9, 0, 0, 0, 0, time.UTC), want: 0.5},
+		{name: "Outdated", now: time.Date(2031, 1, 1, 0, 0, 0, 0, time.UTC), want: 0},
+	}
+
+	config := DefaultConfig()
+	config.FreshDays = 365
+	config.MaxAgeDays = 3 * 365
+
+	scorer := newTestScorer(t, config)
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			scorer.Now = func() time.Time { return tt.now }
+			assert.InDelta(t, tt.want, scorer.scoreRevisionRecency(&md).Score, 0.01)
+		})
+	}
+}
+
+func TestLoadConfig(t *testing.T) {
+	dir := t.TempDir()
+
+	valid := filepath.Join(dir, "valid.yaml")
+	require.NoError(t, os.WriteFile(valid, []byte("weights:\n  thumbnail: 0\n  license: 3\nminAbstractLength: 100\n"), 0o600))
+
+	config, err := LoadConfig(valid)
+	require.NoError(t, err)
+	assert.InDelta(t, 0, config.Weights.Thumbnail, 0)
+	assert.InDelta(t, 3, config.Weights.License, 0)
+	assert.InDelta(t, 1, config.Weights.OptionalFields, 0)
+	assert.Equal(t, 100, config.MinAbstractLength)
+	assert.Equal(t, defaultMaxAgeDays, config.MaxAgeDays)
+
+	invalid := filepath.Join(dir, "invalid.yaml")
+	require.NoError(t, os.WriteFile(invalid, []byte("weights:\n  license: -1\n"), 0o600))
+
+	_, err = LoadConfig(invalid)
+	require.EqualError(t, err, "invalid weight for license: must not be negative")
+}
+
+func TestAggregateByOrganisation(t *testing.T) {
+	records := []RecordScore{
+		{OrganisationName: "Org A", Score: 40, Criteria: []CriterionScore{{Criterion: Thumbnail, Score: 0}}},
+		{OrganisationName: "Org B", Score: 90, Criteria: []CriterionScore{{Criterion: Thumbnail, Score: 1}}},
+		{OrganisationName: "Org A", Score: 60, Criteria: []CriterionScore{{Criterion: Thumbnail, Score: 1}}},
+	}
+
+	expected := []OrganisationScore{
+		{OrganisationName: "Org B", Records: 1, Average: 90, Min: 90, Max: 90, Criteria: map[Criterion]float64{Thumbnail: 1}},
+		{OrganisationName: "Org A", Records: 2, Average: 50, Min: 40, Max: 60, Criteria: map[Criterion]float64{Thumbnail: 0.5}},
+	}
+	assert.Equal(t, expected, AggregateByOrganisation(records))
+}
+
+func TestWriteHTML(t *testing.T) {
+	records := []RecordScore{{
+		MetadataID:       "id-1",
+		Title:            "<Title>",
+		OrganisationName: "Org A",
+		Score:            85,
+		Criteria:         []CriterionScore{{Criterion: Thumbnail, Score: 1}},
+	}}
+
+	var buf bytes.Buffer
+	require.NoError(t, WriteHTML(&buf, records, AggregateByOrganisation(records)))
+
+	html := buf.String()
+	assert.Contains(t, html, "<td>&lt;Title&gt;</td>")
+	assert.Contains(t, html, `<td class="score good">85.0</td>`)
+	assert.Contains(t, html, `<td class="score" title="">100%</td>`)
+}
+
+func newTestScorer(t *testing.T, config Config) *Scorer {
+	t.Helper()
+
+	scorer, err := NewScorer(config)
+	require.NoError(t, err)
+
+	scorer.Now = func() time.Time { return time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC) }
+
+	return scorer
+}
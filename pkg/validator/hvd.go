@@ -0,0 +1,220 @@
+package validator
+
+import (
+	"slices"
+	"strings"
+
+	"github.com/pdok/pdok-metadata-tool/v2/pkg/model/codelist"
+	"github.com/pdok/pdok-metadata-tool/v2/pkg/model/hvd"
+	"github.com/pdok/pdok-metadata-tool/v2/pkg/model/iso1911x"
+)
+
+// Requirement IDs for the HVD obligations of Implementing Regulation (EU) 2023/138
+// as described in the Dutch "handreiking HVD".
+const (
+	HVDCategoryExists = "hvd/category-exists"
+	HVDCategoryParent = "hvd/category-parent"
+	HVDOpenLicense    = "hvd/open-license"
+	HVDAPIAvailable   = "hvd/api-available"
+	HVDContact        = "hvd/contact"
+	HVDDocumentation  = "hvd/documentation"
+)
+
+// openLicensePrefix is the prefix of the DataLicense values that are open (CC0, PDM and CC-BY).
+const openLicensePrefix = "Open data"
+
+// documentationProtocols holds protocols of online resources that document an API.
+// OGC API landing pages are included as they link to the API definition.
+var documentationProtocols = []string{"OAS", "OGC:API"}
+
+// documentationMarkers holds terms in the name or description of an online resource that mark it as documentation.
+var documentationMarkers = []string{"documentation", "documentatie", "landingpage", "landing page", "openapi"}
+
+// HVDValidator checks metadata records with HVD categories against the HVD obligations.
+type HVDValidator struct {
+	Codelist *codelist.Codelist
+	HVDRepo  hvd.CategoryProvider
+}
+
+// NewHVDValidator creates a new instance of HVDValidator.
+func NewHVDValidator(hvdRepo hvd.CategoryProvider) (*HVDValidator, error) {
+	cl, err := codelist.NewCodelist()
+	if err != nil {
+		return nil, err
+	}
+
+	return &HVDValidator{Codelist: cl, HVDRepo: hvdRepo}, nil
+}
+
+// Validate checks a single record. For datasets, coupledServices holds the service records
+// that operate on the dataset. Records without HVD categories are reported as not applicable.
+func (v *HVDValidator) Validate(
+	md *iso1911x.MDMetadata,
+	coupledServices []*iso1911x.MDMetadata,
+) RecordReport {
+	report := newRecordReport(md)
+
+	codes := getHVDCategoryCodes(md)
+	if len(codes) == 0 {
+		return report
+	}
+
+	report.Applicable = true
+	report.Results = append(v.checkCategories(codes),
+		v.checkOpenLicense(md),
+		checkAPIAvailable(md, coupledServices),
+		check(
+			HVDContact,
+			"A point of contact with an e-mail address or contact URL is given",
+			md.GetContactEmail() != "" || md.GetContactURL() != "",
+			"no e-mail address or contact URL found for the point of contact",
+		),
+		checkDocumentation(md, coupledServices),
+	)
+
+	return report
+}
+
+// ValidateAll checks multiple records. Datasets are coupled to the services among
+// the given records that refer to them through operatesOn.
+func (v *HVDValidator) ValidateAll(mds []iso1911x.MDMetadata) []RecordReport {
+	operatedOnBy := map[string][]*iso1911x.MDMetadata{}
+
+	for i := range mds {
+		md := &mds[i]
+		if md.GetMetaDataType() != iso1911x.Service || md.IdentificationInfo.SVServiceIdentification == nil {
+			continue
+		}
+
+		for _, id := range md.GetOperatesOnForService() {
+			operatedOnBy[id] = append(operatedOnBy[id], md)
+		}
+	}
+
+	reports := make([]RecordReport, 0, len(mds))
+	for i := range mds {
+		reports = append(reports, v.Validate(&mds[i], operatedOnBy[iso1911x.NormalizeXMLText(mds[i].UUID)]))
+	}
+
+	return reports
+}
+
+func (v *HVDValidator) checkCategories(codes []string) []RequirementResult {
+	var unknown, missingParents []string
+
+	for _, code := range codes {
+		category, err := v.HVDRepo.GetHVDCategoryByCode(code)
+		if err != nil || category == nil {
+			unknown = append(unknown, code)
+
+			continue
+		}
+
+		if category.Parent == "" {
+			continue
+		}
+
+		if parent, err := v.HVDRepo.GetHVDCategoryByCode(category.Parent); err != nil || parent == nil {
+			missingParents = append(missingParents, category.Parent)
+		}
+	}
+
+	return []RequirementResult{
+		check(
+			HVDCategoryExists,
+			"All HVD category codes exist in the HVD category thesaurus",
+			len(unknown) == 0,
+			"unknown HVD category codes: "+strings.Join(unknown, ", "),
+		),
+		check(
+			HVDCategoryParent,
+			"The parents of all HVD categories exist in the HVD category thesaurus",
+			len(missingParents) == 0,
+			"unknown parent HVD category codes: "+strings.Join(missingParents, ", "),
+		),
+	}
+}
+
+func (v *HVDValidator) checkOpenLicense(md *iso1911x.MDMetadata) RequirementResult {
+	message := "no license from the data licenses codelist found"
+
+	for _, oc := range md.GetOtherConstraints() {
+		license, ok := v.Codelist.GetDataLicenseByURI(iso1911x.NormalizeXMLText(oc.Href))
+		if !ok {
+			continue
+		}
+
+		if strings.HasPrefix(license.Value, openLicensePrefix) {
+			return check(HVDOpenLicense, "An open license (CC0, PDM or CC-BY) is given", true, "")
+		}
+
+		message = "license '" + license.Value + "' is not an open license"
+	}
+
+	return check(HVDOpenLicense, "An open license (CC0, PDM or CC-BY) is given", false, message)
+}
+
+func checkAPIAvailable(md *iso1911x.MDMetadata, coupledServices []*iso1911x.MDMetadata) RequirementResult {
+	description := "The data is available through an API, coupled with operatesOn"
+
+	if md.GetMetaDataType() == iso1911x.Service {
+		return check(
+			HVDAPIAvailable,
+			description,
+			md.IdentificationInfo.SVServiceIdentification != nil &&
+				len(md.GetOperatesOnForService()) > 0,
+			"no operatesOn reference to a dataset found",
+		)
+	}
+
+	return check(
+		HVDAPIAvailable,
+		description,
+		len(coupledServices) > 0,
+		"no service among the checked records operates on this dataset",
+	)
+}
+
+// checkDocumentation checks for API documentation on the record itself or, for datasets, on a coupled service.
+func checkDocumentation(md *iso1911x.MDMetadata, coupledServices []*iso1911x.MDMetadata) RequirementResult {
+	ok := hasDocumentation(md) || slices.ContainsFunc(coupledServices, hasDocumentation)
+
+	return check(
+		HVDDocumentation,
+		"A link to the API documentation (i.e. OpenAPI definition or landing page) is given",
+		ok,
+		"no online resource with API documentation found",
+	)
+}
+
+func hasDocumentation(md *iso1911x.MDMetadata) bool {
+	return slices.ContainsFunc(md.GetOnlineResources(), func(ol iso1911x.OnlineResource) bool {
+		if ol.URL == "" {
+			return false
+		}
+
+		for _, protocol := range documentationProtocols {
+			if strings.HasPrefix(ol.Protocol, protocol) {
+				return true
+			}
+		}
+
+		text := strings.ToLower(ol.Name + " " + ol.Description)
+		for _, marker := range documentationMarkers {
+			if strings.Contains(text, marker) {
+				return true
+			}
+		}
+
+		return false
+	})
+}
+
+// getHVDCategoryCodes returns the raw HVD category codes as used in the keywords, without lookup.
+func getHVDCategoryCodes(md *iso1911x.MDMetadata) (codes []string) {
+	for _, category := range md.GetHVDCategories(nil) {
+		codes = append(codes, category.ID)
+	}
+
+	return codes
+}
@@ -0,0 +1,148 @@
+package validator
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"github.com/pdok/pdok-metadata-tool/v2/internal/common"
+	"github.com/pdok/pdok-metadata-tool/v2/internal/testutil"
+	"github.com/pdok/pdok-metadata-tool/v2/pkg/model/hvd"
+	"github.com/pdok/pdok-metadata-tool/v2/pkg/model/iso1911x"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// mockCategoryProvider is a stand-in for the HVDRepository without downloading the thesaurus.
+type mockCategoryProvider map[string]hvd.HVDCategory
+
+func (m mockCategoryProvider) GetHVDCategoryByCode(code string) (*hvd.HVDCategory, error) {
+	category, ok := m[code]
+	if !ok {
+		return nil, errors.New("no HVD category found for code: " + code)
+	}
+
+	return &category, nil
+}
+
+func getMockCategoryProvider() mockCategoryProvider {
+	return mockCategoryProvider{
+		"c_ac64a52d": {ID: "c_ac64a52d", LabelDutch: "Geospatiale data"},
+		"c_b79e35eb": {ID: "c_b79e35eb", LabelDutch: "Mobiliteit"},
+		"c_dd313021": {ID: "c_dd313021", LabelDutch: "Aardobservatie en milieu"},
+		"c_e1da4e07": {ID: "c_e1da4e07", Parent: "c_ac64a52d", LabelDutch: "Administratieve eenheden"},
+	}
+}
+
+func TestHVDValidator_ValidateAll(t *testing.T) {
+	root := common.GetProjectRoot()
+	examples := filepath.Join(root, "examples")
+	generated := filepath.Join(root, "pkg", "generator", "iso19119", "testdata", "expected")
+
+	mds := []iso1911x.MDMetadata{
+		testutil.LoadMDMetadata(t, filepath.Join(examples, "ISO19115", "5951efa2-1ff3-4763-a966-a2f5497679ee.xml")),
+		testutil.LoadMDMetadata(t, filepath.Join(examples, "ISO19119", "dae8f9e3-99af-4d21-9feb-29f2a1693077.xml")),
+		testutil.LoadMDMetadata(t, filepath.Join(generated, "inspire_hvd_complex_oaf_interoperable.xml")),
+		testutil.LoadMDMetadata(t, filepath.Join(generated, "regular_wms.xml")),
+	}
+
+	v, err := NewHVDValidator(getMockCategoryProvider())
+	require.NoError(t, err)
+
+	reports := v.ValidateAll(mds)
+	require.Len(t, reports, len(mds))
+
+	tests := []struct {
+		name           string
+		report         RecordReport
+		wantApplicable bool
+		wantFailures   []string
+	}{
+		{
+			name:           "Harvested HVD dataset coupled to a WMS without documentation",
+			report:         reports[0],
+			wantApplicable: true,
+			wantFailures:   []string{HVDDocumentation},
+		},
+		{
+			name:           "Harvested HVD WMS without documentation",
+			report:         reports[1],
+			wantApplicable: true,
+			wantFailures:   []string{HVDDocumentation},
+		},
+		{
+			name:           "Generated HVD OGC API Features",
+			report:         reports[2],
+			wantApplicable: true,
+		},
+		{
+			name:           "Generated regular WMS is not HVD",
+			report:         reports[3],
+			wantApplicable: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.wantApplicable, tt.report.Applicable)
+			assert.Equal(t, tt.wantFailures, getIDsByStatus(tt.report, Fail))
+		})
+	}
+}
+
+func TestHVDValidator_Validate(t *testing.T) {
+	root := common.GetProjectRoot()
+	dataset := testutil.LoadMDMetadata(t, filepath.Join(
+		root, "examples", "ISO19115", "5951efa2-1ff3-4763-a966-a2f5497679ee.xml"))
+
+	tests := []struct {
+		name         string
+		provider     mockCategoryProvider
+		wantFailures []string
+		wantMessages []string
+	}{
+		{
+			name:         "Uncoupled dataset with known category",
+			provider:     getMockCategoryProvider(),
+			wantFailures: []string{HVDAPIAvailable, HVDDocumentation},
+		},
+		{
+			name:         "Uncoupled dataset with unknown category",
+			provider:     mockCategoryProvider{},
+			wantFailures: []string{HVDCategoryExists, HVDAPIAvailable, HVDDocumentation},
+			wantMessages: []string{"unknown HVD category codes: c_b79e35eb"},
+		},
+		{
+			name: "Uncoupled dataset with unknown parent category",
+			provider: mockCategoryProvider{
+				"c_b79e35eb": {ID: "c_b79e35eb", Parent: "c_missing"},
+			},
+			wantFailures: []string{HVDCategoryParent, HVDAPIAvailable, HVDDocumentation},
+			wantMessages: []string{"unknown parent HVD category codes: c_missing"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v, err := NewHVDValidator(tt.provider)
+			require.NoError(t, err)
+
+			report := v.Validate(&dataset, nil)
+
+			assert.True(t, report.Applicable)
+			assert.Equal(t, tt.wantFailures, getIDsByStatus(report, Fail))
+
+			for _, message := range tt.wantMessages {
+				assert.Contains(t, getMessages(report), message)
+			}
+		})
+	}
+}
+
+func getMessages(report RecordReport) (messages []string) {
+	for _, result := range report.Results {
+		messages = append(messages, result.Message)
+	}
+
+	return messages
+}
@@ -0,0 +1,281 @@
+package validator
+
+import (
+	"slices"
+	"strings"
+
+	"github.com/pdok/pdok-metadata-tool/v2/pkg/model/codelist"
+	"github.com/pdok/pdok-metadata-tool/v2/pkg/model/iso1911x"
+)
+
+// Requirement IDs for the INSPIRE metadata Technical Guidelines 2.0 conformance classes.
+// The prefix denotes the conformance class: common, dataset, service (network services) and sds (spatial data services).
+const (
+	InspireCommonLimitationsOnPublicAccess = "inspire-common/limitations-on-public-access"
+	InspireDatasetConformity               = "inspire-dataset/conformity-1089-2010"
+	InspireDatasetResourceLocator          = "inspire-dataset/resource-locator"
+	InspireServiceSpatialDataServiceType   = "inspire-service/spatial-data-service-type"
+	InspireServiceCategory                 = "inspire-service/category"
+	InspireServiceResourceLocator          = "inspire-service/resource-locator"
+	InspireServiceCoupledResource          = "inspire-service/coupled-resource"
+	InspireServiceConformity               = "inspire-service/conformity"
+	InspireSDSServiceType                  = "inspire-sds/spatial-data-service-type"
+	InspireSDSConformanceClass             = "inspire-sds/conformance-class"
+	InspireSDSConformity                   = "inspire-sds/conformity-1089-2010"
+)
+
+const (
+	inspireRegulation1089Title = "1089/2010"
+	inspireRegulation1089Href  = "2010/1089"
+	inspireRegulation976Title  = "976/2009"
+	inspireRegulation976Href   = "2009/976"
+	spatialDataServiceTypeSDS  = "other"
+)
+
+// spatialDataServiceTypes holds the values of the INSPIRE SpatialDataServiceType codelist.
+var spatialDataServiceTypes = []string{
+	"discovery",
+	"view",
+	"download",
+	"transformation",
+	"invoke",
+	spatialDataServiceTypeSDS,
+}
+
+// coupledServiceTypes holds the service types that must declare the datasets they operate on.
+var coupledServiceTypes = []string{"view", "download"}
+
+// InspireValidator checks metadata records against the INSPIRE metadata Technical Guidelines 2.0.
+type InspireValidator struct {
+	Codelist *codelist.Codelist
+}
+
+// NewInspireValidator creates a new instance of InspireValidator.
+func NewInspireValidator() (*InspireValidator, error) {
+	cl, err := codelist.NewCodelist()
+	if err != nil {
+		return nil, err
+	}
+
+	return &InspireValidator{Codelist: cl}, nil
+}
+
+// Validate checks a single dataset or service metadata record.
+// Records that are not INSPIRE are reported as not applicable without results.
+func (v *InspireValidator) Validate(md *iso1911x.MDMetadata) RecordReport {
+	report := newRecordReport(md)
+
+	switch md.GetMetaDataType() {
+	case iso1911x.Service:
+		if md.IdentificationInfo.SVServiceIdentification == nil || !v.isInspireService(md) {
+			return report
+		}
+
+		report.Applicable = true
+		report.Results = v.validateService(md)
+	case iso1911x.Dataset:
+		if md.IdentificationInfo.MDDataIdentification == nil || !v.isInspireDataset(md) {
+			return report
+		}
+
+		report.Applicable = true
+		report.Results = v.validateDataset(md)
+	}
+
+	return report
+}
+
+// ValidateAll checks multiple metadata records.
+func (v *InspireValidator) ValidateAll(mds []iso1911x.MDMetadata) []RecordReport {
+	reports := make([]RecordReport, 0, len(mds))
+	for i := range mds {
+		reports = append(reports, v.Validate(&mds[i]))
+	}
+
+	return reports
+}
+
+// GetSDSConformanceClass returns the declared spatial data service conformance class
+// (invocable, interoperable or harmonised), or an empty string when none is declared.
+func (v *InspireValidator) GetSDSConformanceClass(md *iso1911x.MDMetadata) string {
+	statement := v.getSDSConformityStatement(md)
+	if statement == nil {
+		return ""
+	}
+
+	for _, category := range v.Codelist.SDSServiceCategory {
+		if normalizeScheme(statement.Href) == normalizeScheme(category.URI) {
+			return category.Value
+		}
+	}
+
+	return ""
+}
+
+func (v *InspireValidator) validateDataset(md *iso1911x.MDMetadata) []RequirementResult {
+	results := []RequirementResult{
+		check(
+			InspireDatasetConformity,
+			"A conformity statement citing Commission Regulation (EU) No 1089/2010 is given",
+			slices.ContainsFunc(md.GetConformityStatements(), citesRegulation1089),
+			"no conformity statement citing Regulation 1089/2010 found in the data quality section",
+		),
+		checkLimitationsOnPublicAccess(md),
+	}
+
+	// The resource locator is conditional for datasets: mandatory when a linkage is available.
+	locator := check(
+		InspireDatasetResourceLocator,
+		"A resource locator (linkage to a service or download) is given when available",
+		hasResourceLocator(md),
+		"no resource locator found in the distribution info",
+	)
+	if locator.Status == Fail {
+		locator.Status = Warning
+	}
+
+	return append(results, locator)
+}
+
+func (v *InspireValidator) validateService(md *iso1911x.MDMetadata) []RequirementResult {
+	serviceType := strings.ToLower(
+		iso1911x.NormalizeXMLText(md.IdentificationInfo.SVServiceIdentification.ServiceType),
+	)
+	sdsClass := v.GetSDSConformanceClass(md)
+
+	results := []RequirementResult{
+		check(
+			InspireServiceSpatialDataServiceType,
+			"The service type is a value from the INSPIRE SpatialDataServiceType codelist",
+			slices.Contains(spatialDataServiceTypes, serviceType),
+			"service type '"+serviceType+"' is not in the SpatialDataServiceType codelist",
+		),
+		check(
+			InspireServiceCategory,
+			"A keyword from the INSPIRE SpatialDataServiceCategory codelist is given",
+			len(md.GetSpatialDataServiceCategories()) > 0,
+			"no SpatialDataServiceCategory keyword found",
+		),
+		check(
+			InspireServiceResourceLocator,
+			"A resource locator (linkage to the service access point) is given",
+			hasResourceLocator(md),
+			"no resource locator found in the distribution info",
+		),
+		checkLimitationsOnPublicAccess(md),
+	}
+
+	if slices.Contains(coupledServiceTypes, serviceType) || sdsClass != "" {
+		results = append(results, check(
+			InspireServiceCoupledResource,
+			"The coupled resources (operatesOn) the service operates on are given",
+			len(md.IdentificationInfo.SVServiceIdentification.OperatesOn) > 0,
+			"no operatesOn reference to a dataset found",
+		))
+	}
+
+	if serviceType == spatialDataServiceTypeSDS || sdsClass != "" {
+		return append(results, v.validateSDS(md, serviceType, sdsClass)...)
+	}
+
+	return append(results, check(
+		InspireServiceConformity,
+		"A conformity statement citing Commission Regulation (EC) No 976/2009 on network services is given",
+		slices.ContainsFunc(md.GetConformityStatements(), citesRegulation976),
+		"no conformity statement citing Regulation 976/2009 found in the data quality section",
+	))
+}
+
+func (v *InspireValidator) validateSDS(
+	md *iso1911x.MDMetadata,
+	serviceType string,
+	sdsClass string,
+) []RequirementResult {
+	statement := v.getSDSConformityStatement(md)
+
+	results := []RequirementResult{
+		check(
+			InspireSDSServiceType,
+			"The service type of a spatial data service is 'other'",
+			serviceType == spatialDataServiceTypeSDS,
+			"service type '"+serviceType+"' declared for a spatial data service",
+		),
+		check(
+			InspireSDSConformanceClass,
+			"Conformance to the invocable, interoperable or harmonised SDS conformance class is declared",
+			statement != nil && statement.Pass == "true",
+			"no passed conformity statement for an SDS conformance class found",
+		),
+	}
+
+	if sdsClass == "interoperable" || sdsClass == "harmonised" {
+		results = append(results, check(
+			InspireSDSConformity,
+			"A conformity statement citing Commission Regulation (EU) No 1089/2010 is given",
+			slices.ContainsFunc(md.GetConformityStatements(), citesRegulation1089),
+			"no conformity statement citing Regulation 1089/2010 found for an "+sdsClass+" SDS",
+		))
+	}
+
+	return results
+}
+
+func (v *InspireValidator) isInspireDataset(md *iso1911x.MDMetadata) bool {
+	return md.GetInspireVariantForDataset() != "" || len(md.GetInspireThemes()) > 0
+}
+
+func (v *InspireValidator) isInspireService(md *iso1911x.MDMetadata) bool {
+	return len(md.GetInspireThemes()) > 0 ||
+		len(md.GetSpatialDataServiceCategories()) > 0 ||
+		v.getSDSConformityStatement(md) != nil
+}
+
+func (v *InspireValidator) getSDSConformityStatement(
+	md *iso1911x.MDMetadata,
+) *iso1911x.ConformityStatement {
+	statements := md.GetConformityStatements()
+	for i := range statements {
+		for _, category := range v.Codelist.SDSServiceCategory {
+			if normalizeScheme(statements[i].Href) == normalizeScheme(category.URI) {
+				return &statements[i]
+			}
+		}
+	}
+
+	return nil
+}
+
+func checkLimitationsOnPublicAccess(md *iso1911x.MDMetadata) RequirementResult {
+	return check(
+		InspireCommonLimitationsOnPublicAccess,
+		"Limitations on public access are given using the INSPIRE LimitationsOnPublicAccess codelist",
+		len(md.GetLimitationsOnPublicAccess()) > 0,
+		"no otherConstraints anchor from the LimitationsOnPublicAccess codelist found",
+	)
+}
+
+func citesRegulation1089(statement iso1911x.ConformityStatement) bool {
+	return strings.Contains(statement.Title, inspireRegulation1089Title) ||
+		strings.Contains(statement.Href, inspireRegulation1089Href)
+}
+
+func citesRegulation976(statement iso1911x.ConformityStatement) bool {
+	return strings.Contains(statement.Title, inspireRegulation976Title) ||
+		strings.Contains(statement.Href, inspireRegulation976Href)
+}
+
+func hasResourceLocator(md *iso1911x.MDMetadata) bool {
+	if md.GetMetaDataType() == iso1911x.Dataset {
+		return len(md.GetResourceLocatorsForDataset()) > 0
+	}
+
+	return slices.ContainsFunc(
+		md.GetServiceEndpointsForService(),
+		func(ep iso1911x.ServiceEndpoint) bool { return ep.URL != "" },
+	)
+}
+
+// normalizeScheme makes URIs comparable regardless of http or https.
+func normalizeScheme(uri string) string {
+	return strings.Replace(strings.TrimSpace(uri), "https://", "http://", 1)
+}
@@ -0,0 +1,152 @@
+package validator
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/pdok/pdok-metadata-tool/v2/internal/common"
+	"github.com/pdok/pdok-metadata-tool/v2/internal/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestInspireValidator_Validate(t *testing.T) {
+	root := common.GetProjectRoot()
+	generated := filepath.Join(root, "pkg", "generator", "iso19119", "testdata", "expected")
+	examples := filepath.Join(root, "examples")
+
+	tests := []struct {
+		name           string
+		file           string
+		wantApplicable bool
+		wantSDSClass   string
+		wantFailures   []string
+		wantWarnings   []string
+	}{
+		{
+			name:           "Generated INSPIRE harmonised WMS",
+			file:           filepath.Join(generated, "inspire_harmonised_wms.xml"),
+			wantApplicable: true,
+		},
+		{
+			name:           "Generated INSPIRE harmonised WFS (interoperable SDS)",
+			file:           filepath.Join(generated, "inspire_harmonised_wfs.xml"),
+			wantApplicable: true,
+			wantSDSClass:   "interoperable",
+		},
+		{
+			name:           "Generated INSPIRE HVD WFS (invocable SDS)",
+			file:           filepath.Join(generated, "inspire_hvd_complex_wfs_invocable.xml"),
+			wantApplicable: true,
+			wantSDSClass:   "invocable",
+		},
+		{
+			name:           "Generated regular WMS is not INSPIRE",
+			file:           filepath.Join(generated, "regular_wms.xml"),
+			wantApplicable: false,
+		},
+		{
+			name:           "Harvested service without limitations and conformity",
+			file:           filepath.Join(examples, "ISO19119", "392e6a4e-5274-11ea-954f-080027325297.xml"),
+			wantApplicable: true,
+			wantFailures: []string{
+				InspireCommonLimitationsOnPublicAccess,
+				InspireServiceConformity,
+			},
+		},
+		{
+			name:           "Network service citing another specification than Regulation 976/2009",
+			file:           filepath.Join(root, "pkg", "validator", "testdata", "inspire_service_unrelated_conformity.xml"),
+			wantApplicable: true,
+			wantFailures:   []string{InspireServiceConformity},
+		},
+		{
+			name:           "Example service with type other but no SDS conformance class",
+			file:           filepath.Join(examples, "ISO19119", "Voorbeeld_Metadata_Services_2019_max.xml"),
+			wantApplicable: true,
+			wantFailures: []string{
+				InspireServiceCategory,
+				InspireSDSConformanceClass,
+			},
+		},
+		{
+			name:           "Harmonised dataset without resource locator",
+			file:           filepath.Join(examples, "ISO19115", "Invasieve_Exoten_INSPIRE_geharmoniseerd.xml"),
+			wantApplicable: true,
+			wantWarnings:   []string{InspireDatasetResourceLocator},
+		},
+		{
+			name:           "Harmonised dataset with resource locators",
+			file:           filepath.Join(examples, "ISO19115", "Wetlands_INSPIRE_geharmoniseerd.xml"),
+			wantApplicable: true,
+		},
+		{
+			name:           "Harmonised dataset with resource locator of a distributor",
+			file:           filepath.Join(root, "pkg", "validator", "testdata", "inspire_dataset_distributor_locator.xml"),
+			wantApplicable: true,
+		},
+		{
+			name:           "Non INSPIRE dataset",
+			file:           filepath.Join(examples, "ISO19115", "25d77eb3-c4f6-4e6a-b974-8a93a1ace20a.xml"),
+			wantApplicable: false,
+		},
+	}
+
+	v, err := NewInspireValidator()
+	require.NoError(t, err)
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			md := testutil.LoadMDMetadata(t, tt.file)
+			report := v.Validate(&md)
+
+			assert.Equal(t, tt.wantApplicable, report.Applicable)
+			assert.Equal(t, tt.wantSDSClass, v.GetSDSConformanceClass(&md))
+			assert.Equal(t, tt.wantFailures, getIDsByStatus(report, Fail))
+			assert.Equal(t, tt.wantWarnings, getIDsByStatus(report, Warning))
+			assert.Equal(t, len(tt.wantFailures) == 0, report.Passed())
+
+			if !tt.wantApplicable {
+				assert.Empty(t, report.Results)
+			}
+		})
+	}
+}
+
+func TestSummarizeByOrganisation(t *testing.T) {
+	reports := []RecordReport{
+		{OrganisationName: "B", Applicable: true, Results: []RequirementResult{{ID: "x", Status: Pass}}},
+		{OrganisationName: "A", Applicable: true, Results: []RequirementResult{{ID: "x", Status: Fail}}},
+		{OrganisationName: "A", Applicable: true, Results: []RequirementResult{
+			{ID: "x", Status: Fail},
+			{ID: "y", Status: Fail},
+		}},
+		{OrganisationName: "A", Applicable: false},
+	}
+
+	summaries := SummarizeByOrganisation(reports)
+
+	require.Len(t, summaries, 2)
+	assert.Equal(t, OrganisationSummary{
+		OrganisationName: "A",
+		Records:          2,
+		NonCompliant:     2,
+		Failures:         map[string]int{"x": 2, "y": 1},
+	}, summaries[0])
+	assert.Equal(t, OrganisationSummary{
+		OrganisationName: "B",
+		Records:          1,
+		Compliant:        1,
+		Failures:         map[string]int{},
+	}, summaries[1])
+}
+
+func getIDsByStatus(report RecordReport, status Status) (ids []string) {
+	for _, result := range report.Results {
+		if result.Status == status {
+			ids = append(ids, result.ID)
+		}
+	}
+
+	return ids
+}
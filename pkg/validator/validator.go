@@ -0,0 +1,139 @@
+// Package validator provides conformance checks on metadata records, i.e. for INSPIRE and HVD requirements.
+// The checks work on the unified iso1911x.MDMetadata model, so they can be used on generated output
+// as well as on harvested records.
+package validator
+
+import (
+	"sort"
+
+	"github.com/pdok/pdok-metadata-tool/v2/pkg/model/iso1911x"
+)
+
+// Status holds the possible outcomes of a requirement check.
+type Status string
+
+// Values for Status.
+const (
+	Pass          Status = "PASS"
+	Fail          Status = "FAIL"
+	Warning       Status = "WARNING"
+	NotApplicable Status = "N/A"
+)
+
+// RequirementResult holds the outcome of a single requirement for a single record.
+type RequirementResult struct {
+	ID          string `json:"id"`
+	Description string `json:"description"`
+	Status      Status `json:"status"`
+	Message     string `json:"message,omitempty"`
+}
+
+// RecordReport holds all requirement results for a single metadata record.
+type RecordReport struct {
+	MetadataID       string                `json:"metadataId"`
+	Title            string                `json:"title"`
+	OrganisationName string                `json:"organisationName"`
+	MetadataType     iso1911x.MetadataType `json:"metadataType"`
+	// Applicable is false when the record is not in scope of the checked requirements, i.e. not INSPIRE.
+	Applicable bool                `json:"applicable"`
+	Results    []RequirementResult `json:"results"`
+}
+
+// Passed returns true when none of the requirement results failed.
+func (r *RecordReport) Passed() bool {
+	for _, result := range r.Results {
+		if result.Status == Fail {
+			return false
+		}
+	}
+
+	return true
+}
+
+// Failures returns the failed requirement results.
+func (r *RecordReport) Failures() (failures []RequirementResult) {
+	for _, result := range r.Results {
+		if result.Status == Fail {
+			failures = append(failures, result)
+		}
+	}
+
+	return failures
+}
+
+// OrganisationSummary aggregates record reports for a single organisation.
+type OrganisationSummary struct {
+	OrganisationName string         `json:"organisationName"`
+	Records          int            `json:"records"`
+	Compliant        int            `json:"compliant"`
+	NonCompliant     int            `json:"nonCompliant"`
+	Failures         map[string]int `json:"failures"` // Number of failures per requirement ID
+}
+
+// SummarizeByOrganisation aggregates the applicable reports per organisation, sorted by organisation name.
+func SummarizeByOrganisation(reports []RecordReport) []OrganisationSummary {
+	summaries := map[string]*OrganisationSummary{}
+
+	for i := range reports {
+		report := &reports[i]
+		if !report.Applicable {
+			continue
+		}
+
+		summary, ok := summaries[report.OrganisationName]
+		if !ok {
+			summary = &OrganisationSummary{
+				OrganisationName: report.OrganisationName,
+				Failures:         map[string]int{},
+			}
+			summaries[report.OrganisationName] = summary
+		}
+
+		summary.Records++
+
+		if report.Passed() {
+			summary.Compliant++
+		} else {
+			summary.NonCompliant++
+		}
+
+		for _, failure := range report.Failures() {
+			summary.Failures[failure.ID]++
+		}
+	}
+
+	result := make([]OrganisationSummary, 0, len(summaries))
+	for _, summary := range summaries {
+		result = append(result, *summary)
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].OrganisationName < result[j].OrganisationName
+	})
+
+	return result
+}
+
+func newRecordReport(md *iso1911x.MDMetadata) RecordReport {
+	return RecordReport{
+		MetadataID:       iso1911x.NormalizeXMLText(md.UUID),
+		Title:            md.GetTitle(),
+		OrganisationName: md.GetOrganisationName(),
+		MetadataType:     md.GetMetaDataType(),
+	}
+}
+
+func check(id, description string, ok bool, failMessage string) RequirementResult {
+	result := RequirementResult{
+		ID:          id,
+		Description: description,
+		Status:      Pass,
+	}
+
+	if !ok {
+		result.Status = Fail
+		result.Message = failMessage
+	}
+
+	return result
+}